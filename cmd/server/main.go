@@ -6,6 +6,7 @@ import (
 	"BlockCertify/internal/handlers"
 	"BlockCertify/internal/logger"
 	"BlockCertify/internal/middleware"
+	"BlockCertify/internal/models"
 	"BlockCertify/internal/repositories"
 	"BlockCertify/internal/routes"
 	"BlockCertify/internal/security"
@@ -24,11 +25,17 @@ func main() {
 	//Load conf
 	cfg, err := config.Load()
 	if err != nil {
-		slog.Error("Failed to load config: %v", err)
+		slog.Error("Failed to load config", "err", err)
 	}
 
 	r := gin.Default()
 
+	// The rate limiter keys on ClientIP, which only honours X-Forwarded-For
+	// from these proxies
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Fatalf("Failed to set trusted proxies: %v", err)
+	}
+
 	// CORS config
 	r.Use(cors.New(cors.Config{
 		AllowOrigins: []string{
@@ -49,19 +56,39 @@ func main() {
 
 	db, err := database.Init(cfg.Db)
 	if err != nil {
-		slog.Error("Failed to initialize database: %v", err)
+		slog.Error("Failed to initialize database", "err", err)
 	}
 
 	err = database.Migrate(db)
 	if err != nil {
-		slog.Error("Failed to migrate database: %v", err)
+		slog.Error("Failed to migrate database", "err", err)
+	}
+
+	err = database.TagNetwork(db, cfg.Blockchain.UntaggedNetwork)
+	if err != nil {
+		slog.Error("Failed to tag records with their network", "err", err)
+	}
+
+	err = database.TagStorage(db)
+	if err != nil {
+		slog.Error("Failed to tag diplomas with their document store", "err", err)
+	}
+
+	err = database.TagUniversity(db)
+	if err != nil {
+		slog.Error("Failed to link diplomas to their university", "err", err)
 	}
 
-	contractRepo, err := repositories.NewContractRepository(cfg)
+	err = repositories.PrepareDevNetwork(cfg)
 	if err != nil {
-		log.Fatalf("Failed to initialize contract repository: %v", err)
+		log.Fatalf("Failed to prepare the dev network: %v", err)
 	}
-	defer contractRepo.Close()
+
+	ledgers, err := repositories.NewLedgers(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize ledgers: %v", err)
+	}
+	defer ledgers.Close()
 
 	//Initialize repositories
 	userRepo := repositories.NewUserRepository(db)
@@ -69,6 +96,16 @@ func main() {
 	uniRepo := repositories.NewUniversityRepository(db)
 	facultyRepo := repositories.NewFacultyRepository(db)
 	departmentRepo := repositories.NewDepartmentRepository(db)
+	batchRepo := repositories.NewBatchJobRepository(db)
+	anchorRepo := repositories.NewAnchorRepository(db)
+	issuerKeyRepo := repositories.NewIssuerKeyRepository(db)
+	issuanceRepo := repositories.NewIssuanceRepository(db)
+	outboxRepo := repositories.NewOutboxRepository(db)
+	finalityRepo := repositories.NewFinalityRepository(db)
+	indexerRepo := repositories.NewIndexerRepository(db)
+	reconciliationRepo := repositories.NewReconciliationRepository(db)
+	documentKeyRepo := repositories.NewDocumentKeyRepository(db)
+	arweaveRepo := repositories.NewArweaveRepository(db)
 
 	tokenHelper := security.NewJWTHelper(
 		cfg.JWTConfig.JWTSecret,
@@ -76,31 +113,59 @@ func main() {
 	)
 
 	//Mock Services
-	//arweaveService := services.NewMockArweaveService("DEBUG_FAKE_ARWEAVE_TX")
+	//documentStores := services.NewDocumentStores(services.NewMockArweaveService("DEBUG_FAKE_ARWEAVE_TX"))
 	//blockchainService := services.NewMockBlockchainService()
 
 	//Initialize services
-	arweaveService := services.NewArweaveService(cfg)
-	blockchainService := services.NewBlockChainService(cfg, contractRepo)
-	diplomaService := services.NewDiplomaService(arweaveService, blockchainService, diplomaRepo)
-	userService := services.NewUserService(userRepo, tokenHelper, uniRepo)
+	arweaveService := services.NewArweaveService(cfg, arweaveRepo)
+	arweaveTracker := services.NewArweaveTracker(cfg, arweaveService, arweaveRepo)
+	documentStores := services.NewConfiguredDocumentStores(cfg, arweaveService, documentKeyRepo)
+	transactionManager := services.NewTransactionManager(cfg, ledgers, outboxRepo, diplomaRepo)
+	blockchainService := services.NewBlockChainService(cfg, ledgers, transactionManager)
+	diplomaService := services.NewDiplomaService(documentStores, blockchainService, diplomaRepo, uniRepo)
+	credentialService := services.NewCredentialService(cfg, diplomaRepo, uniRepo, issuerKeyRepo,
+		services.NewW3CFormatter(),
+		services.NewOpenBadgeFormatter(),
+		services.NewEDCFormatter(),
+	)
+	issuanceService := services.NewIssuanceService(blockchainService, transactionManager, issuanceRepo, diplomaRepo, uniRepo)
+	anchorService := services.NewAnchorService(cfg, blockchainService, anchorRepo)
+	finalityService := services.NewFinalityService(cfg, blockchainService, finalityRepo)
+	indexerService := services.NewIndexerService(cfg, ledgers, indexerRepo)
+	reconciliationService := services.NewReconciliationService(documentStores, blockchainService, reconciliationRepo)
+	batchService := services.NewBatchService(documentStores, blockchainService, diplomaService, batchRepo, cfg.Server.BatchDir)
+	userService := services.NewUserService(userRepo, tokenHelper, uniRepo, diplomaRepo)
+	if err := userService.EnsureSuperadmin(cfg.Superadmin.Email, cfg.Superadmin.Password); err != nil {
+		log.Fatalf("Failed to create the superadmin: %v", err)
+	}
 	AuthMiddleware := middleware.NewAuthMiddleware(tokenHelper, userRepo)
+	publicRateLimiter := middleware.NewRateLimiter(cfg.Server.PublicRateLimit, cfg.Server.PublicRateBurst)
 	uniService := services.NewUniversityService(uniRepo)
-	walletService := services.NewWalletService()
+	walletService := services.NewWalletService(cfg)
+	fundingService := services.NewFundingService(cfg, arweaveService, blockchainService)
 	facultyService := services.NewFacultyService(facultyRepo)
 	departmentService := services.NewDepartmentService(departmentRepo)
 
 	//Initialize handlers
 	diplomaHandler := handlers.NewDiplomaHandler(diplomaService)
+	batchHandler := handlers.NewBatchHandler(batchService)
+	credentialHandler := handlers.NewCredentialHandler(credentialService, diplomaService)
+	issuanceHandler := handlers.NewIssuanceHandler(issuanceService, transactionManager)
 	userHandler := handlers.NewUserHandler(userService, uniService)
-	walletHandler := handlers.NewWalletHandler(walletService)
+	walletHandler := handlers.NewWalletHandler(walletService, fundingService)
 	facultyHandler := handlers.NewFacultyHandler(facultyService)
 	departmentHandler := handlers.NewDepartmentHandler(departmentService)
+	universityHandler := handlers.NewUniversityHandler(uniService)
+	indexerHandler := handlers.NewIndexerHandler(indexerService)
+	reconciliationHandler := handlers.NewReconciliationHandler(reconciliationService)
 
 	api := r.Group("/api/v1")
 	auth := api.Group("/auth")
 	diploma := api.Group("/diploma")
 	wallet := api.Group("/wallet")
+	public := api.Group("/public")
+	platform := api.Group("/platform/universities")
+	indexer := api.Group("/platform/indexer")
 
 	//Public routes
 	routes.UserRoutes(auth, userHandler)
@@ -109,12 +174,58 @@ func main() {
 	routes.FacultyRoutes(api, facultyHandler)
 	routes.DepartmentRoutes(api, departmentHandler)
 
+	//Public verification routes (rate limited, no JWT)
+	public.Use(publicRateLimiter.Limit())
+	routes.PublicRoutes(public, diplomaHandler)
+	routes.PublicCredentialRoutes(public, credentialHandler)
+
 	//Protected routes
 	diploma.Use(AuthMiddleware.Authorize())
-	routes.DiplomaRoutes(diploma, diplomaHandler)
+	routes.DiplomaRoutes(diploma, diplomaHandler, AuthMiddleware)
+	routes.BatchRoutes(diploma, batchHandler, AuthMiddleware)
+	routes.CredentialRoutes(diploma, credentialHandler, AuthMiddleware)
+	routes.IssuanceRoutes(diploma, issuanceHandler, AuthMiddleware)
+	routes.StudentInviteRoutes(diploma, userHandler, AuthMiddleware)
+	routes.ReconciliationRoutes(diploma, reconciliationHandler, AuthMiddleware)
+
+	wallet.Use(AuthMiddleware.Authorize(), AuthMiddleware.RequireRole(models.RoleAdmin, models.RoleSuperAdmin))
 	routes.WalletRoutes(wallet, walletHandler)
 
+	platform.Use(AuthMiddleware.Authorize(), AuthMiddleware.RequireRole(models.RoleSuperAdmin))
+	routes.UniversityAdminRoutes(platform, universityHandler)
+	routes.AdminInviteRoutes(platform, userHandler)
+
+	indexer.Use(AuthMiddleware.Authorize(), AuthMiddleware.RequireRole(models.RoleSuperAdmin))
+	routes.IndexerRoutes(indexer, indexerHandler)
+
+	//Wrap issuer keys stored before DOCUMENT_MASTER_KEY was set
+	if err := credentialService.WrapIssuerKeys(); err != nil {
+		slog.Error("Failed to wrap issuer keys", "err", err)
+	}
+	//Resume batch jobs interrupted by a restart
+	batchService.Start()
+	//Anchor queued diplomas as Merkle roots (ANCHORING_MODE=merkle)
+	anchorService.Start()
+	//Track, re-send and fee-bump backend transactions
+	transactionManager.Start()
+	//Wait for CONFIRMATION_DEPTH blocks and follow reorgs
+	finalityService.Start()
+	//Index DiplomaStored events (INDEXER_ENABLED=true)
+	indexerService.Start()
+	//Close reconciliation jobs interrupted by a restart
+	reconciliationService.Start()
+	//Sign storeDiploma on the backend (ISSUANCE_MODE=server)
+	issuanceService.Start()
+	//Wait for Arweave uploads to become permanent and re-seed dropped ones
+	arweaveTracker.Start()
+	//Warn before the Arweave or MATIC wallet runs dry
+	fundingService.Start()
+
 	r.Static("/public", "./public")
+	//Serve the local document store (STORAGE_BACKEND=local)
+	if cfg.Storage.Configured(config.StorageLocal) {
+		r.Static("/documents", cfg.Storage.Local.Dir)
+	}
 	//Start server
 	log.Printf("Server running on port %s", cfg.Server.Port)
 	if err := r.Run(":" + cfg.Server.Port); err != nil {
@@ -0,0 +1,64 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.24;
+
+/// @title DiplomaRegistry (v1)
+/// @notice Stores the SHA-256 hash of a diploma with the Arweave transaction
+/// holding the file. Diplomas are numbered and looked up by their hex hash.
+/// This is the legacy contract: it cannot revoke diplomas or anchor Merkle
+/// roots, and only its deployer may store. The ABI matches
+/// internal/repositories/abi/v1.json.
+contract DiplomaRegistry {
+    struct Diploma {
+        string diplomaHash;
+        string arweaveTxId;
+        address owner;
+        uint256 timestamp;
+        bool exists;
+    }
+
+    address private immutable issuer = msg.sender;
+    uint256 public diplomaCount;
+    mapping(uint256 => Diploma) public diplomas;
+    mapping(string => uint256) public hashToId;
+    mapping(string => bool) public hashExists;
+
+    event DiplomaStored(
+        uint256 indexed diplomaId,
+        string diplomaHash,
+        string arweaveTxId,
+        address indexed owner,
+        uint256 timestamp
+    );
+
+    function storeDiploma(string memory _diplomaHash, string memory _arweaveTxId) public returns (uint256) {
+        require(msg.sender == issuer, "Only the issuer can store");
+        require(bytes(_diplomaHash).length > 0, "Empty diploma hash");
+        require(bytes(_arweaveTxId).length > 0, "Empty Arweave transaction ID");
+        require(!hashExists[_diplomaHash], "Diploma already stored");
+
+        diplomaCount++;
+        diplomas[diplomaCount] = Diploma(_diplomaHash, _arweaveTxId, msg.sender, block.timestamp, true);
+        hashToId[_diplomaHash] = diplomaCount;
+        hashExists[_diplomaHash] = true;
+
+        emit DiplomaStored(diplomaCount, _diplomaHash, _arweaveTxId, msg.sender, block.timestamp);
+        return diplomaCount;
+    }
+
+    /// @notice Unknown ids return an empty record rather than reverting.
+    function getDiploma(uint256 _diplomaId)
+        public
+        view
+        returns (string memory diplomaHash, string memory arweaveTxId, address owner, uint256 timestamp)
+    {
+        Diploma storage d = diplomas[_diplomaId];
+        return (d.diplomaHash, d.arweaveTxId, d.owner, d.timestamp);
+    }
+
+    function verifyDiploma(string memory _diplomaHash) public view returns (bool exists, string memory arweaveTxId) {
+        if (!hashExists[_diplomaHash]) {
+            return (false, "");
+        }
+        return (true, diplomas[hashToId[_diplomaHash]].arweaveTxId);
+    }
+}
@@ -0,0 +1,99 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.24;
+
+/// @title DiplomaRegistryV2
+/// @notice Diplomas are keyed by their 32 byte SHA-256 hash. Only registered
+/// issuers may issue, revoke or anchor; the deployer owns the registry and is
+/// an issuer itself. The ABI matches internal/repositories/abi/v2.json.
+contract DiplomaRegistryV2 {
+    struct Diploma {
+        string arweaveTxId;
+        address issuer;
+        uint256 timestamp;
+        bool revoked;
+        string revocationReason;
+    }
+
+    address public owner = msg.sender;
+    mapping(bytes32 => uint256) public anchoredRoots;
+
+    mapping(bytes32 => Diploma) private diplomas;
+    mapping(address => bool) private issuers;
+
+    event DiplomaIssued(bytes32 indexed diplomaHash, address indexed issuer, string arweaveTxId, uint256 timestamp);
+    event DiplomaRevoked(bytes32 indexed diplomaHash, address indexed revokedBy, string reason, uint256 timestamp);
+    event IssuerAdded(address indexed issuer, string name);
+    event IssuerRemoved(address indexed issuer);
+    event RootAnchored(bytes32 indexed root, address indexed issuer, uint256 leafCount, uint256 timestamp);
+
+    modifier onlyOwner() {
+        require(msg.sender == owner, "Only the owner");
+        _;
+    }
+
+    modifier onlyIssuer() {
+        require(isIssuer(msg.sender), "Only registered issuers");
+        _;
+    }
+
+    function isIssuer(address _issuer) public view returns (bool) {
+        return _issuer == owner || issuers[_issuer];
+    }
+
+    function addIssuer(address _issuer, string memory _name) public onlyOwner {
+        require(_issuer != address(0), "Empty issuer");
+        issuers[_issuer] = true;
+        emit IssuerAdded(_issuer, _name);
+    }
+
+    function removeIssuer(address _issuer) public onlyOwner {
+        require(issuers[_issuer], "Not an issuer");
+        issuers[_issuer] = false;
+        emit IssuerRemoved(_issuer);
+    }
+
+    function issueDiploma(bytes32 _diplomaHash, string memory _arweaveTxId) public onlyIssuer {
+        require(_diplomaHash != bytes32(0), "Empty diploma hash");
+        require(bytes(_arweaveTxId).length > 0, "Empty Arweave transaction ID");
+        require(diplomas[_diplomaHash].timestamp == 0, "Diploma already issued");
+
+        diplomas[_diplomaHash] = Diploma(_arweaveTxId, msg.sender, block.timestamp, false, "");
+        emit DiplomaIssued(_diplomaHash, msg.sender, _arweaveTxId, block.timestamp);
+    }
+
+    function revokeDiploma(bytes32 _diplomaHash, string memory _reason) public onlyIssuer {
+        Diploma storage d = diplomas[_diplomaHash];
+        require(d.timestamp != 0, "Diploma not found");
+        require(d.issuer == msg.sender || msg.sender == owner, "Only the issuer can revoke");
+        require(!d.revoked, "Diploma already revoked");
+
+        d.revoked = true;
+        d.revocationReason = _reason;
+        emit DiplomaRevoked(_diplomaHash, msg.sender, _reason, block.timestamp);
+    }
+
+    function anchorRoot(bytes32 _root, uint256 _leafCount) public onlyIssuer {
+        require(_root != bytes32(0), "Empty root");
+        require(_leafCount > 0, "Empty batch");
+        require(anchoredRoots[_root] == 0, "Root already anchored");
+
+        anchoredRoots[_root] = block.timestamp;
+        emit RootAnchored(_root, msg.sender, _leafCount, block.timestamp);
+    }
+
+    /// @notice Unknown hashes return an empty record with a zero timestamp.
+    function getDiploma(bytes32 _diplomaHash)
+        public
+        view
+        returns (
+            string memory arweaveTxId,
+            address issuer,
+            uint256 timestamp,
+            bool revoked,
+            string memory revocationReason
+        )
+    {
+        Diploma storage d = diplomas[_diplomaHash];
+        return (d.arweaveTxId, d.issuer, d.timestamp, d.revoked, d.revocationReason);
+    }
+}
@@ -12,7 +12,7 @@ interface User {
 interface AuthContextType {
     user: User | null;
     login: (email: string, password: string) => Promise<void>;
-    register: (data: { firstName: string; lastName: string; email: string; universityID: string; password: string }) => Promise<void>;
+    register: (data: { firstName: string; lastName: string; email: string; universityID: string; password: string; inviteToken: string }) => Promise<void>;
     logout: () => void;
     isAuthenticated: boolean;
     isAdmin: boolean;
@@ -46,7 +46,7 @@ export const AuthProvider: React.FC<{ children: React.ReactNode }> = ({ children
         }
     };
 
-    const register = async (data: { firstName: string; lastName: string; email: string; universityID: string; password: string }) => {
+    const register = async (data: { firstName: string; lastName: string; email: string; universityID: string; password: string; inviteToken: string }) => {
         try {
             await api.post('/v1/auth/user/register/admin', data);
         } catch (error: any) {
@@ -1,7 +1,7 @@
 import React, { useState } from 'react';
-import { useNavigate, Link } from 'react-router-dom';
+import { useNavigate, useSearchParams, Link } from 'react-router-dom';
 import { motion } from 'framer-motion';
-import { Shield, Lock, User, Mail, School, Loader2, ArrowLeft, Check, Search, ChevronDown } from 'lucide-react';
+import { Shield, Lock, User, Mail, School, Loader2, ArrowLeft, Check, Search, ChevronDown, KeyRound } from 'lucide-react';
 import { useAuth } from '../context/AuthContext';
 import api from '../services/api';
 
@@ -11,13 +11,15 @@ interface University {
 }
 
 const Register: React.FC = () => {
+    const [searchParams] = useSearchParams();
     const [formData, setFormData] = useState({
         firstName: '',
         lastName: '',
         email: '',
         universityID: '',
         password: '',
-        confirmPassword: ''
+        confirmPassword: '',
+        inviteToken: searchParams.get('invite') || ''
     });
     const [universities, setUniversities] = useState<University[]>([]);
     const [fetchingUniversities, setFetchingUniversities] = useState(true);
@@ -187,6 +189,22 @@ const Register: React.FC = () => {
                         </div>
                     </div>
 
+                    <div>
+                        <label className="block text-sm font-medium text-gray-400 mb-2">Invite Code</label>
+                        <div className="relative">
+                            <KeyRound className="absolute left-4 top-1/2 -translate-y-1/2 h-5 w-5 text-gray-500" />
+                            <input
+                                type="text"
+                                name="inviteToken"
+                                value={formData.inviteToken}
+                                onChange={handleChange}
+                                placeholder="From your invitation"
+                                className="w-full pl-12 pr-4 py-3 bg-white/5 border border-white/10 rounded-xl focus:outline-none focus:ring-2 focus:ring-brand-primary/50 focus:border-brand-primary transition-all"
+                                required
+                            />
+                        </div>
+                    </div>
+
                     <div>
                         <label className="block text-sm font-medium text-gray-400 mb-2">University / Institution</label>
                         <div className="relative">
@@ -16,7 +16,7 @@ const History: React.FC = () => {
         const fetchDiplomas = async () => {
             try {
                 const data = await diplomaService.getAllDiplomas();
-                setLogs(data);
+                setLogs(data.items ?? []);
             } catch (err) {
                 console.error("Failed to fetch diplomas", err);
             } finally {
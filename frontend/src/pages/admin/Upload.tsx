@@ -146,7 +146,9 @@ const UploadDiploma: React.FC = () => {
 
             const { txHash, blockNumber } = await storeDiplomaWithMetaMask(
                 prepared.diplomaHash,
-                prepared.arweaveTxID
+                prepared.arweaveTxID,
+                prepared.contractVersion,
+                prepared.contractAddress || undefined
             );
 
             // ── Step 4: Polygon confirming (tx already submitted, just UX feedback) ──
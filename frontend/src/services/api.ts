@@ -36,6 +36,13 @@ export interface PrepareUploadResponse {
     diplomaHash: string;
     arweaveTxID: string;
     arweaveUrl: string;
+    storage: string;
+    contentAddress: string;
+    // Where MetaMask has to send the issuing transaction
+    network: string;
+    chainId: number;
+    contractAddress: string;
+    contractVersion: string;
 }
 
 export interface ConfirmUploadPayload {
@@ -84,12 +91,12 @@ export const diplomaService = {
     },
 
     verify: async (diplomaId: string) => {
-        const response = await api.post('/v1/diploma/verify', { DiplomaID: diplomaId });
+        const response = await api.post('/v1/public/verify', { DiplomaID: diplomaId });
         return response.data;
     },
 
-    getAllDiplomas: async () => {
-        const response = await api.get('/v1/diploma/records');
+    getAllDiplomas: async (params: Record<string, string | number> = {}) => {
+        const response = await api.get('/v1/diploma/records', { params });
         return response.data;
     },
 
@@ -4,13 +4,27 @@ import CONTRACT_ABI from '../contractABI.json'; // extract your ABI to a JSON fi
 
 const CONTRACT_ADDRESS = import.meta.env.VITE_CONTRACT_ADDRESS;
 
+// The issuing call differs between contract versions; the backend reports
+// which one the active network runs in its prepare response
+const ISSUE_ABIS: Record<string, string[]> = {
+    v1: ['function storeDiploma(string _diplomaHash, string _arweaveTxId) returns (uint256)'],
+    v2: ['function issueDiploma(bytes32 _diplomaHash, string _arweaveTxId)'],
+};
+
 export async function storeDiplomaWithMetaMask(
     diplomaHash: string,
-    arweaveTxId: string
+    arweaveTxId: string,
+    contractVersion = 'v1',
+    contractAddress: string = CONTRACT_ADDRESS
 ): Promise<{ txHash: string; blockNumber: number }> {
     if (!window.ethereum) throw new Error('MetaMask not installed');
 
-    if (!CONTRACT_ADDRESS) {
+    const issueAbi = ISSUE_ABIS[contractVersion];
+    if (!issueAbi) {
+        throw new Error(`Unsupported contract version ${contractVersion}`);
+    }
+
+    if (!contractAddress) {
         throw new Error(
             'Contract address is not configured. ' +
             'Add VITE_CONTRACT_ADDRESS to your frontend/.env file and restart the dev server.'
@@ -21,10 +35,13 @@ export async function storeDiplomaWithMetaMask(
     const provider = new ethers.BrowserProvider(window.ethereum);
     const signer = await provider.getSigner(); // This is the MetaMask account
 
-    const contract = new ethers.Contract(CONTRACT_ADDRESS, CONTRACT_ABI, signer);
+    const contract = new ethers.Contract(contractAddress, issueAbi, signer);
 
-    // MetaMask will pop up asking the user to sign & pay gas
-    const tx = await contract.storeDiploma(diplomaHash, arweaveTxId);
+    // MetaMask will pop up asking the user to sign & pay gas. v2 keys
+    // diplomas by the raw 32 byte hash and only accepts registered issuers
+    const tx = contractVersion === 'v2'
+        ? await contract.issueDiploma('0x' + diplomaHash.replace(/^0x/, ''), arweaveTxId)
+        : await contract.storeDiploma(diplomaHash, arweaveTxId);
     const receipt = await tx.wait();
 
     return {
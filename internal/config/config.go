@@ -1,10 +1,14 @@
 package config
 
 import (
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"math/big"
+	"net"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -13,8 +17,11 @@ import (
 type Config struct {
 	Server     ServerConfig
 	Arweave    ArweaveConfig
+	Storage    StorageConfig
 	Blockchain BlockChainConfig
+	Funding    FundingConfig
 	JWTConfig  JWTConfig
+	Superadmin SuperadminConfig
 	Db         DatabaseConfig
 }
 
@@ -22,21 +29,236 @@ type ServerConfig struct {
 	Port          string
 	UploadDir     string
 	MaxUploadSize int64
+
+	// BatchDir keeps the PDFs extracted from batch archives until their
+	// upload succeeds; it must survive restarts for jobs to resume.
+	BatchDir string
+
+	// Rate limit for the unauthenticated /public routes, per client IP
+	PublicRateLimit int // requests per minute
+	PublicRateBurst int
+
+	// TrustedProxies are the IPs and CIDRs allowed to set X-Forwarded-For.
+	// Without any, the client IP is the address of the connection.
+	TrustedProxies []string
 }
 
 type ArweaveConfig struct {
-	WalletKey string
+	WalletKey string // the keyfile JSON itself, or the path of the keyfile
 	Host      string
 	Port      int
 	Protocol  string
+	// ConfirmationDepth is how many blocks an upload needs before its file is
+	// considered permanent
+	ConfirmationDepth int
+	// PayloadDir keeps the data of every upload until it is permanent, so a
+	// dropped transaction can be posted again
+	PayloadDir string
+
+	// Bundling packs batch uploads as ANS-104 data items, BundleSize to a
+	// bundle transaction, or posts every upload to BundlerURL
+	Bundling   string
+	BundlerURL string
+	BundleSize int
 }
 
-type BlockChainConfig struct {
+// ARWEAVE_BUNDLING modes
+const (
+	ArweaveBundlingOff     = "off"
+	ArweaveBundlingBundle  = "bundle"  // batches go out as one bundle transaction signed here
+	ArweaveBundlingBundler = "bundler" // every upload is a data item posted to ARWEAVE_BUNDLER_URL
+)
+
+// GatewayURL is the Arweave node or gateway uploads and reads go through.
+func (c ArweaveConfig) GatewayURL() string {
+	if (c.Protocol == "https" && c.Port == 443) || (c.Protocol == "http" && c.Port == 80) {
+		return fmt.Sprintf("%s://%s", c.Protocol, c.Host)
+	}
+	return fmt.Sprintf("%s://%s:%d", c.Protocol, c.Host, c.Port)
+}
+
+// Document stores new diplomas can be written to (STORAGE_BACKEND)
+const (
+	StorageArweave = "arweave"
+	StorageIPFS    = "ipfs"
+	StorageS3      = "s3"
+	StorageLocal   = "local"
+)
+
+// StorageConfig selects where diploma PDFs are stored. Backend receives new
+// uploads; every other store that is configured stays readable so diplomas
+// stored there before a switch still verify.
+type StorageConfig struct {
+	Backend string
+	IPFS    IPFSConfig
+	S3      S3Config
+	Local   LocalStorageConfig
+
+	// EncryptDocuments seals each new PDF with its own AES-256-GCM data key
+	// before upload. MasterKey (hex, 32 bytes) wraps the data keys and must
+	// stay configured as long as encrypted diplomas exist.
+	EncryptDocuments bool
+	MasterKey        string
+	MasterKeyID      string
+}
+
+type IPFSConfig struct {
+	APIURL     string // Kubo RPC API, e.g. http://127.0.0.1:5001
+	GatewayURL string // public gateway for links
+}
+
+// S3Config is any S3-compatible object store (AWS, MinIO, R2, ...).
+type S3Config struct {
+	Endpoint        string // e.g. https://s3.eu-central-1.amazonaws.com
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	Prefix          string // key prefix of uploaded diplomas
+	PublicURL       string // base URL for links; the bucket URL when empty
+}
+
+type LocalStorageConfig struct {
+	Dir       string
+	PublicURL string // base URL the directory is served under
+}
+
+// Configured reports whether the store has enough settings to be used.
+func (c StorageConfig) Configured(name string) bool {
+	switch name {
+	case StorageArweave:
+		return true
+	case StorageIPFS:
+		return c.IPFS.APIURL != ""
+	case StorageS3:
+		return c.S3.Bucket != ""
+	case StorageLocal:
+		return c.Local.Dir != ""
+	}
+	return false
+}
+
+// NetworkProfile is an EVM network the diploma contract is deployed on.
+type NetworkProfile struct {
+	Name            string
+	ChainID         int
 	RPCURL          string
-	PrivateKey      string
+	ExplorerTxURL   string // with a single %s for the transaction hash; empty when there is no explorer
 	ContractAddress string
-	ChainID         int
-	MinBalance      string // in MATIC
+	ContractVersion string // ABI of ContractAddress, e.g. v1 or v2
+	// Contracts this network used before ContractAddress. They are only read,
+	// so diplomas anchored on them still verify.
+	LegacyContracts []ContractDeployment
+}
+
+// ContractDeployment is one deployed diploma contract and its ABI version.
+type ContractDeployment struct {
+	Address string
+	Version string
+}
+
+// TxURL links a transaction on the network's block explorer.
+func (n NetworkProfile) TxURL(txHash string) string {
+	if n.ExplorerTxURL == "" {
+		return ""
+	}
+	return fmt.Sprintf(n.ExplorerTxURL, txHash)
+}
+
+// knownNetworks are the defaults a profile starts from. Any field can be
+// overridden through <NAME>_RPC_URL, <NAME>_CHAIN_ID, <NAME>_EXPLORER_TX_URL
+// and <NAME>_CONTRACT_ADDRESS, and the contract versions through
+// <NAME>_CONTRACT_VERSION and <NAME>_LEGACY_CONTRACTS.
+var knownNetworks = map[string]NetworkProfile{
+	// A local node such as anvil or geth --dev. Without a contract address the
+	// contract is deployed at startup, see BlockChainConfig.ContractBytecodeDir.
+	DevNetwork: {
+		Name:    DevNetwork,
+		ChainID: 31337,
+		RPCURL:  "http://127.0.0.1:8545",
+	},
+	"amoy": {
+		Name:          "amoy",
+		ChainID:       80002,
+		RPCURL:        "https://rpc-amoy.polygon.technology",
+		ExplorerTxURL: "https://amoy.polygonscan.com/tx/%s",
+	},
+	"polygon": {
+		Name:          "polygon",
+		ChainID:       137,
+		RPCURL:        "https://polygon-rpc.com",
+		ExplorerTxURL: "https://polygonscan.com/tx/%s",
+	},
+}
+
+// DevNetwork is the profile of a local development chain.
+const DevNetwork = "dev"
+
+// DevPrivateKey is the first prefunded account of anvil and hardhat, used on
+// the dev network when PRIVATE_KEY is not set. It is public; never fund it
+// on a real network.
+const DevPrivateKey = "ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80"
+
+type BlockChainConfig struct {
+	// Network is where new diplomas are anchored. Networks also holds the
+	// older networks listed in LEGACY_NETWORKS so their records still verify.
+	Network  string
+	Networks map[string]NetworkProfile
+	// Diplomas stored before they recorded a network are tagged with this one
+	UntaggedNetwork string
+
+	// Directory holding <version>.json ABI files; the embedded ABIs are used
+	// when empty
+	ContractABIDir string
+	// Directory holding the compiled contracts (make contracts), deployed on
+	// the dev network when it has no contract address
+	ContractBytecodeDir string
+
+	PrivateKey string
+	MinBalance string // in MATIC
+	// RevocationOnChain anchors revocations through the contract's revokeDiploma
+	// method. Leave disabled for contracts deployed without it.
+	RevocationOnChain bool
+
+	// MerkleAnchoring queues diplomas and anchors them as a single Merkle root
+	// per batch instead of one storeDiploma transaction each. Requires a
+	// contract with anchorRoot.
+	MerkleAnchoring      bool
+	MerkleBatchSize      int
+	MerkleAnchorInterval time.Duration
+
+	// ServerIssuance replaces the MetaMask step: the backend signs
+	// storeDiploma itself, with the university's key from
+	// UniversityPrivateKeys (by YÖK code) or PrivateKey otherwise.
+	ServerIssuance        bool
+	UniversityPrivateKeys map[string]string
+
+	// Outgoing transactions still unmined after TxStuckAfter are replaced
+	// with fees raised by TxFeeBumpPercent, up to TxMaxFeeGwei. One the node
+	// rejects TxMaxRejections times fails, and a self-transfer takes its nonce
+	TxStuckAfter     time.Duration
+	TxFeeBumpPercent int
+	TxMaxFeeGwei     string
+	TxMaxRejections  int
+
+	// ConfirmationDepth is how many blocks must be built on an anchoring
+	// transaction before its diplomas are marked final
+	ConfirmationDepth uint64
+
+	// IndexerEnabled indexes the contract's DiplomaStored events from
+	// IndexerStartBlock on, IndexerBlockRange blocks per eth_getLogs call
+	IndexerEnabled    bool
+	IndexerStartBlock uint64
+	IndexerBlockRange uint64
+}
+
+// FundingConfig sets when the Arweave and MATIC wallets are reported as
+// running low: once either can pay for fewer than AlertDiplomas more
+// diplomas of DocumentSize bytes. Both are checked every CheckInterval.
+type FundingConfig struct {
+	DocumentSize  int
+	AlertDiplomas int
+	CheckInterval time.Duration
 }
 
 type JWTConfig struct {
@@ -44,6 +266,13 @@ type JWTConfig struct {
 	JWTSecret      string
 }
 
+// SuperadminConfig seeds the platform's first superadmin on startup, while
+// no superadmin exists yet. Leave Email empty to create none.
+type SuperadminConfig struct {
+	Email    string
+	Password string
+}
+
 type DatabaseConfig struct {
 	Host     string
 	Port     string
@@ -64,10 +293,117 @@ func Load() (*Config, error) {
 		// .env simply doesn't exist; continue using environment variables.
 	}
 
-	chainIDStr := getEnvOrDefault("POLYGON_CHAIN_ID", "80002")
-	chainID, err := strconv.Atoi(chainIDStr)
+	network := strings.ToLower(getEnvOrDefault("NETWORK", "amoy"))
+	networks, err := loadNetworks(network, os.Getenv("LEGACY_NETWORKS"))
+	if err != nil {
+		return nil, err
+	}
+
+	publicRateLimit, err := strconv.Atoi(getEnvOrDefault("PUBLIC_RATE_LIMIT", "30"))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse PUBLIC_RATE_LIMIT from env var: %w", err)
+	}
+
+	publicRateBurst, err := strconv.Atoi(getEnvOrDefault("PUBLIC_RATE_BURST", "10"))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse PUBLIC_RATE_BURST from env var: %w", err)
+	}
+
+	trustedProxies, err := parseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse TRUSTED_PROXIES from env var: %w", err)
+	}
+
+	merkleBatchSize, err := strconv.Atoi(getEnvOrDefault("MERKLE_BATCH_SIZE", "256"))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse MERKLE_BATCH_SIZE from env var: %w", err)
+	}
+
+	merkleAnchorInterval, err := time.ParseDuration(getEnvOrDefault("MERKLE_ANCHOR_INTERVAL", "10m"))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse MERKLE_ANCHOR_INTERVAL from env var: %w", err)
+	}
+
+	universityPrivateKeys, err := parseUniversityKeys(os.Getenv("UNIVERSITY_PRIVATE_KEYS"))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse UNIVERSITY_PRIVATE_KEYS from env var: %w", err)
+	}
+
+	txStuckAfter, err := time.ParseDuration(getEnvOrDefault("TX_STUCK_AFTER", "3m"))
 	if err != nil {
-		return nil, fmt.Errorf("could not parse POLYGON_CHAIN_ID from env var: %w", err)
+		return nil, fmt.Errorf("could not parse TX_STUCK_AFTER from env var: %w", err)
+	}
+
+	txFeeBumpPercent, err := strconv.Atoi(getEnvOrDefault("TX_FEE_BUMP_PERCENT", "20"))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse TX_FEE_BUMP_PERCENT from env var: %w", err)
+	}
+
+	txMaxRejections, err := strconv.Atoi(getEnvOrDefault("TX_MAX_REJECTIONS", "5"))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse TX_MAX_REJECTIONS from env var: %w", err)
+	}
+
+	// A dev node only mines when it receives a transaction, so it would
+	// never reach the production depth
+	defaultConfirmationDepth := "32"
+	if network == DevNetwork {
+		defaultConfirmationDepth = "1"
+	}
+
+	confirmationDepth, err := strconv.ParseUint(getEnvOrDefault("CONFIRMATION_DEPTH", defaultConfirmationDepth), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse CONFIRMATION_DEPTH from env var: %w", err)
+	}
+
+	indexerStartBlock, err := strconv.ParseUint(getEnvOrDefault("INDEXER_START_BLOCK", "0"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse INDEXER_START_BLOCK from env var: %w", err)
+	}
+
+	indexerBlockRange, err := strconv.ParseUint(getEnvOrDefault("INDEXER_BLOCK_RANGE", "2000"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse INDEXER_BLOCK_RANGE from env var: %w", err)
+	}
+
+	privateKey := os.Getenv("PRIVATE_KEY")
+	if privateKey == "" && network == DevNetwork {
+		privateKey = DevPrivateKey
+	}
+
+	arweavePort, err := strconv.Atoi(getEnvOrDefault("ARWEAVE_PORT", "443"))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse ARWEAVE_PORT from env var: %w", err)
+	}
+
+	arweaveConfirmationDepth, err := strconv.Atoi(getEnvOrDefault("ARWEAVE_CONFIRMATION_DEPTH", "15"))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse ARWEAVE_CONFIRMATION_DEPTH from env var: %w", err)
+	}
+
+	arweaveBundleSize, err := strconv.Atoi(getEnvOrDefault("ARWEAVE_BUNDLE_SIZE", "100"))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse ARWEAVE_BUNDLE_SIZE from env var: %w", err)
+	}
+
+	fundingDocumentSizeKB, err := strconv.Atoi(getEnvOrDefault("FUNDING_DOCUMENT_SIZE_KB", "256"))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse FUNDING_DOCUMENT_SIZE_KB from env var: %w", err)
+	}
+
+	fundingAlertDiplomas, err := strconv.Atoi(getEnvOrDefault("FUNDING_ALERT_DIPLOMAS", "100"))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse FUNDING_ALERT_DIPLOMAS from env var: %w", err)
+	}
+
+	fundingCheckInterval, err := time.ParseDuration(getEnvOrDefault("FUNDING_CHECK_INTERVAL", "15m"))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse FUNDING_CHECK_INTERVAL from env var: %w", err)
+	}
+
+	localStorageDir := os.Getenv("LOCAL_STORAGE_DIR")
+	if localStorageDir == "" && strings.EqualFold(os.Getenv("STORAGE_BACKEND"), StorageLocal) {
+		localStorageDir = "documents"
 	}
 
 	jwtExpStr := os.Getenv("JWT_EXP_HOURS")
@@ -81,24 +417,93 @@ func Load() (*Config, error) {
 			Port:          getEnvOrDefault("PORT", "8080"),
 			UploadDir:     getEnvOrDefault("UPLOAD_DIR", "upload"),
 			MaxUploadSize: 10 << 20, // 10 MB
+			BatchDir:      getEnvOrDefault("BATCH_UPLOAD_DIR", "uploads/batch"),
+
+			PublicRateLimit: publicRateLimit,
+			PublicRateBurst: publicRateBurst,
+			TrustedProxies:  trustedProxies,
 		},
 		Arweave: ArweaveConfig{
 			WalletKey: os.Getenv("ARWEAVE_KEY"),
-			Host:      "arweave.net",
-			Port:      443,
-			Protocol:  "https",
+			Host:      getEnvOrDefault("ARWEAVE_HOST", "arweave.net"),
+			Port:      arweavePort,
+			Protocol:  getEnvOrDefault("ARWEAVE_PROTOCOL", "https"),
+
+			ConfirmationDepth: arweaveConfirmationDepth,
+			PayloadDir:        getEnvOrDefault("ARWEAVE_PAYLOAD_DIR", "uploads/arweave"),
+
+			Bundling:   strings.ToLower(getEnvOrDefault("ARWEAVE_BUNDLING", ArweaveBundlingOff)),
+			BundlerURL: strings.TrimSuffix(os.Getenv("ARWEAVE_BUNDLER_URL"), "/"),
+			BundleSize: arweaveBundleSize,
+		},
+		Storage: StorageConfig{
+			Backend: strings.ToLower(getEnvOrDefault("STORAGE_BACKEND", StorageArweave)),
+			IPFS: IPFSConfig{
+				APIURL:     strings.TrimSuffix(os.Getenv("IPFS_API_URL"), "/"),
+				GatewayURL: strings.TrimSuffix(getEnvOrDefault("IPFS_GATEWAY_URL", "https://ipfs.io"), "/"),
+			},
+			S3: S3Config{
+				Endpoint:        strings.TrimSuffix(os.Getenv("S3_ENDPOINT"), "/"),
+				Region:          getEnvOrDefault("S3_REGION", "us-east-1"),
+				Bucket:          os.Getenv("S3_BUCKET"),
+				AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+				SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+				Prefix:          getEnvOrDefault("S3_PREFIX", "diplomas/"),
+				PublicURL:       strings.TrimSuffix(os.Getenv("S3_PUBLIC_URL"), "/"),
+			},
+			Local: LocalStorageConfig{
+				Dir:       localStorageDir,
+				PublicURL: strings.TrimSuffix(getEnvOrDefault("LOCAL_STORAGE_URL", "http://localhost:"+getEnvOrDefault("PORT", "8080")+"/documents"), "/"),
+			},
+
+			EncryptDocuments: getEnvOrDefault("DOCUMENT_ENCRYPTION", "false") == "true",
+			MasterKey:        strings.TrimPrefix(os.Getenv("DOCUMENT_MASTER_KEY"), "0x"),
+			MasterKeyID:      getEnvOrDefault("DOCUMENT_MASTER_KEY_ID", "1"),
 		},
 		Blockchain: BlockChainConfig{
-			RPCURL:          getEnvOrDefault("POLYGON_RPC_URL", "https://polygon-rpc.com"),
-			PrivateKey:      os.Getenv("PRIVATE_KEY"),
-			ContractAddress: os.Getenv("CONTRACT_ADDRESS"),
-			ChainID:         chainID,
-			MinBalance:      "0.03",
+			Network:         network,
+			Networks:        networks,
+			UntaggedNetwork: strings.ToLower(getEnvOrDefault("UNTAGGED_RECORDS_NETWORK", network)),
+
+			ContractABIDir:      os.Getenv("CONTRACT_ABI_DIR"),
+			ContractBytecodeDir: getEnvOrDefault("CONTRACT_BYTECODE_DIR", "contracts/build"),
+
+			PrivateKey: privateKey,
+			MinBalance: "0.03",
+
+			RevocationOnChain: getEnvOrDefault("REVOCATION_ON_CHAIN", "false") == "true",
+
+			MerkleAnchoring:      getEnvOrDefault("ANCHORING_MODE", "single") == "merkle",
+			MerkleBatchSize:      merkleBatchSize,
+			MerkleAnchorInterval: merkleAnchorInterval,
+
+			ServerIssuance:        getEnvOrDefault("ISSUANCE_MODE", "wallet") == "server",
+			UniversityPrivateKeys: universityPrivateKeys,
+
+			TxStuckAfter:     txStuckAfter,
+			TxFeeBumpPercent: txFeeBumpPercent,
+			TxMaxFeeGwei:     getEnvOrDefault("TX_MAX_FEE_GWEI", "1000"),
+			TxMaxRejections:  txMaxRejections,
+
+			ConfirmationDepth: confirmationDepth,
+
+			IndexerEnabled:    getEnvOrDefault("INDEXER_ENABLED", "false") == "true",
+			IndexerStartBlock: indexerStartBlock,
+			IndexerBlockRange: indexerBlockRange,
+		},
+		Funding: FundingConfig{
+			DocumentSize:  fundingDocumentSizeKB << 10,
+			AlertDiplomas: fundingAlertDiplomas,
+			CheckInterval: fundingCheckInterval,
 		},
 		JWTConfig: JWTConfig{
 			JWTExpireHours: time.Duration(jwtExp),
 			JWTSecret:      os.Getenv("JWT_SECRET_KEY"),
 		},
+		Superadmin: SuperadminConfig{
+			Email:    os.Getenv("SUPERADMIN_EMAIL"),
+			Password: os.Getenv("SUPERADMIN_PASSWORD"),
+		},
 		Db: DatabaseConfig{
 			Host:     os.Getenv("APP_DB_HOST"),
 			Port:     os.Getenv("APP_DB_PORT"),
@@ -115,14 +520,94 @@ func Load() (*Config, error) {
 }
 
 func (c *Config) Validate() error {
-	if c.Arweave.WalletKey == "" {
+	if !c.Storage.Configured(c.Storage.Backend) {
+		return fmt.Errorf("STORAGE_BACKEND %q is unknown or not configured", c.Storage.Backend)
+	}
+	if c.Storage.Backend == StorageArweave && c.Arweave.WalletKey == "" {
 		return fmt.Errorf("ARWEAVE_KEY is required")
 	}
+	switch c.Arweave.Bundling {
+	case ArweaveBundlingOff, ArweaveBundlingBundle:
+	case ArweaveBundlingBundler:
+		if c.Arweave.BundlerURL == "" {
+			return fmt.Errorf("ARWEAVE_BUNDLING=bundler needs ARWEAVE_BUNDLER_URL")
+		}
+	default:
+		return fmt.Errorf("ARWEAVE_BUNDLING must be off, bundle or bundler")
+	}
+	if c.Arweave.BundleSize <= 0 {
+		return fmt.Errorf("ARWEAVE_BUNDLE_SIZE must be positive")
+	}
+	if c.Storage.EncryptDocuments && c.Storage.MasterKey == "" {
+		return fmt.Errorf("DOCUMENT_ENCRYPTION needs DOCUMENT_MASTER_KEY")
+	}
+	if key, err := hex.DecodeString(c.Storage.MasterKey); c.Storage.MasterKey != "" && (err != nil || len(key) != 32) {
+		return fmt.Errorf("DOCUMENT_MASTER_KEY must be 32 bytes of hex")
+	}
+	if c.Storage.Configured(StorageS3) && (c.Storage.S3.Endpoint == "" || c.Storage.S3.AccessKeyID == "" || c.Storage.S3.SecretAccessKey == "") {
+		return fmt.Errorf("S3_BUCKET needs S3_ENDPOINT, S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY")
+	}
 	if c.Blockchain.PrivateKey == "" {
 		return fmt.Errorf("PRIVATE_KEY is required")
 	}
-	if c.Blockchain.ContractAddress == "" {
-		return fmt.Errorf("CONTRACT_ADDRESS is required")
+	for _, n := range c.Blockchain.Networks {
+		// The active dev network gets its contract deployed at startup
+		if n.ContractAddress == "" && !(n.Name == DevNetwork && n.Name == c.Blockchain.Network) {
+			return fmt.Errorf("contract address of network %q is required", n.Name)
+		}
+		if n.RPCURL == "" || n.ChainID == 0 {
+			return fmt.Errorf("network %q needs an RPC URL and a chain ID", n.Name)
+		}
+		if n.ExplorerTxURL != "" && !strings.Contains(n.ExplorerTxURL, "%s") {
+			return fmt.Errorf("explorer URL of network %q needs a %%s for the transaction hash", n.Name)
+		}
+	}
+	if _, ok := c.Blockchain.Networks[c.Blockchain.UntaggedNetwork]; !ok {
+		return fmt.Errorf("UNTAGGED_RECORDS_NETWORK %q is not a configured network", c.Blockchain.UntaggedNetwork)
+	}
+	if c.Blockchain.MerkleAnchoring && c.Blockchain.MerkleBatchSize <= 0 {
+		return fmt.Errorf("MERKLE_BATCH_SIZE must be positive")
+	}
+	if c.Blockchain.MerkleAnchoring && c.Blockchain.MerkleAnchorInterval <= 0 {
+		return fmt.Errorf("MERKLE_ANCHOR_INTERVAL must be positive")
+	}
+	// The legacy v1 contract has no anchorRoot method
+	if c.Blockchain.MerkleAnchoring && c.Blockchain.Networks[c.Blockchain.Network].ContractVersion == "v1" {
+		return fmt.Errorf("ANCHORING_MODE=merkle needs a contract that can anchor roots, not CONTRACT_VERSION=v1")
+	}
+	if c.Blockchain.MerkleAnchoring && c.Blockchain.ServerIssuance {
+		return fmt.Errorf("ANCHORING_MODE=merkle and ISSUANCE_MODE=server cannot be combined")
+	}
+	if c.Blockchain.TxStuckAfter <= 0 {
+		return fmt.Errorf("TX_STUCK_AFTER must be positive")
+	}
+	// Nodes refuse replacements that raise the fees by less than 10%
+	if c.Blockchain.TxFeeBumpPercent < 10 {
+		return fmt.Errorf("TX_FEE_BUMP_PERCENT must be at least 10")
+	}
+	if _, ok := new(big.Float).SetString(c.Blockchain.TxMaxFeeGwei); !ok {
+		return fmt.Errorf("TX_MAX_FEE_GWEI must be a number")
+	}
+	if c.Blockchain.TxMaxRejections <= 0 {
+		return fmt.Errorf("TX_MAX_REJECTIONS must be positive")
+	}
+	if c.Blockchain.ConfirmationDepth == 0 {
+		return fmt.Errorf("CONFIRMATION_DEPTH must be positive")
+	}
+	if c.Arweave.ConfirmationDepth <= 0 {
+		return fmt.Errorf("ARWEAVE_CONFIRMATION_DEPTH must be positive")
+	}
+	if c.Blockchain.IndexerEnabled && c.Blockchain.IndexerBlockRange == 0 {
+		return fmt.Errorf("INDEXER_BLOCK_RANGE must be positive")
+	}
+	if c.Funding.DocumentSize <= 0 {
+		return fmt.Errorf("FUNDING_DOCUMENT_SIZE_KB must be positive")
+	}
+	if c.Funding.AlertDiplomas < 0 {
+		return fmt.Errorf("FUNDING_ALERT_DIPLOMAS must not be negative")
+	}
+	if c.Funding.CheckInterval <= 0 {
+		return fmt.Errorf("FUNDING_CHECK_INTERVAL must be positive")
 	}
 	if c.Db.Host == "" {
 		return fmt.Errorf("APP_DB_HOST is required")
@@ -142,6 +627,134 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// ActiveNetwork returns the profile new diplomas are anchored on.
+func (c BlockChainConfig) ActiveNetwork() NetworkProfile {
+	return c.Networks[c.Network]
+}
+
+// DevMode reports whether new diplomas go to a local development chain.
+func (c BlockChainConfig) DevMode() bool {
+	return c.Network == DevNetwork
+}
+
+// loadNetworks builds the active network profile and those listed in
+// legacy (comma separated). The active one also honours the original
+// POLYGON_RPC_URL, POLYGON_CHAIN_ID and CONTRACT_ADDRESS variables.
+func loadNetworks(active, legacy string) (map[string]NetworkProfile, error) {
+	networks := make(map[string]NetworkProfile)
+
+	names := []string{active}
+	for _, name := range strings.Split(legacy, ",") {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	for _, name := range names {
+		if _, ok := networks[name]; ok {
+			continue
+		}
+
+		profile := knownNetworks[name]
+		profile.Name = name
+		prefix := strings.ToUpper(name) + "_"
+
+		profile.ContractVersion = "v1"
+		legacyContracts := os.Getenv(prefix + "LEGACY_CONTRACTS")
+
+		if name == active {
+			profile.RPCURL = getEnvOrDefault("POLYGON_RPC_URL", profile.RPCURL)
+			profile.ContractAddress = os.Getenv("CONTRACT_ADDRESS")
+			profile.ContractVersion = getEnvOrDefault("CONTRACT_VERSION", profile.ContractVersion)
+			legacyContracts = getEnvOrDefault("LEGACY_CONTRACTS", legacyContracts)
+			if value := os.Getenv("POLYGON_CHAIN_ID"); value != "" {
+				chainID, err := strconv.Atoi(value)
+				if err != nil {
+					return nil, fmt.Errorf("could not parse POLYGON_CHAIN_ID from env var: %w", err)
+				}
+				profile.ChainID = chainID
+			}
+		}
+
+		profile.RPCURL = getEnvOrDefault(prefix+"RPC_URL", profile.RPCURL)
+		profile.ExplorerTxURL = getEnvOrDefault(prefix+"EXPLORER_TX_URL", profile.ExplorerTxURL)
+		profile.ContractAddress = getEnvOrDefault(prefix+"CONTRACT_ADDRESS", profile.ContractAddress)
+		profile.ContractVersion = getEnvOrDefault(prefix+"CONTRACT_VERSION", profile.ContractVersion)
+		if value := os.Getenv(prefix + "CHAIN_ID"); value != "" {
+			chainID, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("could not parse %sCHAIN_ID from env var: %w", prefix, err)
+			}
+			profile.ChainID = chainID
+		}
+
+		contracts, err := parseContractDeployments(legacyContracts)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse %sLEGACY_CONTRACTS from env var: %w", prefix, err)
+		}
+		profile.LegacyContracts = contracts
+
+		networks[name] = profile
+	}
+
+	return networks, nil
+}
+
+// parseContractDeployments reads "version:address,version:address", newest
+// first.
+func parseContractDeployments(value string) ([]ContractDeployment, error) {
+	var contracts []ContractDeployment
+	for _, entry := range strings.Split(value, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		version, address, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		version = strings.TrimSpace(version)
+		address = strings.TrimSpace(address)
+		if !ok || version == "" || address == "" {
+			return nil, fmt.Errorf("invalid entry %q, expected version:address", entry)
+		}
+		contracts = append(contracts, ContractDeployment{Address: address, Version: version})
+	}
+	return contracts, nil
+}
+
+// parseUniversityKeys reads "yokCode:hexKey,yokCode:hexKey".
+func parseUniversityKeys(value string) (map[string]string, error) {
+	keys := make(map[string]string)
+	if strings.TrimSpace(value) == "" {
+		return keys, nil
+	}
+
+	for _, entry := range strings.Split(value, ",") {
+		yokCode, key, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		yokCode = strings.TrimSpace(yokCode)
+		key = strings.TrimPrefix(strings.TrimSpace(key), "0x")
+		if !ok || yokCode == "" || key == "" {
+			return nil, fmt.Errorf("invalid entry %q, expected yokCode:privateKey", entry)
+		}
+		keys[yokCode] = key
+	}
+
+	return keys, nil
+}
+
+// parseTrustedProxies reads "ip,cidr,...".
+func parseTrustedProxies(value string) ([]string, error) {
+	var proxies []string
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(entry); err != nil && net.ParseIP(entry) == nil {
+			return nil, fmt.Errorf("invalid entry %q, expected an IP or CIDR", entry)
+		}
+		proxies = append(proxies, entry)
+	}
+	return proxies, nil
+}
+
 func getEnvOrDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
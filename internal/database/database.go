@@ -23,10 +23,26 @@ func Init(cfg config.DatabaseConfig) (*gorm.DB, error) {
 func Migrate(db *gorm.DB) error {
 	return db.AutoMigrate(
 		&models.Admin{},
+		&models.AdminInvite{},
+		&models.ArweaveDataItem{},
+		&models.ArweaveUpload{},
+		&models.BatchJob{},
+		&models.BatchJobItem{},
+		&models.ChainTransaction{},
 		&models.Department{},
 		&models.Diploma{},
+		&models.DiplomaIssuance{},
 		&models.DiplomaMetaData{},
+		&models.DiplomaRevocation{},
+		&models.DiplomaStoredEvent{},
+		&models.DocumentKey{},
 		&models.Faculties{},
+		&models.IndexerCursor{},
+		&models.IssuerKey{},
+		&models.MerkleAnchor{},
+		&models.ReconciliationJob{},
+		&models.ReconciliationMismatch{},
+		&models.SignerNonce{},
 		&models.Student{},
 		&models.Universities{},
 		&models.User{},
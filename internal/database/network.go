@@ -0,0 +1,44 @@
+package database
+
+import (
+	"BlockCertify/internal/models"
+	"log/slog"
+
+	"gorm.io/gorm"
+)
+
+// TagNetwork assigns network to the records written before diplomas,
+// anchors and transactions recorded which ledger they live on. It only
+// touches rows without a network, so it is safe to run on every start.
+func TagNetwork(db *gorm.DB, network string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		tables := []string{
+			models.TableDiploma,
+			models.TableMerkleAnchor,
+			models.TableChainTransaction,
+			models.TableSignerNonce,
+			models.TableDiplomaStoredEvent,
+		}
+
+		for _, table := range tables {
+			query := tx.Table(table).Where("network IS NULL OR network = ''")
+			// Diplomas still waiting for anchoring get the network they end up on
+			if table == models.TableDiploma {
+				query = query.Where("polygon_tx_id <> ''")
+			}
+
+			result := query.Update("network", network)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				slog.Info("Tagged records with their network", "table", table, "network", network, "rows", result.RowsAffected)
+			}
+		}
+
+		// Indexer cursors are per network now
+		return tx.Model(&models.IndexerCursor{}).
+			Where("name NOT LIKE ?", "%:%").
+			Update("name", gorm.Expr("name || ':' || ?", network)).Error
+	})
+}
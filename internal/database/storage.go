@@ -0,0 +1,41 @@
+package database
+
+import (
+	"BlockCertify/internal/config"
+	"BlockCertify/internal/models"
+	"log/slog"
+
+	"gorm.io/gorm"
+)
+
+// TagStorage fills in the content address of diplomas uploaded before the
+// document store was recorded, and their Arweave status. They were all
+// stored on Arweave, where the reference is the TxID itself.
+func TagStorage(db *gorm.DB) error {
+
+	result := db.Model(&models.Diploma{}).
+		Where("(storage IS NULL OR storage = '' OR storage = ?) AND (content_address IS NULL OR content_address = '') AND arweave_tx_id <> ''", config.StorageArweave).
+		Updates(map[string]interface{}{
+			"storage":         config.StorageArweave,
+			"content_address": gorm.Expr("arweave_tx_id"),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		slog.Info("Tagged diplomas with their document store", "store", config.StorageArweave, "rows", result.RowsAffected)
+	}
+
+	// Arweave diplomas from before the tracker start out pending; it checks
+	// them once and marks the settled ones permanent
+	result = db.Model(&models.Diploma{}).
+		Where("storage = ? AND (arweave_status IS NULL OR arweave_status = '') AND arweave_tx_id <> ''", config.StorageArweave).
+		Update("arweave_status", models.ArweaveStatusPending)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		slog.Info("Queued diplomas for Arweave tracking", "rows", result.RowsAffected)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package database
+
+import (
+	"BlockCertify/internal/models"
+	"fmt"
+	"log/slog"
+
+	"github.com/gofrs/uuid/v5"
+	"gorm.io/gorm"
+)
+
+// TagUniversity links diplomas created before they recorded their issuing
+// university to it, matching the university name in their metadata. A name
+// shared by two universities matches neither. Rows without a match are
+// logged and reported as an error: no admin can see them until their
+// metadata or the university name is corrected. It only touches rows
+// without a university, so it is safe to run on every start.
+func TagUniversity(db *gorm.DB) error {
+
+	result := db.Exec(fmt.Sprintf(`
+		UPDATE %[1]s SET university_id = u.id
+		FROM %[2]s m, %[3]s u
+		WHERE m.diploma_id = %[1]s.id
+		  AND LOWER(TRIM(m.university)) = LOWER(TRIM(u.name))
+		  AND (SELECT COUNT(*) FROM %[3]s other WHERE LOWER(TRIM(other.name)) = LOWER(TRIM(u.name))) = 1
+		  AND (%[1]s.university_id IS NULL OR %[1]s.university_id = ?)`,
+		models.TableDiploma, models.TableName, models.Universities{}.TableName()), uuid.Nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		slog.Info("Linked diplomas to their university", "rows", result.RowsAffected)
+	}
+
+	var orphans []struct {
+		PublicID   string
+		University string
+	}
+	err := db.Table(models.TableDiploma+" d").
+		Select("d.public_id, m.university").
+		Joins("LEFT JOIN "+models.TableName+" m ON m.diploma_id = d.id").
+		Where("d.university_id IS NULL OR d.university_id = ?", uuid.Nil).
+		Scan(&orphans).Error
+	if err != nil {
+		return err
+	}
+	for _, orphan := range orphans {
+		slog.Error("Diploma matches no university and is hidden from every admin", "publicId", orphan.PublicID, "university", orphan.University)
+	}
+	if len(orphans) > 0 {
+		return fmt.Errorf("%d diplomas could not be linked to a university", len(orphans))
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package dto
+
+import "time"
+
+type AnchoringStatusResponse struct {
+	DiplomaID    string                     `json:"diplomaId"`
+	Status       string                     `json:"status"` // pending, mined, failed
+	Transactions []ChainTransactionResponse `json:"transactions"`
+}
+
+type ChainTransactionResponse struct {
+	Kind        string     `json:"kind"`
+	Network     string     `json:"network"`
+	Status      string     `json:"status"` // pending, mined, failed, replaced
+	Error       string     `json:"error,omitempty"`
+	TxHash      string     `json:"txHash"`
+	Signer      string     `json:"signer"`
+	Nonce       uint64     `json:"nonce"`
+	GasTipCap   string     `json:"maxPriorityFeePerGas"`
+	GasFeeCap   string     `json:"maxFeePerGas"`
+	BlockNumber uint64     `json:"blockNumber,omitempty"`
+	GasUsed     uint64     `json:"gasUsed,omitempty"`
+	SentAt      *time.Time `json:"sentAt,omitempty"`
+	MinedAt     *time.Time `json:"minedAt,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
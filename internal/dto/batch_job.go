@@ -0,0 +1,50 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// BatchManifestRow is one parsed row of a batch manifest CSV.
+// Error is set when the row failed validation.
+type BatchManifestRow struct {
+	RowNumber int
+	FileName  string
+	Metadata  DiplomaMetadataRequest
+	Error     string
+}
+
+type BatchJobResponse struct {
+	ID         uuid.UUID `json:"id"`
+	Status     string    `json:"status"`
+	TotalItems int       `json:"totalItems"`
+	Pending    int       `json:"pending"`
+	Uploaded   int       `json:"uploaded"`
+	Failed     int       `json:"failed"`
+	Invalid    int       `json:"invalid"`
+	CreatedAt  time.Time `json:"createdAt"`
+
+	Items      []BatchJobItemResponse `json:"items,omitempty"`
+	NextCursor int                    `json:"nextCursor,omitempty"`
+}
+
+// BatchJobItemQuery pages the items of GET /diploma/batch/:jobId. Cursor is
+// the row number of the last item already seen.
+type BatchJobItemQuery struct {
+	Cursor int `form:"cursor"`
+	Limit  int `form:"limit"`
+}
+
+type BatchJobItemResponse struct {
+	ID          uuid.UUID `json:"id"`
+	RowNumber   int       `json:"rowNumber"`
+	FileName    string    `json:"fileName"`
+	StudentName string    `json:"studentName"`
+	Status      string    `json:"status"`
+	Error       string    `json:"error,omitempty"`
+	Attempts    int       `json:"attempts"`
+	DiplomaHash string    `json:"diplomaHash,omitempty"`
+	ArweaveTxID string    `json:"arweaveTxID,omitempty"`
+	ArweaveURL  string    `json:"arweaveUrl,omitempty"`
+}
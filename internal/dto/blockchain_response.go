@@ -1,6 +1,41 @@
 package dto
 
+import "time"
+
 type BlockchainResult struct {
+	Network         string
+	ExplorerURL     string
 	TransactionHash string
 	BlockNumber     uint64
+	BlockHash       string
+	GasUsed         uint64
+	Timestamp       time.Time
+	// Pending is set when the transaction was sent but not mined in time;
+	// only the network and transaction fields are filled in
+	Pending bool
+}
+
+// ChainDiplomaRecord is what the contract stores for a diploma hash.
+type ChainDiplomaRecord struct {
+	Exists      bool
+	ArweaveTxID string // from verifyDiploma
+
+	// From the full record of the contract that holds the hash; on v1 that
+	// is getDiploma(hashToId(hash))
+	ContractAddress   string
+	ContractVersion   string
+	ID                string // the v1 contract's counter, decimal
+	DiplomaHash       string
+	RecordArweaveTxID string
+	Owner             string
+	Revoked           bool
+	Timestamp         time.Time
+}
+
+// SubmittedTransaction is a transaction the backend broadcast but has not
+// seen mined yet.
+type SubmittedTransaction struct {
+	Network         string
+	TransactionHash string
+	Signer          string
 }
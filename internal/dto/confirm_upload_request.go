@@ -15,7 +15,7 @@ type ConfirmUploadRequest struct {
 	FirstName      string `json:"firstName" binding:"required"`
 	LastName       string `json:"lastName" binding:"required"`
 	Email          string `json:"email" binding:"required"`
-	University     string `json:"university" binding:"required"`
+	University     string `json:"university"` // ignored, the caller's university is used
 	Faculty        string `json:"faculty"`
 	Department     string `json:"department" binding:"required"`
 	GraduationYear int    `json:"graduationYear" binding:"required"`
@@ -0,0 +1,15 @@
+package dto
+
+// CredentialVerifyResponse is the result of checking a Verifiable Credential
+// exported by /diploma/records/:diplomaId/credential.
+type CredentialVerifyResponse struct {
+	Verified  bool   `json:"verified"`
+	Status    string `json:"status,omitempty"`
+	DiplomaID string `json:"diplomaId,omitempty"`
+	Issuer    string `json:"issuer,omitempty"`
+
+	SignatureValid bool     `json:"signatureValid"`
+	IssuerKnown    bool     `json:"issuerKnown"`
+	RecordMatch    bool     `json:"recordMatch"`
+	Details        []string `json:"details,omitempty"`
+}
@@ -0,0 +1,28 @@
+package dto
+
+// Agreement values describe how the on-chain and off-chain records relate.
+const (
+	AgreementMatch        = "match"
+	AgreementOnChainOnly  = "onchain_only"
+	AgreementOffChainOnly = "offchain_only"
+	AgreementMismatch     = "mismatch"
+	AgreementNotFound     = "not_found"
+)
+
+type VerifyFileResponse struct {
+	Verified    bool   `json:"verified"`
+	Status      string `json:"status,omitempty"`
+	DiplomaHash string `json:"diplomaHash"`
+
+	// On-chain (contract) view
+	OnChain            bool   `json:"onChain"`
+	OnChainArweaveTxID string `json:"onChainArweaveTxID,omitempty"`
+	OnChainNetwork     string `json:"onChainNetwork,omitempty"`
+
+	// Off-chain (database) view
+	InDatabase bool            `json:"inDatabase"`
+	Record     *VerifyResponse `json:"record,omitempty"`
+
+	Agreement  string   `json:"agreement"`
+	Mismatches []string `json:"mismatches,omitempty"`
+}
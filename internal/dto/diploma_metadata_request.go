@@ -4,7 +4,7 @@ type DiplomaMetadataRequest struct {
 	FirstName      string `json:"firstName" binding:"required"`
 	LastName       string `json:"lastName" binding:"required"`
 	Email          string `json:"email" binding:"required,email"`
-	University     string `json:"university" binding:"required"`
+	University     string `json:"university"` // ignored, the caller's university is used
 	Faculty        string `json:"faculty" binding:"required"`
 	Department     string `json:"department" binding:"required"`
 	GraduationYear int    `json:"graduationYear" binding:"required"`
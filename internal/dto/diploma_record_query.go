@@ -0,0 +1,14 @@
+package dto
+
+// DiplomaRecordQuery holds the query string of GET /diploma/records.
+// Dates use the YYYY-MM-DD format and are matched against the issue date.
+type DiplomaRecordQuery struct {
+	Cursor         string `form:"cursor"`
+	Limit          int    `form:"limit"`
+	GraduationYear int    `form:"graduationYear"`
+	Faculty        string `form:"faculty"`
+	Department     string `form:"department"`
+	From           string `form:"from"`
+	To             string `form:"to"`
+	Search         string `form:"q"`
+}
@@ -8,4 +8,11 @@ type HistoryResponse struct {
 	Department string    `json:"department"`
 	CreateDate time.Time `json:"createDate"`
 	DiplomaPdf string    `json:"diplomaPdf"`
+	Status     string    `json:"status"`
+}
+
+type HistoryPageResponse struct {
+	Items      []HistoryResponse `json:"items"`
+	Total      int64             `json:"total"`
+	NextCursor string            `json:"nextCursor,omitempty"`
 }
@@ -0,0 +1,5 @@
+package dto
+
+type RevokeDiplomaRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
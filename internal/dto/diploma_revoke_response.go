@@ -0,0 +1,12 @@
+package dto
+
+import "time"
+
+type RevokeDiplomaResponse struct {
+	DiplomaID     string    `json:"diplomaId"`
+	Status        string    `json:"status"`
+	Reason        string    `json:"reason"`
+	RevokedBy     string    `json:"revokedBy"`
+	RevokedAt     time.Time `json:"revokedAt"`
+	PolygonTxHash string    `json:"polygonTxHash,omitempty"`
+}
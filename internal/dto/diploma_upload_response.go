@@ -5,6 +5,9 @@ type UploadResponse struct {
 	DiplomaHash   string `json:"diplomaHash"`
 	ArweaveTxID   string `json:"arweaveTxID"`
 	ArweaveURL    string `json:"arweaveUrl"`
+	Storage       string `json:"storage"`
+	Network       string `json:"network,omitempty"`
 	PolygonTxHash string `json:"polygonTxHash"`
 	BlockNumber   uint64 `json:"blockNumber"`
+	Status        string `json:"status,omitempty"`
 }
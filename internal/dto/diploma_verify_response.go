@@ -2,13 +2,31 @@ package dto
 
 type VerifyResponse struct {
 	Verified      bool   `json:"verified"`
+	Status        string `json:"status,omitempty"`
 	DiplomaHash   string `json:"diplomaHash"`
 	ArweaveTxID   string `json:"arweaveTxID,omitempty"`
 	ArweaveURL    string `json:"arweaveUrl,omitempty"`
+	Storage       string `json:"storage,omitempty"`
 	StudentName   string `json:"studentName,omitempty"`
 	University    string `json:"university,omitempty"`
 	Degree        string `json:"degree,omitempty"` // Represented by Department/Faculty in models
 	IssueDate     string `json:"issueDate,omitempty"`
+	Network       string `json:"network,omitempty"`
 	PolygonTxHash string `json:"polygonTxHash,omitempty"`
 	DiplomaID     string `json:"diplomaID"`
+
+	// confirming, final or orphaned once the diploma is on-chain
+	Finality      string `json:"finality,omitempty"`
+	Confirmations uint64 `json:"confirmations,omitempty"`
+
+	// pending, confirming, permanent or dropped for Arweave documents
+	ArweaveStatus string `json:"arweaveStatus,omitempty"`
+	// Bundle transaction carrying the PDF when ArweaveTxID is a data item
+	ArweaveBundleID string `json:"arweaveBundleID,omitempty"`
+
+	RevocationReason string `json:"revocationReason,omitempty"`
+	RevokedAt        string `json:"revokedAt,omitempty"`
+
+	Merkle *MerkleProof        `json:"merkle,omitempty"`
+	Checks *VerificationChecks `json:"checks,omitempty"`
 }
@@ -0,0 +1,35 @@
+package dto
+
+import "time"
+
+// IndexerReportResponse compares the indexed DiplomaStored events with the
+// diploma table.
+type IndexerReportResponse struct {
+	Network       string `json:"network"`
+	StartBlock    uint64 `json:"startBlock"`
+	IndexedBlock  uint64 `json:"indexedBlock"`
+	IndexedEvents int64  `json:"indexedEvents"`
+
+	// On-chain diplomas with no database row
+	ChainOnly []IndexedEventResponse `json:"chainOnly"`
+	// Database rows whose storeDiploma event was not found on-chain
+	DatabaseOnly []UnindexedDiplomaResponse `json:"databaseOnly"`
+}
+
+type IndexedEventResponse struct {
+	ChainDiplomaID string    `json:"chainDiplomaId"`
+	DiplomaHash    string    `json:"diplomaHash"`
+	ArweaveTxID    string    `json:"arweaveTxID"`
+	Owner          string    `json:"owner"`
+	Timestamp      time.Time `json:"timestamp"`
+	BlockNumber    uint64    `json:"blockNumber"`
+	TxHash         string    `json:"txHash"`
+}
+
+type UnindexedDiplomaResponse struct {
+	DiplomaID     string `json:"diplomaId"`
+	DiplomaHash   string `json:"diplomaHash"`
+	PolygonTxHash string `json:"polygonTxHash"`
+	BlockNumber   uint64 `json:"blockNumber"`
+	UniversityID  string `json:"universityId"`
+}
@@ -0,0 +1,17 @@
+package dto
+
+import "time"
+
+type IssuanceResponse struct {
+	DiplomaID   string     `json:"diplomaId"`
+	Status      string     `json:"status"`
+	Attempts    int        `json:"attempts"`
+	Error       string     `json:"error,omitempty"`
+	Signer      string     `json:"signer,omitempty"`
+	TxHash      string     `json:"polygonTxHash,omitempty"`
+	BlockNumber uint64     `json:"blockNumber,omitempty"`
+	BlockHash   string     `json:"blockHash,omitempty"`
+	GasUsed     uint64     `json:"gasUsed,omitempty"`
+	SubmittedAt *time.Time `json:"submittedAt,omitempty"`
+	ConfirmedAt *time.Time `json:"confirmedAt,omitempty"`
+}
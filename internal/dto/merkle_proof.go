@@ -0,0 +1,9 @@
+package dto
+
+// MerkleProof lets a third party check a diploma without trusting our API:
+// recompute the leaf, fold in the proof and compare with the anchored root.
+type MerkleProof struct {
+	Root      string   `json:"root"`
+	Proof     []string `json:"proof"`
+	LeafIndex int      `json:"leafIndex"`
+}
@@ -2,6 +2,16 @@ package dto
 
 type PrepareUploadResponse struct {
 	DiplomaHash string `json:"diplomaHash"`
-	ArweaveTxID string `json:"arweaveTxID"`
-	ArweaveURL  string `json:"arweaveUrl"`
+	// The document reference to pass to storeDiploma: the Arweave TxID, or
+	// <store>://<address> for the other document stores
+	ArweaveTxID    string `json:"arweaveTxID"`
+	ArweaveURL     string `json:"arweaveUrl"`
+	Storage        string `json:"storage"`
+	ContentAddress string `json:"contentAddress"`
+
+	// Where MetaMask has to send storeDiploma
+	Network         string `json:"network"`
+	ChainID         int    `json:"chainId"`
+	ContractAddress string `json:"contractAddress"`
+	ContractVersion string `json:"contractVersion"`
 }
@@ -0,0 +1,34 @@
+package dto
+
+// PublicDiplomaResponse is what third parties see when verifying a diploma.
+// It deliberately leaves out private metadata such as email, student number
+// and nationality.
+type PublicDiplomaResponse struct {
+	Verified       bool   `json:"verified"`
+	Status         string `json:"status"`
+	DiplomaID      string `json:"diplomaId"`
+	StudentName    string `json:"studentName"`
+	University     string `json:"university,omitempty"`
+	Faculty        string `json:"faculty,omitempty"`
+	Department     string `json:"department,omitempty"`
+	Degree         string `json:"degree,omitempty"`
+	GraduationYear int    `json:"graduationYear,omitempty"`
+	IssueDate      string `json:"issueDate,omitempty"`
+
+	DiplomaHash     string `json:"diplomaHash"`
+	ArweaveTxID     string `json:"arweaveTxID,omitempty"`
+	ArweaveURL      string `json:"arweaveUrl,omitempty"`
+	Storage         string `json:"storage,omitempty"`
+	Network         string `json:"network,omitempty"`
+	PolygonTxHash   string `json:"polygonTxHash,omitempty"`
+	Finality        string `json:"finality,omitempty"`
+	Confirmations   uint64 `json:"confirmations,omitempty"`
+	ArweaveStatus   string `json:"arweaveStatus,omitempty"`
+	ArweaveBundleID string `json:"arweaveBundleID,omitempty"`
+
+	RevocationReason string `json:"revocationReason,omitempty"`
+	RevokedAt        string `json:"revokedAt,omitempty"`
+
+	Merkle *MerkleProof        `json:"merkle,omitempty"`
+	Checks *VerificationChecks `json:"checks,omitempty"`
+}
@@ -0,0 +1,21 @@
+package dto
+
+// QueueUploadRequest queues an uploaded diploma for Merkle anchoring. It is
+// the Merkle mode counterpart of ConfirmUploadRequest: there is no Polygon
+// transaction yet, the backend anchors the diploma with the next batch.
+type QueueUploadRequest struct {
+	// Diploma identity (returned by /prepare)
+	DiplomaHash string `json:"diplomaHash" binding:"required"`
+	ArweaveTxID string `json:"arweaveTxID" binding:"required"`
+
+	// Student metadata
+	FirstName      string `json:"firstName" binding:"required"`
+	LastName       string `json:"lastName" binding:"required"`
+	Email          string `json:"email" binding:"required"`
+	University     string `json:"university"` // ignored, the caller's university is used
+	Faculty        string `json:"faculty"`
+	Department     string `json:"department" binding:"required"`
+	GraduationYear int    `json:"graduationYear" binding:"required"`
+	StudentNumber  string `json:"studentNumber"`
+	Nationality    string `json:"nationality"`
+}
@@ -0,0 +1,6 @@
+package dto
+
+type ReconciliationRequest struct {
+	// Fill in missing PolygonTxID / ArweaveURL from the chain and Arweave
+	Repair bool `json:"repair"`
+}
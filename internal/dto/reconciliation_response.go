@@ -0,0 +1,27 @@
+package dto
+
+import "time"
+
+type ReconciliationJobResponse struct {
+	JobID      string     `json:"jobId"`
+	Status     string     `json:"status"`
+	Repair     bool       `json:"repair"`
+	Error      string     `json:"error,omitempty"`
+	Checked    int        `json:"checked"`
+	Mismatches int        `json:"mismatches"`
+	Repaired   int        `json:"repaired"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+
+	Items []ReconciliationMismatchResponse `json:"items,omitempty"`
+}
+
+type ReconciliationMismatchResponse struct {
+	DiplomaID   string `json:"diplomaId"`
+	DiplomaHash string `json:"diplomaHash"`
+	Kind        string `json:"kind"`
+	Expected    string `json:"expected,omitempty"`
+	Actual      string `json:"actual,omitempty"`
+	Details     string `json:"details,omitempty"`
+	Repaired    bool   `json:"repaired"`
+}
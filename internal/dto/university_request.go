@@ -0,0 +1,6 @@
+package dto
+
+type UniversityRequest struct {
+	Name    string `json:"name" validate:"required,min=2"`
+	YokCode string `json:"yokCode" validate:"required"`
+}
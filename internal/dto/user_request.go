@@ -4,8 +4,13 @@ type RegisterRequest struct {
 	FirstName    string `json:"firstName" validate:"required,min=2"`
 	LastName     string `json:"lastName" validate:"required,min=2"`
 	Email        string `json:"email" validate:"required,email"`
-	UniversityID string `json:"universityId" validate:"required"`
+	UniversityID string `json:"universityId"` // optional, must match the invite's
 	Password     string `json:"password" validate:"required,min=8"`
+	InviteToken  string `json:"inviteToken" validate:"required"`
+}
+
+type AdminInviteRequest struct {
+	Email string `json:"email" validate:"required,email"`
 }
 
 type LoginRequest struct {
@@ -1,8 +1,17 @@
 package dto
 
+import "time"
+
 type LoginResponse struct {
 	Token     string `json:"token"` // Aliasing for frontend
 	TokenType string `json:"tokenType"`
 	ExpiresIn int64  `json:"expiresIn"`
 	Role      string `json:"role"`
 }
+
+type AdminInviteResponse struct {
+	Token        string    `json:"token"` // shown once, not stored
+	UniversityID string    `json:"universityId"`
+	Email        string    `json:"email"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+}
@@ -0,0 +1,18 @@
+package dto
+
+// VerificationChecks is the per-source breakdown of a diploma verification.
+// Each flag is computed independently so a verifier can see which layer disagrees.
+type VerificationChecks struct {
+	// ChainMatch: the contract knows the hash and points to the same Arweave TxID
+	ChainMatch bool `json:"chainMatch"`
+	// ArweaveMatch: the file hash recorded by the document store (the Arweave
+	// File-Hash tag) and the SHA-256 of the stored data equal the hash
+	ArweaveMatch bool `json:"arweaveMatch"`
+	// DBMatch: the database row is complete and consistent with the public ID
+	DBMatch bool `json:"dbMatch"`
+
+	Details []string `json:"details,omitempty"`
+	// Warnings do not fail the verification, e.g. an Arweave file that is
+	// stored but not permanent yet
+	Warnings []string `json:"warnings,omitempty"`
+}
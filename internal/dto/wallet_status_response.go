@@ -0,0 +1,27 @@
+package dto
+
+import "time"
+
+type WalletStatusResponse struct {
+	Arweave WalletFundingResponse `json:"arweave"`
+	Matic   WalletFundingResponse `json:"matic"`
+	// RemainingDiplomas is how many more diplomas both wallets can pay for;
+	// absent when neither pays per diploma or a balance is unknown
+	RemainingDiplomas *int64    `json:"remainingDiplomas,omitempty"`
+	Alerts            []string  `json:"alerts"`
+	CheckedAt         time.Time `json:"checkedAt"`
+}
+
+type WalletFundingResponse struct {
+	Currency string `json:"currency"` // AR or MATIC
+	Network  string `json:"network,omitempty"`
+	Address  string `json:"address,omitempty"`
+	Balance  string `json:"balance,omitempty"`
+	// PaysDiplomas is false when this wallet is not charged for new
+	// diplomas, e.g. with another storage backend or a bundler
+	PaysDiplomas      bool   `json:"paysDiplomas"`
+	DiplomaCost       string `json:"diplomaCost,omitempty"` // estimated, at current prices
+	RemainingDiplomas *int64 `json:"remainingDiplomas,omitempty"`
+	Low               bool   `json:"low"`
+	Error             string `json:"error,omitempty"`
+}
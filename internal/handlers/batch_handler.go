@@ -0,0 +1,322 @@
+package handlers
+
+import (
+	"BlockCertify/internal/dto"
+	"BlockCertify/internal/helper"
+	"BlockCertify/internal/services"
+	"BlockCertify/internal/utils"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid/v5"
+)
+
+// maxBatchUploadSize bounds the whole multipart body of a batch upload.
+const maxBatchUploadSize = 1 << 30 // 1 GB
+
+// batchManifestColumns lists the CSV header columns a manifest must contain.
+// "university" may be present but is ignored, the admin's university is used.
+var batchManifestColumns = []string{
+	"firstName", "lastName", "email", "faculty", "department",
+	"graduationYear", "studentNumber", "nationality", "file",
+}
+
+type BatchHandler struct {
+	service     services.BatchService
+	fileManager *utils.FileManager
+}
+
+func NewBatchHandler(service services.BatchService) *BatchHandler {
+	return &BatchHandler{
+		service:     service,
+		fileManager: utils.NewFileManager("uploads"),
+	}
+}
+
+// CreateBatch receives a CSV manifest and a ZIP of PDFs and queues one
+// upload per manifest row. Rows are validated up front, invalid ones are
+// reported in the job instead of rejecting the whole batch.
+func (h *BatchHandler) CreateBatch(c *gin.Context) {
+
+	contentType := c.GetHeader("Content-Type")
+	if !strings.HasPrefix(contentType, "multipart/form-data") {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid content type",
+			"details": "multipart/form-data required",
+		})
+		return
+	}
+
+	actor, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not found",
+		})
+		return
+	}
+
+	universityID, ok := currentUniversityID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Admin is not linked to a university",
+		})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBatchUploadSize)
+
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid multipart request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	var (
+		rows        []dto.BatchManifestRow
+		archivePath string
+	)
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Failed to read multipart data",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		switch part.FormName() {
+
+		case "manifest":
+			rows, err = parseBatchManifest(part)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":   "Invalid manifest",
+					"details": err.Error(),
+				})
+				return
+			}
+
+		case "archive":
+			if !strings.HasSuffix(strings.ToLower(part.FileName()), ".zip") {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":   "Invalid file type",
+					"details": "archive must be a ZIP file",
+				})
+				return
+			}
+
+			archivePath, err = h.fileManager.SaveUploadedFile(part, uuid.Must(uuid.NewV4()).String()+".zip")
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error":   "Failed to save file",
+					"details": err.Error(),
+				})
+				return
+			}
+			defer h.fileManager.DeleteFile(archivePath)
+		}
+	}
+
+	if rows == nil || archivePath == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "manifest and archive are required",
+		})
+		return
+	}
+
+	response, err := h.service.CreateJob(rows, archivePath, universityID, actor)
+	if err != nil {
+		writeAppError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, response)
+}
+
+// ListBatches returns the batch jobs of the admin's university.
+func (h *BatchHandler) ListBatches(c *gin.Context) {
+
+	universityID, ok := currentUniversityID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Admin is not linked to a university",
+		})
+		return
+	}
+
+	response, err := h.service.ListJobs(universityID)
+	if err != nil {
+		writeAppError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetBatch returns the per-row report of a batch job, one page of rows at a
+// time. The cursor and limit query parameters page through the rows.
+func (h *BatchHandler) GetBatch(c *gin.Context) {
+
+	universityID, ok := currentUniversityID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Admin is not linked to a university",
+		})
+		return
+	}
+
+	jobID, err := uuid.FromString(c.Param("jobId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid job id",
+		})
+		return
+	}
+
+	var query dto.BatchJobItemQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid query",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	response, err := h.service.GetJob(jobID, universityID, query)
+	if err != nil {
+		writeAppError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// RetryBatch re-queues the failed items of a batch job. The optional itemId
+// query parameter limits the retry to a single row.
+func (h *BatchHandler) RetryBatch(c *gin.Context) {
+
+	universityID, ok := currentUniversityID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Admin is not linked to a university",
+		})
+		return
+	}
+
+	jobID, err := uuid.FromString(c.Param("jobId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid job id",
+		})
+		return
+	}
+
+	itemID := uuid.Nil
+	if raw := strings.TrimSpace(c.Query("itemId")); raw != "" {
+		itemID, err = uuid.FromString(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid item id",
+			})
+			return
+		}
+	}
+
+	response, err := h.service.Retry(jobID, itemID, universityID)
+	if err != nil {
+		writeAppError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, response)
+}
+
+// parseBatchManifest reads a manifest CSV. Header names are matched case
+// insensitively, row numbers are counted from the first data row.
+func parseBatchManifest(r io.Reader) ([]dto.BatchManifestRow, error) {
+
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, errors.New("manifest is empty")
+		}
+		return nil, err
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	for _, name := range batchManifestColumns {
+		if _, ok := columns[strings.ToLower(name)]; !ok {
+			return nil, fmt.Errorf("missing column %q", name)
+		}
+	}
+
+	var rows []dto.BatchManifestRow
+
+	for rowNumber := 1; ; rowNumber++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		field := func(name string) string {
+			i, ok := columns[strings.ToLower(name)]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		row := dto.BatchManifestRow{
+			RowNumber: rowNumber,
+			FileName:  field("file"),
+			Metadata: dto.DiplomaMetadataRequest{
+				FirstName:      field("firstName"),
+				LastName:       field("lastName"),
+				Email:          field("email"),
+				Faculty:        field("faculty"),
+				Department:     field("department"),
+				GraduationYear: helper.AtoiSafe(field("graduationYear")),
+				StudentNumber:  field("studentNumber"),
+				Nationality:    field("nationality"),
+			},
+		}
+
+		if row.FileName == "" {
+			row.Error = "file is required"
+		} else if err := validateUploadMetadata(row.Metadata); err != nil {
+			row.Error = err.Error()
+		}
+
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return nil, errors.New("manifest has no rows")
+	}
+
+	return rows, nil
+}
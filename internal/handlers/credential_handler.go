@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	apperrors "BlockCertify/internal/pkg/errors"
+	"BlockCertify/internal/services"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CredentialHandler struct {
+	service  services.CredentialService
+	diplomas services.DiplomaService
+}
+
+func NewCredentialHandler(service services.CredentialService, diplomas services.DiplomaService) *CredentialHandler {
+	return &CredentialHandler{
+		service:  service,
+		diplomas: diplomas,
+	}
+}
+
+// GetCredential downloads the diploma as a signed W3C Verifiable Credential.
+// The optional format query parameter picks the layout (vc, ob3, edc, or
+// edc-xml for the EDC credential in XML).
+// Access rules are the same as for the diploma PDF.
+func (h *CredentialHandler) GetCredential(c *gin.Context) {
+
+	diplomaID := strings.TrimSpace(c.Param("diplomaId"))
+
+	user, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not found",
+		})
+		return
+	}
+
+	universityID, _ := currentUniversityID(c)
+	if !h.diplomas.CanAccessDiploma(diplomaID, user, universityID) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Insufficient permissions",
+		})
+		return
+	}
+
+	format := strings.ToLower(strings.TrimSpace(c.Query("format")))
+
+	layout := format
+	if format == services.EDCXMLFormat {
+		layout = "edc"
+	}
+
+	credential, err := h.service.IssueCredential(diplomaID, layout)
+	if err != nil {
+		appErr, ok := err.(*apperrors.AppError)
+		if ok {
+			errDetails := ""
+			if appErr.Err != nil {
+				errDetails = appErr.Err.Error()
+			}
+			c.JSON(statusForAppError(appErr), gin.H{
+				"error":   appErr.Message,
+				"details": errDetails,
+				"code":    appErr.Code,
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+		}
+		return
+	}
+
+	if format == services.EDCXMLFormat {
+		body, err := services.EncodeEDCXML(credential)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.edc.xml", diplomaID))
+		c.Data(http.StatusOK, "application/xml; charset=utf-8", body)
+		return
+	}
+
+	if format == "" {
+		format = "credential"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.%s.json", diplomaID, format))
+	c.JSON(http.StatusOK, credential)
+}
+
+// VerifyCredential checks a credential posted as JSON.
+func (h *CredentialHandler) VerifyCredential(c *gin.Context) {
+
+	var credential map[string]any
+	if err := c.ShouldBindJSON(&credential); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid credential",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	response, err := h.service.VerifyCredential(credential)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"BlockCertify/internal/dto"
 	"BlockCertify/internal/helper"
+	"BlockCertify/internal/models"
 	apperrors "BlockCertify/internal/pkg/errors"
 	"BlockCertify/internal/services"
 	"BlockCertify/internal/utils"
@@ -17,8 +18,12 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid/v5"
 )
 
+// maxVerifyFileSize bounds PDFs posted to the public verify endpoint.
+const maxVerifyFileSize = 10 << 20 // 10 MB
+
 type DiplomaHandler struct {
 	service     services.DiplomaService
 	fileManager *utils.FileManager
@@ -170,17 +175,54 @@ func (h *DiplomaHandler) ConfirmUpload(c *gin.Context) {
 		return
 	}
 
-	response, err := h.service.ConfirmUpload(req)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
+	universityID, ok := currentUniversityID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Admin is not linked to a university",
 		})
 		return
 	}
 
+	response, err := h.service.ConfirmUpload(req, universityID)
+	if err != nil {
+		writeAppError(c, err)
+		return
+	}
+
 	c.JSON(http.StatusOK, response)
 }
 
+// QueueUpload records a diploma uploaded via /prepare for backend anchoring.
+// It replaces the MetaMask step when ANCHORING_MODE=merkle or
+// ISSUANCE_MODE=server.
+func (h *DiplomaHandler) QueueUpload(c *gin.Context) {
+
+	var req dto.QueueUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	universityID, ok := currentUniversityID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Admin is not linked to a university",
+		})
+		return
+	}
+
+	response, err := h.service.QueueUpload(req, universityID)
+	if err != nil {
+		writeAppError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, response)
+}
+
 func (h *DiplomaHandler) Verify(c *gin.Context) {
 
 	var req dto.VerifyDiplomaRequest
@@ -192,8 +234,16 @@ func (h *DiplomaHandler) Verify(c *gin.Context) {
 		return
 	}
 
+	universityID, ok := currentUniversityID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Admin is not linked to a university",
+		})
+		return
+	}
+
 	// Verify diploma
-	response, err := h.service.Verify(req)
+	response, err := h.service.Verify(req, universityID)
 	if err != nil {
 		appErr, ok := err.(*apperrors.AppError)
 		if ok {
@@ -211,6 +261,117 @@ func (h *DiplomaHandler) Verify(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// VerifyFile verifies a diploma from the PDF itself. The file is hashed and
+// checked against the contract and the database; it is never stored.
+func (h *DiplomaHandler) VerifyFile(c *gin.Context) {
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxVerifyFileSize)
+
+	fileHeader, err := c.FormFile("diploma")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid multipart request",
+			"details": "diploma file is missing",
+		})
+		return
+	}
+
+	if !strings.HasSuffix(strings.ToLower(fileHeader.Filename), ".pdf") {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid file type",
+			"details": "Only PDF files are allowed",
+		})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to read file",
+			"details": err.Error(),
+		})
+		return
+	}
+	defer file.Close()
+
+	// Public uploads get a random name so concurrent requests never collide
+	filePath, err := h.fileManager.SaveFile(file, uuid.Must(uuid.NewV4()).String()+".pdf")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to save file",
+			"details": err.Error(),
+		})
+		return
+	}
+	defer h.fileManager.DeleteFile(filePath)
+
+	diplomaHash, err := utils.HashFile(filePath)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to hash file",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	response, err := h.service.VerifyFile(diplomaHash)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to verify diploma",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// PublicVerify verifies a diploma by its public ID without requiring a login.
+func (h *DiplomaHandler) PublicVerify(c *gin.Context) {
+
+	var req dto.VerifyDiplomaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	h.writePublicDiploma(c, strings.TrimSpace(req.DiplomaID))
+}
+
+// GetPublicDiploma returns the public view of a diploma by its public ID.
+func (h *DiplomaHandler) GetPublicDiploma(c *gin.Context) {
+	h.writePublicDiploma(c, strings.TrimSpace(c.Param("diplomaId")))
+}
+
+func (h *DiplomaHandler) writePublicDiploma(c *gin.Context, publicID string) {
+
+	if publicID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid Diploma Id",
+		})
+		return
+	}
+
+	response, err := h.service.GetPublicDiploma(publicID)
+	if err != nil {
+		appErr, ok := err.(*apperrors.AppError)
+		if ok {
+			c.JSON(statusForAppError(appErr), gin.H{
+				"error": appErr.Message,
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to verify diploma",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
 func (h *DiplomaHandler) GetDiplomaById(c *gin.Context) {
 
 	publicID := strings.TrimSpace(c.Param("diplomaId"))
@@ -219,43 +380,151 @@ func (h *DiplomaHandler) GetDiplomaById(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "Invalid Diploma Id",
 		})
+		return
 	}
 
 	slog.Info("stream diploma request", "publicID", publicID)
 
-	arweaveUrl := h.service.GetArweaveUrlByDiplomaID(publicID)
-
-	resp, err := http.Get(arweaveUrl)
+	// Read through the document stores, so every backend works and
+	// encrypted PDFs come back decrypted
+	pdf, err := h.service.ReadDiplomaPDF(publicID)
 	if err != nil {
-		slog.Error("Failed to fetch diploma", "err", err)
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Failed to fetch diploma",
+		slog.Error("Failed to read diploma", "publicID", publicID, "err", err)
+		appErr, ok := err.(*apperrors.AppError)
+		if ok {
+			c.JSON(statusForAppError(appErr), gin.H{
+				"error": appErr.Message,
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to fetch diploma",
+			})
+		}
+		return
+	}
+
+	c.Header("Content-Disposition", "inline; filename=diploma.pdf")
+	c.Data(http.StatusOK, "application/pdf", pdf)
+}
+
+// GetDiplomaRecords lists diplomas page by page. Students only ever get their own.
+func (h *DiplomaHandler) GetDiplomaRecords(c *gin.Context) {
+
+	user, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not found",
 		})
 		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		slog.Error("arweave returned non-200 status code", "statusCode", resp.StatusCode)
-		c.JSON(resp.StatusCode, gin.H{
-			"error": "file not found",
+	if user.Role == models.RoleStudent {
+		c.JSON(http.StatusOK, h.service.GetDiplomasOwnedBy(user))
+		return
+	}
+
+	universityID, ok := currentUniversityID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Admin is not linked to a university",
 		})
+		return
 	}
 
-	c.Header("Content-Type", "application/pdf")
-	c.Header("Content-Disposition", "inline; filename=diploma.pdf")
+	var query dto.DiplomaRecordQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid query",
+			"details": err.Error(),
+		})
+		return
+	}
 
-	_, err = io.Copy(c.Writer, resp.Body)
+	records, err := h.service.GetDiplomaRecords(query, universityID)
 	if err != nil {
-		slog.Error("Failed to write response", "err", err)
+		appErr, ok := err.(*apperrors.AppError)
+		if ok {
+			c.JSON(statusForAppError(appErr), gin.H{
+				"error": appErr.Message,
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to list diplomas",
+			})
+		}
+		return
 	}
+
+	c.JSON(http.StatusOK, records)
 }
 
-func (h *DiplomaHandler) GetDiplomaRecords(c *gin.Context) {
+// GetDiplomaRecordById streams a diploma PDF to a logged-in user.
+// Students may only open diplomas issued to them, admins only diplomas of
+// their own university.
+func (h *DiplomaHandler) GetDiplomaRecordById(c *gin.Context) {
 
-	records := h.service.GetAllDiplomaFromDatabase()
+	user, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not found",
+		})
+		return
+	}
 
-	c.JSON(http.StatusOK, records)
+	universityID, _ := currentUniversityID(c)
+	if !h.service.CanAccessDiploma(strings.TrimSpace(c.Param("diplomaId")), user, universityID) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Insufficient permissions",
+		})
+		return
+	}
+
+	h.GetDiplomaById(c)
+}
+
+// RevokeDiploma revokes an issued diploma on behalf of the logged-in registrar.
+func (h *DiplomaHandler) RevokeDiploma(c *gin.Context) {
+
+	publicID := strings.TrimSpace(c.Param("diplomaId"))
+	if publicID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid Diploma Id",
+		})
+		return
+	}
+
+	var req dto.RevokeDiplomaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	actor, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not found",
+		})
+		return
+	}
+
+	universityID, ok := currentUniversityID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Admin is not linked to a university",
+		})
+		return
+	}
+
+	response, err := h.service.Revoke(publicID, req, actor, universityID)
+	if err != nil {
+		writeAppError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
 func validateUploadMetadata(meta dto.DiplomaMetadataRequest) error {
@@ -268,9 +537,6 @@ func validateUploadMetadata(meta dto.DiplomaMetadataRequest) error {
 	if strings.TrimSpace(meta.Email) == "" {
 		return errors.New("email is required")
 	}
-	if strings.TrimSpace(meta.University) == "" {
-		return errors.New("university is required")
-	}
 	if strings.TrimSpace(meta.Department) == "" {
 		return errors.New("department is required")
 	}
@@ -280,6 +546,78 @@ func validateUploadMetadata(meta dto.DiplomaMetadataRequest) error {
 	return nil
 }
 
+// statusForAppError maps client-caused application errors to 4xx codes.
+// Everything else is treated as a server side failure.
+func statusForAppError(appErr *apperrors.AppError) int {
+	switch appErr.Code {
+	case apperrors.ErrInvalidRequest,
+		apperrors.ErrInvalidInvite,
+		apperrors.ErrTransactionNotFound,
+		apperrors.ErrTransactionMismatch:
+		return http.StatusBadRequest
+	case apperrors.ErrDiplomaNotFound,
+		apperrors.ErrUniversityNotFound,
+		apperrors.ErrBatchJobNotFound,
+		apperrors.ErrReconciliationNotFound:
+		return http.StatusNotFound
+	case apperrors.ErrForbidden:
+		return http.StatusForbidden
+	case apperrors.ErrDiplomaExists,
+		apperrors.ErrUserExists,
+		apperrors.ErrDiplomaRevoked,
+		apperrors.ErrNotSupported:
+		return http.StatusConflict
+	case apperrors.ErrInsufficientBalance:
+		return http.StatusServiceUnavailable
+	case apperrors.ErrDocumentReadFailed:
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeAppError writes err as JSON, with the status statusForAppError picks
+// for application errors and 500 for anything else.
+func writeAppError(c *gin.Context, err error) {
+	appErr, ok := err.(*apperrors.AppError)
+	if ok {
+		errDetails := ""
+		if appErr.Err != nil {
+			errDetails = appErr.Err.Error()
+		}
+		c.JSON(statusForAppError(appErr), gin.H{
+			"error":   appErr.Message,
+			"details": errDetails,
+			"code":    appErr.Code,
+		})
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error": err.Error(),
+	})
+}
+
+// currentUser returns the user stored in the context by AuthMiddleware.
+func currentUser(c *gin.Context) (*models.User, bool) {
+	value, exists := c.Get("user")
+	if !exists {
+		return nil, false
+	}
+	user, ok := value.(*models.User)
+	return user, ok && user != nil
+}
+
+// currentUniversityID returns the university AuthMiddleware scoped the admin to.
+func currentUniversityID(c *gin.Context) (uuid.UUID, bool) {
+	value, exists := c.Get("universityID")
+	if !exists {
+		return uuid.Nil, false
+	}
+	universityID, ok := value.(uuid.UUID)
+	return universityID, ok && universityID != uuid.Nil
+}
+
 func readPartValue(part *multipart.Part) string {
 	b, _ := io.ReadAll(part)
 	return strings.TrimSpace(string(b))
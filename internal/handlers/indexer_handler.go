@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"BlockCertify/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IndexerHandler serves the platform level DiplomaStored indexer routes.
+// Only superadmins reach these handlers.
+type IndexerHandler struct {
+	service services.IndexerService
+}
+
+func NewIndexerHandler(service services.IndexerService) *IndexerHandler {
+	return &IndexerHandler{
+		service: service,
+	}
+}
+
+// GetReport returns the on-chain diplomas missing from the database and the
+// database rows missing on-chain.
+func (h *IndexerHandler) GetReport(c *gin.Context) {
+
+	report, err := h.service.GetReport()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// Sync indexes new blocks right away instead of waiting for the next poll.
+func (h *IndexerHandler) Sync(c *gin.Context) {
+
+	indexed, err := h.service.Sync()
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"indexed": indexed})
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"BlockCertify/internal/services"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type IssuanceHandler struct {
+	service      services.IssuanceService
+	transactions services.TransactionManager
+}
+
+func NewIssuanceHandler(service services.IssuanceService, transactions services.TransactionManager) *IssuanceHandler {
+	return &IssuanceHandler{
+		service:      service,
+		transactions: transactions,
+	}
+}
+
+// GetIssuance returns the state and receipt of a server signed diploma.
+func (h *IssuanceHandler) GetIssuance(c *gin.Context) {
+
+	universityID, ok := currentUniversityID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Admin is not linked to a university",
+		})
+		return
+	}
+
+	response, err := h.service.GetIssuance(strings.TrimSpace(c.Param("diplomaId")), universityID)
+	if err != nil {
+		writeAppError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// RetryIssuance re-queues a failed server signed issuance.
+func (h *IssuanceHandler) RetryIssuance(c *gin.Context) {
+
+	universityID, ok := currentUniversityID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Admin is not linked to a university",
+		})
+		return
+	}
+
+	response, err := h.service.Retry(strings.TrimSpace(c.Param("diplomaId")), universityID)
+	if err != nil {
+		writeAppError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, response)
+}
+
+// GetAnchoringStatus lists the backend transactions anchoring a diploma and
+// whether they are pending, mined, failed or replaced.
+func (h *IssuanceHandler) GetAnchoringStatus(c *gin.Context) {
+
+	universityID, ok := currentUniversityID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Admin is not linked to a university",
+		})
+		return
+	}
+
+	response, err := h.transactions.GetAnchoringStatus(strings.TrimSpace(c.Param("diplomaId")), universityID)
+	if err != nil {
+		writeAppError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
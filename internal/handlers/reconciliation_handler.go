@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"BlockCertify/internal/dto"
+	"BlockCertify/internal/services"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+var reconciliationCSVColumns = []string{
+	"diplomaId", "diplomaHash", "kind", "expected", "actual", "details", "repaired",
+}
+
+type ReconciliationHandler struct {
+	service services.ReconciliationService
+}
+
+func NewReconciliationHandler(service services.ReconciliationService) *ReconciliationHandler {
+	return &ReconciliationHandler{
+		service: service,
+	}
+}
+
+// StartReconciliation queues a reconciliation of every diploma of the
+// admin's university. With {"repair": true} missing PolygonTxID and
+// ArweaveURL values are filled in as well.
+func (h *ReconciliationHandler) StartReconciliation(c *gin.Context) {
+
+	var req dto.ReconciliationRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+	}
+
+	actor, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	universityID, ok := currentUniversityID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Admin is not linked to a university",
+		})
+		return
+	}
+
+	response, err := h.service.StartJob(req, actor, universityID)
+	if err != nil {
+		writeAppError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, response)
+}
+
+func (h *ReconciliationHandler) ListReconciliations(c *gin.Context) {
+
+	universityID, ok := currentUniversityID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Admin is not linked to a university",
+		})
+		return
+	}
+
+	response, err := h.service.ListJobs(universityID)
+	if err != nil {
+		writeAppError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *ReconciliationHandler) GetReconciliation(c *gin.Context) {
+
+	universityID, ok := currentUniversityID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Admin is not linked to a university",
+		})
+		return
+	}
+
+	response, err := h.service.GetJob(c.Param("jobId"), universityID)
+	if err != nil {
+		writeAppError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// DownloadReport serves the mismatches of a job as an attachment, in CSV
+// (?format=csv, the default) or JSON.
+func (h *ReconciliationHandler) DownloadReport(c *gin.Context) {
+
+	universityID, ok := currentUniversityID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Admin is not linked to a university",
+		})
+		return
+	}
+
+	format := strings.ToLower(c.DefaultQuery("format", "csv"))
+	if format != "csv" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or json"})
+		return
+	}
+
+	response, err := h.service.GetJob(c.Param("jobId"), universityID)
+	if err != nil {
+		writeAppError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=reconciliation-%s.%s", response.JobID, format))
+
+	if format == "json" {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+
+	writer := csv.NewWriter(c.Writer)
+	_ = writer.Write(reconciliationCSVColumns)
+	for _, item := range response.Items {
+		_ = writer.Write([]string{
+			csvCell(item.DiplomaID),
+			csvCell(item.DiplomaHash),
+			csvCell(item.Kind),
+			csvCell(item.Expected),
+			csvCell(item.Actual),
+			csvCell(item.Details),
+			strconv.FormatBool(item.Repaired),
+		})
+	}
+	writer.Flush()
+}
+
+// csvCell keeps spreadsheets from evaluating a value as a formula: values
+// that start with a formula character get a leading quote.
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"BlockCertify/internal/dto"
+	"BlockCertify/internal/services"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UniversityHandler serves the platform level university management routes.
+// Only superadmins reach these handlers.
+type UniversityHandler struct {
+	service services.UniversityService
+}
+
+func NewUniversityHandler(service services.UniversityService) *UniversityHandler {
+	return &UniversityHandler{
+		service: service,
+	}
+}
+
+func (h *UniversityHandler) CreateUniversity(c *gin.Context) {
+
+	var req dto.UniversityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	actor, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	response, err := h.service.CreateUniversity(req, actor)
+	if err != nil {
+		writeAppError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+func (h *UniversityHandler) UpdateUniversity(c *gin.Context) {
+
+	var req dto.UniversityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	actor, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	response, err := h.service.UpdateUniversity(strings.TrimSpace(c.Param("universityId")), req, actor)
+	if err != nil {
+		writeAppError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *UniversityHandler) DeleteUniversity(c *gin.Context) {
+
+	if err := h.service.DeleteUniversity(strings.TrimSpace(c.Param("universityId"))); err != nil {
+		writeAppError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Success",
+	})
+}
@@ -91,6 +91,74 @@ func (h *UserHandler) RegisterAdmin(c *gin.Context) {
 
 }
 
+// RegisterStudent opens a student account from an admin's invite.
+func (h *UserHandler) RegisterStudent(c *gin.Context) {
+
+	var req dto.RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if err := h.service.RegisterStudent(req); err != nil {
+		writeAppError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Success",
+	})
+}
+
+// CreateStudentInvite invites the graduate named on a diploma of the admin's
+// university; registering as a student requires the returned token.
+func (h *UserHandler) CreateStudentInvite(c *gin.Context) {
+
+	actor, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+	universityID, ok := currentUniversityID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin is not linked to a university"})
+		return
+	}
+
+	response, err := h.service.CreateStudentInvite(c.Param("diplomaId"), universityID, actor)
+	if err != nil {
+		writeAppError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// CreateAdminInvite invites the admin of a university; registering as an
+// admin requires the returned token.
+func (h *UserHandler) CreateAdminInvite(c *gin.Context) {
+
+	var req dto.AdminInviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	actor, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	response, err := h.service.CreateInvite(c.Param("universityId"), req, actor)
+	if err != nil {
+		writeAppError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
 func (h *UserHandler) GetUniversities(c *gin.Context) {
 
 	universities, err := h.uniService.GetUniversitiesFromDBRecord()
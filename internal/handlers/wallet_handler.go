@@ -11,11 +11,13 @@ import (
 
 type WalletHandler struct {
 	service services.WalletService
+	funding services.FundingService
 }
 
-func NewWalletHandler(service services.WalletService) *WalletHandler {
+func NewWalletHandler(service services.WalletService, funding services.FundingService) *WalletHandler {
 	return &WalletHandler{
 		service: service,
+		funding: funding,
 	}
 }
 
@@ -55,6 +57,7 @@ func (h *WalletHandler) GetArweaveKeyFileJSON(c *gin.Context) {
 			"error":   err.Error(),
 			"details": "failed to connect wallet",
 		})
+		return
 	}
 
 	address := h.service.GetAddress(wallet)
@@ -67,3 +70,9 @@ func (h *WalletHandler) GetArweaveKeyFileJSON(c *gin.Context) {
 		"status":  "wallet connected",
 	})
 }
+
+// GetWalletStatus shows the AR and MATIC balances new diplomas are paid
+// from, what one more diploma costs and how many more both can pay for.
+func (h *WalletHandler) GetWalletStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, h.funding.Status())
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"BlockCertify/internal/models"
 	"BlockCertify/internal/repositories"
 	"BlockCertify/internal/security"
 	"net/http"
@@ -11,6 +12,7 @@ import (
 
 type AuthMiddleware interface {
 	Authorize() gin.HandlerFunc
+	RequireRole(roles ...models.UserRole) gin.HandlerFunc
 }
 
 type authMiddleware struct {
@@ -53,6 +55,7 @@ func (s *authMiddleware) Authorize() gin.HandlerFunc {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 				"error": "Invalid token",
 			})
+			return
 		}
 
 		user, err := s.userRepo.FindByEmail(email)
@@ -60,10 +63,52 @@ func (s *authMiddleware) Authorize() gin.HandlerFunc {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 				"error": "User not found",
 			})
+			return
 		}
 
 		c.Set("user", user)
+
+		// University admins are always scoped to their own university
+		if user.Role == models.RoleAdmin {
+			admin, err := s.userRepo.FindAdminByUserID(user.ID)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+					"error": "Admin is not linked to a university",
+				})
+				return
+			}
+			c.Set("universityID", admin.UniversityID)
+		}
+
 		c.Next()
 
 	}
 }
+
+// RequireRole only lets users with one of the given roles through.
+// It must run after Authorize, which loads the user from the database.
+func (s *authMiddleware) RequireRole(roles ...models.UserRole) gin.HandlerFunc {
+
+	return func(c *gin.Context) {
+
+		value, exists := c.Get("user")
+		user, ok := value.(*models.User)
+		if !exists || !ok || user == nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "User not found",
+			})
+			return
+		}
+
+		for _, role := range roles {
+			if user.Role == role {
+				c.Next()
+				return
+			}
+		}
+
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error": "Insufficient permissions",
+		})
+	}
+}
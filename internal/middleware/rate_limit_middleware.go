@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RateLimiter interface {
+	Limit() gin.HandlerFunc
+}
+
+// rateLimiter is an in-memory token bucket keyed by client IP.
+// Buckets refill continuously at requestsPerMinute and hold at most burst tokens.
+type rateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	rate    float64 // tokens per second
+	burst   float64
+}
+
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+func NewRateLimiter(requestsPerMinute, burst int) RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	rl := &rateLimiter{
+		buckets: make(map[string]*bucket),
+		rate:    float64(requestsPerMinute) / 60,
+		burst:   float64(burst),
+	}
+
+	go rl.evictIdle(10 * time.Minute)
+
+	return rl
+}
+
+func (rl *rateLimiter) Limit() gin.HandlerFunc {
+
+	return func(c *gin.Context) {
+
+		allowed, retryAfter := rl.allow(c.ClientIP(), time.Now())
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error": "Too many requests",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func (rl *rateLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &bucket{tokens: rl.burst, lastSeen: now}
+		rl.buckets[key] = b
+	}
+
+	b.tokens += now.Sub(b.lastSeen).Seconds() * rl.rate
+	if b.tokens > rl.burst {
+		b.tokens = rl.burst
+	}
+	b.lastSeen = now
+
+	if b.tokens < 1 {
+		if rl.rate <= 0 {
+			return false, time.Minute
+		}
+		return false, time.Duration((1 - b.tokens) / rl.rate * float64(time.Second))
+	}
+
+	b.tokens--
+	return true, 0
+}
+
+// evictIdle drops buckets that have been full for a while so the map does not
+// grow with every client that ever called the API.
+func (rl *rateLimiter) evictIdle(idle time.Duration) {
+	ticker := time.NewTicker(idle)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		rl.mu.Lock()
+		for key, b := range rl.buckets {
+			if now.Sub(b.lastSeen) > idle {
+				delete(rl.buckets, key)
+			}
+		}
+		rl.mu.Unlock()
+	}
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+const TableAdminInvite = "admin_invite"
+
+func (AdminInvite) TableName() string {
+	return TableAdminInvite
+}
+
+// AdminInvite lets the holder of its token register with Role at one
+// university: as its admin when a superadmin invited them, or as a student
+// when an admin invited the graduate named on one of its diplomas. Only the
+// token's hash is stored; the token itself is shown once, to the inviter.
+type AdminInvite struct {
+	ID           uuid.UUID `gorm:"primary_key;type:uuid"`
+	UniversityID uuid.UUID `gorm:"type:uuid;index;not null"`
+	Email        string    `gorm:"not null"`
+	Role         UserRole  `gorm:"not null;default:admin"`
+	TokenHash    string    `gorm:"uniqueIndex;not null"`
+	ExpiresAt    time.Time
+	UsedAt       *time.Time
+	CreatedByID  uuid.UUID `gorm:"type:uuid"`
+
+	CreatedAt time.Time
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+const (
+	TableArweaveUpload   = "arweave_upload"
+	TableArweaveDataItem = "arweave_data_item"
+)
+
+func (ArweaveUpload) TableName() string {
+	return TableArweaveUpload
+}
+
+func (ArweaveDataItem) TableName() string {
+	return TableArweaveDataItem
+}
+
+// ArweaveStatus is how settled a diploma's Arweave transaction is. It is
+// empty for diplomas kept in another document store.
+type ArweaveStatus string
+
+const (
+	// Posted, not in a block yet
+	ArweaveStatusPending ArweaveStatus = "pending"
+	// Mined, waiting for ARWEAVE_CONFIRMATION_DEPTH blocks on top of it
+	ArweaveStatusConfirming ArweaveStatus = "confirming"
+	// Deep enough and its data is seeded; the file is permanent
+	ArweaveStatusPermanent ArweaveStatus = "permanent"
+	// The gateway no longer knows the transaction or has no data for it; it
+	// is re-posted while its signed copy is kept
+	ArweaveStatusDropped ArweaveStatus = "dropped"
+	// Dropped for longer than its anchor stays valid and uploaded again
+	// under a new ID; only set on the upload, see ReplacedBy
+	ArweaveStatusReplaced ArweaveStatus = "replaced"
+)
+
+// ArweaveUpload is a signed Arweave transaction, a single PDF or a bundle of
+// them, kept until it is permanent so the tracker can post it and its data
+// chunks again if it is dropped.
+type ArweaveUpload struct {
+	ID            uuid.UUID     `gorm:"primary_key;type:uuid"`
+	TxID          string        `gorm:"uniqueIndex;not null"`
+	Status        ArweaveStatus `gorm:"index;not null"`
+	Confirmations int
+	BlockHeight   int
+	// SignedTx is the goar transaction header as JSON; the data is kept in
+	// ARWEAVE_PAYLOAD_DIR. Cleared once the transaction is permanent
+	SignedTx []byte
+	Reposts  int       // attempts to post it again, failed ones included
+	PostedAt time.Time // last time it was posted
+	// ReplacedBy is the upload carrying the same data under a new ID. The
+	// contract and Merkle leaves keep the old ID; reads follow this link
+	ReplacedBy string `gorm:"index"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// ArweaveDataItem is one PDF stored as an ANS-104 data item and the bundle
+// transaction carrying it. Items posted to a bundler learn their BundleID
+// once the bundle shows up on the gateway.
+type ArweaveDataItem struct {
+	ID       uuid.UUID `gorm:"primary_key;type:uuid"`
+	ItemID   string    `gorm:"uniqueIndex;not null"`
+	BundleID string    `gorm:"index"`
+	Bundler  string    // endpoint the item was posted to; empty when bundled here
+
+	CreatedAt time.Time
+}
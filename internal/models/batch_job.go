@@ -0,0 +1,79 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+const (
+	TableBatchJob     = "batch_job"
+	TableBatchJobItem = "batch_job_item"
+)
+
+type BatchJobStatus string
+
+const (
+	BatchJobPending             BatchJobStatus = "pending"
+	BatchJobProcessing          BatchJobStatus = "processing"
+	BatchJobCompleted           BatchJobStatus = "completed"
+	BatchJobCompletedWithErrors BatchJobStatus = "completed_with_errors"
+)
+
+type BatchItemStatus string
+
+const (
+	BatchItemPending   BatchItemStatus = "pending"
+	BatchItemUploading BatchItemStatus = "uploading"
+	BatchItemUploaded  BatchItemStatus = "uploaded"
+	BatchItemFailed    BatchItemStatus = "failed"  // retryable
+	BatchItemInvalid   BatchItemStatus = "invalid" // rejected by validation, never retried
+)
+
+func (BatchJob) TableName() string {
+	return TableBatchJob
+}
+
+func (BatchJobItem) TableName() string {
+	return TableBatchJobItem
+}
+
+type BatchJob struct {
+	ID           uuid.UUID `gorm:"primary_key;type:uuid"`
+	UniversityID uuid.UUID `gorm:"type:uuid;index;not null"`
+	CreatedBy    uuid.UUID `gorm:"type:uuid"`
+	Status       BatchJobStatus
+	TotalItems   int
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+
+	Items []BatchJobItem `gorm:"foreignKey:JobID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+type BatchJobItem struct {
+	ID        uuid.UUID `gorm:"primary_key;type:uuid"`
+	JobID     uuid.UUID `gorm:"type:uuid;index;not null"`
+	RowNumber int
+	FileName  string
+	FilePath  string          // extracted PDF, kept until the item is uploaded
+	Status    BatchItemStatus `gorm:"index"`
+	Error     string
+	Attempts  int
+
+	DiplomaHash string
+	ArweaveTxID string
+	ArweaveURL  string
+
+	// Diploma metadata from the manifest row
+	FirstName      string
+	LastName       string
+	Email          string
+	Faculty        string
+	Department     string
+	GraduationYear int
+	StudentNumber  string
+	Nationality    string
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+const (
+	TableChainTransaction = "chain_transaction"
+	TableSignerNonce      = "signer_nonce"
+)
+
+func (ChainTransaction) TableName() string {
+	return TableChainTransaction
+}
+
+func (SignerNonce) TableName() string {
+	return TableSignerNonce
+}
+
+type TxKind string
+
+const (
+	TxKindStoreDiploma  TxKind = "store_diploma"
+	TxKindRevokeDiploma TxKind = "revoke_diploma"
+	TxKindAnchorRoot    TxKind = "anchor_root"
+	// TxKindReleaseNonce is an empty self-transfer that uses up the nonce of
+	// a transaction the node kept rejecting; its reference is that
+	// transaction's hash
+	TxKindReleaseNonce TxKind = "release_nonce"
+)
+
+type TxStatus string
+
+const (
+	TxPending  TxStatus = "pending"  // signed, waiting to be mined
+	TxMined    TxStatus = "mined"    // receipt with success status
+	TxFailed   TxStatus = "failed"   // reverted, or the nonce was used by another transaction
+	TxReplaced TxStatus = "replaced" // superseded by a fee-bumped transaction with the same nonce
+)
+
+// ChainTransaction is one signed transaction in the outbox. A fee bump adds a
+// new row with the same network, signer and nonce; whichever of them is mined wins and
+// the others end up replaced.
+type ChainTransaction struct {
+	ID uuid.UUID `gorm:"primary_key;type:uuid"`
+	// Reference is what the transaction anchors: the diploma hash for
+	// store/revoke, the Merkle root for anchorRoot
+	Kind      TxKind   `gorm:"index:idx_chain_transaction_reference;not null"`
+	Reference string   `gorm:"index:idx_chain_transaction_reference;not null"`
+	Status    TxStatus `gorm:"index;not null"`
+	Error     string
+
+	Network   string `gorm:"index:idx_chain_transaction_nonce"`
+	Signer    string `gorm:"index:idx_chain_transaction_nonce;not null"`
+	Nonce     uint64 `gorm:"index:idx_chain_transaction_nonce"`
+	To        string
+	Data      []byte
+	Gas       uint64
+	GasTipCap string // wei
+	GasFeeCap string // wei
+	TxHash    string `gorm:"uniqueIndex;not null"`
+	RawTx     []byte // signed RLP, rebroadcast until the node accepts it
+	// Rejections counts the broadcasts the node refused
+	Rejections int
+
+	ReplacedBy  *uuid.UUID `gorm:"type:uuid"`
+	BlockNumber uint64
+	BlockHash   string
+	GasUsed     uint64
+
+	SentAt  *time.Time // accepted by the node
+	MinedAt *time.Time
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// SignerNonce is the next nonce the outbox hands out for a signer address on
+// one network.
+type SignerNonce struct {
+	Network   string `gorm:"primary_key"`
+	Address   string `gorm:"primary_key"`
+	NextNonce uint64
+	UpdatedAt time.Time
+}
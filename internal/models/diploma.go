@@ -13,17 +13,72 @@ func (Diploma) TableName() string {
 }
 
 type Diploma struct {
-	ID          uuid.UUID `gorm:"primary_key;type:uuid"`
-	PublicID    string    `gorm:"uniqueIndex;not null"`
-	Hash        string
+	ID       uuid.UUID `gorm:"primary_key;type:uuid"`
+	PublicID string    `gorm:"uniqueIndex;not null"`
+	Hash     string
+	// ArweaveTxID is the document reference anchored on the contract: the
+	// Arweave TxID itself, or <store>://<address> for the other stores
 	ArweaveTxID string
-	ArweaveURL  string
+	ArweaveURL  string // link to the PDF on its store
+	// Storage is the document store holding the PDF (STORAGE_BACKEND at
+	// upload time) and ContentAddress its TxID, CID or object key there. An
+	// Arweave upload dropped past its anchor is uploaded again and only
+	// ContentAddress and ArweaveURL move to the new TxID
+	Storage        string `gorm:"index;default:arweave"`
+	ContentAddress string
+	// Encrypted PDFs are sealed with a data key kept in DocumentKey; Hash is
+	// still the hash of the plaintext
+	Encrypted bool
+	// Tracked for Arweave documents until the transaction is
+	// ARWEAVE_CONFIRMATION_DEPTH blocks deep and its data is seeded
+	ArweaveStatus        ArweaveStatus `gorm:"index"`
+	ArweaveConfirmations int
+	// Set when the PDF is an ANS-104 data item: ArweaveTxID and
+	// ContentAddress are then the data item ID, and this the bundle
+	// transaction carrying it, which is what gets confirmed
+	ArweaveBundleID string `gorm:"index"`
+	// Network is the ledger profile (NETWORK) the diploma was anchored on;
+	// PolygonTxID and the Merkle root are looked up there
+	Network     string `gorm:"index"`
 	PolygonTxID string
 	PolygonURL  string
+	BlockNumber uint64
+	BlockHash   string
 	Owner       string
 	Timestamp   time.Time
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
 
-	MetaData DiplomaMetaData `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	// Issuing university; every read and write is scoped by it
+	UniversityID uuid.UUID    `gorm:"type:uuid;index"`
+	University   Universities `gorm:"foreignKey:UniversityID;references:ID"`
+
+	// Set when the diploma was anchored as a leaf of a Merkle root instead of
+	// its own storeDiploma transaction. PolygonTxID then points to anchorRoot.
+	MerkleAnchorID  *uuid.UUID `gorm:"type:uuid;index"`
+	MerkleRoot      string
+	MerkleProof     []string `gorm:"serializer:json"` // sibling hashes, leaf to root
+	MerkleLeafIndex int
+
+	// Tracked from the first receipt until the anchoring transaction is
+	// CONFIRMATION_DEPTH blocks deep
+	Finality      Finality `gorm:"index;default:confirming"`
+	Confirmations uint64
+	// FinalityCheckedAt is when the anchoring transaction was last looked up
+	FinalityCheckedAt *time.Time `gorm:"index"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	MetaData   DiplomaMetaData    `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Revocation *DiplomaRevocation `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+// Status reports whether the diploma is still valid or has been revoked.
+func (d *Diploma) Status() DiplomaStatus {
+	if d.Revocation != nil {
+		return DiplomaStatusRevoked
+	}
+	if d.PolygonTxID == "" {
+		return DiplomaStatusPending
+	}
+	return DiplomaStatusValid
 }
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+const TableDiplomaIssuance = "diploma_issuance"
+
+func (DiplomaIssuance) TableName() string {
+	return TableDiplomaIssuance
+}
+
+type IssuanceStatus string
+
+const (
+	IssuancePending   IssuanceStatus = "pending"   // waiting to be signed
+	IssuanceSubmitted IssuanceStatus = "submitted" // broadcast, waiting for the receipt
+	IssuanceConfirmed IssuanceStatus = "confirmed"
+	IssuanceFailed    IssuanceStatus = "failed"
+)
+
+// DiplomaIssuance tracks a storeDiploma transaction the backend signs on
+// behalf of a university (ISSUANCE_MODE=server) and keeps its receipt.
+type DiplomaIssuance struct {
+	ID           uuid.UUID      `gorm:"primary_key;type:uuid"`
+	DiplomaID    uuid.UUID      `gorm:"uniqueIndex;type:uuid;not null"`
+	UniversityID uuid.UUID      `gorm:"type:uuid;index"`
+	Status       IssuanceStatus `gorm:"index;not null"`
+	Attempts     int
+	Error        string
+
+	Signer      string
+	TxHash      string
+	BlockNumber uint64
+	BlockHash   string
+	GasUsed     uint64
+	SubmittedAt *time.Time
+	ConfirmedAt *time.Time
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
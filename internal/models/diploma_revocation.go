@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+const TableDiplomaRevocation = "diploma_revocation"
+
+func (DiplomaRevocation) TableName() string {
+	return TableDiplomaRevocation
+}
+
+type DiplomaRevocation struct {
+	ID            uuid.UUID `gorm:"primary_key;type:uuid"`
+	DiplomaID     uuid.UUID `gorm:"uniqueIndex;type:uuid;not null"`
+	Reason        string    `gorm:"not null"`
+	RevokedBy     uuid.UUID `gorm:"type:uuid"`
+	RevokedByName string
+	RevokedAt     time.Time
+
+	// Set only when the revocation was anchored on-chain
+	PolygonTxID string
+	PolygonURL  string
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
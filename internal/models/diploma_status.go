@@ -0,0 +1,21 @@
+package models
+
+type DiplomaStatus string
+
+const (
+	DiplomaStatusValid   DiplomaStatus = "valid"
+	DiplomaStatusRevoked DiplomaStatus = "revoked"
+	// Queued for Merkle anchoring, not on-chain yet
+	DiplomaStatusPending DiplomaStatus = "pending"
+)
+
+// Finality is how settled the diploma's anchoring transaction is on-chain.
+type Finality string
+
+const (
+	// Mined, waiting for enough blocks on top of it
+	FinalityConfirming Finality = "confirming"
+	FinalityFinal      Finality = "final"
+	// The transaction was dropped from the canonical chain by a reorg
+	FinalityOrphaned Finality = "orphaned"
+)
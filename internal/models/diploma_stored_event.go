@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+const (
+	TableDiplomaStoredEvent = "diploma_stored_event"
+	TableIndexerCursor      = "indexer_cursor"
+)
+
+func (DiplomaStoredEvent) TableName() string {
+	return TableDiplomaStoredEvent
+}
+
+func (IndexerCursor) TableName() string {
+	return TableIndexerCursor
+}
+
+// DiplomaStoredEvent is a DiplomaStored log indexed from the contract.
+type DiplomaStoredEvent struct {
+	ID uuid.UUID `gorm:"primary_key;type:uuid"`
+	// The v1 contract's own counter, a uint256 kept as a decimal string;
+	// empty for v2, which keys diplomas by hash
+	ChainDiplomaID  string
+	DiplomaHash     string `gorm:"index;not null"`
+	ArweaveTxID     string
+	Owner           string
+	Timestamp       time.Time
+	ContractAddress string
+	Network         string `gorm:"index"`
+
+	BlockNumber uint64 `gorm:"index"`
+	BlockHash   string
+	TxHash      string `gorm:"uniqueIndex:idx_diploma_stored_event_log;not null"`
+	LogIndex    uint   `gorm:"uniqueIndex:idx_diploma_stored_event_log"`
+
+	// Set once a diploma row with the same hash exists
+	DiplomaID *uuid.UUID `gorm:"type:uuid;index"`
+
+	CreatedAt time.Time
+}
+
+// IndexerCursor is the last block an indexer has fully processed.
+type IndexerCursor struct {
+	Name      string `gorm:"primary_key"`
+	Block     uint64
+	UpdatedAt time.Time
+}
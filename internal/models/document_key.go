@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+const TableDocumentKey = "document_key"
+
+func (DocumentKey) TableName() string {
+	return TableDocumentKey
+}
+
+// DocumentKey is the data key an encrypted diploma PDF was sealed with,
+// wrapped with the master key named by MasterKeyID. It is keyed by the
+// document reference because the PDF is uploaded before its diploma exists.
+type DocumentKey struct {
+	ID          uuid.UUID `gorm:"primary_key;type:uuid"`
+	Reference   string    `gorm:"uniqueIndex;not null"`
+	DiplomaHash string    `gorm:"index"`
+	MasterKeyID string
+	WrappedKey  []byte
+
+	CreatedAt time.Time
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+const TableIssuerKey = "university_issuer_key"
+
+func (IssuerKey) TableName() string {
+	return TableIssuerKey
+}
+
+// IssuerKey is the Ed25519 key a university signs Verifiable Credentials with.
+// DID is the did:key derived from PublicKey. With a document master key
+// configured the seed is stored wrapped with the master key named by
+// MasterKeyID; keys without one hold the plain seed.
+type IssuerKey struct {
+	ID           uuid.UUID `gorm:"primary_key;type:uuid"`
+	UniversityID uuid.UUID `gorm:"uniqueIndex;type:uuid;not null"`
+	DID          string    `gorm:"uniqueIndex;not null"`
+	PublicKey    string    `gorm:"not null"`          // hex
+	PrivateKey   string    `gorm:"not null" json:"-"` // hex encoded seed, wrapped when MasterKeyID is set
+	MasterKeyID  string    `gorm:"index"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+const TableMerkleAnchor = "merkle_anchor"
+
+func (MerkleAnchor) TableName() string {
+	return TableMerkleAnchor
+}
+
+type MerkleAnchorStatus string
+
+const (
+	MerkleAnchorPending  MerkleAnchorStatus = "pending"  // diplomas reserved, anchorRoot sent or about to be
+	MerkleAnchorAnchored MerkleAnchorStatus = "anchored" // mined, proofs saved on the diplomas
+)
+
+// MerkleAnchor is one anchorRoot transaction covering a batch of diplomas.
+// It is saved as pending, with its diplomas linked, before the root is sent,
+// so a restart anchors the same batch again instead of building a new one.
+type MerkleAnchor struct {
+	ID          uuid.UUID          `gorm:"primary_key;type:uuid"`
+	Root        string             `gorm:"uniqueIndex;not null"`
+	Status      MerkleAnchorStatus `gorm:"index;not null;default:anchored"`
+	LeafCount   int
+	Network     string
+	PolygonTxID string
+	PolygonURL  string
+	BlockNumber uint64
+	BlockHash   string
+	AnchoredAt  time.Time
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+const (
+	TableReconciliationJob      = "reconciliation_job"
+	TableReconciliationMismatch = "reconciliation_mismatch"
+)
+
+type ReconciliationStatus string
+
+const (
+	ReconciliationRunning   ReconciliationStatus = "running"
+	ReconciliationCompleted ReconciliationStatus = "completed"
+	ReconciliationFailed    ReconciliationStatus = "failed"
+)
+
+type MismatchKind string
+
+const (
+	MismatchChainMissing        MismatchKind = "chain_missing"          // verifyDiploma does not know the hash
+	MismatchChainArweave        MismatchKind = "chain_arweave_mismatch" // contract points to another Arweave TxID
+	MismatchChainRecord         MismatchKind = "chain_record_mismatch"  // getDiploma(hashToId(hash)) disagrees with verifyDiploma
+	MismatchMerkleRoot          MismatchKind = "merkle_root_not_anchored"
+	MismatchArweaveMissing      MismatchKind = "arweave_missing"
+	MismatchArweaveHash         MismatchKind = "arweave_hash_mismatch" // File-Hash tag differs from the diploma hash
+	MismatchPolygonTxMissing    MismatchKind = "db_polygon_tx_missing"
+	MismatchArweaveURLMissing   MismatchKind = "db_arweave_url_missing"
+	MismatchUniversityMissing   MismatchKind = "db_university_missing" // not linked to the university its metadata names
+	MismatchReconciliationError MismatchKind = "check_failed"          // a store could not be reached
+)
+
+func (ReconciliationJob) TableName() string {
+	return TableReconciliationJob
+}
+
+func (ReconciliationMismatch) TableName() string {
+	return TableReconciliationMismatch
+}
+
+// ReconciliationJob is one pass over a university's diplomas comparing the
+// database with the contract and Arweave.
+type ReconciliationJob struct {
+	ID           uuid.UUID `gorm:"primary_key;type:uuid"`
+	UniversityID uuid.UUID `gorm:"type:uuid;index;not null"`
+	CreatedBy    uuid.UUID `gorm:"type:uuid"`
+	Status       ReconciliationStatus
+	Repair       bool // fill in missing PolygonTxID / ArweaveURL
+	Error        string
+
+	Checked    int
+	Mismatches int
+	Repaired   int
+
+	FinishedAt *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+
+	Items []ReconciliationMismatch `gorm:"foreignKey:JobID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+type ReconciliationMismatch struct {
+	ID          uuid.UUID `gorm:"primary_key;type:uuid"`
+	JobID       uuid.UUID `gorm:"type:uuid;index;not null"`
+	DiplomaID   uuid.UUID `gorm:"type:uuid"`
+	PublicID    string
+	DiplomaHash string
+	Kind        MismatchKind
+	Expected    string
+	Actual      string
+	Details     string
+	Repaired    bool
+
+	CreatedAt time.Time
+}
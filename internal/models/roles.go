@@ -3,6 +3,7 @@ package models
 type UserRole string
 
 const (
-	RoleAdmin   UserRole = "admin"
-	RoleStudent UserRole = "student"
+	RoleAdmin      UserRole = "admin"
+	RoleStudent    UserRole = "student"
+	RoleSuperAdmin UserRole = "superadmin"
 )
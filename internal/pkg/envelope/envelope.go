@@ -0,0 +1,137 @@
+// Package envelope encrypts documents with a fresh AES-256-GCM data key each
+// and wraps that key with a long-lived master key, so only the small wrapped
+// key has to be stored next to the record and the master key can be kept out
+// of the database.
+//
+// Both the sealed document and the wrapped key are laid out as
+// version || nonce || AES-GCM ciphertext and tag.
+package envelope
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+const (
+	version = 1
+	keySize = 32
+)
+
+// The wrapped key is bound to its purpose so it cannot be passed off as a
+// sealed document or the other way round.
+var wrapAAD = []byte("blockcertify/document-key")
+
+// Seal encrypts plaintext with a new data key and returns the ciphertext and
+// the data key wrapped with masterKey. aad is authenticated but not stored;
+// the same value must be passed to Open.
+func Seal(masterKey, plaintext, aad []byte) ([]byte, []byte, error) {
+
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	ciphertext, err := seal(dataKey, plaintext, aad)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	wrappedKey, err := seal(masterKey, dataKey, wrapAAD)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ciphertext, wrappedKey, nil
+}
+
+// SealWith encrypts plaintext with the data key already wrapped in
+// wrappedKey, so a document written again under the same name stays readable
+// with the key stored for it.
+func SealWith(masterKey, wrappedKey, plaintext, aad []byte) ([]byte, error) {
+
+	dataKey, err := open(masterKey, wrappedKey, wrapAAD)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+
+	return seal(dataKey, plaintext, aad)
+}
+
+// Wrap encrypts a small secret, such as a signing key, directly with
+// masterKey. purpose is authenticated, so a secret wrapped for one use cannot
+// be unwrapped as another.
+func Wrap(masterKey, secret, purpose []byte) ([]byte, error) {
+	return seal(masterKey, secret, secretAAD(purpose))
+}
+
+// Unwrap decrypts a secret wrapped by Wrap with the same purpose.
+func Unwrap(masterKey, wrapped, purpose []byte) ([]byte, error) {
+	return open(masterKey, wrapped, secretAAD(purpose))
+}
+
+func secretAAD(purpose []byte) []byte {
+	return append([]byte("blockcertify/secret/"), purpose...)
+}
+
+// Open unwraps the data key with masterKey and decrypts ciphertext with it.
+func Open(masterKey, wrappedKey, ciphertext, aad []byte) ([]byte, error) {
+
+	dataKey, err := open(masterKey, wrappedKey, wrapAAD)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+
+	plaintext, err := open(dataKey, ciphertext, aad)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt document: %w", err)
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != keySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", keySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func seal(key, plaintext, aad []byte) ([]byte, error) {
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 1+gcm.NonceSize(), 1+gcm.NonceSize()+len(plaintext)+gcm.Overhead())
+	out[0] = version
+	if _, err := rand.Read(out[1:]); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return gcm.Seal(out, out[1:], plaintext, aad), nil
+}
+
+func open(key, sealed, aad []byte) ([]byte, error) {
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < 1+gcm.NonceSize()+gcm.Overhead() {
+		return nil, errors.New("ciphertext is too short")
+	}
+	if sealed[0] != version {
+		return nil, fmt.Errorf("unsupported envelope version %d", sealed[0])
+	}
+
+	nonce := sealed[1 : 1+gcm.NonceSize()]
+	return gcm.Open(nil, nonce, sealed[1+gcm.NonceSize():], aad)
+}
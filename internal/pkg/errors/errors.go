@@ -12,6 +12,7 @@ var (
 	ErrInvalidFile         = "INVALID_FILE"
 	ErrHashingFailed       = "HASHING_FAILED"
 	ErrArweaveUploadFailed = "ARWEAVE_UPLOAD_FAILED"
+	ErrStorageUploadFailed = "STORAGE_UPLOAD_FAILED"
 	ErrBlockchainFailed    = "BLOCKCHAIN_FAILED"
 	ErrDiplomaExists       = "DIPLOMA_EXISTS"
 	ErrInsufficientBalance = "INSUFFICIENT_BALANCE"
@@ -25,6 +26,18 @@ var (
 	ErrUniversityNotFound  = "UNIVERSITY_NOT_FOUND"
 	ErrUserCreationFailed  = "USER_CREATION_FAILED"
 	ErrAdminCreationFailed = "ADMIN_CREATION_FAILED"
+	ErrInvalidInvite       = "INVALID_INVITE"
+	ErrTransactionNotFound = "TRANSACTION_NOT_FOUND"
+	ErrTransactionMismatch = "TRANSACTION_MISMATCH"
+	ErrDiplomaNotFound     = "DIPLOMA_NOT_FOUND"
+	ErrDiplomaRevoked      = "DIPLOMA_REVOKED"
+	ErrForbidden           = "FORBIDDEN"
+	ErrBatchJobNotFound    = "BATCH_JOB_NOT_FOUND"
+	ErrCredentialFailed    = "CREDENTIAL_FAILED"
+	ErrNotSupported        = "NOT_SUPPORTED"
+	ErrDocumentReadFailed  = "DOCUMENT_READ_FAILED"
+
+	ErrReconciliationNotFound = "RECONCILIATION_NOT_FOUND"
 )
 
 func New(code, message string, err error) *AppError {
@@ -0,0 +1,119 @@
+// Package merkle builds the Merkle trees used to anchor many diplomas with a
+// single contract call.
+//
+// The layout follows OpenZeppelin's MerkleProof: pairs are sorted before they
+// are hashed, so a proof is just the list of sibling hashes and can be checked
+// on-chain with MerkleProof.verify. Leaves are double hashed to keep them from
+// being confused with internal nodes.
+package merkle
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+type Hash [32]byte
+
+func (h Hash) Hex() string {
+	return "0x" + hex.EncodeToString(h[:])
+}
+
+// ParseHash decodes a 0x prefixed, 32 byte hex string.
+func ParseHash(s string) (Hash, error) {
+	var h Hash
+	b, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil {
+		return h, err
+	}
+	if len(b) != len(h) {
+		return h, fmt.Errorf("invalid hash length %d", len(b))
+	}
+	copy(h[:], b)
+	return h, nil
+}
+
+// Leaf commits to a diploma hash and the Arweave transaction holding the PDF,
+// the same pair storeDiploma writes for a single diploma:
+// keccak256(bytes.concat(keccak256(abi.encodePacked(diplomaHash, arweaveTxId)))).
+func Leaf(diplomaHash, arweaveTxID string) Hash {
+	inner := crypto.Keccak256([]byte(diplomaHash), []byte(arweaveTxID))
+	var h Hash
+	copy(h[:], crypto.Keccak256(inner))
+	return h
+}
+
+type Tree struct {
+	levels [][]Hash
+}
+
+// New builds a tree over the leaves in the given order. A lone node at the end
+// of a level is promoted to the next level unchanged.
+func New(leaves []Hash) (*Tree, error) {
+	if len(leaves) == 0 {
+		return nil, errors.New("merkle: no leaves")
+	}
+
+	level := make([]Hash, len(leaves))
+	copy(level, leaves)
+	levels := [][]Hash{level}
+
+	for len(level) > 1 {
+		next := make([]Hash, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			next = append(next, hashPair(level[i], level[i+1]))
+		}
+		levels = append(levels, next)
+		level = next
+	}
+
+	return &Tree{levels: levels}, nil
+}
+
+func (t *Tree) Root() Hash {
+	return t.levels[len(t.levels)-1][0]
+}
+
+// Proof returns the sibling hashes from the leaf at index up to the root.
+func (t *Tree) Proof(index int) ([]Hash, error) {
+	if index < 0 || index >= len(t.levels[0]) {
+		return nil, fmt.Errorf("merkle: leaf index %d out of range", index)
+	}
+
+	var proof []Hash
+	for _, level := range t.levels[:len(t.levels)-1] {
+		sibling := index ^ 1
+		if sibling < len(level) {
+			proof = append(proof, level[sibling])
+		}
+		index /= 2
+	}
+
+	return proof, nil
+}
+
+// Verify reports whether proof links leaf to root.
+func Verify(leaf Hash, proof []Hash, root Hash) bool {
+	computed := leaf
+	for _, sibling := range proof {
+		computed = hashPair(computed, sibling)
+	}
+	return computed == root
+}
+
+func hashPair(a, b Hash) Hash {
+	if bytes.Compare(a[:], b[:]) > 0 {
+		a, b = b, a
+	}
+	var h Hash
+	copy(h[:], crypto.Keccak256(a[:], b[:]))
+	return h
+}
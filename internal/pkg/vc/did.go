@@ -0,0 +1,68 @@
+package vc
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil/base58"
+)
+
+const didKeyPrefix = "did:key:"
+
+// Multicodec prefix of an Ed25519 public key (0xed, varint encoded)
+var ed25519Multicodec = []byte{0xed, 0x01}
+
+// DIDKey returns the did:key identifier of an Ed25519 public key.
+func DIDKey(pub ed25519.PublicKey) string {
+	return didKeyPrefix + multibase(append(append([]byte{}, ed25519Multicodec...), pub...))
+}
+
+// VerificationMethod returns the key ID used in proofs for a did:key.
+func VerificationMethod(did string) string {
+	return did + "#" + strings.TrimPrefix(did, didKeyPrefix)
+}
+
+// ResolveVerificationMethod extracts the Ed25519 public key from a did:key
+// verification method. did:key documents are derived from the identifier
+// itself, so nothing is fetched over the network.
+func ResolveVerificationMethod(method string) (ed25519.PublicKey, string, error) {
+
+	did, fragment, _ := strings.Cut(method, "#")
+	if !strings.HasPrefix(did, didKeyPrefix) {
+		return nil, "", fmt.Errorf("unsupported verification method %q", method)
+	}
+
+	identifier := strings.TrimPrefix(did, didKeyPrefix)
+	if fragment != "" && fragment != identifier {
+		return nil, "", errors.New("verification method fragment does not match the DID")
+	}
+
+	decoded, err := decodeMultibase(identifier)
+	if err != nil {
+		return nil, "", err
+	}
+	if !bytes.HasPrefix(decoded, ed25519Multicodec) || len(decoded) != len(ed25519Multicodec)+ed25519.PublicKeySize {
+		return nil, "", errors.New("did:key is not an Ed25519 key")
+	}
+
+	return ed25519.PublicKey(decoded[len(ed25519Multicodec):]), did, nil
+}
+
+// multibase encodes b as base58btc with the "z" prefix.
+func multibase(b []byte) string {
+	return "z" + base58.Encode(b)
+}
+
+func decodeMultibase(s string) ([]byte, error) {
+	if !strings.HasPrefix(s, "z") {
+		return nil, errors.New("only base58btc multibase values are supported")
+	}
+	decoded := base58.Decode(s[1:])
+	if len(decoded) == 0 {
+		return nil, errors.New("invalid base58btc value")
+	}
+	return decoded, nil
+}
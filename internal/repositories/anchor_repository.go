@@ -0,0 +1,114 @@
+package repositories
+
+import (
+	"BlockCertify/internal/models"
+
+	"github.com/gofrs/uuid/v5"
+	"gorm.io/gorm"
+)
+
+type AnchorRepository interface {
+	GetPendingDiplomas(limit int) ([]models.Diploma, error)
+	CreateAnchor(anchor *models.MerkleAnchor, diplomas []models.Diploma) error
+	GetPendingAnchors() ([]models.MerkleAnchor, error)
+	GetAnchorDiplomas(anchorID uuid.UUID) ([]models.Diploma, error)
+	UpdateAnchor(anchor *models.MerkleAnchor) error
+	SaveAnchor(anchor *models.MerkleAnchor, diplomas []models.Diploma) error
+}
+
+type anchorRepository struct {
+	db *gorm.DB
+}
+
+func NewAnchorRepository(db *gorm.DB) AnchorRepository {
+	return &anchorRepository{
+		db: db,
+	}
+}
+
+// GetPendingDiplomas returns diplomas queued for Merkle anchoring, oldest first.
+// Diplomas waiting for a server signed storeDiploma are left alone.
+func (r *anchorRepository) GetPendingDiplomas(limit int) ([]models.Diploma, error) {
+	var diplomas []models.Diploma
+	err := r.db.
+		Where("polygon_tx_id = '' AND merkle_anchor_id IS NULL").
+		Where("NOT EXISTS (SELECT 1 FROM diploma_issuance WHERE diploma_issuance.diploma_id = diploma.id)").
+		Order("id ASC").
+		Limit(limit).
+		Find(&diplomas).Error
+	if err != nil {
+		return nil, err
+	}
+	return diplomas, nil
+}
+
+// CreateAnchor stores a pending anchor and links the diplomas it covers to it
+// in a single transaction, which takes them out of the pending queue.
+func (r *anchorRepository) CreateAnchor(anchor *models.MerkleAnchor, diplomas []models.Diploma) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(anchor).Error; err != nil {
+			return err
+		}
+
+		ids := make([]uuid.UUID, len(diplomas))
+		for i, d := range diplomas {
+			ids[i] = d.ID
+		}
+		return tx.Model(&models.Diploma{}).
+			Where("id IN ?", ids).
+			Update("merkle_anchor_id", anchor.ID).Error
+	})
+}
+
+// GetPendingAnchors returns the anchors whose root is not mined yet, oldest
+// first.
+func (r *anchorRepository) GetPendingAnchors() ([]models.MerkleAnchor, error) {
+	var anchors []models.MerkleAnchor
+	err := r.db.
+		Where("status = ?", models.MerkleAnchorPending).
+		Order("id ASC").
+		Find(&anchors).Error
+	if err != nil {
+		return nil, err
+	}
+	return anchors, nil
+}
+
+// GetAnchorDiplomas returns the diplomas of an anchor in leaf order.
+func (r *anchorRepository) GetAnchorDiplomas(anchorID uuid.UUID) ([]models.Diploma, error) {
+	var diplomas []models.Diploma
+	err := r.db.
+		Where("merkle_anchor_id = ?", anchorID).
+		Order("id ASC").
+		Find(&diplomas).Error
+	if err != nil {
+		return nil, err
+	}
+	return diplomas, nil
+}
+
+func (r *anchorRepository) UpdateAnchor(anchor *models.MerkleAnchor) error {
+	return r.db.Save(anchor).Error
+}
+
+// SaveAnchor stores the mined anchor and the proofs of every diploma it
+// covers in a single transaction.
+func (r *anchorRepository) SaveAnchor(anchor *models.MerkleAnchor, diplomas []models.Diploma) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(anchor).Error; err != nil {
+			return err
+		}
+
+		for i := range diplomas {
+			d := &diplomas[i]
+			err := tx.Model(d).
+				Select("MerkleAnchorID", "MerkleRoot", "MerkleProof", "MerkleLeafIndex", "Network", "PolygonTxID", "PolygonURL", "BlockNumber", "BlockHash", "Finality", "Timestamp").
+				Updates(d).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,196 @@
+package repositories
+
+import (
+	"BlockCertify/internal/config"
+	"BlockCertify/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ArweaveRepository interface {
+	CreateUpload(upload *models.ArweaveUpload) error
+	GetUpload(txID string) (*models.ArweaveUpload, error)
+	GetUnsettledTxIDs(limit int) ([]string, error)
+	UpdateStatus(txID string, status models.ArweaveStatus, confirmations, blockHeight int) error
+	RecordRepost(txID string, postedAt time.Time) error
+	ReplaceUpload(txID, replacementID, url string) error
+	CreateDataItems(items []models.ArweaveDataItem) error
+	GetDataItem(itemID string) (*models.ArweaveDataItem, error)
+	SetBundle(itemID, bundleID string) error
+}
+
+type arweaveRepository struct {
+	db *gorm.DB
+}
+
+func NewArweaveRepository(db *gorm.DB) ArweaveRepository {
+	return &arweaveRepository{
+		db: db,
+	}
+}
+
+func (r *arweaveRepository) CreateUpload(upload *models.ArweaveUpload) error {
+	return r.db.Create(upload).Error
+}
+
+func (r *arweaveRepository) GetUpload(txID string) (*models.ArweaveUpload, error) {
+	var upload models.ArweaveUpload
+	if err := r.db.Where("tx_id = ?", txID).First(&upload).Error; err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+// GetUnsettledTxIDs returns the Arweave transactions that are not permanent
+// yet: uploads, including those no diploma refers to yet, and diplomas
+// stored before uploads were recorded. Diplomas stored as data items count
+// by their bundle, or by the item ID while its bundle is unknown, and
+// re-uploaded ones by the content address they were moved to.
+func (r *arweaveRepository) GetUnsettledTxIDs(limit int) ([]string, error) {
+
+	var uploads []string
+	err := r.db.Model(&models.ArweaveUpload{}).
+		Where("status NOT IN ?", []models.ArweaveStatus{models.ArweaveStatusPermanent, models.ArweaveStatusReplaced}).
+		Order("posted_at ASC").
+		Limit(limit).
+		Pluck("tx_id", &uploads).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var diplomas []string
+	err = r.db.Model(&models.Diploma{}).
+		Select("DISTINCT COALESCE(NULLIF(arweave_bundle_id, ''), NULLIF(content_address, ''), arweave_tx_id)").
+		Where("storage = ? AND arweave_tx_id <> '' AND arweave_status <> '' AND arweave_status <> ?", config.StorageArweave, models.ArweaveStatusPermanent).
+		Limit(limit).
+		Scan(&diplomas).Error
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(uploads))
+	txIDs := make([]string, 0, len(uploads)+len(diplomas))
+	for _, txID := range append(uploads, diplomas...) {
+		if !seen[txID] {
+			seen[txID] = true
+			txIDs = append(txIDs, txID)
+		}
+	}
+	return txIDs, nil
+}
+
+// UpdateStatus records the status on the upload and on every diploma stored
+// in the transaction, directly or as a data item of the bundle. The signed
+// copy is dropped once it is permanent.
+func (r *arweaveRepository) UpdateStatus(txID string, status models.ArweaveStatus, confirmations, blockHeight int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{
+			"status":        status,
+			"confirmations": confirmations,
+			"block_height":  blockHeight,
+		}
+		if status == models.ArweaveStatusPermanent {
+			updates["signed_tx"] = nil
+		}
+
+		err := tx.Model(&models.ArweaveUpload{}).
+			Where("tx_id = ?", txID).
+			Updates(updates).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Diploma{}).
+			Where("storage = ? AND (arweave_tx_id = ? OR arweave_bundle_id = ? OR content_address = ?)", config.StorageArweave, txID, txID, txID).
+			Updates(map[string]interface{}{
+				"arweave_status":        status,
+				"arweave_confirmations": confirmations,
+			}).Error
+	})
+}
+
+func (r *arweaveRepository) RecordRepost(txID string, postedAt time.Time) error {
+	return r.db.Model(&models.ArweaveUpload{}).
+		Where("tx_id = ?", txID).
+		Updates(map[string]interface{}{
+			"reposts":   gorm.Expr("reposts + 1"),
+			"posted_at": postedAt,
+		}).Error
+}
+
+// ReplaceUpload records that the data of txID was uploaded again as
+// replacementID. The diplomas keep the reference anchored on the contract;
+// their content address and URL, or the bundle their data items travel in,
+// move to the replacement, which is tracked from pending again.
+func (r *arweaveRepository) ReplaceUpload(txID, replacementID, url string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Uploads replaced before point straight to the newest copy
+		err := tx.Model(&models.ArweaveUpload{}).
+			Where("tx_id = ? OR replaced_by = ?", txID, txID).
+			Updates(map[string]interface{}{
+				"status":      models.ArweaveStatusReplaced,
+				"replaced_by": replacementID,
+				"signed_tx":   nil,
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&models.ArweaveDataItem{}).
+			Where("bundle_id = ?", txID).
+			Update("bundle_id", replacementID).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&models.Diploma{}).
+			Where("storage = ? AND arweave_bundle_id = ?", config.StorageArweave, txID).
+			Updates(map[string]interface{}{
+				"arweave_bundle_id":     replacementID,
+				"arweave_status":        models.ArweaveStatusPending,
+				"arweave_confirmations": 0,
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Diploma{}).
+			Where("storage = ? AND COALESCE(arweave_bundle_id, '') = '' AND (content_address = ? OR arweave_tx_id = ?)", config.StorageArweave, txID, txID).
+			Updates(map[string]interface{}{
+				"content_address":       replacementID,
+				"arweave_url":           url,
+				"arweave_status":        models.ArweaveStatusPending,
+				"arweave_confirmations": 0,
+			}).Error
+	})
+}
+
+func (r *arweaveRepository) CreateDataItems(items []models.ArweaveDataItem) error {
+	return r.db.Create(&items).Error
+}
+
+func (r *arweaveRepository) GetDataItem(itemID string) (*models.ArweaveDataItem, error) {
+	var item models.ArweaveDataItem
+	if err := r.db.Where("item_id = ?", itemID).First(&item).Error; err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// SetBundle records the bundle a data item was found in on the item and on
+// the diplomas stored in it.
+func (r *arweaveRepository) SetBundle(itemID, bundleID string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.ArweaveDataItem{}).
+			Where("item_id = ?", itemID).
+			Update("bundle_id", bundleID).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Diploma{}).
+			Where("storage = ? AND arweave_tx_id = ?", config.StorageArweave, itemID).
+			Update("arweave_bundle_id", bundleID).Error
+	})
+}
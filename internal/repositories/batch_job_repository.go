@@ -0,0 +1,147 @@
+package repositories
+
+import (
+	"BlockCertify/internal/models"
+
+	"github.com/gofrs/uuid/v5"
+	"gorm.io/gorm"
+)
+
+type BatchJobRepository interface {
+	Create(job *models.BatchJob) error
+	GetJob(jobID, universityID uuid.UUID) (*models.BatchJob, error)
+	ListJobs(universityID uuid.UUID) ([]models.BatchJob, error)
+	CountItems(jobIDs ...uuid.UUID) ([]BatchItemCount, error)
+	GetItems(jobID uuid.UUID, afterRow, limit int) ([]models.BatchJobItem, error)
+	GetUnfinishedJobs() ([]models.BatchJob, error)
+	GetItemsByStatus(jobID uuid.UUID, statuses ...models.BatchItemStatus) ([]models.BatchJobItem, error)
+	UpdateItem(item *models.BatchJobItem) error
+	ResetFailedItems(jobID uuid.UUID, itemID uuid.UUID) (int64, error)
+	UpdateJobStatus(jobID uuid.UUID, status models.BatchJobStatus) error
+}
+
+// BatchItemCount is the number of items of a job in one status.
+type BatchItemCount struct {
+	JobID  uuid.UUID
+	Status models.BatchItemStatus
+	Count  int
+}
+
+type batchJobRepository struct {
+	db *gorm.DB
+}
+
+func NewBatchJobRepository(db *gorm.DB) BatchJobRepository {
+	return &batchJobRepository{
+		db: db,
+	}
+}
+
+// Create stores the job together with all of its items.
+func (r *batchJobRepository) Create(job *models.BatchJob) error {
+	return r.db.Create(job).Error
+}
+
+func (r *batchJobRepository) GetJob(jobID, universityID uuid.UUID) (*models.BatchJob, error) {
+	var job models.BatchJob
+	err := r.db.
+		Where("id = ? AND university_id = ?", jobID, universityID).
+		First(&job).Error
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *batchJobRepository) ListJobs(universityID uuid.UUID) ([]models.BatchJob, error) {
+	var jobs []models.BatchJob
+	err := r.db.
+		Where("university_id = ?", universityID).
+		Order("id DESC").
+		Find(&jobs).Error
+	if err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// CountItems counts the items of the jobs per status without loading them.
+func (r *batchJobRepository) CountItems(jobIDs ...uuid.UUID) ([]BatchItemCount, error) {
+	var counts []BatchItemCount
+	if len(jobIDs) == 0 {
+		return counts, nil
+	}
+	err := r.db.Model(&models.BatchJobItem{}).
+		Select("job_id, status, COUNT(*) AS count").
+		Where("job_id IN ?", jobIDs).
+		Group("job_id, status").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// GetItems returns up to limit items of the job after the given row number.
+func (r *batchJobRepository) GetItems(jobID uuid.UUID, afterRow, limit int) ([]models.BatchJobItem, error) {
+	var items []models.BatchJobItem
+	err := r.db.
+		Where("job_id = ? AND row_number > ?", jobID, afterRow).
+		Order("row_number ASC").
+		Limit(limit).
+		Find(&items).Error
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *batchJobRepository) GetUnfinishedJobs() ([]models.BatchJob, error) {
+	var jobs []models.BatchJob
+	err := r.db.
+		Where("status IN ?", []models.BatchJobStatus{models.BatchJobPending, models.BatchJobProcessing}).
+		Order("id ASC").
+		Find(&jobs).Error
+	if err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func (r *batchJobRepository) GetItemsByStatus(jobID uuid.UUID, statuses ...models.BatchItemStatus) ([]models.BatchJobItem, error) {
+	var items []models.BatchJobItem
+	err := r.db.
+		Where("job_id = ? AND status IN ?", jobID, statuses).
+		Order("row_number ASC").
+		Find(&items).Error
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *batchJobRepository) UpdateItem(item *models.BatchJobItem) error {
+	return r.db.Save(item).Error
+}
+
+// ResetFailedItems puts failed items back to pending. With a nil itemID every
+// failed item of the job is reset.
+func (r *batchJobRepository) ResetFailedItems(jobID uuid.UUID, itemID uuid.UUID) (int64, error) {
+	query := r.db.Model(&models.BatchJobItem{}).
+		Where("job_id = ? AND status = ?", jobID, models.BatchItemFailed)
+	if itemID != uuid.Nil {
+		query = query.Where("id = ?", itemID)
+	}
+
+	result := query.Updates(map[string]interface{}{
+		"status": models.BatchItemPending,
+		"error":  "",
+	})
+	return result.RowsAffected, result.Error
+}
+
+func (r *batchJobRepository) UpdateJobStatus(jobID uuid.UUID, status models.BatchJobStatus) error {
+	return r.db.Model(&models.BatchJob{}).
+		Where("id = ?", jobID).
+		Update("status", status).Error
+}
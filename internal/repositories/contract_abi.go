@@ -0,0 +1,42 @@
+package repositories
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// The ABI of every contract version we have deployed, named <version>.json.
+//
+//go:embed abi/*.json
+var embeddedABIs embed.FS
+
+// loadContractABI parses the ABI of a contract version from dir, or from the
+// embedded copies when dir is empty.
+func loadContractABI(dir, version string) (abi.ABI, error) {
+
+	name := version + ".json"
+
+	var (
+		raw []byte
+		err error
+	)
+	if dir != "" {
+		raw, err = os.ReadFile(filepath.Join(dir, name))
+	} else {
+		raw, err = embeddedABIs.ReadFile("abi/" + name)
+	}
+	if err != nil {
+		return abi.ABI{}, fmt.Errorf("no ABI for contract version %q: %w", version, err)
+	}
+
+	parsed, err := abi.JSON(bytes.NewReader(raw))
+	if err != nil {
+		return abi.ABI{}, fmt.Errorf("failed to parse contract ABI %s: %w", name, err)
+	}
+	return parsed, nil
+}
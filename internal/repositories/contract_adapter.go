@@ -0,0 +1,387 @@
+package repositories
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// DiplomaRecord is what a diploma contract stores for a hash, whatever its
+// version.
+type DiplomaRecord struct {
+	ContractAddress common.Address
+	ContractVersion string
+
+	ID          *big.Int // v1 counter; nil on contracts keyed by the hash itself
+	DiplomaHash string
+	ArweaveTxID string
+	Owner       common.Address // v1 sender, v2 registered issuer
+	Timestamp   *big.Int
+	Revoked     bool
+}
+
+// UnsupportedError reports an operation the contract version has no method
+// for, such as revoking a diploma on the legacy v1 contract. Nothing is sent
+// for it, so callers can refuse the request before queueing a transaction.
+type UnsupportedError struct {
+	Operation string
+	Version   string
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("%s is not supported by contract %s", e.Operation, e.Version)
+}
+
+// contractCaller runs an eth_call with the given calldata against one contract.
+type contractCaller func(data []byte) ([]byte, error)
+
+// contractAdapter maps the repository's operations onto the method and event
+// signatures of one contract version.
+type contractAdapter interface {
+	// hasDiploma reports whether the contract stores the hash, revoked or not
+	hasDiploma(call contractCaller, diplomaHash string) (bool, string, error)
+	// verifyDiploma reports whether the contract stores the hash unrevoked
+	verifyDiploma(call contractCaller, diplomaHash string) (bool, string, error)
+	getDiploma(call contractCaller, diplomaHash string) (*DiplomaRecord, error)
+	rootTimestamp(call contractCaller, root [32]byte) (*big.Int, error)
+
+	packStoreDiploma(diplomaHash, arweaveTxID string) ([]byte, error)
+	packRevokeDiploma(diplomaHash, reason string) ([]byte, error)
+	packAnchorRoot(root [32]byte, leafCount int) ([]byte, error)
+
+	// storedTopic is the event emitted when a diploma is stored
+	storedTopic() common.Hash
+	unpackStored(vLog *types.Log) (*DiplomaStoredEvent, error)
+}
+
+func newContractAdapter(version string, contractABI abi.ABI) (contractAdapter, error) {
+	switch version {
+	case "v1":
+		return &v1Adapter{abi: contractABI}, nil
+	case "v2":
+		return &v2Adapter{abi: contractABI}, nil
+	}
+	return nil, fmt.Errorf("unsupported contract version %q", version)
+}
+
+// callMethod packs a view call, runs it and unpacks exactly outputs values.
+func callMethod(contractABI abi.ABI, call contractCaller, method string, outputs int, args ...interface{}) ([]interface{}, error) {
+
+	data, err := contractABI.Pack(method, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := call(data)
+	if err != nil {
+		return nil, err
+	}
+
+	unpacked, err := contractABI.Unpack(method, result)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(unpacked) != outputs {
+		return nil, fmt.Errorf("Unexpected return values of %s", method)
+	}
+	return unpacked, nil
+}
+
+func anchoredRoot(contractABI abi.ABI, call contractCaller, root [32]byte) (*big.Int, error) {
+
+	unpacked, err := callMethod(contractABI, call, "anchoredRoots", 1, root)
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp, ok := unpacked[0].(*big.Int)
+	if !ok {
+		return nil, fmt.Errorf("Failed to parse timestamp value")
+	}
+	return timestamp, nil
+}
+
+// v1Adapter is the original contract: diplomas are keyed by a counter and
+// looked up by their hex hash string.
+type v1Adapter struct {
+	abi abi.ABI
+}
+
+// hasDiploma asks verifyDiploma; v1 cannot revoke, so the two agree.
+func (a *v1Adapter) hasDiploma(call contractCaller, diplomaHash string) (bool, string, error) {
+	return a.verifyDiploma(call, diplomaHash)
+}
+
+func (a *v1Adapter) verifyDiploma(call contractCaller, diplomaHash string) (bool, string, error) {
+
+	unpacked, err := callMethod(a.abi, call, "verifyDiploma", 2, diplomaHash)
+	if err != nil {
+		return false, "", err
+	}
+
+	exists, ok := unpacked[0].(bool)
+	if !ok {
+		return false, "", fmt.Errorf("Failed to parse exists value")
+	}
+
+	arweaveTxID, ok := unpacked[1].(string)
+	if !ok {
+		return false, "", fmt.Errorf("Failed to parse arweaveTxId value")
+	}
+	return exists, arweaveTxID, nil
+}
+
+// getDiploma reads the record through hashToId and getDiploma(id), so a
+// disagreement between the two mappings shows up in the result.
+func (a *v1Adapter) getDiploma(call contractCaller, diplomaHash string) (*DiplomaRecord, error) {
+
+	unpacked, err := callMethod(a.abi, call, "hashToId", 1, diplomaHash)
+	if err != nil {
+		return nil, err
+	}
+
+	id, ok := unpacked[0].(*big.Int)
+	if !ok {
+		return nil, fmt.Errorf("Failed to parse id value")
+	}
+
+	unpacked, err = callMethod(a.abi, call, "getDiploma", 4, id)
+	if err != nil {
+		return nil, err
+	}
+
+	storedHash, ok := unpacked[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("Failed to parse diplomaHash value")
+	}
+	arweaveTxID, ok := unpacked[1].(string)
+	if !ok {
+		return nil, fmt.Errorf("Failed to parse arweaveTxId value")
+	}
+	owner, ok := unpacked[2].(common.Address)
+	if !ok {
+		return nil, fmt.Errorf("Failed to parse owner value")
+	}
+	timestamp, ok := unpacked[3].(*big.Int)
+	if !ok {
+		return nil, fmt.Errorf("Failed to parse timestamp value")
+	}
+
+	return &DiplomaRecord{
+		ID:          id,
+		DiplomaHash: storedHash,
+		ArweaveTxID: arweaveTxID,
+		Owner:       owner,
+		Timestamp:   timestamp,
+	}, nil
+}
+
+func (a *v1Adapter) rootTimestamp(call contractCaller, root [32]byte) (*big.Int, error) {
+	return nil, &UnsupportedError{Operation: "anchoredRoots", Version: "v1"}
+}
+
+func (a *v1Adapter) packStoreDiploma(diplomaHash, arweaveTxID string) ([]byte, error) {
+	return a.abi.Pack("storeDiploma", diplomaHash, arweaveTxID)
+}
+
+func (a *v1Adapter) packRevokeDiploma(diplomaHash, reason string) ([]byte, error) {
+	return nil, &UnsupportedError{Operation: "revokeDiploma", Version: "v1"}
+}
+
+func (a *v1Adapter) packAnchorRoot(root [32]byte, leafCount int) ([]byte, error) {
+	return nil, &UnsupportedError{Operation: "anchorRoot", Version: "v1"}
+}
+
+func (a *v1Adapter) storedTopic() common.Hash {
+	return a.abi.Events["DiplomaStored"].ID
+}
+
+// unpackStored decodes DiplomaStored(uint256 indexed diplomaId, string
+// diplomaHash, string arweaveTxId, address indexed owner, uint256 timestamp).
+func (a *v1Adapter) unpackStored(vLog *types.Log) (*DiplomaStoredEvent, error) {
+
+	if len(vLog.Topics) != 3 {
+		return nil, fmt.Errorf("unexpected DiplomaStored topics")
+	}
+
+	values, err := a.abi.Events["DiplomaStored"].Inputs.NonIndexed().Unpack(vLog.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack DiplomaStored event: %w", err)
+	}
+	if len(values) != 3 {
+		return nil, fmt.Errorf("unexpected DiplomaStored event data")
+	}
+
+	diplomaHash, ok := values[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("failed to parse diplomaHash value")
+	}
+	arweaveTxID, ok := values[1].(string)
+	if !ok {
+		return nil, fmt.Errorf("failed to parse arweaveTxId value")
+	}
+	timestamp, ok := values[2].(*big.Int)
+	if !ok {
+		return nil, fmt.Errorf("failed to parse timestamp value")
+	}
+
+	return &DiplomaStoredEvent{
+		DiplomaID:   new(big.Int).SetBytes(vLog.Topics[1].Bytes()),
+		DiplomaHash: diplomaHash,
+		ArweaveTxID: arweaveTxID,
+		Owner:       common.BytesToAddress(vLog.Topics[2].Bytes()),
+		Timestamp:   timestamp,
+	}, nil
+}
+
+// v2Adapter is the contract with an issuer registry and revocation state:
+// diplomas are keyed by the 32 byte hash itself and only registered issuers
+// may issue, revoke or anchor.
+type v2Adapter struct {
+	abi abi.ABI
+}
+
+// diplomaKey converts the hex SHA-256 diploma hash to the contract's key.
+func diplomaKey(diplomaHash string) ([32]byte, error) {
+	var key [32]byte
+	raw, err := hex.DecodeString(strings.TrimPrefix(diplomaHash, "0x"))
+	if err != nil || len(raw) != len(key) {
+		return key, fmt.Errorf("diploma hash %q is not a 32 byte hex string", diplomaHash)
+	}
+	copy(key[:], raw)
+	return key, nil
+}
+
+func (a *v2Adapter) hasDiploma(call contractCaller, diplomaHash string) (bool, string, error) {
+
+	record, err := a.getDiploma(call, diplomaHash)
+	if err != nil {
+		return false, "", err
+	}
+	if record.Timestamp.Sign() == 0 {
+		return false, "", nil
+	}
+	return true, record.ArweaveTxID, nil
+}
+
+// verifyDiploma treats a revoked diploma as not verified; getDiploma still
+// returns its record with Revoked set.
+func (a *v2Adapter) verifyDiploma(call contractCaller, diplomaHash string) (bool, string, error) {
+
+	record, err := a.getDiploma(call, diplomaHash)
+	if err != nil {
+		return false, "", err
+	}
+	if record.Timestamp.Sign() == 0 || record.Revoked {
+		return false, "", nil
+	}
+	return true, record.ArweaveTxID, nil
+}
+
+func (a *v2Adapter) getDiploma(call contractCaller, diplomaHash string) (*DiplomaRecord, error) {
+
+	key, err := diplomaKey(diplomaHash)
+	if err != nil {
+		return nil, err
+	}
+
+	unpacked, err := callMethod(a.abi, call, "getDiploma", 5, key)
+	if err != nil {
+		return nil, err
+	}
+
+	arweaveTxID, ok := unpacked[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("Failed to parse arweaveTxId value")
+	}
+	issuer, ok := unpacked[1].(common.Address)
+	if !ok {
+		return nil, fmt.Errorf("Failed to parse issuer value")
+	}
+	timestamp, ok := unpacked[2].(*big.Int)
+	if !ok {
+		return nil, fmt.Errorf("Failed to parse timestamp value")
+	}
+	revoked, ok := unpacked[3].(bool)
+	if !ok {
+		return nil, fmt.Errorf("Failed to parse revoked value")
+	}
+
+	record := &DiplomaRecord{
+		ArweaveTxID: arweaveTxID,
+		Owner:       issuer,
+		Timestamp:   timestamp,
+		Revoked:     revoked,
+	}
+	if timestamp.Sign() != 0 {
+		record.DiplomaHash = hex.EncodeToString(key[:])
+	}
+	return record, nil
+}
+
+func (a *v2Adapter) rootTimestamp(call contractCaller, root [32]byte) (*big.Int, error) {
+	return anchoredRoot(a.abi, call, root)
+}
+
+func (a *v2Adapter) packStoreDiploma(diplomaHash, arweaveTxID string) ([]byte, error) {
+	key, err := diplomaKey(diplomaHash)
+	if err != nil {
+		return nil, err
+	}
+	return a.abi.Pack("issueDiploma", key, arweaveTxID)
+}
+
+func (a *v2Adapter) packRevokeDiploma(diplomaHash, reason string) ([]byte, error) {
+	key, err := diplomaKey(diplomaHash)
+	if err != nil {
+		return nil, err
+	}
+	return a.abi.Pack("revokeDiploma", key, reason)
+}
+
+func (a *v2Adapter) packAnchorRoot(root [32]byte, leafCount int) ([]byte, error) {
+	return a.abi.Pack("anchorRoot", root, big.NewInt(int64(leafCount)))
+}
+
+func (a *v2Adapter) storedTopic() common.Hash {
+	return a.abi.Events["DiplomaIssued"].ID
+}
+
+// unpackStored decodes DiplomaIssued(bytes32 indexed diplomaHash, address
+// indexed issuer, string arweaveTxId, uint256 timestamp).
+func (a *v2Adapter) unpackStored(vLog *types.Log) (*DiplomaStoredEvent, error) {
+
+	if len(vLog.Topics) != 3 {
+		return nil, fmt.Errorf("unexpected DiplomaIssued topics")
+	}
+
+	values, err := a.abi.Events["DiplomaIssued"].Inputs.NonIndexed().Unpack(vLog.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack DiplomaIssued event: %w", err)
+	}
+	if len(values) != 2 {
+		return nil, fmt.Errorf("unexpected DiplomaIssued event data")
+	}
+
+	arweaveTxID, ok := values[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("failed to parse arweaveTxId value")
+	}
+	timestamp, ok := values[1].(*big.Int)
+	if !ok {
+		return nil, fmt.Errorf("failed to parse timestamp value")
+	}
+
+	return &DiplomaStoredEvent{
+		DiplomaHash: hex.EncodeToString(vLog.Topics[1].Bytes()),
+		ArweaveTxID: arweaveTxID,
+		Owner:       common.BytesToAddress(vLog.Topics[2].Bytes()),
+		Timestamp:   timestamp,
+	}, nil
+}
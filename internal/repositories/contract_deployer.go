@@ -0,0 +1,168 @@
+package repositories
+
+import (
+	"BlockCertify/internal/config"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// contractNames maps a contract version to the Solidity contract in
+// contracts/, compiled to <name>.bin by make contracts.
+var contractNames = map[string]string{
+	"v1": "DiplomaRegistry",
+	"v2": "DiplomaRegistryV2",
+}
+
+const deployWaitTimeout = 30 * time.Second
+
+// PrepareDevNetwork deploys the diploma contract on the dev network when it
+// is the active one and has no contract address yet, and records the new
+// address in cfg. A dev node usually starts from an empty chain, so the
+// contract is deployed again on every start unless CONTRACT_ADDRESS is set.
+func PrepareDevNetwork(cfg *config.Config) error {
+
+	if !cfg.Blockchain.DevMode() {
+		return nil
+	}
+
+	profile := cfg.Blockchain.ActiveNetwork()
+	if profile.ContractAddress != "" {
+		return nil
+	}
+
+	address, err := DeployContract(profile, cfg.Blockchain.ContractBytecodeDir, cfg.Blockchain.PrivateKey)
+	if err != nil {
+		return fmt.Errorf("failed to deploy the %s contract on %s: %w", profile.ContractVersion, profile.Name, err)
+	}
+
+	profile.ContractAddress = address.Hex()
+	cfg.Blockchain.Networks[profile.Name] = profile
+
+	slog.Info("Deployed diploma contract on the dev network",
+		"address", profile.ContractAddress, "version", profile.ContractVersion, "rpc", profile.RPCURL)
+	return nil
+}
+
+// DeployContract deploys the compiled contract of the network's contract
+// version from bytecodeDir, signed with privateKeyHex, and returns its
+// address once the deployment is mined.
+func DeployContract(network config.NetworkProfile, bytecodeDir, privateKeyHex string) (common.Address, error) {
+
+	client, err := ethclient.Dial(network.RPCURL)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to connect to %s: %w", network.Name, err)
+	}
+	defer client.Close()
+
+	return DeployContractWithClient(client, network, bytecodeDir, privateKeyHex)
+}
+
+// DeployContractWithClient is DeployContract on a client that is already
+// connected; network.RPCURL is not used.
+func DeployContractWithClient(client EthClient, network config.NetworkProfile, bytecodeDir, privateKeyHex string) (common.Address, error) {
+
+	name, ok := contractNames[network.ContractVersion]
+	if !ok {
+		return common.Address{}, fmt.Errorf("unsupported contract version %q", network.ContractVersion)
+	}
+
+	bytecode, err := loadBytecode(filepath.Join(bytecodeDir, name+".bin"))
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	privateKey, err := crypto.HexToECDSA(strings.TrimPrefix(privateKeyHex, "0x"))
+	if err != nil {
+		return common.Address{}, fmt.Errorf("invalid private key: %w", err)
+	}
+	from := crypto.PubkeyToAddress(privateKey.PublicKey)
+
+	ctx, cancel := context.WithTimeout(context.Background(), deployWaitTimeout)
+	defer cancel()
+
+	nonce, err := client.PendingNonceAt(ctx, from)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to get nonce: %w", err)
+	}
+
+	gas, err := client.EstimateGas(ctx, ethereum.CallMsg{From: from, Data: bytecode})
+	if err != nil {
+		return common.Address{}, fmt.Errorf("gas estimation failed: %w", err)
+	}
+
+	gasTipCap, err := client.SuggestGasTipCap(ctx)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to get gas tip: %w", err)
+	}
+
+	head, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to get latest block: %w", err)
+	}
+	gasFeeCap := new(big.Int).Add(gasTipCap, new(big.Int).Mul(head.BaseFee, big.NewInt(2)))
+
+	chainID := big.NewInt(int64(network.ChainID))
+	tx, err := types.SignTx(types.NewTx(&types.DynamicFeeTx{
+		ChainID:   chainID,
+		Nonce:     nonce,
+		GasTipCap: gasTipCap,
+		GasFeeCap: gasFeeCap,
+		Gas:       gas,
+		Value:     big.NewInt(0),
+		Data:      bytecode,
+	}), types.LatestSignerForChainID(chainID), privateKey)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to sign deployment: %w", err)
+	}
+
+	if err := client.SendTransaction(ctx, tx); err != nil {
+		return common.Address{}, fmt.Errorf("failed to send deployment: %w", err)
+	}
+
+	for {
+		receipt, err := transactionReceipt(ctx, client, tx.Hash())
+		if err == nil {
+			if receipt.Status != types.ReceiptStatusSuccessful {
+				return common.Address{}, fmt.Errorf("deployment %s reverted", tx.Hash().Hex())
+			}
+			return receipt.ContractAddress, nil
+		}
+		if err != ethereum.NotFound {
+			return common.Address{}, fmt.Errorf("failed to get deployment receipt: %w", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return common.Address{}, fmt.Errorf("deployment %s not mined: %w", tx.Hash().Hex(), ctx.Err())
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+// loadBytecode reads a hex encoded creation bytecode as written by solc --bin.
+func loadBytecode(path string) ([]byte, error) {
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("no compiled contract, run make contracts: %w", err)
+	}
+
+	bytecode, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(string(raw)), "0x"))
+	if err != nil || len(bytecode) == 0 {
+		return nil, fmt.Errorf("%s does not hold hex encoded bytecode", path)
+	}
+	return bytecode, nil
+}
@@ -2,265 +2,156 @@ package repositories
 
 import (
 	"BlockCertify/internal/config"
-	"bytes"
 	"context"
 	"crypto/ecdsa"
+	"errors"
 	"fmt"
 	"math/big"
-	"time"
+	"strings"
 
 	"github.com/ethereum/go-ethereum"
-	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
 )
 
-const contractABI = `[
-  {
-    "anonymous": false,
-    "inputs": [
-      {
-        "indexed": true,
-        "internalType": "uint256",
-        "name": "diplomaId",
-        "type": "uint256"
-      },
-      {
-        "indexed": false,
-        "internalType": "string",
-        "name": "diplomaHash",
-        "type": "string"
-      },
-      {
-        "indexed": false,
-        "internalType": "string",
-        "name": "arweaveTxId",
-        "type": "string"
-      },
-      {
-        "indexed": true,
-        "internalType": "address",
-        "name": "owner",
-        "type": "address"
-      },
-      {
-        "indexed": false,
-        "internalType": "uint256",
-        "name": "timestamp",
-        "type": "uint256"
-      }
-    ],
-    "name": "DiplomaStored",
-    "type": "event"
-  },
-  {
-    "inputs": [],
-    "name": "diplomaCount",
-    "outputs": [
-      {
-        "internalType": "uint256",
-        "name": "",
-        "type": "uint256"
-      }
-    ],
-    "stateMutability": "view",
-    "type": "function"
-  },
-  {
-    "inputs": [
-      {
-        "internalType": "uint256",
-        "name": "",
-        "type": "uint256"
-      }
-    ],
-    "name": "diplomas",
-    "outputs": [
-      {
-        "internalType": "string",
-        "name": "diplomaHash",
-        "type": "string"
-      },
-      {
-        "internalType": "string",
-        "name": "arweaveTxId",
-        "type": "string"
-      },
-      {
-        "internalType": "address",
-        "name": "owner",
-        "type": "address"
-      },
-      {
-        "internalType": "uint256",
-        "name": "timestamp",
-        "type": "uint256"
-      },
-      {
-        "internalType": "bool",
-        "name": "exists",
-        "type": "bool"
-      }
-    ],
-    "stateMutability": "view",
-    "type": "function"
-  },
-  {
-    "inputs": [
-      {
-        "internalType": "uint256",
-        "name": "_diplomaId",
-        "type": "uint256"
-      }
-    ],
-    "name": "getDiploma",
-    "outputs": [
-      {
-        "internalType": "string",
-        "name": "diplomaHash",
-        "type": "string"
-      },
-      {
-        "internalType": "string",
-        "name": "arweaveTxId",
-        "type": "string"
-      },
-      {
-        "internalType": "address",
-        "name": "owner",
-        "type": "address"
-      },
-      {
-        "internalType": "uint256",
-        "name": "timestamp",
-        "type": "uint256"
-      }
-    ],
-    "stateMutability": "view",
-    "type": "function"
-  },
-  {
-    "inputs": [
-      {
-        "internalType": "string",
-        "name": "",
-        "type": "string"
-      }
-    ],
-    "name": "hashExists",
-    "outputs": [
-      {
-        "internalType": "bool",
-        "name": "",
-        "type": "bool"
-      }
-    ],
-    "stateMutability": "view",
-    "type": "function"
-  },
-  {
-    "inputs": [
-      {
-        "internalType": "string",
-        "name": "",
-        "type": "string"
-      }
-    ],
-    "name": "hashToId",
-    "outputs": [
-      {
-        "internalType": "uint256",
-        "name": "",
-        "type": "uint256"
-      }
-    ],
-    "stateMutability": "view",
-    "type": "function"
-  },
-  {
-    "inputs": [
-      {
-        "internalType": "string",
-        "name": "_diplomaHash",
-        "type": "string"
-      },
-      {
-        "internalType": "string",
-        "name": "_arweaveTxId",
-        "type": "string"
-      }
-    ],
-    "name": "storeDiploma",
-    "outputs": [
-      {
-        "internalType": "uint256",
-        "name": "",
-        "type": "uint256"
-      }
-    ],
-    "stateMutability": "nonpayable",
-    "type": "function"
-  },
-  {
-    "inputs": [
-      {
-        "internalType": "string",
-        "name": "_diplomaHash",
-        "type": "string"
-      }
-    ],
-    "name": "verifyDiploma",
-    "outputs": [
-      {
-        "internalType": "bool",
-        "name": "exists",
-        "type": "bool"
-      },
-      {
-        "internalType": "string",
-        "name": "arweaveTxId",
-        "type": "string"
-      }
-    ],
-    "stateMutability": "view",
-    "type": "function"
-  }
-]`
+// DiplomaStoredEvent is the decoded form of the log a contract emits when a
+// diploma is stored: DiplomaStored on v1, DiplomaIssued on v2.
+type DiplomaStoredEvent struct {
+	DiplomaID       *big.Int // nil on v2, which has no counter
+	DiplomaHash     string
+	ArweaveTxID     string
+	Owner           common.Address
+	Timestamp       *big.Int
+	ContractAddress common.Address
+
+	BlockNumber uint64
+	BlockHash   common.Hash
+	TxHash      common.Hash
+	LogIndex    uint
+}
+
+// ContractCall is encoded calldata for one of a network's diploma contracts.
+type ContractCall struct {
+	To   common.Address
+	Data []byte
+}
+
+// EthClient is the part of an Ethereum client the ledger and the deployer
+// use. It is an *ethclient.Client, or a simulated backend's client in tests.
+type EthClient interface {
+	ethereum.BlockNumberReader
+	ethereum.ChainReader
+	ethereum.ChainStateReader
+	ethereum.ContractCaller
+	ethereum.GasEstimator
+	ethereum.GasPricer
+	ethereum.GasPricer1559
+	ethereum.LogFilterer
+	ethereum.PendingStateReader
+	ethereum.TransactionReader
+	ethereum.TransactionSender
+}
+
+var _ EthClient = (*ethclient.Client)(nil)
+
+// deployedContract is one diploma contract with the adapter for its version.
+type deployedContract struct {
+	address common.Address
+	version string
+	adapter contractAdapter
+}
 
+// ContractRepository is the Ledger of one EVM network profile. New diplomas
+// go to the current contract; the legacy contracts of the profile are only
+// read, so records anchored on them before an upgrade still verify.
 type ContractRepository struct {
-	client          *ethclient.Client
-	contractAddress common.Address
-	contractABI     abi.ABI
-	privateKey      string
-	chainID         *big.Int
+	network   config.NetworkProfile
+	client    EthClient
+	contracts []deployedContract // current first, then legacy newest first
+	chainID   *big.Int
 }
 
-func NewContractRepository(cfg *config.Config) (*ContractRepository, error) {
-	client, err := ethclient.Dial(cfg.Blockchain.RPCURL)
+func NewContractRepository(network config.NetworkProfile, abiDir string) (*ContractRepository, error) {
+
+	client, err := ethclient.Dial(network.RPCURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to blockchain: %w", err)
+		return nil, fmt.Errorf("failed to connect to %s: %w", network.Name, err)
 	}
 
-	//TODO : put a file reader for ABI and read from file instead of hardcoding
-	parsedABI, err := abi.JSON(bytes.NewReader([]byte(contractABI)))
+	ledger, err := NewContractRepositoryWithClient(network, abiDir, client)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse contract ABI: %w", err)
+		client.Close()
+		return nil, err
+	}
+	return ledger, nil
+}
+
+// NewContractRepositoryWithClient is NewContractRepository on a client that
+// is already connected; network.RPCURL is not used.
+func NewContractRepositoryWithClient(network config.NetworkProfile, abiDir string, client EthClient) (*ContractRepository, error) {
+
+	deployments := append([]config.ContractDeployment{{
+		Address: network.ContractAddress,
+		Version: network.ContractVersion,
+	}}, network.LegacyContracts...)
+
+	contracts := make([]deployedContract, 0, len(deployments))
+	for _, d := range deployments {
+		contractABI, err := loadContractABI(abiDir, d.Version)
+		if err != nil {
+			return nil, err
+		}
+		adapter, err := newContractAdapter(d.Version, contractABI)
+		if err != nil {
+			return nil, err
+		}
+		contracts = append(contracts, deployedContract{
+			address: common.HexToAddress(d.Address),
+			version: d.Version,
+			adapter: adapter,
+		})
 	}
 
 	return &ContractRepository{
-		client:          client,
-		contractAddress: common.HexToAddress(cfg.Blockchain.ContractAddress),
-		contractABI:     parsedABI,
-		privateKey:      cfg.Blockchain.PrivateKey,
-		chainID:         big.NewInt(int64(cfg.Blockchain.ChainID)),
+		network:   network,
+		client:    client,
+		contracts: contracts,
+		chainID:   big.NewInt(int64(network.ChainID)),
 	}, nil
 }
 
+func (r *ContractRepository) Network() config.NetworkProfile {
+	return r.network
+}
+
+func (r *ContractRepository) ExplorerTxURL(txHash string) string {
+	return r.network.TxURL(txHash)
+}
+
 func (r *ContractRepository) Close() {
-	if r.client != nil {
-		r.client.Close()
+	if closer, ok := r.client.(interface{ Close() }); ok {
+		closer.Close()
+	}
+}
+
+// ContractAddress is the current contract, the one new diplomas are sent to.
+func (r *ContractRepository) ContractAddress() common.Address {
+	return r.contracts[0].address
+}
+
+func (r *ContractRepository) current() deployedContract {
+	return r.contracts[0]
+}
+
+func (r *ContractRepository) caller(address common.Address) contractCaller {
+	return func(data []byte) ([]byte, error) {
+		ctx := context.Background()
+		return r.client.CallContract(ctx, ethereum.CallMsg{
+			To:   &address,
+			Data: data,
+		}, nil)
 	}
 }
 
@@ -286,127 +177,284 @@ func (r *ContractRepository) GetFeeData() (*big.Int, *big.Int, error) {
 	return gasPrice, gasTipCap, nil
 }
 
+// VerifyDiploma asks the current contract and then each legacy one whether
+// it holds the hash unrevoked, and returns the Arweave TxID of the first that
+// does. A revoked diploma does not verify.
 func (r *ContractRepository) VerifyDiploma(diplomaHash string) (bool, string, error) {
 
-	ctx := context.Background()
+	for _, c := range r.contracts {
+		valid, arweaveTxID, err := c.adapter.verifyDiploma(r.caller(c.address), diplomaHash)
+		if err != nil {
+			return false, "", fmt.Errorf("contract %s: %w", c.address.Hex(), err)
+		}
+		if valid {
+			return true, arweaveTxID, nil
+		}
+	}
+	return false, "", nil
+}
+
+// findDiploma returns the contract that stores the hash, revoked or not, or
+// the current contract when none does.
+func (r *ContractRepository) findDiploma(diplomaHash string) (deployedContract, bool, string, error) {
+
+	for _, c := range r.contracts {
+		exists, arweaveTxID, err := c.adapter.hasDiploma(r.caller(c.address), diplomaHash)
+		if err != nil {
+			return c, false, "", fmt.Errorf("contract %s: %w", c.address.Hex(), err)
+		}
+		if exists {
+			return c, true, arweaveTxID, nil
+		}
+	}
+	return r.current(), false, "", nil
+}
+
+// GetDiplomaRecord returns the full record of a hash from the contract that
+// holds it, or an empty record from the current contract when none does.
+func (r *ContractRepository) GetDiplomaRecord(diplomaHash string) (*DiplomaRecord, error) {
 
-	data, err := r.contractABI.Pack("verifyDiploma", diplomaHash)
+	c, _, _, err := r.findDiploma(diplomaHash)
 	if err != nil {
-		return false, "", err
+		return nil, err
 	}
 
-	result, err := r.client.CallContract(ctx, ethereum.CallMsg{
-		To:   &r.contractAddress,
-		Data: data,
-	}, nil)
+	record, err := c.adapter.getDiploma(r.caller(c.address), diplomaHash)
 	if err != nil {
-		return false, "", err
+		return nil, err
 	}
+	record.ContractAddress = c.address
+	record.ContractVersion = c.version
+	return record, nil
+}
 
-	unpacked, err := r.contractABI.Unpack("verifyDiploma", result)
+// PackStoreDiploma encodes storing a diploma on the current contract.
+func (r *ContractRepository) PackStoreDiploma(diplomaHash, arweaveTxID string) (*ContractCall, error) {
+	c := r.current()
+	data, err := c.adapter.packStoreDiploma(diplomaHash, arweaveTxID)
 	if err != nil {
-		return false, "", err
+		return nil, err
 	}
+	return &ContractCall{To: c.address, Data: data}, nil
+}
+
+// PackRevokeDiploma encodes revoking a diploma on the contract that holds it.
+// It returns an *UnsupportedError when that contract cannot revoke.
+func (r *ContractRepository) PackRevokeDiploma(diplomaHash, reason string) (*ContractCall, error) {
 
-	if len(unpacked) < 2 {
-		return false, "", fmt.Errorf("Unexpected return values")
+	c, _, _, err := r.findDiploma(diplomaHash)
+	if err != nil {
+		return nil, err
 	}
 
-	exists, ok := unpacked[0].(bool)
-	if !ok {
-		return false, "", fmt.Errorf("Failed to parse exists value")
+	data, err := c.adapter.packRevokeDiploma(diplomaHash, reason)
+	if err != nil {
+		return nil, err
 	}
+	return &ContractCall{To: c.address, Data: data}, nil
+}
 
-	arweaveTxID, ok := unpacked[1].(string)
-	if !ok {
-		return false, "", fmt.Errorf("Failed to parse arweaveTxId value")
+// PackAnchorRoot encodes anchorRoot on the current contract, which writes the
+// root of a Merkle tree of diplomas. It returns an *UnsupportedError when
+// the current contract cannot anchor roots.
+func (r *ContractRepository) PackAnchorRoot(root [32]byte, leafCount int) (*ContractCall, error) {
+	c := r.current()
+	data, err := c.adapter.packAnchorRoot(root, leafCount)
+	if err != nil {
+		return nil, err
 	}
-	return exists, arweaveTxID, nil
+	return &ContractCall{To: c.address, Data: data}, nil
 }
 
-func (r *ContractRepository) StoreDiploma(diplomaHash, arweaveTxID string) (*types.Receipt, error) {
+// GetRootTimestamp returns the block timestamp at which root was anchored on
+// any of the contracts, or zero when none of them knows it. Contracts that
+// cannot anchor roots are skipped.
+func (r *ContractRepository) GetRootTimestamp(root [32]byte) (*big.Int, error) {
 
-	ctx := context.Background()
+	for _, c := range r.contracts {
+		timestamp, err := c.adapter.rootTimestamp(r.caller(c.address), root)
+		var unsupported *UnsupportedError
+		if errors.As(err, &unsupported) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("contract %s: %w", c.address.Hex(), err)
+		}
+		if timestamp.Sign() != 0 {
+			return timestamp, nil
+		}
+	}
+	return new(big.Int), nil
+}
+
+// SignerAddress returns the address of a hex encoded private key.
+func SignerAddress(privateKeyHex string) (common.Address, error) {
 
-	privateKey, err := crypto.HexToECDSA(r.privateKey)
+	privateKey, err := crypto.HexToECDSA(privateKeyHex)
 	if err != nil {
-		return nil, fmt.Errorf("invalid private key: %w", err)
+		return common.Address{}, fmt.Errorf("invalid private key: %w", err)
 	}
 
 	publicKey := privateKey.Public()
 	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
 	if !ok {
-		return nil, fmt.Errorf("failed to cast public key")
+		return common.Address{}, fmt.Errorf("failed to cast public key")
 	}
 
-	fromAddress := crypto.PubkeyToAddress(*publicKeyECDSA)
+	return crypto.PubkeyToAddress(*publicKeyECDSA), nil
+}
 
-	nonce, err := r.client.PendingNonceAt(ctx, fromAddress)
-	if err != nil {
-		return nil, err
-	}
+func (r *ContractRepository) EstimateGas(from common.Address, call *ContractCall) (uint64, error) {
+	ctx := context.Background()
 
-	gasPrice, gasTipCap, err := r.GetFeeData()
+	gasLimit, err := r.client.EstimateGas(ctx, ethereum.CallMsg{
+		From: from,
+		To:   &call.To,
+		Data: call.Data,
+	})
 	if err != nil {
-		return nil, err
+		return 0, fmt.Errorf("gas estimation failed: %w", err)
 	}
+	return gasLimit, nil
+}
 
-	data, err := r.contractABI.Pack("storeDiploma", diplomaHash, arweaveTxID)
-	if err != nil {
-		return nil, err
-	}
+// PendingNonceAt returns the next nonce of address including transactions
+// still in the node's mempool.
+func (r *ContractRepository) PendingNonceAt(address common.Address) (uint64, error) {
+	ctx := context.Background()
+	return r.client.PendingNonceAt(ctx, address)
+}
 
-	gasLimit, err := r.client.EstimateGas(ctx, ethereum.CallMsg{
-		From: fromAddress,
-		To:   &r.contractAddress,
-		Data: data,
-	})
+// NonceAt returns the number of transactions of address mined in the latest block.
+func (r *ContractRepository) NonceAt(address common.Address) (uint64, error) {
+	ctx := context.Background()
+	return r.client.NonceAt(ctx, address, nil)
+}
+
+// SignTransaction signs an EIP-1559 contract call without sending it.
+func (r *ContractRepository) SignTransaction(privateKeyHex string, nonce, gas uint64, gasTipCap, gasFeeCap *big.Int, call *ContractCall) (*types.Transaction, error) {
+
+	privateKey, err := crypto.HexToECDSA(privateKeyHex)
 	if err != nil {
-		return nil, fmt.Errorf("gas estimation failed: %w", err)
+		return nil, fmt.Errorf("invalid private key: %w", err)
 	}
 
 	tx := types.NewTx(&types.DynamicFeeTx{
 		ChainID:   r.chainID,
 		Nonce:     nonce,
 		GasTipCap: gasTipCap,
-		GasFeeCap: gasPrice,
-		Gas:       gasLimit,
-		To:        &r.contractAddress,
+		GasFeeCap: gasFeeCap,
+		Gas:       gas,
+		To:        &call.To,
 		Value:     big.NewInt(0),
-		Data:      data,
+		Data:      call.Data,
 	})
 
-	signedTx, err := types.SignTx(tx, types.LatestSignerForChainID(r.chainID), privateKey)
-	if err != nil {
-		return nil, err
-	}
+	return types.SignTx(tx, types.LatestSignerForChainID(r.chainID), privateKey)
+}
 
-	if err := r.client.SendTransaction(ctx, signedTx); err != nil {
-		return nil, err
+func (r *ContractRepository) SendTransaction(tx *types.Transaction) error {
+	ctx := context.Background()
+	return r.client.SendTransaction(ctx, tx)
+}
+
+// BlockNumber returns the number of the latest block.
+func (r *ContractRepository) BlockNumber() (uint64, error) {
+	ctx := context.Background()
+	return r.client.BlockNumber(ctx)
+}
+
+// GetTransactionReceipt returns the receipt of an already mined transaction.
+// It returns ethereum.NotFound while the transaction is pending or unknown.
+func (r *ContractRepository) GetTransactionReceipt(txHash common.Hash) (*types.Receipt, error) {
+	ctx := context.Background()
+	return transactionReceipt(ctx, r.client, txHash)
+}
+
+// transactionReceipt reports a receipt the node has not indexed yet as
+// ethereum.NotFound, since the node answers with a plain error until its
+// transaction index catches up with the chain.
+func transactionReceipt(ctx context.Context, client EthClient, txHash common.Hash) (*types.Receipt, error) {
+	receipt, err := client.TransactionReceipt(ctx, txHash)
+	if err != nil && strings.Contains(err.Error(), "transaction indexing is in progress") {
+		return nil, ethereum.NotFound
 	}
+	return receipt, err
+}
 
-	receipt, err := r.waitForReceipt(signedTx.Hash())
-	if err != nil {
-		return nil, err
+// ParseDiplomaStored looks for the event the current contract emits when a
+// diploma is stored. Logs emitted by other contracts are ignored, so the
+// returned event is always from the current contract address.
+func (r *ContractRepository) ParseDiplomaStored(receipt *types.Receipt) (*DiplomaStoredEvent, error) {
+
+	c := r.current()
+	for _, vLog := range receipt.Logs {
+		if vLog.Address != c.address {
+			continue
+		}
+		if len(vLog.Topics) != 3 || vLog.Topics[0] != c.adapter.storedTopic() {
+			continue
+		}
+
+		return unpackDiplomaStored(c, vLog)
 	}
 
-	return receipt, nil
+	return nil, fmt.Errorf("DiplomaStored event not found in transaction logs")
 }
 
-func (r *ContractRepository) waitForReceipt(txHash common.Hash) (*types.Receipt, error) {
+// FilterDiplomaStored returns the diploma stored events emitted by any of
+// the contracts between two blocks, inclusive.
+func (r *ContractRepository) FilterDiplomaStored(fromBlock, toBlock uint64) ([]DiplomaStoredEvent, error) {
 
 	ctx := context.Background()
 
-	for i := 0; i < 60; i++ {
-		receipt, err := r.client.TransactionReceipt(ctx, txHash)
-		if err == nil {
-			return receipt, nil
-		}
-		// Wait and retry
-		select {
-		case <-time.After(time.Second):
+	byAddress := make(map[common.Address]deployedContract, len(r.contracts))
+	addresses := make([]common.Address, 0, len(r.contracts))
+	topics := make([]common.Hash, 0, len(r.contracts))
+	for _, c := range r.contracts {
+		byAddress[c.address] = c
+		addresses = append(addresses, c.address)
+		topics = append(topics, c.adapter.storedTopic())
+	}
+
+	logs, err := r.client.FilterLogs(ctx, ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(fromBlock),
+		ToBlock:   new(big.Int).SetUint64(toBlock),
+		Addresses: addresses,
+		Topics:    [][]common.Hash{topics},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]DiplomaStoredEvent, 0, len(logs))
+	for i := range logs {
+		c := byAddress[logs[i].Address]
+		// The filter matches every version's topic on every address
+		if len(logs[i].Topics) != 3 || logs[i].Topics[0] != c.adapter.storedTopic() {
 			continue
 		}
+		stored, err := unpackDiplomaStored(c, &logs[i])
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, *stored)
 	}
-	return nil, fmt.Errorf("transaction receipt not found after timeout")
+
+	return events, nil
+}
+
+func unpackDiplomaStored(c deployedContract, vLog *types.Log) (*DiplomaStoredEvent, error) {
+
+	event, err := c.adapter.unpackStored(vLog)
+	if err != nil {
+		return nil, err
+	}
+
+	event.ContractAddress = vLog.Address
+	event.BlockNumber = vLog.BlockNumber
+	event.BlockHash = vLog.BlockHash
+	event.TxHash = vLog.TxHash
+	event.LogIndex = vLog.Index
+	return event, nil
 }
@@ -2,16 +2,40 @@ package repositories
 
 import (
 	"BlockCertify/internal/models"
+	"strings"
+	"time"
 
+	"github.com/gofrs/uuid/v5"
 	"gorm.io/gorm"
 )
 
+// DiplomaFilter narrows down a diploma listing. Zero values are ignored.
+// Results are ordered newest first by ID, which is a time-ordered UUIDv7, so
+// Cursor is simply the ID of the last row of the previous page.
+type DiplomaFilter struct {
+	UniversityID   uuid.UUID
+	Cursor         uuid.UUID
+	Limit          int
+	GraduationYear int
+	Faculty        string
+	Department     string
+	From           time.Time
+	To             time.Time
+	Search         string
+}
+
 type DiplomaRepository interface {
 	CreateTransaction() *gorm.DB
+	GetByID(id uuid.UUID) (*models.Diploma, error)
 	GetByDiplomaID(diplomaID string) (*models.Diploma, error)
+	GetByDiplomaIDForUniversity(diplomaID string, universityID uuid.UUID) (*models.Diploma, error)
+	GetByHash(hash string) (*models.Diploma, error)
+	GetByOwnerEmail(email string) ([]models.Diploma, error)
 	GetHashFromArweaveTxID(arweaveTxID string) (string, error)
 	GetHashFromPolygonTxID(polygonTxID string) (string, error)
-	GetAllDiplomaFromDatabase() <-chan models.Diploma
+	GetAllDiplomaFromDatabase(universityID uuid.UUID) <-chan models.Diploma
+	ListDiplomas(filter DiplomaFilter) ([]models.Diploma, int64, error)
+	CreateRevocation(revocation *models.DiplomaRevocation) error
 }
 
 type diplomaRepository struct {
@@ -28,15 +52,56 @@ func (r *diplomaRepository) CreateTransaction() *gorm.DB {
 	return r.db.Begin()
 }
 
+func (r *diplomaRepository) GetByID(id uuid.UUID) (*models.Diploma, error) {
+	var diploma models.Diploma
+	err := r.db.Preload("MetaData").Preload("Revocation").Where("id = ?", id).First(&diploma).Error
+	if err != nil {
+		return nil, err
+	}
+	return &diploma, nil
+}
+
 func (r *diplomaRepository) GetByDiplomaID(diplomaID string) (*models.Diploma, error) {
 	var diploma models.Diploma
-	err := r.db.Preload("MetaData").Where("public_id = ?", diplomaID).First(&diploma).Error
+	err := r.db.Preload("MetaData").Preload("Revocation").Where("public_id = ?", diplomaID).First(&diploma).Error
 	if err != nil {
 		return nil, err
 	}
 	return &diploma, nil
 }
 
+func (r *diplomaRepository) GetByDiplomaIDForUniversity(diplomaID string, universityID uuid.UUID) (*models.Diploma, error) {
+	var diploma models.Diploma
+	err := r.db.Preload("MetaData").Preload("Revocation").
+		Where("public_id = ? AND university_id = ?", diplomaID, universityID).
+		First(&diploma).Error
+	if err != nil {
+		return nil, err
+	}
+	return &diploma, nil
+}
+
+func (r *diplomaRepository) GetByHash(hash string) (*models.Diploma, error) {
+	var diploma models.Diploma
+	err := r.db.Preload("MetaData").Preload("Revocation").Where("hash = ?", hash).First(&diploma).Error
+	if err != nil {
+		return nil, err
+	}
+	return &diploma, nil
+}
+
+func (r *diplomaRepository) GetByOwnerEmail(email string) ([]models.Diploma, error) {
+	var diplomas []models.Diploma
+	err := r.db.Preload("MetaData").Preload("Revocation").
+		Joins("JOIN "+models.TableName+" ON "+models.TableName+".diploma_id = "+models.TableDiploma+".id").
+		Where(models.TableName+".email = ?", email).
+		Find(&diplomas).Error
+	if err != nil {
+		return nil, err
+	}
+	return diplomas, nil
+}
+
 func (r *diplomaRepository) GetHashFromArweaveTxID(arweaveTxID string) (string, error) {
 	var diploma models.Diploma
 	err := r.db.Where("arweave_tx_id = ?", arweaveTxID).First(&diploma).Error
@@ -57,7 +122,7 @@ func (r *diplomaRepository) GetHashFromPolygonTxID(polygonTxID string) (string,
 	return hash, nil
 }
 
-func (r *diplomaRepository) GetAllDiplomaFromDatabase() <-chan models.Diploma {
+func (r *diplomaRepository) GetAllDiplomaFromDatabase(universityID uuid.UUID) <-chan models.Diploma {
 
 	ch := make(chan models.Diploma)
 
@@ -65,7 +130,10 @@ func (r *diplomaRepository) GetAllDiplomaFromDatabase() <-chan models.Diploma {
 		defer close(ch)
 
 		var diplomas []models.Diploma
-		if err := r.db.Preload("MetaData").Find(&diplomas).Error; err != nil {
+		err := r.db.Preload("MetaData").Preload("Revocation").
+			Where("university_id = ?", universityID).
+			Find(&diplomas).Error
+		if err != nil {
 			return
 		}
 
@@ -77,3 +145,65 @@ func (r *diplomaRepository) GetAllDiplomaFromDatabase() <-chan models.Diploma {
 
 	return ch
 }
+
+func (r *diplomaRepository) CreateRevocation(revocation *models.DiplomaRevocation) error {
+	return r.db.Create(revocation).Error
+}
+
+// ListDiplomas returns one page of diplomas and the total number of rows
+// matching the filter, regardless of the cursor.
+func (r *diplomaRepository) ListDiplomas(filter DiplomaFilter) ([]models.Diploma, int64, error) {
+
+	query := r.db.Model(&models.Diploma{}).
+		Joins("JOIN "+models.TableName+" ON "+models.TableName+".diploma_id = "+models.TableDiploma+".id").
+		Where(models.TableDiploma+".university_id = ?", filter.UniversityID)
+
+	if filter.GraduationYear != 0 {
+		query = query.Where(models.TableName+".graduation_year = ?", filter.GraduationYear)
+	}
+	if filter.Faculty != "" {
+		query = query.Where("LOWER("+models.TableName+".faculty) = ?", strings.ToLower(filter.Faculty))
+	}
+	if filter.Department != "" {
+		query = query.Where("LOWER("+models.TableName+".department) = ?", strings.ToLower(filter.Department))
+	}
+	if !filter.From.IsZero() {
+		query = query.Where(models.TableDiploma+".created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where(models.TableDiploma+".created_at < ?", filter.To)
+	}
+	if filter.Search != "" {
+		pattern := "%" + escapeLike(filter.Search) + "%"
+		query = query.Where(
+			"("+models.TableName+".first_name || ' ' || "+models.TableName+".last_name) ILIKE ? OR "+models.TableName+".student_number ILIKE ?",
+			pattern, pattern,
+		)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if filter.Cursor != uuid.Nil {
+		query = query.Where(models.TableDiploma+".id < ?", filter.Cursor)
+	}
+
+	var diplomas []models.Diploma
+	err := query.
+		Preload("MetaData").
+		Preload("Revocation").
+		Order(models.TableDiploma + ".id DESC").
+		Limit(filter.Limit).
+		Find(&diplomas).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return diplomas, total, nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -0,0 +1,40 @@
+package repositories
+
+import (
+	"BlockCertify/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DocumentKeyRepository interface {
+	// Save stores the key of a document, replacing the one kept for the same
+	// reference when the document was written again
+	Save(key *models.DocumentKey) error
+	GetByReference(reference string) (*models.DocumentKey, error)
+}
+
+type documentKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewDocumentKeyRepository(db *gorm.DB) DocumentKeyRepository {
+	return &documentKeyRepository{
+		db: db,
+	}
+}
+
+func (r *documentKeyRepository) Save(key *models.DocumentKey) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "reference"}},
+		DoUpdates: clause.AssignmentColumns([]string{"diploma_hash", "master_key_id", "wrapped_key"}),
+	}).Create(key).Error
+}
+
+func (r *documentKeyRepository) GetByReference(reference string) (*models.DocumentKey, error) {
+	var key models.DocumentKey
+	if err := r.db.Where("reference = ?", reference).First(&key).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
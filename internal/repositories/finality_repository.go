@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"BlockCertify/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type FinalityRepository interface {
+	GetUnfinalized(limit int, orphanedBefore time.Time) ([]models.Diploma, error)
+	UpdateFinality(polygonTxID string, blockNumber uint64, blockHash string, confirmations uint64, finality models.Finality) error
+	MarkChecked(polygonTxID string) error
+}
+
+type finalityRepository struct {
+	db *gorm.DB
+}
+
+func NewFinalityRepository(db *gorm.DB) FinalityRepository {
+	return &finalityRepository{
+		db: db,
+	}
+}
+
+// GetUnfinalized returns one diploma per anchoring transaction that is not
+// final yet, least recently checked first, so a backlog larger than limit is
+// worked through in turns. The leaves of a Merkle anchor share their
+// transaction and its finality. Orphaned transactions are only returned once
+// they were last checked before orphanedBefore, in case they are mined again.
+func (r *finalityRepository) GetUnfinalized(limit int, orphanedBefore time.Time) ([]models.Diploma, error) {
+	var diplomas []models.Diploma
+	err := r.db.Model(&models.Diploma{}).
+		Select("polygon_tx_id, MIN(network) AS network, MIN(block_number) AS block_number, MIN(block_hash) AS block_hash, "+
+			"MIN(finality) AS finality, MIN(confirmations) AS confirmations, MIN(finality_checked_at) AS finality_checked_at").
+		Where("polygon_tx_id <> '' AND finality <> ?", models.FinalityFinal).
+		Where("finality <> ? OR finality_checked_at IS NULL OR finality_checked_at < ?", models.FinalityOrphaned, orphanedBefore).
+		Group("polygon_tx_id").
+		Order("MIN(finality_checked_at) ASC NULLS FIRST, MIN(block_number) ASC").
+		Limit(limit).
+		Find(&diplomas).Error
+	if err != nil {
+		return nil, err
+	}
+	return diplomas, nil
+}
+
+// UpdateFinality updates every diploma anchored by the transaction, and its
+// Merkle anchor if there is one, in a single transaction.
+func (r *finalityRepository) UpdateFinality(polygonTxID string, blockNumber uint64, blockHash string, confirmations uint64, finality models.Finality) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.Diploma{}).
+			Where("polygon_tx_id = ?", polygonTxID).
+			Updates(map[string]interface{}{
+				"block_number":        blockNumber,
+				"block_hash":          blockHash,
+				"confirmations":       confirmations,
+				"finality":            finality,
+				"finality_checked_at": time.Now().UTC(),
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&models.MerkleAnchor{}).
+			Where("polygon_tx_id = ?", polygonTxID).
+			Updates(map[string]interface{}{
+				"block_number": blockNumber,
+				"block_hash":   blockHash,
+			}).Error
+	})
+}
+
+// MarkChecked records that the transaction was looked up without a change.
+func (r *finalityRepository) MarkChecked(polygonTxID string) error {
+	return r.db.Model(&models.Diploma{}).
+		Where("polygon_tx_id = ?", polygonTxID).
+		Update("finality_checked_at", time.Now().UTC()).Error
+}
@@ -0,0 +1,111 @@
+package repositories
+
+import (
+	"BlockCertify/internal/models"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IndexerRepository interface {
+	GetCursor(name string) (uint64, bool, error)
+	SaveEvents(name string, events []models.DiplomaStoredEvent, block uint64) error
+	LinkDiplomas() (int64, error)
+	CountEvents(network string) (int64, error)
+	GetChainOnly(network string, limit int) ([]models.DiplomaStoredEvent, error)
+	GetDatabaseOnly(network string, fromBlock, toBlock uint64, limit int) ([]models.Diploma, error)
+}
+
+type indexerRepository struct {
+	db *gorm.DB
+}
+
+func NewIndexerRepository(db *gorm.DB) IndexerRepository {
+	return &indexerRepository{
+		db: db,
+	}
+}
+
+// GetCursor returns the last indexed block, and false when the indexer has
+// not run yet.
+func (r *indexerRepository) GetCursor(name string) (uint64, bool, error) {
+	var cursor models.IndexerCursor
+	err := r.db.Where("name = ?", name).First(&cursor).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return cursor.Block, true, nil
+}
+
+// SaveEvents stores the events of a block range and moves the cursor to its
+// last block in one transaction. Events already stored are skipped.
+func (r *indexerRepository) SaveEvents(name string, events []models.DiplomaStoredEvent, block uint64) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if len(events) > 0 {
+			err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&events).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "name"}},
+			DoUpdates: clause.AssignmentColumns([]string{"block", "updated_at"}),
+		}).Create(&models.IndexerCursor{Name: name, Block: block}).Error
+	})
+}
+
+// LinkDiplomas attaches indexed events to the diploma rows with the same hash
+// on the same network.
+func (r *indexerRepository) LinkDiplomas() (int64, error) {
+	result := r.db.Exec(`UPDATE diploma_stored_event SET diploma_id = diploma.id
+		FROM diploma
+		WHERE diploma_stored_event.diploma_id IS NULL AND diploma.hash = diploma_stored_event.diploma_hash
+			AND diploma.network = diploma_stored_event.network`)
+	return result.RowsAffected, result.Error
+}
+
+func (r *indexerRepository) CountEvents(network string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.DiplomaStoredEvent{}).Where("network = ?", network).Count(&count).Error
+	return count, err
+}
+
+// GetChainOnly returns events indexed on network that no diploma row refers to.
+func (r *indexerRepository) GetChainOnly(network string, limit int) ([]models.DiplomaStoredEvent, error) {
+	var events []models.DiplomaStoredEvent
+	err := r.db.
+		Where("network = ? AND diploma_id IS NULL", network).
+		Order("block_number ASC, log_index ASC").
+		Limit(limit).
+		Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// GetDatabaseOnly returns network's diplomas anchored with their own storeDiploma call
+// inside the indexed block range for which no event was indexed. Merkle
+// anchored diplomas never emit DiplomaStored and are left out. Diplomas
+// anchored before block numbers were recorded have block 0 and are always
+// returned, for the caller to place by their transaction.
+func (r *indexerRepository) GetDatabaseOnly(network string, fromBlock, toBlock uint64, limit int) ([]models.Diploma, error) {
+	var diplomas []models.Diploma
+	err := r.db.
+		Where("network = ? AND polygon_tx_id <> '' AND merkle_root = ''", network).
+		Where("(block_number BETWEEN ? AND ? OR block_number = 0)", fromBlock, toBlock).
+		Where(`NOT EXISTS (SELECT 1 FROM diploma_stored_event WHERE diploma_stored_event.diploma_hash = diploma.hash
+			AND diploma_stored_event.network = diploma.network)`).
+		Order("block_number ASC").
+		Limit(limit).
+		Find(&diplomas).Error
+	if err != nil {
+		return nil, err
+	}
+	return diplomas, nil
+}
@@ -0,0 +1,76 @@
+package repositories
+
+import (
+	"BlockCertify/internal/models"
+
+	"github.com/gofrs/uuid/v5"
+	"gorm.io/gorm"
+)
+
+type IssuanceRepository interface {
+	GetUnfinished() ([]models.DiplomaIssuance, error)
+	GetByDiplomaID(diplomaID uuid.UUID) (*models.DiplomaIssuance, error)
+	Update(issuance *models.DiplomaIssuance) error
+	Confirm(issuance *models.DiplomaIssuance, diploma *models.Diploma) error
+	ResetFailed(diplomaID uuid.UUID) (int64, error)
+}
+
+type issuanceRepository struct {
+	db *gorm.DB
+}
+
+func NewIssuanceRepository(db *gorm.DB) IssuanceRepository {
+	return &issuanceRepository{
+		db: db,
+	}
+}
+
+// GetUnfinished returns issuances that still need to be signed or confirmed,
+// oldest first.
+func (r *issuanceRepository) GetUnfinished() ([]models.DiplomaIssuance, error) {
+	var issuances []models.DiplomaIssuance
+	err := r.db.
+		Where("status IN ?", []models.IssuanceStatus{models.IssuancePending, models.IssuanceSubmitted}).
+		Order("id ASC").
+		Find(&issuances).Error
+	if err != nil {
+		return nil, err
+	}
+	return issuances, nil
+}
+
+func (r *issuanceRepository) GetByDiplomaID(diplomaID uuid.UUID) (*models.DiplomaIssuance, error) {
+	var issuance models.DiplomaIssuance
+	err := r.db.Where("diploma_id = ?", diplomaID).First(&issuance).Error
+	if err != nil {
+		return nil, err
+	}
+	return &issuance, nil
+}
+
+func (r *issuanceRepository) Update(issuance *models.DiplomaIssuance) error {
+	return r.db.Save(issuance).Error
+}
+
+// Confirm stores the receipt and copies the anchor onto the diploma in a
+// single transaction.
+func (r *issuanceRepository) Confirm(issuance *models.DiplomaIssuance, diploma *models.Diploma) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(issuance).Error; err != nil {
+			return err
+		}
+		return tx.Model(diploma).
+			Select("Network", "PolygonTxID", "PolygonURL", "BlockNumber", "BlockHash", "Finality", "Timestamp").
+			Updates(diploma).Error
+	})
+}
+
+func (r *issuanceRepository) ResetFailed(diplomaID uuid.UUID) (int64, error) {
+	result := r.db.Model(&models.DiplomaIssuance{}).
+		Where("diploma_id = ? AND status = ?", diplomaID, models.IssuanceFailed).
+		Updates(map[string]interface{}{
+			"status": models.IssuancePending,
+			"error":  "",
+		})
+	return result.RowsAffected, result.Error
+}
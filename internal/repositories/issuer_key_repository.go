@@ -0,0 +1,65 @@
+package repositories
+
+import (
+	"BlockCertify/internal/models"
+
+	"github.com/gofrs/uuid/v5"
+	"gorm.io/gorm"
+)
+
+type IssuerKeyRepository interface {
+	GetByUniversityID(universityID uuid.UUID) (*models.IssuerKey, error)
+	GetByDID(did string) (*models.IssuerKey, error)
+	Create(key *models.IssuerKey) error
+	// GetUnwrapped lists the keys whose seed is stored in plain
+	GetUnwrapped() ([]models.IssuerKey, error)
+	// SetWrapped replaces a plain seed with its wrapped form
+	SetWrapped(id uuid.UUID, wrappedSeed, masterKeyID string) error
+}
+
+type issuerKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewIssuerKeyRepository(db *gorm.DB) IssuerKeyRepository {
+	return &issuerKeyRepository{
+		db: db,
+	}
+}
+
+func (r *issuerKeyRepository) GetByUniversityID(universityID uuid.UUID) (*models.IssuerKey, error) {
+	var key models.IssuerKey
+	err := r.db.Where("university_id = ?", universityID).First(&key).Error
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *issuerKeyRepository) GetByDID(did string) (*models.IssuerKey, error) {
+	var key models.IssuerKey
+	err := r.db.Where("did = ?", did).First(&key).Error
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *issuerKeyRepository) Create(key *models.IssuerKey) error {
+	return r.db.Create(key).Error
+}
+
+func (r *issuerKeyRepository) GetUnwrapped() ([]models.IssuerKey, error) {
+	var keys []models.IssuerKey
+	err := r.db.Where("master_key_id IS NULL OR master_key_id = ''").Find(&keys).Error
+	return keys, err
+}
+
+func (r *issuerKeyRepository) SetWrapped(id uuid.UUID, wrappedSeed, masterKeyID string) error {
+	return r.db.Model(&models.IssuerKey{}).
+		Where("id = ? AND (master_key_id IS NULL OR master_key_id = '')", id).
+		Updates(map[string]interface{}{
+			"private_key":   wrappedSeed,
+			"master_key_id": masterKeyID,
+		}).Error
+}
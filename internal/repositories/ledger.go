@@ -0,0 +1,118 @@
+package repositories
+
+import (
+	"BlockCertify/internal/config"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Ledger is the diploma contract on one network. ContractRepository is the
+// EVM implementation; each configured network profile gets its own.
+type Ledger interface {
+	Network() config.NetworkProfile
+	ExplorerTxURL(txHash string) string
+	ContractAddress() common.Address
+	Close()
+
+	VerifyDiploma(diplomaHash string) (bool, string, error)
+	GetDiplomaRecord(diplomaHash string) (*DiplomaRecord, error)
+	GetRootTimestamp(root [32]byte) (*big.Int, error)
+
+	PackStoreDiploma(diplomaHash, arweaveTxID string) (*ContractCall, error)
+	PackRevokeDiploma(diplomaHash, reason string) (*ContractCall, error)
+	PackAnchorRoot(root [32]byte, leafCount int) (*ContractCall, error)
+
+	GetBalance(address common.Address) (*big.Int, error)
+	GetFeeData() (*big.Int, *big.Int, error)
+	EstimateGas(from common.Address, call *ContractCall) (uint64, error)
+	PendingNonceAt(address common.Address) (uint64, error)
+	NonceAt(address common.Address) (uint64, error)
+	SignTransaction(privateKeyHex string, nonce, gas uint64, gasTipCap, gasFeeCap *big.Int, call *ContractCall) (*types.Transaction, error)
+	SendTransaction(tx *types.Transaction) error
+
+	BlockNumber() (uint64, error)
+	GetTransactionReceipt(txHash common.Hash) (*types.Receipt, error)
+	ParseDiplomaStored(receipt *types.Receipt) (*DiplomaStoredEvent, error)
+	FilterDiplomaStored(fromBlock, toBlock uint64) ([]DiplomaStoredEvent, error)
+}
+
+var _ Ledger = (*ContractRepository)(nil)
+
+// Ledgers holds a Ledger for the active network, where new diplomas are
+// anchored, and for every legacy network older diplomas still point to.
+type Ledgers struct {
+	active  string
+	ledgers map[string]Ledger
+}
+
+func NewLedgers(cfg *config.Config) (*Ledgers, error) {
+	ledgers := &Ledgers{
+		active:  cfg.Blockchain.Network,
+		ledgers: make(map[string]Ledger),
+	}
+
+	for name, profile := range cfg.Blockchain.Networks {
+		ledger, err := NewContractRepository(profile, cfg.Blockchain.ContractABIDir)
+		if err != nil {
+			ledgers.Close()
+			return nil, err
+		}
+		ledgers.ledgers[name] = ledger
+	}
+
+	if _, ok := ledgers.ledgers[ledgers.active]; !ok {
+		ledgers.Close()
+		return nil, fmt.Errorf("network %q is not configured", ledgers.active)
+	}
+
+	return ledgers, nil
+}
+
+// LedgersOf wraps ledgers that are already open, keyed by their network
+// name; the first one is active.
+func LedgersOf(active Ledger, legacy ...Ledger) *Ledgers {
+	ledgers := &Ledgers{
+		active:  active.Network().Name,
+		ledgers: map[string]Ledger{active.Network().Name: active},
+	}
+	for _, ledger := range legacy {
+		ledgers.ledgers[ledger.Network().Name] = ledger
+	}
+	return ledgers
+}
+
+func (l *Ledgers) Active() Ledger {
+	return l.ledgers[l.active]
+}
+
+// Get returns the ledger of network, or the active one when network is empty.
+func (l *Ledgers) Get(network string) (Ledger, error) {
+	if network == "" {
+		return l.Active(), nil
+	}
+	ledger, ok := l.ledgers[network]
+	if !ok {
+		return nil, fmt.Errorf("network %q is not configured", network)
+	}
+	return ledger, nil
+}
+
+// All returns every ledger, the active one first.
+func (l *Ledgers) All() []Ledger {
+	all := []Ledger{l.Active()}
+	for name, ledger := range l.ledgers {
+		if name != l.active {
+			all = append(all, ledger)
+		}
+	}
+	return all
+}
+
+func (l *Ledgers) Close() {
+	for _, ledger := range l.ledgers {
+		ledger.Close()
+	}
+}
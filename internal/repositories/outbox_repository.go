@@ -0,0 +1,145 @@
+package repositories
+
+import (
+	"BlockCertify/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OutboxRepository interface {
+	Enqueue(txn *models.ChainTransaction, chainNonce uint64, sign func(txn *models.ChainTransaction) error) error
+	AddReplacement(replaced, replacement *models.ChainTransaction) error
+	AddRelease(rejected, release *models.ChainTransaction) error
+	GetPending() ([]models.ChainTransaction, error)
+	GetAttempts(network, signer string, nonce uint64) ([]models.ChainTransaction, error)
+	GetByReferences(references []string) ([]models.ChainTransaction, error)
+	Update(txn *models.ChainTransaction) error
+	Settle(attempts []models.ChainTransaction) error
+}
+
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &outboxRepository{
+		db: db,
+	}
+}
+
+// Enqueue allocates the signer's next nonce on the transaction's network, lets sign fill in the signed
+// transaction and stores it, all under a row lock on the signer's nonce. The
+// nonce is the larger of the locally tracked one and chainNonce, so
+// transactions sent with the same key from elsewhere are not reused.
+func (r *outboxRepository) Enqueue(txn *models.ChainTransaction, chainNonce uint64, sign func(txn *models.ChainTransaction) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.SignerNonce{Network: txn.Network, Address: txn.Signer, NextNonce: chainNonce}).Error
+		if err != nil {
+			return err
+		}
+
+		var next models.SignerNonce
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("network = ? AND address = ?", txn.Network, txn.Signer).
+			First(&next).Error
+		if err != nil {
+			return err
+		}
+
+		txn.Nonce = max(next.NextNonce, chainNonce)
+		if err := sign(txn); err != nil {
+			return err
+		}
+
+		if err := tx.Create(txn).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&next).Update("next_nonce", txn.Nonce+1).Error
+	})
+}
+
+// AddReplacement stores a fee-bumped copy of a transaction and marks the
+// original as replaced by it.
+func (r *outboxRepository) AddReplacement(replaced, replacement *models.ChainTransaction) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(replacement).Error; err != nil {
+			return err
+		}
+
+		replaced.Status = models.TxReplaced
+		replaced.ReplacedBy = &replacement.ID
+		return tx.Save(replaced).Error
+	})
+}
+
+// AddRelease stores the self-transfer that takes over the nonce of a
+// rejected transaction, along with the rejected transaction's final state.
+func (r *outboxRepository) AddRelease(rejected, release *models.ChainTransaction) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(release).Error; err != nil {
+			return err
+		}
+
+		rejected.ReplacedBy = &release.ID
+		return tx.Save(rejected).Error
+	})
+}
+
+// GetPending returns every transaction still waiting to be mined, oldest first.
+func (r *outboxRepository) GetPending() ([]models.ChainTransaction, error) {
+	var txns []models.ChainTransaction
+	err := r.db.
+		Where("status = ?", models.TxPending).
+		Order("network ASC, signer ASC, nonce ASC, created_at ASC").
+		Find(&txns).Error
+	if err != nil {
+		return nil, err
+	}
+	return txns, nil
+}
+
+// GetAttempts returns every transaction signed for the same network, signer
+// and nonce, i.e. the original and its fee-bumped replacements, oldest first.
+func (r *outboxRepository) GetAttempts(network, signer string, nonce uint64) ([]models.ChainTransaction, error) {
+	var txns []models.ChainTransaction
+	err := r.db.
+		Where("network = ? AND signer = ? AND nonce = ?", network, signer, nonce).
+		Order("created_at ASC").
+		Find(&txns).Error
+	if err != nil {
+		return nil, err
+	}
+	return txns, nil
+}
+
+func (r *outboxRepository) GetByReferences(references []string) ([]models.ChainTransaction, error) {
+	var txns []models.ChainTransaction
+	err := r.db.
+		Where("reference IN ?", references).
+		Order("created_at ASC").
+		Find(&txns).Error
+	if err != nil {
+		return nil, err
+	}
+	return txns, nil
+}
+
+func (r *outboxRepository) Update(txn *models.ChainTransaction) error {
+	return r.db.Save(txn).Error
+}
+
+// Settle saves the final state of every attempt for one nonce in a single
+// transaction.
+func (r *outboxRepository) Settle(attempts []models.ChainTransaction) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for i := range attempts {
+			if err := tx.Save(&attempts[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
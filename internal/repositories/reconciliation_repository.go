@@ -0,0 +1,123 @@
+package repositories
+
+import (
+	"BlockCertify/internal/models"
+	"fmt"
+
+	"github.com/gofrs/uuid/v5"
+	"gorm.io/gorm"
+)
+
+type ReconciliationRepository interface {
+	CreateJob(job *models.ReconciliationJob) error
+	UpdateJob(job *models.ReconciliationJob) error
+	GetJob(jobID, universityID uuid.UUID) (*models.ReconciliationJob, error)
+	ListJobs(universityID uuid.UUID) ([]models.ReconciliationJob, error)
+	FailRunningJobs(reason string) (int64, error)
+	AddMismatches(mismatches []models.ReconciliationMismatch) error
+	GetDiplomas(universityID uuid.UUID, afterID uuid.UUID, limit int) ([]models.Diploma, error)
+	GetStoredEvent(network, diplomaHash string) (*models.DiplomaStoredEvent, error)
+	RepairDiploma(diploma *models.Diploma, columns ...string) error
+}
+
+type reconciliationRepository struct {
+	db *gorm.DB
+}
+
+func NewReconciliationRepository(db *gorm.DB) ReconciliationRepository {
+	return &reconciliationRepository{
+		db: db,
+	}
+}
+
+func (r *reconciliationRepository) CreateJob(job *models.ReconciliationJob) error {
+	return r.db.Create(job).Error
+}
+
+func (r *reconciliationRepository) UpdateJob(job *models.ReconciliationJob) error {
+	return r.db.Omit("Items").Save(job).Error
+}
+
+// GetJob loads a job with its mismatches, scoped to the university.
+func (r *reconciliationRepository) GetJob(jobID, universityID uuid.UUID) (*models.ReconciliationJob, error) {
+	var job models.ReconciliationJob
+	err := r.db.
+		Preload("Items", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at ASC")
+		}).
+		Where("id = ? AND university_id = ?", jobID, universityID).
+		First(&job).Error
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *reconciliationRepository) ListJobs(universityID uuid.UUID) ([]models.ReconciliationJob, error) {
+	var jobs []models.ReconciliationJob
+	err := r.db.
+		Where("university_id = ?", universityID).
+		Order("created_at DESC").
+		Find(&jobs).Error
+	if err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// FailRunningJobs closes jobs that were interrupted by a restart.
+func (r *reconciliationRepository) FailRunningJobs(reason string) (int64, error) {
+	result := r.db.Model(&models.ReconciliationJob{}).
+		Where("status = ?", models.ReconciliationRunning).
+		Updates(map[string]interface{}{
+			"status": models.ReconciliationFailed,
+			"error":  reason,
+		})
+	return result.RowsAffected, result.Error
+}
+
+func (r *reconciliationRepository) AddMismatches(mismatches []models.ReconciliationMismatch) error {
+	if len(mismatches) == 0 {
+		return nil
+	}
+	return r.db.Create(&mismatches).Error
+}
+
+// GetDiplomas pages through a university's diplomas by primary key. UUIDv7
+// keys keep the walk in creation order. Diplomas not linked to any
+// university are included when their metadata names this one, with the same
+// rule database.TagUniversity applies, so they are audited too.
+func (r *reconciliationRepository) GetDiplomas(universityID uuid.UUID, afterID uuid.UUID, limit int) ([]models.Diploma, error) {
+	var diplomas []models.Diploma
+	err := r.db.
+		Where("id > ?", afterID).
+		Where(fmt.Sprintf(`(university_id = ? OR ((university_id IS NULL OR university_id = ?) AND id IN (
+			SELECT m.diploma_id FROM %[1]s m, %[2]s u
+			WHERE u.id = ? AND LOWER(TRIM(m.university)) = LOWER(TRIM(u.name))
+			  AND (SELECT COUNT(*) FROM %[2]s other WHERE LOWER(TRIM(other.name)) = LOWER(TRIM(u.name))) = 1)))`,
+			models.TableName, models.Universities{}.TableName()), universityID, uuid.Nil, universityID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&diplomas).Error
+	if err != nil {
+		return nil, err
+	}
+	return diplomas, nil
+}
+
+// GetStoredEvent returns the DiplomaStored event indexed on network for a hash.
+func (r *reconciliationRepository) GetStoredEvent(network, diplomaHash string) (*models.DiplomaStoredEvent, error) {
+	var event models.DiplomaStoredEvent
+	err := r.db.
+		Where("network = ? AND diploma_hash = ?", network, diplomaHash).
+		Order("block_number ASC").
+		First(&event).Error
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+func (r *reconciliationRepository) RepairDiploma(diploma *models.Diploma, columns ...string) error {
+	return r.db.Model(diploma).Select(columns).Updates(diploma).Error
+}
@@ -11,6 +11,9 @@ import (
 type UniversityRepository interface {
 	GetUniversityByID(id string) (models.Universities, error)
 	GetUniversitiesFromDBRecord() ([]dto.UniversitiesResponse, error)
+	Create(university *models.Universities) error
+	Update(university *models.Universities) error
+	Delete(id string) error
 }
 
 type universityRepository struct {
@@ -50,3 +53,15 @@ func (r *universityRepository) GetUniversitiesFromDBRecord() ([]dto.Universities
 
 	return response, nil
 }
+
+func (r *universityRepository) Create(university *models.Universities) error {
+	return r.db.Create(university).Error
+}
+
+func (r *universityRepository) Update(university *models.Universities) error {
+	return r.db.Save(university).Error
+}
+
+func (r *universityRepository) Delete(id string) error {
+	return r.db.Where("id = ?", id).Delete(&models.Universities{}).Error
+}
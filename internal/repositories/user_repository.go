@@ -2,7 +2,9 @@ package repositories
 
 import (
 	"BlockCertify/internal/models"
+	"time"
 
+	"github.com/gofrs/uuid/v5"
 	"gorm.io/gorm"
 )
 
@@ -11,6 +13,11 @@ type UserRepository interface {
 	Exists(email string) (bool, error)
 	Create(user *models.User) error
 	CreateAdmin(admin *models.Admin) error
+	FindAdminByUserID(userID uuid.UUID) (*models.Admin, error)
+	CreateInvite(invite *models.AdminInvite) error
+	FindInviteByTokenHash(tokenHash string) (*models.AdminInvite, error)
+	CreateInvited(inviteID uuid.UUID, usedAt time.Time, user *models.User, admin *models.Admin) (bool, error)
+	HasRole(role models.UserRole) (bool, error)
 	CreateTransaction() *gorm.DB
 }
 
@@ -56,6 +63,67 @@ func (r *userRepository) CreateAdmin(admin *models.Admin) error {
 	return r.db.Create(admin).Error
 }
 
+func (r *userRepository) FindAdminByUserID(userID uuid.UUID) (*models.Admin, error) {
+	var admin models.Admin
+	err := r.db.Where("user_id = ?", userID).First(&admin).Error
+	if err != nil {
+		return nil, err
+	}
+	return &admin, nil
+}
+
+func (r *userRepository) CreateInvite(invite *models.AdminInvite) error {
+	return r.db.Create(invite).Error
+}
+
+func (r *userRepository) FindInviteByTokenHash(tokenHash string) (*models.AdminInvite, error) {
+	var invite models.AdminInvite
+	err := r.db.Where("token_hash = ?", tokenHash).First(&invite).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+// CreateInvited marks the invite used and creates the user, and the admin
+// when one is given, in one transaction. It reports false and creates
+// nothing when another registration used the invite first.
+func (r *userRepository) CreateInvited(inviteID uuid.UUID, usedAt time.Time, user *models.User, admin *models.Admin) (bool, error) {
+	used := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.AdminInvite{}).
+			Where("id = ? AND used_at IS NULL", inviteID).
+			Update("used_at", usedAt)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return nil
+		}
+
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		if admin != nil {
+			if err := tx.Create(admin).Error; err != nil {
+				return err
+			}
+		}
+		used = true
+		return nil
+	})
+	return used, err
+}
+
+// HasRole reports whether any user has role.
+func (r *userRepository) HasRole(role models.UserRole) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.User{}).
+		Where("role = ?", role).
+		Count(&count).Error
+	return count > 0, err
+}
+
 func (r *userRepository) CreateTransaction() *gorm.DB {
 	return r.db.Begin()
 }
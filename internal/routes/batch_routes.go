@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"BlockCertify/internal/handlers"
+	"BlockCertify/internal/middleware"
+	"BlockCertify/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func BatchRoutes(diploma *gin.RouterGroup, b *handlers.BatchHandler, auth middleware.AuthMiddleware) {
+
+	batch := diploma.Group("/batch", auth.RequireRole(models.RoleAdmin))
+
+	batch.POST("", b.CreateBatch)
+	batch.GET("", b.ListBatches)
+	batch.GET("/:jobId", b.GetBatch)
+	batch.POST("/:jobId/retry", b.RetryBatch)
+
+}
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"BlockCertify/internal/handlers"
+	"BlockCertify/internal/middleware"
+	"BlockCertify/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func CredentialRoutes(diploma *gin.RouterGroup, h *handlers.CredentialHandler, auth middleware.AuthMiddleware) {
+
+	adminOrStudent := auth.RequireRole(models.RoleAdmin, models.RoleStudent)
+
+	diploma.GET("/records/:diplomaId/credential", adminOrStudent, h.GetCredential)
+
+}
+
+func PublicCredentialRoutes(public *gin.RouterGroup, h *handlers.CredentialHandler) {
+
+	public.POST("/credentials/verify", h.VerifyCredential)
+
+}
@@ -2,16 +2,23 @@ package routes
 
 import (
 	"BlockCertify/internal/handlers"
+	"BlockCertify/internal/middleware"
+	"BlockCertify/internal/models"
 
 	"github.com/gin-gonic/gin"
 )
 
-func DiplomaRoutes(diploma *gin.RouterGroup, d *handlers.DiplomaHandler) {
+func DiplomaRoutes(diploma *gin.RouterGroup, d *handlers.DiplomaHandler, auth middleware.AuthMiddleware) {
 
-	diploma.POST("/prepare", d.PrepareUpload)
-	diploma.POST("/confirm", d.ConfirmUpload)
-	diploma.POST("/verify", d.Verify)
-	diploma.GET("/records", d.GetDiplomaRecords)
-	diploma.GET("/records/:diplomaId", d.GetDiplomaById)
+	adminOnly := auth.RequireRole(models.RoleAdmin)
+	adminOrStudent := auth.RequireRole(models.RoleAdmin, models.RoleStudent)
+
+	diploma.POST("/prepare", adminOnly, d.PrepareUpload)
+	diploma.POST("/confirm", adminOnly, d.ConfirmUpload)
+	diploma.POST("/queue", adminOnly, d.QueueUpload)
+	diploma.POST("/verify", adminOnly, d.Verify)
+	diploma.GET("/records", adminOrStudent, d.GetDiplomaRecords)
+	diploma.GET("/records/:diplomaId", adminOrStudent, d.GetDiplomaRecordById)
+	diploma.POST("/records/:diplomaId/revoke", adminOnly, d.RevokeDiploma)
 
 }
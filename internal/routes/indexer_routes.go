@@ -0,0 +1,12 @@
+package routes
+
+import (
+	"BlockCertify/internal/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func IndexerRoutes(indexer *gin.RouterGroup, h *handlers.IndexerHandler) {
+	indexer.GET("/report", h.GetReport)
+	indexer.POST("/sync", h.Sync)
+}
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"BlockCertify/internal/handlers"
+	"BlockCertify/internal/middleware"
+	"BlockCertify/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func IssuanceRoutes(diploma *gin.RouterGroup, h *handlers.IssuanceHandler, auth middleware.AuthMiddleware) {
+
+	adminOnly := auth.RequireRole(models.RoleAdmin)
+
+	diploma.GET("/records/:diplomaId/issuance", adminOnly, h.GetIssuance)
+	diploma.POST("/records/:diplomaId/issuance/retry", adminOnly, h.RetryIssuance)
+	diploma.GET("/records/:diplomaId/anchoring", adminOnly, h.GetAnchoringStatus)
+
+}
@@ -11,6 +11,7 @@ func UserRoutes(api *gin.RouterGroup, h *handlers.UserHandler) {
 	{
 		user.POST("/login", h.Login)
 		user.POST("/register/admin", h.RegisterAdmin)
+		user.POST("/register/student", h.RegisterStudent)
 		user.POST("/logout", h.Logout)
 	}
 }
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"BlockCertify/internal/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func PublicRoutes(public *gin.RouterGroup, d *handlers.DiplomaHandler) {
+
+	public.POST("/verify", d.PublicVerify)
+	public.POST("/verify/file", d.VerifyFile)
+	public.GET("/diplomas/:diplomaId", d.GetPublicDiploma)
+	public.GET("/diplomas/:diplomaId/pdf", d.GetDiplomaById)
+
+}
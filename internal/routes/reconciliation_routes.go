@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"BlockCertify/internal/handlers"
+	"BlockCertify/internal/middleware"
+	"BlockCertify/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ReconciliationRoutes(diploma *gin.RouterGroup, h *handlers.ReconciliationHandler, auth middleware.AuthMiddleware) {
+
+	reconciliation := diploma.Group("/reconciliation", auth.RequireRole(models.RoleAdmin))
+
+	reconciliation.POST("", h.StartReconciliation)
+	reconciliation.GET("", h.ListReconciliations)
+	reconciliation.GET("/:jobId", h.GetReconciliation)
+	reconciliation.GET("/:jobId/report", h.DownloadReport)
+
+}
@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"BlockCertify/internal/handlers"
+	"BlockCertify/internal/middleware"
+	"BlockCertify/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func UniversityAdminRoutes(universities *gin.RouterGroup, h *handlers.UniversityHandler) {
+	universities.POST("", h.CreateUniversity)
+	universities.PUT("/:universityId", h.UpdateUniversity)
+	universities.DELETE("/:universityId", h.DeleteUniversity)
+}
+
+func AdminInviteRoutes(universities *gin.RouterGroup, h *handlers.UserHandler) {
+	universities.POST("/:universityId/invites", h.CreateAdminInvite)
+}
+
+// StudentInviteRoutes lets university admins invite the graduates named on
+// their diplomas.
+func StudentInviteRoutes(diploma *gin.RouterGroup, h *handlers.UserHandler, auth middleware.AuthMiddleware) {
+	diploma.POST("/records/:diplomaId/student-invite", auth.RequireRole(models.RoleAdmin), h.CreateStudentInvite)
+}
@@ -8,4 +8,5 @@ import (
 
 func WalletRoutes(api *gin.RouterGroup, h *handlers.WalletHandler) {
 	api.POST("/upload-key-file", h.GetArweaveKeyFileJSON)
+	api.GET("/status", h.GetWalletStatus)
 }
@@ -0,0 +1,225 @@
+package services
+
+import (
+	"BlockCertify/internal/config"
+	"BlockCertify/internal/models"
+	"BlockCertify/internal/pkg/merkle"
+	"BlockCertify/internal/repositories"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// AnchorService anchors queued diplomas on-chain as Merkle roots, one
+// anchorRoot transaction per batch of up to batchSize diplomas.
+type AnchorService interface {
+	Start()
+	AnchorPending() (int, error)
+}
+
+type anchorService struct {
+	Blockchain BlockchainService
+	repo       repositories.AnchorRepository
+	batchSize  int
+	interval   time.Duration
+	mu         sync.Mutex
+}
+
+func NewAnchorService(cfg *config.Config, blockchain BlockchainService, repo repositories.AnchorRepository) AnchorService {
+	return &anchorService{
+		Blockchain: blockchain,
+		repo:       repo,
+		batchSize:  cfg.Blockchain.MerkleBatchSize,
+		interval:   cfg.Blockchain.MerkleAnchorInterval,
+	}
+}
+
+// Start runs AnchorPending on every tick. It does nothing unless Merkle
+// anchoring is enabled.
+func (s *anchorService) Start() {
+
+	if !s.Blockchain.MerkleAnchoringEnabled() {
+		return
+	}
+
+	slog.Info("Merkle anchoring enabled", "batchSize", s.batchSize, "interval", s.interval)
+
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if _, err := s.AnchorPending(); err != nil {
+				slog.Error("Merkle anchoring failed", "err", err)
+			}
+		}
+	}()
+}
+
+// AnchorPending anchors every queued diploma and returns how many were
+// anchored. Anchors left pending by an earlier run go first, with the
+// diplomas they were built from.
+func (s *anchorService) AnchorPending() (int, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	anchored := 0
+
+	anchors, err := s.repo.GetPendingAnchors()
+	if err != nil {
+		return anchored, fmt.Errorf("failed to load pending anchors: %w", err)
+	}
+	for i := range anchors {
+		diplomas, err := s.repo.GetAnchorDiplomas(anchors[i].ID)
+		if err != nil {
+			return anchored, fmt.Errorf("failed to load diplomas of anchor %s: %w", anchors[i].Root, err)
+		}
+
+		err = s.anchorBatch(&anchors[i], diplomas)
+		if errors.Is(err, ErrTransactionPending) {
+			// The outbox keeps tracking it; the next run waits for it again
+			return anchored, nil
+		}
+		if err != nil {
+			return anchored, err
+		}
+		anchored += len(diplomas)
+	}
+
+	for {
+		diplomas, err := s.repo.GetPendingDiplomas(s.batchSize)
+		if err != nil {
+			return anchored, fmt.Errorf("failed to load pending diplomas: %w", err)
+		}
+		if len(diplomas) == 0 {
+			return anchored, nil
+		}
+
+		anchor, err := s.reserve(diplomas)
+		if err != nil {
+			return anchored, err
+		}
+
+		err = s.anchorBatch(anchor, diplomas)
+		if errors.Is(err, ErrTransactionPending) {
+			return anchored, nil
+		}
+		if err != nil {
+			return anchored, err
+		}
+		anchored += len(diplomas)
+
+		if len(diplomas) < s.batchSize {
+			return anchored, nil
+		}
+	}
+}
+
+// reserve saves a pending anchor for a batch before its root is sent, so the
+// batch cannot change if the transaction outlives this run.
+func (s *anchorService) reserve(diplomas []models.Diploma) (*models.MerkleAnchor, error) {
+
+	tree, err := merkleTree(diplomas)
+	if err != nil {
+		return nil, err
+	}
+
+	anchor := &models.MerkleAnchor{
+		ID:        uuid.Must(uuid.NewV7()),
+		Root:      tree.Root().Hex(),
+		Status:    models.MerkleAnchorPending,
+		LeafCount: len(diplomas),
+		Network:   s.Blockchain.Network().Name,
+	}
+	if err := s.repo.CreateAnchor(anchor, diplomas); err != nil {
+		return nil, fmt.Errorf("failed to save pending Merkle anchor: %w", err)
+	}
+
+	return anchor, nil
+}
+
+func (s *anchorService) anchorBatch(anchor *models.MerkleAnchor, diplomas []models.Diploma) error {
+
+	tree, err := merkleTree(diplomas)
+	if err != nil {
+		return err
+	}
+	root := tree.Root()
+	if root.Hex() != anchor.Root || len(diplomas) != anchor.LeafCount {
+		return fmt.Errorf("diplomas of Merkle anchor %s no longer hash to its root %s", anchor.ID, anchor.Root)
+	}
+
+	slog.Info("Anchoring Merkle root", "root", anchor.Root, "leaves", anchor.LeafCount)
+
+	result, err := s.Blockchain.AnchorMerkleRoot(root, anchor.LeafCount)
+	if err != nil {
+		return err
+	}
+	if result.Pending {
+		if result.TransactionHash != anchor.PolygonTxID {
+			anchor.Network = result.Network
+			anchor.PolygonTxID = result.TransactionHash
+			anchor.PolygonURL = result.ExplorerURL
+			if err := s.repo.UpdateAnchor(anchor); err != nil {
+				slog.Error("Failed to update pending Merkle anchor", "root", anchor.Root, "polygonTxHash", result.TransactionHash, "err", err)
+			}
+		}
+		return ErrTransactionPending
+	}
+
+	anchor.Status = models.MerkleAnchorAnchored
+	anchor.Network = result.Network
+	anchor.PolygonTxID = result.TransactionHash
+	anchor.PolygonURL = result.ExplorerURL
+	anchor.BlockNumber = result.BlockNumber
+	anchor.BlockHash = result.BlockHash
+	anchor.AnchoredAt = result.Timestamp
+
+	for i := range diplomas {
+		proof, err := tree.Proof(i)
+		if err != nil {
+			return err
+		}
+
+		encoded := make([]string, len(proof))
+		for j, h := range proof {
+			encoded[j] = h.Hex()
+		}
+
+		d := &diplomas[i]
+		d.MerkleAnchorID = &anchor.ID
+		d.MerkleRoot = anchor.Root
+		d.MerkleProof = encoded
+		d.MerkleLeafIndex = i
+		d.Network = anchor.Network
+		d.PolygonTxID = anchor.PolygonTxID
+		d.PolygonURL = anchor.PolygonURL
+		d.BlockNumber = anchor.BlockNumber
+		d.BlockHash = anchor.BlockHash
+		d.Finality = models.FinalityConfirming
+		d.Timestamp = anchor.AnchoredAt
+	}
+
+	if err := s.repo.SaveAnchor(anchor, diplomas); err != nil {
+		// The root is on-chain but the proofs were not saved; the anchor
+		// stays pending and the next run saves them
+		slog.Error("Failed to save Merkle anchor", "root", anchor.Root, "polygonTxHash", anchor.PolygonTxID, "err", err)
+		return fmt.Errorf("failed to save Merkle anchor: %w", err)
+	}
+
+	return nil
+}
+
+// merkleTree builds the tree of a batch; leaf i is diplomas[i].
+func merkleTree(diplomas []models.Diploma) (*merkle.Tree, error) {
+	leaves := make([]merkle.Hash, len(diplomas))
+	for i, d := range diplomas {
+		leaves[i] = merkle.Leaf(d.Hash, d.ArweaveTxID)
+	}
+	return merkle.New(leaves)
+}
@@ -2,58 +2,172 @@ package services
 
 import (
 	"BlockCertify/internal/config"
+	"BlockCertify/internal/models"
 	apperrors "BlockCertify/internal/pkg/errors"
+	"BlockCertify/internal/repositories"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"log/slog"
 	"math/big"
+	"net/http"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/everFinance/goar"
 	"github.com/everFinance/goar/types"
+	"github.com/everFinance/goar/utils"
+	"github.com/gofrs/uuid/v5"
 )
 
+// ArweaveService is the Arweave DocumentStore. A transaction is only
+// permanent once it is mined and its data chunks are seeded, so uploads are
+// recorded with their signed header for ArweaveTracker to follow, and their
+// data is kept in ARWEAVE_PAYLOAD_DIR until then.
+//
+// With ARWEAVE_BUNDLING set, PDFs are stored as ANS-104 data items instead:
+// UploadBatch packs a whole batch into one bundle transaction, or every
+// upload is posted to a bundler that bundles it with others. A data item ID
+// is read like a TxID through the gateway.
 type ArweaveService interface {
-	Upload(filePath, fileHash string) (string, error)
+	DocumentStore
+	TransactionStatus(txID string) (ArweaveTxStatus, error)
+	HasData(txID string) (bool, error)
+	// Repost posts a signed transaction recorded by Upload and its data
+	// chunks again, reading the data back from the payload directory. It
+	// keeps its ID, so references to it stay valid. A mined transaction
+	// only needs its chunks.
+	Repost(signedTx []byte, mined bool) error
+	// Reupload posts the data of a recorded upload as a new transaction and
+	// returns its ID
+	Reupload(signedTx []byte) (string, error)
+	// AnchorAge returns how many blocks were mined since the block a signed
+	// transaction is anchored to
+	AnchorAge(signedTx []byte) (int, error)
+	// ReleasePayload drops the kept data of a permanent or replaced upload
+	ReleasePayload(txID string)
+	// BundleOf returns the bundle transaction the gateway found a data item
+	// in, or "" while it has none
+	BundleOf(itemID string) (string, error)
+	// WalletBalance returns the upload wallet's address and its balance in
+	// winston
+	WalletBalance() (string, *big.Int, error)
+	// Price is what storing size bytes costs now, in winston
+	Price(size int) (*big.Int, error)
+}
+
+// ArweaveTxStatus is what the gateway knows about a transaction.
+type ArweaveTxStatus struct {
+	Known         bool // false once the gateway has neither mined nor pending trace of it
+	BlockHeight   int
+	Confirmations int // 0 while pending
 }
 
 type arweaveService struct {
-	client *goar.Client
-	wallet *goar.Wallet
+	gateway string
+	client  *goar.Client
+	wallet  *goar.Wallet // nil when no key is configured; the store is read-only then
+	uploads repositories.ArweaveRepository
+	http    *http.Client
+	// payloadDir keeps the data of uploads until they are permanent
+	payloadDir string
+
+	bundling   string
+	bundlerURL string
+	bundleSize int
 }
 
-func NewArweaveService(cfg *config.Config) ArweaveService {
+func NewArweaveService(cfg *config.Config, uploads repositories.ArweaveRepository) ArweaveService {
 
-	client := goar.NewClient("https://arweave.net")
+	gateway := cfg.Arweave.GatewayURL()
+	client := goar.NewClient(gateway)
 
-	wallet, err := goar.NewWalletFromPath("./arweave_keyfile.json", "https://arweave.net")
-	if err != nil {
-		slog.Error("Failed to create Arweave wallet", "err", err)
+	var wallet *goar.Wallet
+	if cfg.Arweave.WalletKey != "" {
+		var err error
+		wallet, err = loadArweaveWallet(cfg.Arweave.WalletKey, gateway)
+		if err != nil {
+			slog.Error("Failed to create Arweave wallet", "err", err)
+		}
 	}
 
 	return &arweaveService{
-		client: client,
-		wallet: wallet,
+		gateway: gateway,
+		client:  client,
+		wallet:  wallet,
+		uploads: uploads,
+		http:    &http.Client{Timeout: storeRequestTimeout},
+
+		payloadDir: cfg.Arweave.PayloadDir,
+
+		bundling:   cfg.Arweave.Bundling,
+		bundlerURL: cfg.Arweave.BundlerURL,
+		bundleSize: cfg.Arweave.BundleSize,
 	}
 
 }
 
+// loadArweaveWallet reads ARWEAVE_KEY as the keyfile JSON itself or, when it
+// is not JSON, as the path of the keyfile.
+func loadArweaveWallet(key, gateway string) (*goar.Wallet, error) {
+	if strings.HasPrefix(strings.TrimSpace(key), "{") {
+		return goar.NewWallet([]byte(key), gateway)
+	}
+	return goar.NewWalletFromPath(key, gateway)
+}
+
+func (s *arweaveService) Name() string {
+	return config.StorageArweave
+}
+
+func (s *arweaveService) URL(txID string) string {
+	return fmt.Sprintf("%s/%s", s.gateway, txID)
+}
+
 func (s *arweaveService) Upload(filePath, fileHash string) (string, error) {
 
+	if s.wallet == nil {
+		return "", apperrors.New(apperrors.ErrArweaveUploadFailed, "No Arweave wallet configured", errors.New("ARWEAVE_KEY is not a valid keyfile"))
+	}
+
 	// Read file
 	data, err := os.ReadFile(filePath)
 	if err != nil {
 		return "", apperrors.New(apperrors.ErrArweaveUploadFailed, "Failed to read file", err)
 	}
 
+	// A bundle of one saves nothing, but a bundler still spares the
+	// transaction fee
+	if s.bundling == config.ArweaveBundlingBundler {
+		return s.postToBundler(data, fileHash)
+	}
+
 	// Check balance
 	if err := s.checkBalanceForData(data); err != nil {
-		return "", apperrors.New(apperrors.ErrArweaveUploadFailed, "Insufficient Arweave balance for transaction", err)
+		return "", err
 	}
 
-	//Add tags
-	tags := []types.Tag{
+	// Create transaction
+	tx, err := s.wallet.SendData(data, documentTags(fileHash))
+	if err != nil {
+		return "", apperrors.New(apperrors.ErrArweaveUploadFailed, "Failed to upload to Arweave", err)
+	}
+
+	log.Printf("Arweave TxID: %s", tx.ID)
+	s.recordUpload(&tx, data)
+	return tx.ID, nil
+}
+
+// documentTags are the tags of a diploma PDF, as a transaction or data item.
+func documentTags(fileHash string) []types.Tag {
+	return []types.Tag{
 		{
 			Name:  "Content-Type",
 			Value: "application/pdf",
@@ -71,37 +185,478 @@ func (s *arweaveService) Upload(filePath, fileHash string) (string, error) {
 			Value: strconv.FormatInt(time.Now().UnixMilli(), 10),
 		},
 	}
+}
+
+// BatchSize is how many PDFs go into one bundle; 0 with bundling off.
+func (s *arweaveService) BatchSize() int {
+	if s.bundling == config.ArweaveBundlingOff {
+		return 0
+	}
+	return s.bundleSize
+}
+
+// UploadBatch stores the PDFs as ANS-104 data items and returns their IDs in
+// order: all in one bundle transaction, or each posted to the bundler.
+func (s *arweaveService) UploadBatch(files []DocumentFile) ([]string, error) {
+
+	if s.wallet == nil {
+		return nil, apperrors.New(apperrors.ErrArweaveUploadFailed, "No Arweave wallet configured", errors.New("ARWEAVE_KEY is not a valid keyfile"))
+	}
+
+	signer, err := goar.NewItemSigner(s.wallet.Signer)
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrArweaveUploadFailed, "Failed to create data item signer", err)
+	}
+
+	ids := make([]string, 0, len(files))
+	items := make([]types.BundleItem, 0, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file.FilePath)
+		if err != nil {
+			return nil, apperrors.New(apperrors.ErrArweaveUploadFailed, "Failed to read file", err)
+		}
+
+		if s.bundling == config.ArweaveBundlingBundler {
+			id, err := s.postToBundler(data, file.FileHash)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, id)
+			continue
+		}
+
+		item, err := signer.CreateAndSignItem(data, "", "", documentTags(file.FileHash))
+		if err != nil {
+			return nil, apperrors.New(apperrors.ErrArweaveUploadFailed, "Failed to sign data item", err)
+		}
+		ids = append(ids, item.Id)
+		items = append(items, item)
+	}
+
+	if s.bundling == config.ArweaveBundlingBundler {
+		return ids, nil
+	}
+
+	bundle, err := utils.NewBundle(items...)
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrArweaveUploadFailed, "Failed to build bundle", err)
+	}
+
+	if err := s.checkBalanceForData(bundle.BundleBinary); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.wallet.SendBundleTx(context.Background(), 1, bundle.BundleBinary, []types.Tag{{Name: "App-Name", Value: "DiplomaVerification"}})
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrArweaveUploadFailed, "Failed to upload bundle to Arweave", err)
+	}
+
+	slog.Info("Uploaded diploma bundle to Arweave", "bundleTxID", tx.ID, "items", len(ids))
+	s.recordUpload(&tx, bundle.BundleBinary)
+	s.recordDataItems(ids, tx.ID, "")
+	return ids, nil
+}
+
+// postToBundler signs the PDF as a data item and hands it to the bundler,
+// which pays for and posts the bundle transaction.
+func (s *arweaveService) postToBundler(data []byte, fileHash string) (string, error) {
+
+	signer, err := goar.NewItemSigner(s.wallet.Signer)
+	if err != nil {
+		return "", apperrors.New(apperrors.ErrArweaveUploadFailed, "Failed to create data item signer", err)
+	}
+
+	item, err := signer.CreateAndSignItem(data, "", "", documentTags(fileHash))
+	if err != nil {
+		return "", apperrors.New(apperrors.ErrArweaveUploadFailed, "Failed to sign data item", err)
+	}
+
+	resp, err := s.http.Post(s.bundlerURL+"/tx", "application/octet-stream", bytes.NewReader(item.ItemBinary))
+	if err != nil {
+		return "", apperrors.New(apperrors.ErrArweaveUploadFailed, "Failed to post to the Arweave bundler", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", apperrors.New(apperrors.ErrArweaveUploadFailed, "Failed to post to the Arweave bundler",
+			fmt.Errorf("bundler returned %s: %s", resp.Status, bytes.TrimSpace(body)))
+	}
+
+	slog.Info("Posted diploma to Arweave bundler", "dataItemID", item.Id)
+	s.recordDataItems([]string{item.Id}, "", s.bundlerURL)
+	return item.Id, nil
+}
+
+func (s *arweaveService) recordDataItems(ids []string, bundleID, bundler string) {
+
+	if s.uploads == nil {
+		return
+	}
+
+	items := make([]models.ArweaveDataItem, 0, len(ids))
+	for _, id := range ids {
+		items = append(items, models.ArweaveDataItem{
+			ID:       uuid.Must(uuid.NewV7()),
+			ItemID:   id,
+			BundleID: bundleID,
+			Bundler:  bundler,
+		})
+	}
+	if err := s.uploads.CreateDataItems(items); err != nil {
+		slog.Error("Failed to record Arweave data items", "bundleTxID", bundleID, "err", err)
+	}
+}
+
+// recordUpload keeps the signed header for the tracker and the data in the
+// payload directory. The upload has already succeeded, so a failure here is
+// only logged; the diploma is still tracked, it just cannot be re-posted.
+func (s *arweaveService) recordUpload(tx *types.Transaction, data []byte) {
+
+	if s.uploads == nil {
+		return
+	}
+
+	header := *tx
+	header.Data = ""
+	header.DataReader = nil
+	header.Chunks = nil
+
+	err := os.MkdirAll(s.payloadDir, 0755)
+	if err == nil {
+		err = os.WriteFile(s.payloadPath(tx.ID), data, 0644)
+	}
+	if err != nil {
+		slog.Error("Failed to keep Arweave upload data", "txID", tx.ID, "err", err)
+	}
+
+	signedTx, err := json.Marshal(&header)
+	if err == nil {
+		err = s.uploads.CreateUpload(&models.ArweaveUpload{
+			ID:       uuid.Must(uuid.NewV7()),
+			TxID:     tx.ID,
+			Status:   models.ArweaveStatusPending,
+			SignedTx: signedTx,
+			PostedAt: time.Now(),
+		})
+	}
+	if err != nil {
+		slog.Error("Failed to record Arweave upload", "txID", tx.ID, "err", err)
+	}
+}
+
+func (s *arweaveService) TransactionStatus(txID string) (ArweaveTxStatus, error) {
+
+	status, err := s.client.GetTransactionStatus(txID)
+	switch {
+	case errors.Is(err, goar.ErrPendingTx):
+		return ArweaveTxStatus{Known: true}, nil
+	case errors.Is(err, goar.ErrNotFound):
+		return ArweaveTxStatus{}, nil
+	case err != nil:
+		return ArweaveTxStatus{}, fmt.Errorf("failed to fetch Arweave transaction status: %w", err)
+	}
+
+	return ArweaveTxStatus{
+		Known:         true,
+		BlockHeight:   status.BlockHeight,
+		Confirmations: status.NumberOfConfirmations,
+	}, nil
+}
+
+// HasData reports whether the gateway can serve the transaction's data. A
+// mined transaction whose chunks were never seeded has none.
+func (s *arweaveService) HasData(txID string) (bool, error) {
+
+	data, err := s.client.GetTransactionData(txID)
+	if errors.Is(err, goar.ErrNotFound) || errors.Is(err, goar.ErrPendingTx) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch Arweave transaction data: %w", err)
+	}
+	return len(data) > 0, nil
+}
+
+func (s *arweaveService) Repost(signedTx []byte, mined bool) error {
+
+	tx, data, err := s.signedPayload(signedTx)
+	if err != nil {
+		return err
+	}
+	if err := utils.PrepareChunks(tx, data, len(data)); err != nil {
+		return fmt.Errorf("failed to chunk Arweave transaction data: %w", err)
+	}
+
+	tx.Data = utils.Base64Encode(data)
+	uploader, err := goar.CreateUploader(s.client, tx, nil)
+	if err != nil {
+		return err
+	}
+	// The header of a mined transaction is on the weave already; posting it
+	// again is refused once its anchor is too old
+	uploader.TxPosted = mined
+	return uploader.Once()
+}
+
+func (s *arweaveService) Reupload(signedTx []byte) (string, error) {
+
+	if s.wallet == nil {
+		return "", apperrors.New(apperrors.ErrArweaveUploadFailed, "No Arweave wallet configured", errors.New("ARWEAVE_KEY is not a valid keyfile"))
+	}
+
+	original, data, err := s.signedPayload(signedTx)
+	if err != nil {
+		return "", err
+	}
+	tags, err := utils.TagsDecode(original.Tags)
+	if err != nil {
+		return "", fmt.Errorf("invalid signed Arweave transaction tags: %w", err)
+	}
+
+	if err := s.checkBalanceForData(data); err != nil {
+		return "", err
+	}
 
-	// Create transaction
 	tx, err := s.wallet.SendData(data, tags)
 	if err != nil {
 		return "", apperrors.New(apperrors.ErrArweaveUploadFailed, "Failed to upload to Arweave", err)
 	}
 
-	log.Printf("Arweave TxID: %s", tx.ID)
+	slog.Info("Uploaded Arweave data again", "txID", original.ID, "newTxID", tx.ID)
+	s.recordUpload(&tx, data)
 	return tx.ID, nil
 }
 
-func (s *arweaveService) checkBalanceForData(data []byte) error {
-	balance, err := s.client.GetWalletBalance(s.wallet.Signer.Address)
+func (s *arweaveService) AnchorAge(signedTx []byte) (int, error) {
+
+	var tx types.Transaction
+	if err := json.Unmarshal(signedTx, &tx); err != nil {
+		return 0, fmt.Errorf("invalid signed Arweave transaction: %w", err)
+	}
+
+	anchor, err := s.client.GetBlockByID(tx.LastTx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch Arweave anchor block: %w", err)
+	}
+	info, err := s.client.GetInfo()
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch Arweave network info: %w", err)
+	}
+	return int(info.Height - anchor.Height), nil
+}
+
+// signedPayload decodes a header recorded by recordUpload and reads its data
+// back, checked against the data root the header was signed with. Uploads
+// recorded before the data was kept apart carry it in the header.
+func (s *arweaveService) signedPayload(signedTx []byte) (*types.Transaction, []byte, error) {
+
+	var tx types.Transaction
+	if err := json.Unmarshal(signedTx, &tx); err != nil {
+		return nil, nil, fmt.Errorf("invalid signed Arweave transaction: %w", err)
+	}
+
+	var data []byte
+	var err error
+	if tx.Data != "" {
+		data, err = utils.Base64Decode(tx.Data)
+	} else {
+		data, err = os.ReadFile(s.payloadPath(tx.ID))
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read Arweave transaction data: %w", err)
+	}
+
+	check := types.Transaction{}
+	if err := utils.PrepareChunks(&check, data, len(data)); err != nil {
+		return nil, nil, fmt.Errorf("failed to chunk Arweave transaction data: %w", err)
+	}
+	if check.DataRoot != tx.DataRoot {
+		return nil, nil, fmt.Errorf("kept data of Arweave transaction %s does not match its data root", tx.ID)
+	}
+
+	return &tx, data, nil
+}
+
+func (s *arweaveService) ReleasePayload(txID string) {
+	if err := os.Remove(s.payloadPath(txID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Error("Failed to remove Arweave upload data", "txID", txID, "err", err)
+	}
+}
+
+func (s *arweaveService) payloadPath(txID string) string {
+	return filepath.Join(s.payloadDir, txID)
+}
+
+// current returns the transaction carrying the data of txID now: its
+// replacement when it was uploaded again, else txID itself.
+func (s *arweaveService) current(txID string) string {
+
+	if s.uploads == nil {
+		return txID
+	}
+	upload, err := s.uploads.GetUpload(txID)
+	if err != nil || upload.ReplacedBy == "" {
+		return txID
+	}
+	return upload.ReplacedBy
+}
+
+// GetFileHashTag returns the File-Hash tag written by Upload.
+func (s *arweaveService) GetFileHashTag(txID string) (string, error) {
+
+	txID = s.current(txID)
+
+	tags, err := s.client.GetTransactionTags(txID)
+	if errors.Is(err, goar.ErrNotFound) {
+		// Data items are not transactions of their own; only the gateway's
+		// index knows them
+		var item *gatewayItem
+		item, err = s.lookupDataItem(txID)
+		if err == nil {
+			tags = item.Tags
+		}
+	}
+	if err != nil {
+		return "", apperrors.New(apperrors.ErrVerificationFailed, "Failed to fetch Arweave transaction tags", err)
+	}
+
+	for _, tag := range tags {
+		if tag.Name == "File-Hash" {
+			return tag.Value, nil
+		}
+	}
+
+	return "", apperrors.New(apperrors.ErrVerificationFailed, "Arweave transaction has no File-Hash tag", nil)
+}
+
+func (s *arweaveService) GetData(txID string) ([]byte, error) {
+
+	txID = s.current(txID)
+
+	data, err := s.client.GetTransactionData(txID)
+	if errors.Is(err, goar.ErrNotFound) {
+		data, err = s.gatewayData(txID)
+	}
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrVerificationFailed, "Failed to fetch Arweave transaction data", err)
+	}
+
+	return data, nil
+}
+
+// gatewayData fetches data the way a browser does, which also resolves
+// data items inside bundles.
+func (s *arweaveService) gatewayData(id string) ([]byte, error) {
+
+	resp, err := s.http.Get(s.URL(id))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("gateway returned %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// gatewayItem is a transaction or data item as the gateway's GraphQL index
+// describes it.
+type gatewayItem struct {
+	Tags      []types.Tag `json:"tags"`
+	BundledIn *struct {
+		ID string `json:"id"`
+	} `json:"bundledIn"`
+}
+
+func (s *arweaveService) lookupDataItem(id string) (*gatewayItem, error) {
+
+	body, err := s.client.GraphQL(fmt.Sprintf(`{ transaction(id: %q) { tags { name value } bundledIn { id } } }`, id))
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Transaction *gatewayItem `json:"transaction"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+	if result.Transaction == nil {
+		return nil, goar.ErrNotFound
+	}
+	return result.Transaction, nil
+}
+
+func (s *arweaveService) BundleOf(itemID string) (string, error) {
+
+	item, err := s.lookupDataItem(itemID)
+	if errors.Is(err, goar.ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to look up Arweave data item: %w", err)
+	}
+	if item.BundledIn == nil {
+		return "", nil
+	}
+	return item.BundledIn.ID, nil
+}
+
+func (s *arweaveService) WalletBalance() (string, *big.Int, error) {
+
+	if s.wallet == nil {
+		return "", nil, errors.New("no Arweave wallet configured")
+	}
+
+	address := s.wallet.Signer.Address
+	balance, err := s.client.GetWalletBalance(address)
 	if err != nil {
-		slog.Error("Failed to get wallet balance", "err", err)
+		return address, nil, err
 	}
+	if balance == nil {
+		return address, nil, errors.New("gateway returned no balance")
+	}
+	return address, utils.ARToWinston(balance), nil
+}
 
-	//Estimate transaction cost based on data size(WINSTON)
-	priceWinston, err := s.client.GetTransactionPrice(len(data), nil)
+// Price is what the gateway currently charges for storing size bytes, in
+// winston.
+func (s *arweaveService) Price(size int) (*big.Int, error) {
+	price, err := s.client.GetTransactionPrice(size, nil)
 	if err != nil {
-		slog.Error("Failed to get transaction price", "err", err)
+		return nil, err
 	}
+	return big.NewInt(price), nil
+}
 
-	//Convert winston -> AR
-	priceAR := new(big.Float).SetInt64(priceWinston).Quo(new(big.Float).SetInt64(priceWinston), big.NewFloat(1e12))
+// checkBalanceForData refuses an upload the wallet cannot pay for before
+// anything is signed or posted.
+func (s *arweaveService) checkBalanceForData(data []byte) error {
 
-	log.Printf("Arweave Balance: %s AR, estimated tx cost: %s AR", balance.Text('f', 12), priceAR.Text('f', 12))
+	_, balance, err := s.WalletBalance()
+	if err != nil {
+		return apperrors.New(apperrors.ErrArweaveUploadFailed, "Failed to get Arweave wallet balance", err)
+	}
 
-	if balance.Cmp(priceAR) < 0 {
-		slog.Error("Insufficient Arweave balance: required %s AR, available %s AR", priceAR.Text('f', 12), priceAR.Text('s', 12))
+	price, err := s.Price(len(data))
+	if err != nil {
+		return apperrors.New(apperrors.ErrArweaveUploadFailed, "Failed to get Arweave transaction price", err)
 	}
 
+	slog.Info("Arweave upload cost", "balance", formatAR(balance), "price", formatAR(price), "bytes", len(data))
+
+	if balance.Cmp(price) < 0 {
+		return apperrors.New(
+			apperrors.ErrInsufficientBalance,
+			fmt.Sprintf("Insufficient Arweave balance. Required: %s AR, available: %s AR", formatAR(price), formatAR(balance)),
+			nil,
+		)
+	}
 	return nil
 }
+
+func formatAR(winston *big.Int) string {
+	return utils.WinstonToAR(winston).Text('f', 12)
+}
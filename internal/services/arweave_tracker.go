@@ -0,0 +1,249 @@
+package services
+
+import (
+	"BlockCertify/internal/config"
+	"BlockCertify/internal/models"
+	"BlockCertify/internal/repositories"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// Arweave produces a block about every two minutes
+	arweaveBlockTime    = 2 * time.Minute
+	arweavePollInterval = arweaveBlockTime
+	arweaveBatchSize    = 200
+	// A fresh transaction can take a while to reach every gateway node, so
+	// it is only considered dropped when it is still unknown after this
+	arweaveDropAfter = 30 * time.Minute
+	// Minimum time between two posts of the same transaction
+	arweaveRepostInterval = 30 * time.Minute
+	// A transaction anchor is only valid for 50 blocks; a header that was
+	// not mined by then is refused and its data needs a new transaction.
+	// The window is only used when the anchor's height cannot be fetched.
+	arweaveAnchorDepth  = 50
+	arweaveAnchorWindow = arweaveAnchorDepth * arweaveBlockTime
+)
+
+// ArweaveTracker follows Arweave uploads until they are permanent: mined,
+// ARWEAVE_CONFIRMATION_DEPTH blocks deep and with their data seeded. A
+// transaction the gateway forgets, or one mined without its data, is posted
+// again from the signed header and data kept at upload, under the same ID.
+// Once its anchor has expired the data is uploaded again under a new ID.
+// Data items are settled with the bundle transaction carrying them.
+type ArweaveTracker interface {
+	Start()
+	CheckPending() error
+}
+
+type arweaveTracker struct {
+	Arweave ArweaveService
+	repo    repositories.ArweaveRepository
+	depth   int
+	mu      sync.Mutex
+}
+
+func NewArweaveTracker(cfg *config.Config, arweave ArweaveService, repo repositories.ArweaveRepository) ArweaveTracker {
+	return &arweaveTracker{
+		Arweave: arweave,
+		repo:    repo,
+		depth:   cfg.Arweave.ConfirmationDepth,
+	}
+}
+
+func (s *arweaveTracker) Start() {
+
+	slog.Info("Tracking Arweave uploads", "depth", s.depth)
+
+	go func() {
+		ticker := time.NewTicker(arweavePollInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := s.CheckPending(); err != nil {
+				slog.Error("Arweave tracking failed", "err", err)
+			}
+		}
+	}()
+}
+
+// CheckPending re-checks every Arweave transaction that is not permanent yet.
+func (s *arweaveTracker) CheckPending() error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	txIDs, err := s.repo.GetUnsettledTxIDs(arweaveBatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to load unsettled Arweave transactions: %w", err)
+	}
+
+	for _, txID := range txIDs {
+		if err := s.check(txID); err != nil {
+			slog.Error("Failed to check Arweave transaction", "txID", txID, "err", err)
+		}
+	}
+
+	return nil
+}
+
+func (s *arweaveTracker) check(txID string) error {
+
+	upload, err := s.repo.GetUpload(txID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		item, err := s.repo.GetDataItem(txID)
+		if err == nil {
+			return s.checkDataItem(item)
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		// Diplomas stored before uploads were recorded have no signed copy
+		return s.checkTransaction(txID, nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.checkTransaction(txID, upload)
+}
+
+// checkDataItem records the bundle a data item travels in on its diplomas;
+// from the next pass on they are tracked through the bundle.
+func (s *arweaveTracker) checkDataItem(item *models.ArweaveDataItem) error {
+
+	bundleID := item.BundleID
+	if bundleID == "" {
+		var err error
+		bundleID, err = s.Arweave.BundleOf(item.ItemID)
+		if err != nil {
+			return err
+		}
+		// The bundler has not posted it yet
+		if bundleID == "" {
+			return nil
+		}
+		slog.Info("Arweave data item bundled", "dataItemID", item.ItemID, "bundleTxID", bundleID, "bundler", item.Bundler)
+	}
+
+	return s.repo.SetBundle(item.ItemID, bundleID)
+}
+
+func (s *arweaveTracker) checkTransaction(txID string, upload *models.ArweaveUpload) error {
+
+	status, err := s.Arweave.TransactionStatus(txID)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case !status.Known:
+		if upload != nil && time.Since(upload.PostedAt) < arweaveDropAfter {
+			return nil
+		}
+		slog.Warn("Arweave transaction dropped", "txID", txID)
+		if err := s.repo.UpdateStatus(txID, models.ArweaveStatusDropped, 0, 0); err != nil {
+			return err
+		}
+		return s.repost(txID, upload, false)
+
+	case status.Confirmations == 0:
+		return s.repo.UpdateStatus(txID, models.ArweaveStatusPending, 0, 0)
+
+	case status.Confirmations < s.depth:
+		return s.repo.UpdateStatus(txID, models.ArweaveStatusConfirming, status.Confirmations, status.BlockHeight)
+	}
+
+	// Deep enough; it is only permanent if the data made it to the weave too
+	seeded, err := s.Arweave.HasData(txID)
+	if err != nil {
+		return err
+	}
+	if !seeded {
+		slog.Warn("Arweave transaction is mined but its data is not seeded", "txID", txID, "confirmations", status.Confirmations)
+		if err := s.repo.UpdateStatus(txID, models.ArweaveStatusDropped, status.Confirmations, status.BlockHeight); err != nil {
+			return err
+		}
+		return s.repost(txID, upload, true)
+	}
+
+	if err := s.repo.UpdateStatus(txID, models.ArweaveStatusPermanent, status.Confirmations, status.BlockHeight); err != nil {
+		return err
+	}
+	if upload != nil {
+		s.Arweave.ReleasePayload(txID)
+	}
+	return nil
+}
+
+// repost posts the signed transaction and its chunks again. A mined
+// transaction only needs its chunks, which are accepted at any time. One
+// that never made it into a block can only be posted again while its anchor
+// is valid; after that its data is uploaded under a new ID. The attempts are
+// logged and counted on the upload.
+func (s *arweaveTracker) repost(txID string, upload *models.ArweaveUpload, mined bool) error {
+
+	if upload == nil || len(upload.SignedTx) == 0 {
+		slog.Error("Cannot re-seed Arweave transaction: no signed copy was kept", "txID", txID)
+		return nil
+	}
+	if time.Since(upload.PostedAt) < arweaveRepostInterval {
+		return nil
+	}
+
+	if !mined && s.anchorExpired(txID, upload) {
+		return s.reupload(txID, upload)
+	}
+
+	attempt := upload.Reposts + 1
+	repostErr := s.Arweave.Repost(upload.SignedTx, mined)
+	if err := s.repo.RecordRepost(txID, time.Now()); err != nil {
+		return err
+	}
+	if repostErr != nil {
+		return fmt.Errorf("failed to re-post Arweave transaction: %w", repostErr)
+	}
+
+	slog.Info("Re-posted Arweave transaction", "txID", txID, "reposts", attempt)
+	return nil
+}
+
+// anchorExpired compares the height of the block the signed transaction is
+// anchored to with the current one. When the gateway cannot tell, it falls
+// back to the time since the upload.
+func (s *arweaveTracker) anchorExpired(txID string, upload *models.ArweaveUpload) bool {
+
+	age, err := s.Arweave.AnchorAge(upload.SignedTx)
+	if err != nil {
+		slog.Warn("Failed to fetch Arweave anchor height", "txID", txID, "err", err)
+		return time.Since(upload.CreatedAt) > arweaveAnchorWindow
+	}
+	return age >= arweaveAnchorDepth
+}
+
+// reupload replaces a dropped transaction whose anchor has expired. The
+// diplomas keep the reference anchored on the contract; reads of it are
+// served from the replacement.
+func (s *arweaveTracker) reupload(txID string, upload *models.ArweaveUpload) error {
+
+	replacementID, err := s.Arweave.Reupload(upload.SignedTx)
+	if err != nil {
+		if recordErr := s.repo.RecordRepost(txID, time.Now()); recordErr != nil {
+			return recordErr
+		}
+		return fmt.Errorf("failed to re-upload Arweave transaction: %w", err)
+	}
+
+	if err := s.repo.ReplaceUpload(txID, replacementID, s.Arweave.URL(replacementID)); err != nil {
+		return err
+	}
+	s.Arweave.ReleasePayload(txID)
+
+	slog.Warn("Re-uploaded expired Arweave transaction under a new ID", "txID", txID, "newTxID", replacementID)
+	return nil
+}
@@ -0,0 +1,572 @@
+package services
+
+import (
+	"BlockCertify/internal/dto"
+	"BlockCertify/internal/models"
+	apperrors "BlockCertify/internal/pkg/errors"
+	"BlockCertify/internal/repositories"
+	"BlockCertify/internal/utils"
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// maxBatchFileSize bounds every PDF extracted from a batch archive.
+const maxBatchFileSize = 10 << 20 // 10 MB
+
+const (
+	defaultBatchItemsPageSize = 100
+	maxBatchItemsPageSize     = 500
+)
+
+type BatchService interface {
+	CreateJob(rows []dto.BatchManifestRow, archivePath string, universityID uuid.UUID, actor *models.User) (*dto.BatchJobResponse, error)
+	GetJob(jobID, universityID uuid.UUID, query dto.BatchJobItemQuery) (*dto.BatchJobResponse, error)
+	ListJobs(universityID uuid.UUID) ([]dto.BatchJobResponse, error)
+	Retry(jobID, itemID, universityID uuid.UUID) (*dto.BatchJobResponse, error)
+	Start()
+}
+
+type batchService struct {
+	Documents  *DocumentStores
+	Blockchain BlockchainService
+	Diplomas   DiplomaService
+	repo       repositories.BatchJobRepository
+	storageDir string
+	queue      chan batchTask
+}
+
+type batchTask struct {
+	jobID        uuid.UUID
+	universityID uuid.UUID
+}
+
+func NewBatchService(documents *DocumentStores, blockchain BlockchainService, diplomas DiplomaService, repo repositories.BatchJobRepository, storageDir string) BatchService {
+	return &batchService{
+		Documents:  documents,
+		Blockchain: blockchain,
+		Diplomas:   diplomas,
+		repo:       repo,
+		storageDir: storageDir,
+		queue:      make(chan batchTask, 64),
+	}
+}
+
+// Start launches the background worker and re-queues jobs that were still
+// running when the process stopped.
+func (s *batchService) Start() {
+
+	go s.worker()
+
+	jobs, err := s.repo.GetUnfinishedJobs()
+	if err != nil {
+		slog.Error("Failed to load unfinished batch jobs", "err", err)
+		return
+	}
+
+	for _, job := range jobs {
+		slog.Info("Resuming batch job", "jobID", job.ID)
+		s.enqueue(job.ID, job.UniversityID)
+	}
+}
+
+// CreateJob extracts the PDFs referenced by the manifest and stores one item
+// per row. Rows that failed validation, reference a missing file or repeat a
+// file of the same batch are stored as invalid so they show up in the report.
+func (s *batchService) CreateJob(rows []dto.BatchManifestRow, archivePath string, universityID uuid.UUID, actor *models.User) (*dto.BatchJobResponse, error) {
+
+	if len(rows) == 0 {
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "Manifest has no rows", nil)
+	}
+
+	archive, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrInvalidFile, "Failed to open ZIP archive", err)
+	}
+	defer archive.Close()
+
+	entries := make(map[string]*zip.File)
+	for _, f := range archive.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		entries[path.Clean(f.Name)] = f
+		// Manifests usually refer to files by name only
+		if _, exists := entries[path.Base(f.Name)]; !exists {
+			entries[path.Base(f.Name)] = f
+		}
+	}
+
+	jobID := uuid.Must(uuid.NewV7())
+	jobDir := filepath.Join(s.storageDir, jobID.String())
+	if err := os.MkdirAll(jobDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create batch directory: %w", err)
+	}
+
+	job := models.BatchJob{
+		ID:           jobID,
+		UniversityID: universityID,
+		CreatedBy:    actor.ID,
+		Status:       models.BatchJobPending,
+		TotalItems:   len(rows),
+	}
+
+	seenHashes := make(map[string]int)
+
+	for _, row := range rows {
+		item := models.BatchJobItem{
+			ID:             uuid.Must(uuid.NewV7()),
+			JobID:          jobID,
+			RowNumber:      row.RowNumber,
+			FileName:       row.FileName,
+			Status:         models.BatchItemPending,
+			FirstName:      row.Metadata.FirstName,
+			LastName:       row.Metadata.LastName,
+			Email:          row.Metadata.Email,
+			Faculty:        row.Metadata.Faculty,
+			Department:     row.Metadata.Department,
+			GraduationYear: row.Metadata.GraduationYear,
+			StudentNumber:  row.Metadata.StudentNumber,
+			Nationality:    row.Metadata.Nationality,
+		}
+
+		if invalid := s.prepareItem(&item, row, entries, jobDir, seenHashes); invalid != "" {
+			item.Status = models.BatchItemInvalid
+			item.Error = invalid
+		}
+
+		job.Items = append(job.Items, item)
+	}
+
+	if err := s.repo.Create(&job); err != nil {
+		_ = os.RemoveAll(jobDir)
+		return nil, fmt.Errorf("failed to create batch job: %w", err)
+	}
+
+	s.enqueue(job.ID, job.UniversityID)
+
+	response := toBatchJobResponse(&job)
+	for _, item := range job.Items {
+		countBatchItems(response, item.Status, 1)
+	}
+	return response, nil
+}
+
+// prepareItem extracts and hashes the row's PDF. It returns a reason when the
+// row cannot be processed.
+func (s *batchService) prepareItem(item *models.BatchJobItem, row dto.BatchManifestRow, entries map[string]*zip.File, jobDir string, seenHashes map[string]int) string {
+
+	if row.Error != "" {
+		return row.Error
+	}
+
+	if !strings.HasSuffix(strings.ToLower(row.FileName), ".pdf") {
+		return "only PDF files are allowed"
+	}
+
+	entry, ok := entries[path.Clean(row.FileName)]
+	if !ok {
+		return fmt.Sprintf("file %q not found in archive", row.FileName)
+	}
+
+	if entry.UncompressedSize64 > maxBatchFileSize {
+		return "file is larger than 10 MB"
+	}
+
+	filePath := filepath.Join(jobDir, item.ID.String()+".pdf")
+	if err := extractZipEntry(entry, filePath); err != nil {
+		return fmt.Sprintf("failed to extract file: %v", err)
+	}
+
+	hash, err := utils.HashFile(filePath)
+	if err != nil {
+		_ = os.Remove(filePath)
+		return fmt.Sprintf("failed to hash file: %v", err)
+	}
+
+	if firstRow, dup := seenHashes[hash]; dup {
+		_ = os.Remove(filePath)
+		return fmt.Sprintf("same file as row %d", firstRow)
+	}
+	seenHashes[hash] = row.RowNumber
+
+	item.FilePath = filePath
+	item.DiplomaHash = hash
+	return ""
+}
+
+// GetJob returns the job's counts and one page of its items in row order.
+func (s *batchService) GetJob(jobID, universityID uuid.UUID, query dto.BatchJobItemQuery) (*dto.BatchJobResponse, error) {
+
+	job, err := s.repo.GetJob(jobID, universityID)
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrBatchJobNotFound, "Batch job not found", err)
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultBatchItemsPageSize
+	}
+	if limit > maxBatchItemsPageSize {
+		limit = maxBatchItemsPageSize
+	}
+
+	// Fetch one extra item to know whether there is a next page
+	items, err := s.repo.GetItems(job.ID, query.Cursor, limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load batch items: %w", err)
+	}
+
+	responses, err := s.withCounts([]models.BatchJob{*job})
+	if err != nil {
+		return nil, err
+	}
+	response := &responses[0]
+
+	if len(items) > limit {
+		items = items[:limit]
+		response.NextCursor = items[limit-1].RowNumber
+	}
+
+	response.Items = make([]dto.BatchJobItemResponse, 0, len(items))
+	for _, item := range items {
+		response.Items = append(response.Items, dto.BatchJobItemResponse{
+			ID:          item.ID,
+			RowNumber:   item.RowNumber,
+			FileName:    item.FileName,
+			StudentName: fmt.Sprintf("%s %s", item.FirstName, item.LastName),
+			Status:      string(item.Status),
+			Error:       item.Error,
+			Attempts:    item.Attempts,
+			DiplomaHash: item.DiplomaHash,
+			ArweaveTxID: item.ArweaveTxID,
+			ArweaveURL:  item.ArweaveURL,
+		})
+	}
+
+	return response, nil
+}
+
+func (s *batchService) ListJobs(universityID uuid.UUID) ([]dto.BatchJobResponse, error) {
+
+	jobs, err := s.repo.ListJobs(universityID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list batch jobs: %w", err)
+	}
+
+	return s.withCounts(jobs)
+}
+
+// withCounts builds the responses of the jobs with their item counts, which
+// are aggregated in the database instead of loading every item.
+func (s *batchService) withCounts(jobs []models.BatchJob) ([]dto.BatchJobResponse, error) {
+
+	jobIDs := make([]uuid.UUID, 0, len(jobs))
+	for _, job := range jobs {
+		jobIDs = append(jobIDs, job.ID)
+	}
+
+	counts, err := s.repo.CountItems(jobIDs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count batch items: %w", err)
+	}
+
+	response := make([]dto.BatchJobResponse, 0, len(jobs))
+	index := make(map[uuid.UUID]int, len(jobs))
+	for i := range jobs {
+		index[jobs[i].ID] = i
+		response = append(response, *toBatchJobResponse(&jobs[i]))
+	}
+
+	for _, count := range counts {
+		if i, ok := index[count.JobID]; ok {
+			countBatchItems(&response[i], count.Status, count.Count)
+		}
+	}
+
+	return response, nil
+}
+
+// Retry puts failed items back in the queue. Uploaded items are never sent
+// again. A nil itemID retries every failed item of the job.
+func (s *batchService) Retry(jobID, itemID, universityID uuid.UUID) (*dto.BatchJobResponse, error) {
+
+	if _, err := s.repo.GetJob(jobID, universityID); err != nil {
+		return nil, apperrors.New(apperrors.ErrBatchJobNotFound, "Batch job not found", err)
+	}
+
+	reset, err := s.repo.ResetFailedItems(jobID, itemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reset batch items: %w", err)
+	}
+	if reset == 0 {
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "No failed items to retry", nil)
+	}
+
+	if err := s.repo.UpdateJobStatus(jobID, models.BatchJobPending); err != nil {
+		return nil, fmt.Errorf("failed to update batch job: %w", err)
+	}
+
+	s.enqueue(jobID, universityID)
+
+	return s.GetJob(jobID, universityID, dto.BatchJobItemQuery{})
+}
+
+func (s *batchService) enqueue(jobID, universityID uuid.UUID) {
+	go func() {
+		s.queue <- batchTask{jobID: jobID, universityID: universityID}
+	}()
+}
+
+func (s *batchService) worker() {
+	for task := range s.queue {
+		s.processJob(task.jobID, task.universityID)
+	}
+}
+
+func (s *batchService) processJob(jobID, universityID uuid.UUID) {
+
+	slog.Info("Processing batch job", "jobID", jobID)
+
+	if err := s.repo.UpdateJobStatus(jobID, models.BatchJobProcessing); err != nil {
+		slog.Error("Failed to update batch job", "jobID", jobID, "err", err)
+		return
+	}
+
+	// Items left in "uploading" were interrupted by a restart
+	items, err := s.repo.GetItemsByStatus(jobID, models.BatchItemPending, models.BatchItemUploading)
+	if err != nil {
+		slog.Error("Failed to load batch items", "jobID", jobID, "err", err)
+		return
+	}
+
+	// Stores that bundle uploads get the whole batch up front
+	if size := s.Documents.BatchSize(); size > 0 {
+		s.uploadBundled(items, size)
+	}
+
+	for i := range items {
+		if items[i].Status == models.BatchItemFailed {
+			continue
+		}
+		s.processItem(&items[i], universityID)
+	}
+
+	failed, err := s.repo.GetItemsByStatus(jobID, models.BatchItemFailed, models.BatchItemInvalid)
+	if err != nil {
+		slog.Error("Failed to load batch items", "jobID", jobID, "err", err)
+		return
+	}
+
+	status := models.BatchJobCompleted
+	if len(failed) > 0 {
+		status = models.BatchJobCompletedWithErrors
+	}
+
+	if err := s.repo.UpdateJobStatus(jobID, status); err != nil {
+		slog.Error("Failed to update batch job", "jobID", jobID, "err", err)
+	}
+
+	slog.Info("Batch job finished", "jobID", jobID, "status", status)
+}
+
+func (s *batchService) processItem(item *models.BatchJobItem, universityID uuid.UUID) {
+
+	item.Status = models.BatchItemUploading
+	item.Attempts++
+	if err := s.repo.UpdateItem(item); err != nil {
+		slog.Error("Failed to update batch item", "itemID", item.ID, "err", err)
+		return
+	}
+
+	// A retry after a failed queueing step must not upload the PDF twice
+	if item.ArweaveTxID == "" {
+		if err := s.checkNotRegistered(item); err != nil {
+			s.failItem(item, err)
+			return
+		}
+
+		reference, err := s.Documents.Upload(item.FilePath, item.DiplomaHash)
+		if err != nil {
+			s.failItem(item, err)
+			return
+		}
+		if err := s.setUploaded(item, reference); err != nil {
+			s.failItem(item, err)
+			return
+		}
+	}
+
+	// When the backend anchors, nobody signs per diploma, so the batch queues
+	// the diplomas itself
+	if s.Blockchain.MerkleAnchoringEnabled() || s.Blockchain.ServerIssuanceEnabled() {
+		_, err := s.Diplomas.QueueUpload(dto.QueueUploadRequest{
+			DiplomaHash:    item.DiplomaHash,
+			ArweaveTxID:    item.ArweaveTxID,
+			FirstName:      item.FirstName,
+			LastName:       item.LastName,
+			Email:          item.Email,
+			Faculty:        item.Faculty,
+			Department:     item.Department,
+			GraduationYear: item.GraduationYear,
+			StudentNumber:  item.StudentNumber,
+			Nationality:    item.Nationality,
+		}, universityID)
+		if err != nil {
+			s.failItem(item, err)
+			return
+		}
+	}
+
+	item.Status = models.BatchItemUploaded
+	item.Error = ""
+
+	if err := s.repo.UpdateItem(item); err != nil {
+		slog.Error("Failed to update batch item", "itemID", item.ID, "err", err)
+	}
+}
+
+// uploadBundled uploads the PDFs of items that are not stored yet, size at a
+// time, each group in one upload (one ANS-104 bundle on Arweave). The items
+// stay pending for processItem; those that cannot be uploaded are failed.
+func (s *batchService) uploadBundled(items []models.BatchJobItem, size int) {
+
+	var pending []*models.BatchJobItem
+	for i := range items {
+		item := &items[i]
+		if item.ArweaveTxID != "" {
+			continue
+		}
+		if err := s.checkNotRegistered(item); err != nil {
+			item.Attempts++
+			s.failItem(item, err)
+			continue
+		}
+		pending = append(pending, item)
+	}
+
+	for start := 0; start < len(pending); start += size {
+		group := pending[start:min(start+size, len(pending))]
+
+		files := make([]DocumentFile, len(group))
+		for i, item := range group {
+			files[i] = DocumentFile{FilePath: item.FilePath, FileHash: item.DiplomaHash}
+		}
+
+		references, err := s.Documents.UploadBatch(files)
+		if err != nil {
+			for _, item := range group {
+				item.Attempts++
+				s.failItem(item, err)
+			}
+			continue
+		}
+
+		for i, item := range group {
+			if err := s.setUploaded(item, references[i]); err != nil {
+				item.Attempts++
+				s.failItem(item, err)
+				continue
+			}
+			if err := s.repo.UpdateItem(item); err != nil {
+				slog.Error("Failed to update batch item", "itemID", item.ID, "err", err)
+			}
+		}
+	}
+}
+
+// checkNotRegistered refuses a diploma the contract already knows.
+func (s *batchService) checkNotRegistered(item *models.BatchJobItem) error {
+	exists, existingArweaveTxID, err := s.Blockchain.VerifyDiploma(item.DiplomaHash)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("diploma already registered. Arweave TxID: %s", existingArweaveTxID)
+	}
+	return nil
+}
+
+// setUploaded records where the item's PDF was stored and drops the
+// extracted copy.
+func (s *batchService) setUploaded(item *models.BatchJobItem, reference string) error {
+
+	store, address, err := s.Documents.Resolve(reference)
+	if err != nil {
+		return err
+	}
+
+	_ = os.Remove(item.FilePath)
+
+	item.FilePath = ""
+	item.ArweaveTxID = reference
+	item.ArweaveURL = store.URL(address)
+	return nil
+}
+
+func (s *batchService) failItem(item *models.BatchJobItem, err error) {
+	slog.Error("Batch item failed", "itemID", item.ID, "row", item.RowNumber, "err", err)
+	item.Status = models.BatchItemFailed
+	item.Error = err.Error()
+	if err := s.repo.UpdateItem(item); err != nil {
+		slog.Error("Failed to update batch item", "itemID", item.ID, "err", err)
+	}
+}
+
+func extractZipEntry(entry *zip.File, dst string) error {
+
+	src, err := entry.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	// Do not trust the size in the ZIP header
+	written, err := io.Copy(out, io.LimitReader(src, maxBatchFileSize+1))
+	if err != nil {
+		_ = os.Remove(dst)
+		return err
+	}
+	if written > maxBatchFileSize {
+		_ = os.Remove(dst)
+		return errors.New("file is larger than 10 MB")
+	}
+
+	return nil
+}
+
+func toBatchJobResponse(job *models.BatchJob) *dto.BatchJobResponse {
+	return &dto.BatchJobResponse{
+		ID:         job.ID,
+		Status:     string(job.Status),
+		TotalItems: job.TotalItems,
+		CreatedAt:  job.CreatedAt,
+	}
+}
+
+func countBatchItems(response *dto.BatchJobResponse, status models.BatchItemStatus, n int) {
+	switch status {
+	case models.BatchItemPending, models.BatchItemUploading:
+		response.Pending += n
+	case models.BatchItemUploaded:
+		response.Uploaded += n
+	case models.BatchItemFailed:
+		response.Failed += n
+	case models.BatchItemInvalid:
+		response.Invalid += n
+	}
+}
@@ -3,37 +3,102 @@ package services
 import (
 	"BlockCertify/internal/config"
 	"BlockCertify/internal/dto"
+	"BlockCertify/internal/models"
 	apperrors "BlockCertify/internal/pkg/errors"
+	"BlockCertify/internal/pkg/merkle"
 	"BlockCertify/internal/repositories"
 	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
 	"log/slog"
 	"math/big"
+	"strings"
+	"time"
 
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 )
 
 type BlockchainService interface {
 	StoreDiploma(diplomaHash, arweaveTxID string) (*dto.BlockchainResult, error)
 	VerifyDiploma(diplomaHash string) (bool, string, error)
+	ConfirmDiplomaTransaction(txHash, diplomaHash, arweaveTxID string) (*dto.BlockchainResult, error)
+	RevokeDiploma(diplomaHash, reason string) (*dto.BlockchainResult, error)
+	RevocationOnChainEnabled() bool
+	AnchorMerkleRoot(root merkle.Hash, leafCount int) (*dto.BlockchainResult, error)
+	GetMerkleRootTimestamp(root merkle.Hash) (time.Time, bool, error)
+	MerkleAnchoringEnabled() bool
+	SubmitDiploma(yokCode, diplomaHash, arweaveTxID string) (*dto.SubmittedTransaction, error)
+	ServerIssuanceEnabled() bool
+	GetTransactionBlock(txHash string) (uint64, string, bool, error)
+	LatestBlockNumber() (uint64, error)
+	GetChainRecord(diplomaHash string) (*dto.ChainDiplomaRecord, error)
+	Network() config.NetworkProfile
+	Networks() []string
+	ForNetwork(network string) (BlockchainService, error)
+	// Wallet is the backend wallet (PRIVATE_KEY) on the active network
+	Wallet() (*ChainWallet, error)
+	// DiplomaCost estimates the wei the backend wallet spends per diploma
+	// at the current gas price; 0 when it pays nothing per diploma
+	DiplomaCost() (*big.Int, error)
 }
 
+// ChainWallet is the backend wallet and its balance, in wei.
+type ChainWallet struct {
+	Address    common.Address
+	Balance    *big.Int
+	MinBalance *big.Int // sending is refused below this
+}
+
+// How long the synchronous calls wait for the outbox to get their transaction mined
+const (
+	transactionWaitTimeout = 2 * time.Minute
+	anchorWaitTimeout      = 15 * time.Minute
+)
+
+// Gas assumed when a call cannot be estimated, e.g. because the backend
+// wallet is not an issuer of the contract; rough upper bounds
+const (
+	storeDiplomaGasFallback = 200_000
+	anchorRootGasFallback   = 100_000
+)
+
 type blockchainService struct {
-	repo       *repositories.ContractRepository
-	minBalance *big.Int
-	privateKey string
+	repo              repositories.Ledger
+	ledgers           *repositories.Ledgers
+	transactions      TransactionManager
+	minBalance        *big.Int
+	privateKey        string
+	revocationOnChain bool
+	merkleAnchoring   bool
+	merkleBatchSize   int
+	serverIssuance    bool
+	universityKeys    map[string]string
 }
 
-func NewBlockChainService(cfg *config.Config, repo *repositories.ContractRepository) BlockchainService {
+// NewBlockChainService works on the active network of ledgers; use ForNetwork
+// for diplomas anchored elsewhere.
+func NewBlockChainService(cfg *config.Config, ledgers *repositories.Ledgers, transactions TransactionManager) BlockchainService {
 	minBalance, _ := new(big.Float).SetString(cfg.Blockchain.MinBalance)
 	minBalanceWei := new(big.Int)
 	minBalance.Mul(minBalance, big.NewFloat(1e18)).Int(minBalanceWei)
 
 	return &blockchainService{
-		repo:       repo,
-		minBalance: minBalanceWei,
-		privateKey: cfg.Blockchain.PrivateKey,
+		repo:              ledgers.Active(),
+		ledgers:           ledgers,
+		transactions:      transactions,
+		minBalance:        minBalanceWei,
+		privateKey:        cfg.Blockchain.PrivateKey,
+		revocationOnChain: cfg.Blockchain.RevocationOnChain,
+		merkleAnchoring:   cfg.Blockchain.MerkleAnchoring,
+		merkleBatchSize:   cfg.Blockchain.MerkleBatchSize,
+		serverIssuance:    cfg.Blockchain.ServerIssuance,
+		universityKeys:    cfg.Blockchain.UniversityPrivateKeys,
 	}
 }
 
@@ -48,7 +113,7 @@ func (s *blockchainService) StoreDiploma(diplomaHash, arweaveTxID string) (*dto.
 	}
 
 	// Check balance
-	if err := s.checkBalance(); err != nil {
+	if err := s.checkBalance(s.privateKey); err != nil {
 		return nil, err
 	}
 
@@ -63,10 +128,15 @@ func (s *blockchainService) StoreDiploma(diplomaHash, arweaveTxID string) (*dto.
 		formatGwei(gasTipCap),
 	)
 
+	call, err := s.repo.PackStoreDiploma(diplomaHash, arweaveTxID)
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrBlockchainFailed, "Failed to encode diploma transaction", err)
+	}
+
 	// Store diploma
-	receipt, err := s.repo.StoreDiploma(diplomaHash, arweaveTxID)
+	txn, err := s.transactions.Send(s.repo.Network().Name, models.TxKindStoreDiploma, diplomaHash, s.privateKey, call)
 	if err != nil {
-		if err.Error() == "insufficient funds" {
+		if strings.Contains(err.Error(), "insufficient funds") {
 			return nil, apperrors.New(
 				apperrors.ErrInsufficientBalance,
 				"Insufficient MATIC balance. Please get more test tokens from https://faucet.polygon.technology/",
@@ -76,22 +146,184 @@ func (s *blockchainService) StoreDiploma(diplomaHash, arweaveTxID string) (*dto.
 		return nil, apperrors.New(apperrors.ErrBlockchainFailed, "Failed to store diploma", err)
 	}
 
-	if receipt.Status != 1 {
+	mined, err := s.transactions.Wait(txn, transactionWaitTimeout)
+	if errors.Is(err, ErrTransactionPending) {
+		slog.Warn("Transaction not mined yet, still tracked", "txHash", txn.TxHash)
+		return s.pendingResult(txn), nil
+	}
+	if err != nil {
 		return nil, apperrors.New(
 			apperrors.ErrBlockchainFailed,
-			fmt.Sprintf("Transaction reverted. Check on https://amoy.polygonscan.com/tx/%s", receipt.TxHash.Hex()),
-			nil,
+			fmt.Sprintf("Transaction not confirmed. Check on %s", s.repo.ExplorerTxURL(txn.TxHash)),
+			err,
 		)
 	}
 
-	slog.Info("Transaction confirmed in block %d", receipt.BlockNumber.Uint64())
+	slog.Info("Transaction confirmed", "block", mined.BlockNumber)
 
-	return &dto.BlockchainResult{
-		TransactionHash: receipt.TxHash.Hex(),
-		BlockNumber:     receipt.BlockNumber.Uint64(),
+	return s.minedResult(mined), nil
+}
+
+// RevokeDiploma signs and sends a revokeDiploma transaction with the backend key.
+func (s *blockchainService) RevokeDiploma(diplomaHash, reason string) (*dto.BlockchainResult, error) {
+
+	call, err := s.repo.PackRevokeDiploma(diplomaHash, reason)
+	if err != nil {
+		return nil, packError("Failed to encode revocation transaction", err)
+	}
+
+	if err := s.checkBalance(s.privateKey); err != nil {
+		return nil, err
+	}
+
+	txn, err := s.transactions.Send(s.repo.Network().Name, models.TxKindRevokeDiploma, diplomaHash, s.privateKey, call)
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrBlockchainFailed, "Failed to revoke diploma on-chain", err)
+	}
+
+	mined, err := s.transactions.Wait(txn, transactionWaitTimeout)
+	if errors.Is(err, ErrTransactionPending) {
+		slog.Warn("Revocation not mined yet, still tracked", "txHash", txn.TxHash)
+		return s.pendingResult(txn), nil
+	}
+	if err != nil {
+		return nil, apperrors.New(
+			apperrors.ErrBlockchainFailed,
+			fmt.Sprintf("Revocation not confirmed. Check on %s", s.repo.ExplorerTxURL(txn.TxHash)),
+			err,
+		)
+	}
+
+	slog.Info("Revocation confirmed", "block", mined.BlockNumber)
+
+	return s.minedResult(mined), nil
+}
+
+// packError keeps an operation the contract version cannot perform apart
+// from a failed encoding, so it is refused instead of retried.
+func packError(message string, err error) error {
+	var unsupported *repositories.UnsupportedError
+	if errors.As(err, &unsupported) {
+		return apperrors.New(apperrors.ErrNotSupported, fmt.Sprintf("The contract does not support %s", unsupported.Operation), err)
+	}
+	return apperrors.New(apperrors.ErrBlockchainFailed, message, err)
+}
+
+func (s *blockchainService) RevocationOnChainEnabled() bool {
+	return s.revocationOnChain
+}
+
+// AnchorMerkleRoot signs and sends an anchorRoot transaction with the backend key.
+func (s *blockchainService) AnchorMerkleRoot(root merkle.Hash, leafCount int) (*dto.BlockchainResult, error) {
+
+	if err := s.checkBalance(s.privateKey); err != nil {
+		return nil, err
+	}
+
+	// A root sent before a restart is still in the outbox; wait for that
+	// transaction instead of anchoring the same root twice
+	txn, err := s.transactions.Latest(models.TxKindAnchorRoot, root.Hex())
+	if err != nil || txn.Status == models.TxFailed || txn.Network != s.repo.Network().Name {
+		call, err := s.repo.PackAnchorRoot(root, leafCount)
+		if err != nil {
+			return nil, packError("Failed to encode anchor transaction", err)
+		}
+
+		txn, err = s.transactions.Send(s.repo.Network().Name, models.TxKindAnchorRoot, root.Hex(), s.privateKey, call)
+		if err != nil {
+			return nil, apperrors.New(apperrors.ErrBlockchainFailed, "Failed to anchor Merkle root", err)
+		}
+	}
+
+	mined, err := s.transactions.Wait(txn, anchorWaitTimeout)
+	if errors.Is(err, ErrTransactionPending) {
+		slog.Warn("Anchor transaction not mined yet, still tracked", "root", root.Hex(), "txHash", txn.TxHash)
+		return s.pendingResult(txn), nil
+	}
+	if err != nil {
+		return nil, apperrors.New(
+			apperrors.ErrBlockchainFailed,
+			fmt.Sprintf("Anchor transaction not confirmed. Check on %s", s.repo.ExplorerTxURL(txn.TxHash)),
+			err,
+		)
+	}
+
+	slog.Info("Merkle root anchored", "root", root.Hex(), "leaves", leafCount, "block", mined.BlockNumber)
+
+	result := s.minedResult(mined)
+	result.Timestamp = time.Now().UTC()
+
+	// Prefer the block time recorded by the contract
+	if anchoredAt, ok, err := s.GetMerkleRootTimestamp(root); err == nil && ok {
+		result.Timestamp = anchoredAt
+	}
+
+	return result, nil
+}
+
+// GetMerkleRootTimestamp reports whether root is anchored on the contract and when.
+func (s *blockchainService) GetMerkleRootTimestamp(root merkle.Hash) (time.Time, bool, error) {
+
+	timestamp, err := s.repo.GetRootTimestamp(root)
+	if err != nil {
+		return time.Time{}, false, apperrors.New(apperrors.ErrVerificationFailed, "Failed to look up Merkle root", err)
+	}
+	if timestamp.Sign() == 0 {
+		return time.Time{}, false, nil
+	}
+	return time.Unix(timestamp.Int64(), 0).UTC(), true, nil
+}
+
+func (s *blockchainService) MerkleAnchoringEnabled() bool {
+	return s.merkleAnchoring
+}
+
+// SubmitDiploma queues storeDiploma in the outbox, signed with the
+// university's own key when one is configured for its YÖK code, otherwise
+// with PRIVATE_KEY. It returns without waiting for the receipt; the outbox
+// may replace the transaction, so follow it by diploma hash.
+func (s *blockchainService) SubmitDiploma(yokCode, diplomaHash, arweaveTxID string) (*dto.SubmittedTransaction, error) {
+
+	exists, _, err := s.repo.VerifyDiploma(diplomaHash)
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrBlockchainFailed, "Failed to check diploma existence", err)
+	}
+	if exists {
+		return nil, apperrors.New(apperrors.ErrDiplomaExists, "Diploma already registered in blockchain", nil)
+	}
+
+	privateKey := s.privateKey
+	if key, ok := s.universityKeys[yokCode]; ok {
+		privateKey = key
+	}
+
+	if err := s.checkBalance(privateKey); err != nil {
+		return nil, err
+	}
+
+	call, err := s.repo.PackStoreDiploma(diplomaHash, arweaveTxID)
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrBlockchainFailed, "Failed to encode diploma transaction", err)
+	}
+
+	txn, err := s.transactions.Send(s.repo.Network().Name, models.TxKindStoreDiploma, diplomaHash, privateKey, call)
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrBlockchainFailed, "Failed to submit diploma transaction", err)
+	}
+
+	slog.Info("Diploma transaction submitted", "txHash", txn.TxHash, "signer", txn.Signer, "nonce", txn.Nonce)
+
+	return &dto.SubmittedTransaction{
+		Network:         txn.Network,
+		TransactionHash: txn.TxHash,
+		Signer:          txn.Signer,
 	}, nil
 }
 
+func (s *blockchainService) ServerIssuanceEnabled() bool {
+	return s.serverIssuance
+}
+
 func (s *blockchainService) VerifyDiploma(diplomaHash string) (bool, string, error) {
 
 	exists, arweaveTxID, err := s.repo.VerifyDiploma(diplomaHash)
@@ -101,19 +333,119 @@ func (s *blockchainService) VerifyDiploma(diplomaHash string) (bool, string, err
 	return exists, arweaveTxID, nil
 }
 
-func (s *blockchainService) checkBalance() error {
-	privateKey, err := crypto.HexToECDSA(s.privateKey)
+// ConfirmDiplomaTransaction checks a transaction submitted outside the backend
+// (e.g. by MetaMask) against the chain. The transaction must be mined with a
+// success status and emit a DiplomaStored event from our contract carrying the
+// expected diploma hash and Arweave TxID.
+func (s *blockchainService) ConfirmDiplomaTransaction(txHash, diplomaHash, arweaveTxID string) (*dto.BlockchainResult, error) {
+
+	if !isTxHash(txHash) {
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "Invalid Polygon transaction hash", nil)
+	}
+
+	receipt, err := s.repo.GetTransactionReceipt(common.HexToHash(txHash))
 	if err != nil {
-		return apperrors.New(apperrors.ErrBlockchainFailed, "Invalid private key", err)
+		if errors.Is(err, ethereum.NotFound) {
+			return nil, apperrors.New(apperrors.ErrTransactionNotFound, "Transaction not found or not yet mined", nil)
+		}
+		return nil, apperrors.New(apperrors.ErrBlockchainFailed, "Failed to fetch transaction receipt", err)
 	}
 
-	publicKey := privateKey.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		return apperrors.New(apperrors.ErrBlockchainFailed, "Failed to cast public key to ECDSA", err)
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return nil, apperrors.New(apperrors.ErrTransactionMismatch, "Transaction reverted on-chain", nil)
 	}
 
-	fromAddress := crypto.PubkeyToAddress(*publicKeyECDSA)
+	event, err := s.repo.ParseDiplomaStored(receipt)
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrTransactionMismatch, "Transaction did not store a diploma on the configured contract", err)
+	}
+
+	if event.ContractAddress != s.repo.ContractAddress() {
+		return nil, apperrors.New(apperrors.ErrTransactionMismatch, "Transaction was sent to an unexpected contract", nil)
+	}
+	if event.DiplomaHash != diplomaHash {
+		return nil, apperrors.New(apperrors.ErrTransactionMismatch, "Diploma hash does not match the on-chain record", nil)
+	}
+	if event.ArweaveTxID != arweaveTxID {
+		return nil, apperrors.New(apperrors.ErrTransactionMismatch, "Arweave TxID does not match the on-chain record", nil)
+	}
+
+	return &dto.BlockchainResult{
+		Network:         s.repo.Network().Name,
+		ExplorerURL:     s.repo.ExplorerTxURL(receipt.TxHash.Hex()),
+		TransactionHash: receipt.TxHash.Hex(),
+		BlockNumber:     receipt.BlockNumber.Uint64(),
+		BlockHash:       receipt.BlockHash.Hex(),
+		GasUsed:         receipt.GasUsed,
+		Timestamp:       time.Unix(event.Timestamp.Int64(), 0).UTC(),
+	}, nil
+}
+
+// GetTransactionBlock returns the number and hash of the block that currently
+// includes txHash, and false when the transaction is not on the canonical chain.
+func (s *blockchainService) GetTransactionBlock(txHash string) (uint64, string, bool, error) {
+
+	receipt, err := s.repo.GetTransactionReceipt(common.HexToHash(txHash))
+	if err != nil {
+		if errors.Is(err, ethereum.NotFound) {
+			return 0, "", false, nil
+		}
+		return 0, "", false, apperrors.New(apperrors.ErrBlockchainFailed, "Failed to fetch transaction receipt", err)
+	}
+
+	return receipt.BlockNumber.Uint64(), receipt.BlockHash.Hex(), true, nil
+}
+
+func (s *blockchainService) LatestBlockNumber() (uint64, error) {
+
+	head, err := s.repo.BlockNumber()
+	if err != nil {
+		return 0, apperrors.New(apperrors.ErrBlockchainFailed, "Failed to get latest block", err)
+	}
+	return head, nil
+}
+
+// GetChainRecord reads a diploma back through verifyDiploma and the full
+// record (hashToId and getDiploma on v1), so disagreements between the
+// contract's mappings show up too.
+func (s *blockchainService) GetChainRecord(diplomaHash string) (*dto.ChainDiplomaRecord, error) {
+
+	exists, arweaveTxID, err := s.repo.VerifyDiploma(diplomaHash)
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrVerificationFailed, "Failed to verify diploma", err)
+	}
+
+	record := &dto.ChainDiplomaRecord{
+		Exists:      exists,
+		ArweaveTxID: arweaveTxID,
+	}
+	if !exists {
+		return record, nil
+	}
+
+	stored, err := s.repo.GetDiplomaRecord(diplomaHash)
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrVerificationFailed, "Failed to read diploma record", err)
+	}
+
+	if stored.ID != nil {
+		record.ID = stored.ID.String()
+	}
+	record.ContractAddress = stored.ContractAddress.Hex()
+	record.ContractVersion = stored.ContractVersion
+	record.DiplomaHash = stored.DiplomaHash
+	record.Owner = stored.Owner.Hex()
+	record.RecordArweaveTxID = stored.ArweaveTxID
+	record.Revoked = stored.Revoked
+	record.Timestamp = time.Unix(stored.Timestamp.Int64(), 0).UTC()
+	return record, nil
+}
+
+func (s *blockchainService) checkBalance(privateKeyHex string) error {
+	fromAddress, err := walletAddress(privateKeyHex)
+	if err != nil {
+		return err
+	}
 
 	balance, err := s.repo.GetBalance(fromAddress)
 	if err != nil {
@@ -129,7 +461,158 @@ func (s *blockchainService) checkBalance() error {
 	return nil
 }
 
+func walletAddress(privateKeyHex string) (common.Address, error) {
+	privateKey, err := crypto.HexToECDSA(privateKeyHex)
+	if err != nil {
+		return common.Address{}, apperrors.New(apperrors.ErrBlockchainFailed, "Invalid private key", err)
+	}
+
+	publicKeyECDSA, ok := privateKey.Public().(*ecdsa.PublicKey)
+	if !ok {
+		return common.Address{}, apperrors.New(apperrors.ErrBlockchainFailed, "Failed to cast public key to ECDSA", nil)
+	}
+	return crypto.PubkeyToAddress(*publicKeyECDSA), nil
+}
+
+func (s *blockchainService) Wallet() (*ChainWallet, error) {
+	address, err := walletAddress(s.privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	balance, err := s.repo.GetBalance(address)
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrBlockchainFailed, "Failed to get balance", err)
+	}
+	if balance == nil {
+		return nil, apperrors.New(apperrors.ErrBlockchainFailed, "Failed to get balance", errors.New("node returned no balance"))
+	}
+
+	return &ChainWallet{
+		Address:    address,
+		Balance:    balance,
+		MinBalance: new(big.Int).Set(s.minBalance),
+	}, nil
+}
+
+// DiplomaCost prices one storeDiploma call, or with Merkle anchoring an
+// even share of one anchorRoot call over a full batch. It is 0 with
+// ISSUANCE_MODE=wallet, where the admin's MetaMask pays instead.
+func (s *blockchainService) DiplomaCost() (*big.Int, error) {
+	if !s.merkleAnchoring && !s.serverIssuance {
+		return new(big.Int), nil
+	}
+
+	address, err := walletAddress(s.privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	gasPrice, _, err := s.repo.GetFeeData()
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrBlockchainFailed, "Failed to get fee data", err)
+	}
+
+	// A random hash is never registered, so the estimate cannot hit the
+	// duplicate check
+	var sample [32]byte
+	if _, err := rand.Read(sample[:]); err != nil {
+		return nil, apperrors.New(apperrors.ErrBlockchainFailed, "Failed to estimate diploma cost", err)
+	}
+
+	var call *repositories.ContractCall
+	var gas uint64
+	shares := int64(1)
+	if s.merkleAnchoring {
+		call, err = s.repo.PackAnchorRoot(sample, s.merkleBatchSize)
+		gas = anchorRootGasFallback
+		shares = int64(max(s.merkleBatchSize, 1))
+	} else {
+		call, err = s.repo.PackStoreDiploma(hex.EncodeToString(sample[:]), strings.Repeat("x", 43))
+		gas = storeDiplomaGasFallback
+	}
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrBlockchainFailed, "Failed to estimate diploma cost", err)
+	}
+
+	if estimated, err := s.repo.EstimateGas(address, call); err == nil {
+		gas = estimated
+	} else {
+		slog.Debug("Gas estimate failed, assuming the fallback", "gas", gas, "err", err)
+	}
+
+	cost := new(big.Int).Mul(new(big.Int).SetUint64(gas), gasPrice)
+	return cost.Quo(cost, big.NewInt(shares)), nil
+}
+
+// Network is the profile the service reads from and sends to.
+func (s *blockchainService) Network() config.NetworkProfile {
+	return s.repo.Network()
+}
+
+// Networks lists every configured network, the active one first.
+func (s *blockchainService) Networks() []string {
+	var networks []string
+	for _, ledger := range s.ledgers.All() {
+		networks = append(networks, ledger.Network().Name)
+	}
+	return networks
+}
+
+// ForNetwork returns the service bound to another configured network, so
+// diplomas anchored there before a network switch are read and revoked where
+// they live. An empty network means the active one.
+func (s *blockchainService) ForNetwork(network string) (BlockchainService, error) {
+
+	ledger, err := s.ledgers.Get(network)
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrBlockchainFailed, "Diploma was anchored on an unconfigured network", err)
+	}
+
+	bound := *s
+	bound.repo = ledger
+	return &bound, nil
+}
+
+// onNetwork binds blockchain to network unless it is already there. Records
+// without a network predate the ledger profiles and stay on the default one.
+func onNetwork(blockchain BlockchainService, network string) (BlockchainService, error) {
+	if network == "" || network == blockchain.Network().Name {
+		return blockchain, nil
+	}
+	return blockchain.ForNetwork(network)
+}
+
+func (s *blockchainService) minedResult(txn *models.ChainTransaction) *dto.BlockchainResult {
+	return &dto.BlockchainResult{
+		Network:         txn.Network,
+		ExplorerURL:     s.repo.ExplorerTxURL(txn.TxHash),
+		TransactionHash: txn.TxHash,
+		BlockNumber:     txn.BlockNumber,
+		BlockHash:       txn.BlockHash,
+		GasUsed:         txn.GasUsed,
+	}
+}
+
+// pendingResult describes a transaction that was sent but not mined yet.
+func (s *blockchainService) pendingResult(txn *models.ChainTransaction) *dto.BlockchainResult {
+	return &dto.BlockchainResult{
+		Network:         txn.Network,
+		ExplorerURL:     s.repo.ExplorerTxURL(txn.TxHash),
+		TransactionHash: txn.TxHash,
+		Pending:         true,
+	}
+}
+
 func formatGwei(wei *big.Int) string {
 	gwei := new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(1e9))
 	return gwei.String()
 }
+
+func isTxHash(txHash string) bool {
+	if !strings.HasPrefix(txHash, "0x") || len(txHash) != 66 {
+		return false
+	}
+	_, err := hex.DecodeString(txHash[2:])
+	return err == nil
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"BlockCertify/internal/models"
+	"fmt"
+)
+
+const credentialsContextV2 = "https://www.w3.org/ns/credentials/v2"
+
+// CredentialData is everything a formatter may map into a credential.
+type CredentialData struct {
+	Diploma         *models.Diploma
+	University      models.Universities
+	IssuerDID       string
+	ChainID         int
+	ContractAddress string
+}
+
+// CredentialFormatter turns a diploma record into an unsigned credential
+// document. CredentialService signs whatever the formatter returns, so every
+// format must be a W3C Verifiable Credential at its core.
+type CredentialFormatter interface {
+	// Name is the value of the ?format= query parameter
+	Name() string
+	Build(data CredentialData) map[string]any
+}
+
+// credentialEvidence points to the PDF on Arweave and to its Polygon anchor.
+// Every entry is typed Evidence so it is valid in all supported formats.
+func credentialEvidence(data CredentialData) []any {
+
+	diploma := data.Diploma
+
+	anchor := map[string]any{
+		"id":              diploma.PolygonURL,
+		"type":            []any{"Evidence", "PolygonAnchor"},
+		"name":            "Polygon anchor",
+		"chainId":         data.ChainID,
+		"contractAddress": data.ContractAddress,
+		"transactionHash": diploma.PolygonTxID,
+	}
+	if diploma.MerkleRoot != "" {
+		proof := make([]any, len(diploma.MerkleProof))
+		for i, p := range diploma.MerkleProof {
+			proof[i] = p
+		}
+		anchor["merkleRoot"] = diploma.MerkleRoot
+		anchor["merkleProof"] = proof
+		anchor["merkleLeafIndex"] = diploma.MerkleLeafIndex
+	}
+
+	return []any{
+		map[string]any{
+			"id":          diploma.ArweaveURL,
+			"type":        []any{"Evidence", "ArweaveDocument"},
+			"name":        "Diploma PDF",
+			"arweaveTxId": diploma.ArweaveTxID,
+			"mediaType":   "application/pdf",
+			"sha256":      diploma.Hash,
+		},
+		anchor,
+	}
+}
+
+func credentialID(diploma *models.Diploma) string {
+	return "urn:uuid:" + diploma.ID.String()
+}
+
+func degreeTitle(meta models.DiplomaMetaData) string {
+	return fmt.Sprintf("%s Diploma", meta.Department)
+}
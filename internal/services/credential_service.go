@@ -0,0 +1,319 @@
+package services
+
+import (
+	"BlockCertify/internal/config"
+	"BlockCertify/internal/dto"
+	"BlockCertify/internal/models"
+	"BlockCertify/internal/pkg/envelope"
+	apperrors "BlockCertify/internal/pkg/errors"
+	"BlockCertify/internal/pkg/vc"
+	"BlockCertify/internal/repositories"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// CredentialService exports diplomas as W3C Verifiable Credentials signed with
+// the issuing university's key, and verifies credentials exported this way.
+// The document layout is chosen by a CredentialFormatter.
+type CredentialService interface {
+	IssueCredential(diplomaID, format string) (map[string]any, error)
+	Formats() []string
+	VerifyCredential(credential map[string]any) (dto.CredentialVerifyResponse, error)
+	// WrapIssuerKeys wraps every issuer seed still stored in plain with the
+	// document master key
+	WrapIssuerKeys() error
+}
+
+type credentialService struct {
+	repo          repositories.DiplomaRepository
+	uniRepo       repositories.UniversityRepository
+	keyRepo       repositories.IssuerKeyRepository
+	formatters    map[string]CredentialFormatter
+	defaultFormat string
+	networks      map[string]config.NetworkProfile
+	untagged      string
+
+	// Issuer seeds are wrapped with the document master key when one is set
+	masterKey   []byte
+	masterKeyID string
+}
+
+// NewCredentialService registers the given formatters. The first one is used
+// when no format is requested.
+func NewCredentialService(cfg *config.Config, repo repositories.DiplomaRepository, uniRepo repositories.UniversityRepository, keyRepo repositories.IssuerKeyRepository, formatters ...CredentialFormatter) CredentialService {
+
+	s := &credentialService{
+		repo:       repo,
+		uniRepo:    uniRepo,
+		keyRepo:    keyRepo,
+		formatters: make(map[string]CredentialFormatter, len(formatters)),
+		networks:   cfg.Blockchain.Networks,
+		untagged:   cfg.Blockchain.UntaggedNetwork,
+	}
+
+	if cfg.Storage.MasterKey != "" {
+		masterKey, err := hex.DecodeString(cfg.Storage.MasterKey)
+		if err != nil {
+			slog.Error("Invalid document master key", "err", err)
+		} else {
+			s.masterKey = masterKey
+			s.masterKeyID = cfg.Storage.MasterKeyID
+		}
+	}
+
+	for i, f := range formatters {
+		if i == 0 {
+			s.defaultFormat = f.Name()
+		}
+		s.formatters[f.Name()] = f
+	}
+
+	return s
+}
+
+func (s *credentialService) Formats() []string {
+	formats := make([]string, 0, len(s.formatters))
+	for name := range s.formatters {
+		formats = append(formats, name)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// IssueCredential builds and signs the credential for a diploma in the given
+// format. Only anchored, unrevoked diplomas can be exported.
+func (s *credentialService) IssueCredential(diplomaID, format string) (map[string]any, error) {
+
+	if format == "" {
+		format = s.defaultFormat
+	}
+	formatter, ok := s.formatters[format]
+	if !ok {
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, fmt.Sprintf("Unknown credential format %q, expected one of %s", format, strings.Join(s.Formats(), ", ")), nil)
+	}
+
+	diploma, err := s.repo.GetByDiplomaID(diplomaID)
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrDiplomaNotFound, "Diploma not found", err)
+	}
+
+	switch diploma.Status() {
+	case models.DiplomaStatusRevoked:
+		return nil, apperrors.New(apperrors.ErrDiplomaRevoked, "Diploma is revoked", nil)
+	case models.DiplomaStatusPending:
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "Diploma is not anchored on-chain yet", nil)
+	}
+
+	university, err := s.uniRepo.GetUniversityByID(diploma.UniversityID.String())
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrUniversityNotFound, "University not found", err)
+	}
+
+	key, err := s.issuerKey(diploma.UniversityID)
+	if err != nil {
+		return nil, err
+	}
+
+	seed, err := s.issuerSeed(key)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, apperrors.New(apperrors.ErrCredentialFailed, "Issuer key is corrupt", err)
+	}
+
+	// The credential points at the contract the diploma was anchored on
+	network, ok := s.networks[diploma.Network]
+	if !ok {
+		network = s.networks[s.untagged]
+	}
+
+	credential := formatter.Build(CredentialData{
+		Diploma:         diploma,
+		University:      university,
+		IssuerDID:       key.DID,
+		ChainID:         network.ChainID,
+		ContractAddress: network.ContractAddress,
+	})
+
+	err = vc.Sign(credential, ed25519.NewKeyFromSeed(seed), vc.VerificationMethod(key.DID), time.Now())
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrCredentialFailed, "Failed to sign credential", err)
+	}
+
+	return credential, nil
+}
+
+// VerifyCredential checks the proof, that the signing key belongs to a
+// university we know, and that the diploma still exists and is not revoked.
+func (s *credentialService) VerifyCredential(credential map[string]any) (dto.CredentialVerifyResponse, error) {
+
+	var response dto.CredentialVerifyResponse
+
+	did, err := vc.Verify(credential)
+	if err != nil {
+		response.Details = append(response.Details, fmt.Sprintf("signature: %v", err))
+		return response, nil
+	}
+	response.SignatureValid = true
+	response.Issuer = did
+
+	issuer, _ := credential["issuer"].(map[string]any)
+	if issuer == nil || issuer["id"] != did {
+		response.Details = append(response.Details, "issuer: credential issuer is not the signing key")
+		return response, nil
+	}
+
+	key, err := s.keyRepo.GetByDID(did)
+	if err != nil {
+		response.Details = append(response.Details, "issuer: signing key does not belong to a registered university")
+		return response, nil
+	}
+	response.IssuerKnown = true
+
+	credentialID, _ := credential["id"].(string)
+	id, err := uuid.FromString(strings.TrimPrefix(credentialID, "urn:uuid:"))
+	if err != nil {
+		response.Details = append(response.Details, "record: credential id is not a diploma id")
+		return response, nil
+	}
+
+	diploma, err := s.repo.GetByID(id)
+	if err != nil {
+		response.Details = append(response.Details, "record: diploma not found")
+		return response, nil
+	}
+
+	response.DiplomaID = diploma.PublicID
+	response.Status = string(diploma.Status())
+
+	if diploma.UniversityID != key.UniversityID {
+		response.Details = append(response.Details, "record: diploma was issued by a different university")
+		return response, nil
+	}
+	response.RecordMatch = true
+
+	if diploma.Status() == models.DiplomaStatusRevoked {
+		response.Details = append(response.Details, "status: diploma has been revoked")
+	}
+
+	response.Verified = response.SignatureValid &&
+		response.IssuerKnown &&
+		response.RecordMatch &&
+		diploma.Status() == models.DiplomaStatusValid
+
+	return response, nil
+}
+
+// issuerKey returns the university's signing key, generating it on first use.
+func (s *credentialService) issuerKey(universityID uuid.UUID) (*models.IssuerKey, error) {
+
+	if key, err := s.keyRepo.GetByUniversityID(universityID); err == nil {
+		return key, nil
+	}
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrCredentialFailed, "Failed to generate issuer key", err)
+	}
+
+	storedSeed, masterKeyID, err := s.wrapSeed(universityID, privateKey.Seed())
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrCredentialFailed, "Failed to wrap issuer key", err)
+	}
+
+	key := &models.IssuerKey{
+		ID:           uuid.Must(uuid.NewV7()),
+		UniversityID: universityID,
+		DID:          vc.DIDKey(publicKey),
+		PublicKey:    hex.EncodeToString(publicKey),
+		PrivateKey:   storedSeed,
+		MasterKeyID:  masterKeyID,
+	}
+
+	if err := s.keyRepo.Create(key); err != nil {
+		// Another request created the key first
+		if existing, getErr := s.keyRepo.GetByUniversityID(universityID); getErr == nil {
+			return existing, nil
+		}
+		return nil, apperrors.New(apperrors.ErrCredentialFailed, "Failed to save issuer key", err)
+	}
+
+	slog.Info("Generated issuer key", "universityID", universityID, "did", key.DID)
+
+	return key, nil
+}
+
+// wrapSeed returns the seed as it is stored: wrapped with the master key
+// when one is configured, in plain otherwise.
+func (s *credentialService) wrapSeed(universityID uuid.UUID, seed []byte) (string, string, error) {
+	if s.masterKey == nil {
+		return hex.EncodeToString(seed), "", nil
+	}
+	wrapped, err := envelope.Wrap(s.masterKey, seed, issuerKeyPurpose(universityID))
+	if err != nil {
+		return "", "", err
+	}
+	return hex.EncodeToString(wrapped), s.masterKeyID, nil
+}
+
+// issuerSeed returns the Ed25519 seed of key, unwrapping it when it is
+// stored wrapped.
+func (s *credentialService) issuerSeed(key *models.IssuerKey) ([]byte, error) {
+
+	stored, err := hex.DecodeString(key.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	if key.MasterKeyID == "" {
+		return stored, nil
+	}
+	if s.masterKey == nil || key.MasterKeyID != s.masterKeyID {
+		return nil, fmt.Errorf("issuer key is wrapped with master key %q, but %q is configured", key.MasterKeyID, s.masterKeyID)
+	}
+	return envelope.Unwrap(s.masterKey, stored, issuerKeyPurpose(key.UniversityID))
+}
+
+func (s *credentialService) WrapIssuerKeys() error {
+
+	if s.masterKey == nil {
+		slog.Warn("No DOCUMENT_MASTER_KEY configured, issuer keys are stored unencrypted")
+		return nil
+	}
+
+	keys, err := s.keyRepo.GetUnwrapped()
+	if err != nil {
+		return fmt.Errorf("failed to load issuer keys: %w", err)
+	}
+
+	for _, key := range keys {
+		seed, err := hex.DecodeString(key.PrivateKey)
+		if err != nil {
+			slog.Error("Issuer key is corrupt, leaving it as it is", "universityID", key.UniversityID, "err", err)
+			continue
+		}
+		storedSeed, masterKeyID, err := s.wrapSeed(key.UniversityID, seed)
+		if err != nil {
+			return fmt.Errorf("failed to wrap issuer key of university %s: %w", key.UniversityID, err)
+		}
+		if err := s.keyRepo.SetWrapped(key.ID, storedSeed, masterKeyID); err != nil {
+			return fmt.Errorf("failed to save issuer key of university %s: %w", key.UniversityID, err)
+		}
+	}
+
+	if len(keys) > 0 {
+		slog.Info("Wrapped issuer keys with the master key", "keys", len(keys), "masterKeyID", s.masterKeyID)
+	}
+	return nil
+}
+
+// issuerKeyPurpose binds a wrapped seed to its university, so it cannot be
+// swapped onto another one.
+func issuerKeyPurpose(universityID uuid.UUID) []byte {
+	return []byte("issuer-key/" + universityID.String())
+}
@@ -3,6 +3,8 @@ package services
 import (
 	"BlockCertify/internal/dto"
 	"BlockCertify/internal/repositories"
+
+	"github.com/gofrs/uuid/v5"
 )
 
 type HistoryService interface {
@@ -18,9 +20,9 @@ func NewHistoryService(repo repositories.DiplomaRepository) HistoryService {
 	}
 }
 
-func (s *historyService) GetAllDiplomaFromDatabase() []dto.HistoryResponse {
+func (s *historyService) GetAllDiplomaFromDatabase(universityID uuid.UUID) []dto.HistoryResponse {
 
-	ch := s.repo.GetAllDiplomaFromDatabase()
+	ch := s.repo.GetAllDiplomaFromDatabase(universityID)
 
 	var response []dto.HistoryResponse
 
@@ -32,6 +34,7 @@ func (s *historyService) GetAllDiplomaFromDatabase() []dto.HistoryResponse {
 			Department: d.MetaData.Department,
 			CreateDate: d.CreatedAt,
 			DiplomaPdf: d.ArweaveURL,
+			Status:     string(d.Status()),
 		}
 
 		response = append(response, resp)
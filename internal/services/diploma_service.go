@@ -1,11 +1,16 @@
 package services
 
 import (
+	"BlockCertify/internal/config"
 	"BlockCertify/internal/dto"
 	"BlockCertify/internal/helper"
 	"BlockCertify/internal/models"
 	apperrors "BlockCertify/internal/pkg/errors"
+	"BlockCertify/internal/pkg/merkle"
 	"BlockCertify/internal/repositories"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log/slog"
 	"strings"
@@ -16,27 +21,36 @@ import (
 
 type DiplomaService interface {
 	PrepareUpload(filePath, fileHash string, metadata dto.DiplomaMetadataRequest) (*dto.PrepareUploadResponse, error)
-	ConfirmUpload(req dto.ConfirmUploadRequest) (*dto.UploadResponse, error)
-	Verify(req dto.VerifyDiplomaRequest) (dto.VerifyResponse, error)
-	GetArweaveUrlByDiplomaID(diplomaID string) string
-	GetAllDiplomaFromDatabase() []dto.HistoryResponse
+	ConfirmUpload(req dto.ConfirmUploadRequest, universityID uuid.UUID) (*dto.UploadResponse, error)
+	QueueUpload(req dto.QueueUploadRequest, universityID uuid.UUID) (*dto.UploadResponse, error)
+	Verify(req dto.VerifyDiplomaRequest, universityID uuid.UUID) (dto.VerifyResponse, error)
+	VerifyFile(fileHash string) (dto.VerifyFileResponse, error)
+	GetPublicDiploma(diplomaID string) (dto.PublicDiplomaResponse, error)
+	ReadDiplomaPDF(diplomaID string) ([]byte, error)
+	GetDiplomaRecords(query dto.DiplomaRecordQuery, universityID uuid.UUID) (*dto.HistoryPageResponse, error)
+	GetDiplomasOwnedBy(user *models.User) *dto.HistoryPageResponse
+	CanAccessDiploma(diplomaID string, user *models.User, universityID uuid.UUID) bool
+	Revoke(diplomaID string, req dto.RevokeDiplomaRequest, actor *models.User, universityID uuid.UUID) (*dto.RevokeDiplomaResponse, error)
 }
 
 type diplomaService struct {
-	Arweave    ArweaveService
+	Documents  *DocumentStores
 	Blockchain BlockchainService
 	repo       repositories.DiplomaRepository
+	uniRepo    repositories.UniversityRepository
 }
 
-func NewDiplomaService(arweave ArweaveService, blockchain BlockchainService, repo repositories.DiplomaRepository) DiplomaService {
+func NewDiplomaService(documents *DocumentStores, blockchain BlockchainService, repo repositories.DiplomaRepository, uniRepo repositories.UniversityRepository) DiplomaService {
 	return &diplomaService{
 		repo:       repo,
-		Arweave:    arweave,
+		uniRepo:    uniRepo,
+		Documents:  documents,
 		Blockchain: blockchain,
 	}
 }
 
-// PrepareUpload validates the diploma, uploads it to Arweave, and returns the
+// PrepareUpload validates the diploma, uploads it to the active document store
+// (Arweave unless STORAGE_BACKEND says otherwise), and returns the
 // hashes the frontend needs to sign the Polygon transaction via MetaMask.
 // It does NOT touch the Polygon blockchain itself.
 func (s *diplomaService) PrepareUpload(filePath, fileHash string, reqMeta dto.DiplomaMetadataRequest) (*dto.PrepareUploadResponse, error) {
@@ -52,63 +66,98 @@ func (s *diplomaService) PrepareUpload(filePath, fileHash string, reqMeta dto.Di
 		return nil, apperrors.New(apperrors.ErrDiplomaExists, fmt.Sprintf("Diploma already registered. Arweave TxID: %s", existingArweaveTxID), nil)
 	}
 
-	slog.Info("Uploading to Arweave...")
-	arweaveTxID, err := s.Arweave.Upload(filePath, fileHash)
+	// Diplomas queued for Merkle anchoring are not on-chain yet
+	if existing, err := s.repo.GetByHash(fileHash); err == nil {
+		return nil, apperrors.New(apperrors.ErrDiplomaExists, fmt.Sprintf("Diploma already registered. Arweave TxID: %s", existing.ArweaveTxID), nil)
+	}
+
+	slog.Info("Uploading diploma", "store", s.Documents.Active().Name())
+	reference, err := s.Documents.Upload(filePath, fileHash)
 	if err != nil {
 		return nil, err
 	}
 
-	if arweaveTxID == "" {
-		return nil, apperrors.New(
-			apperrors.ErrArweaveUploadFailed,
-			"Arweave upload failed: no transaction ID returned",
-			nil,
-		)
+	store, address, err := s.Documents.Resolve(reference)
+	if err != nil {
+		return nil, err
 	}
 
-	arweaveURL := fmt.Sprintf("https://arweave.net/%s", arweaveTxID)
+	network := s.Blockchain.Network()
 
 	return &dto.PrepareUploadResponse{
-		DiplomaHash: fileHash,
-		ArweaveTxID: arweaveTxID,
-		ArweaveURL:  arweaveURL,
+		DiplomaHash:     fileHash,
+		ArweaveTxID:     reference,
+		ArweaveURL:      store.URL(address),
+		Storage:         store.Name(),
+		ContentAddress:  address,
+		Network:         network.Name,
+		ChainID:         network.ChainID,
+		ContractAddress: network.ContractAddress,
+		ContractVersion: network.ContractVersion,
 	}, nil
 }
 
 // ConfirmUpload saves the diploma record to the database after the frontend
 // has successfully submitted the Polygon transaction via MetaMask.
-func (s *diplomaService) ConfirmUpload(req dto.ConfirmUploadRequest) (*dto.UploadResponse, error) {
+// The transaction is checked on-chain first; nothing posted by the browser is
+// trusted until the receipt and its DiplomaStored event agree with it.
+// The diploma is always issued under the caller's university.
+func (s *diplomaService) ConfirmUpload(req dto.ConfirmUploadRequest, universityID uuid.UUID) (*dto.UploadResponse, error) {
 
 	slog.Info("Confirming diploma upload", "polygonTxHash", req.PolygonTxHash)
 
-	polygonURL := fmt.Sprintf("https://amoy.polygonscan.com/tx/%s", req.PolygonTxHash)
-	arweaveURL := fmt.Sprintf("https://arweave.net/%s", req.ArweaveTxID)
+	university, err := s.uniRepo.GetUniversityByID(universityID.String())
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrUniversityNotFound, "University not found", err)
+	}
 
-	tx := s.repo.CreateTransaction()
+	store, address, err := s.Documents.Resolve(req.ArweaveTxID)
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "Unknown document reference", err)
+	}
+
+	encrypted, err := s.Documents.IsEncrypted(req.ArweaveTxID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up the document key: %w", err)
+	}
+
+	chainResult, err := s.Blockchain.ConfirmDiplomaTransaction(req.PolygonTxHash, req.DiplomaHash, req.ArweaveTxID)
+	if err != nil {
+		slog.Error("On-chain confirmation failed", "polygonTxHash", req.PolygonTxHash, "err", err)
+		return nil, err
+	}
+
+	if _, err := s.repo.GetHashFromPolygonTxID(chainResult.TransactionHash); err == nil {
+		return nil, apperrors.New(apperrors.ErrDiplomaExists, "Diploma already confirmed for this transaction", nil)
+	}
 
 	diplomaID, err := uuid.NewV7()
 	if err != nil {
-		tx.Rollback()
 		return nil, fmt.Errorf("failed to generate diploma ID: %w", err)
 	}
 
 	owner := fmt.Sprintf("%s %s", req.FirstName, req.LastName)
 
 	diploma := models.Diploma{
-		ID:          diplomaID,
-		PublicID:    helper.GenerateDiplomaPublicIDFromUUID(diplomaID),
-		Hash:        req.DiplomaHash,
-		ArweaveTxID: req.ArweaveTxID,
-		ArweaveURL:  arweaveURL,
-		PolygonTxID: req.PolygonTxHash,
-		PolygonURL:  polygonURL,
-		Owner:       owner,
-		Timestamp:   time.Time{},
-	}
-
-	if err := tx.Create(&diploma).Error; err != nil {
-		tx.Rollback()
-		return nil, err
+		ID:             diplomaID,
+		PublicID:       helper.GenerateDiplomaPublicIDFromUUID(diplomaID),
+		Hash:           req.DiplomaHash,
+		ArweaveTxID:    req.ArweaveTxID,
+		ArweaveURL:     store.URL(address),
+		Storage:        store.Name(),
+		ContentAddress: address,
+		Encrypted:      encrypted,
+		ArweaveStatus:  initialArweaveStatus(store),
+		Network:        chainResult.Network,
+		PolygonTxID:    chainResult.TransactionHash,
+		PolygonURL:     chainResult.ExplorerURL,
+		BlockNumber:    chainResult.BlockNumber,
+		BlockHash:      chainResult.BlockHash,
+		Owner:          owner,
+		Finality:       models.FinalityConfirming,
+		Timestamp:      chainResult.Timestamp,
+
+		UniversityID: university.ID,
 	}
 
 	diplomaMetadata := models.DiplomaMetaData{
@@ -117,7 +166,7 @@ func (s *diplomaService) ConfirmUpload(req dto.ConfirmUploadRequest) (*dto.Uploa
 		FirstName:      req.FirstName,
 		LastName:       req.LastName,
 		Email:          req.Email,
-		University:     req.University,
+		University:     university.Name,
 		Faculty:        req.Faculty,
 		Department:     req.Department,
 		GraduationYear: req.GraduationYear,
@@ -125,102 +174,703 @@ func (s *diplomaService) ConfirmUpload(req dto.ConfirmUploadRequest) (*dto.Uploa
 		Nationality:    req.Nationality,
 	}
 
-	if err := tx.Create(&diplomaMetadata).Error; err != nil {
-		tx.Rollback()
+	if err := s.saveDiploma(&diploma, &diplomaMetadata, nil); err != nil {
 		return nil, err
 	}
 
-	tx.Commit()
-
 	return &dto.UploadResponse{
 		Success:       true,
 		DiplomaHash:   req.DiplomaHash,
 		ArweaveTxID:   req.ArweaveTxID,
-		ArweaveURL:    arweaveURL,
-		PolygonTxHash: req.PolygonTxHash,
-		BlockNumber:   req.BlockNumber,
+		ArweaveURL:    diploma.ArweaveURL,
+		Storage:       diploma.Storage,
+		Network:       chainResult.Network,
+		PolygonTxHash: chainResult.TransactionHash,
+		BlockNumber:   chainResult.BlockNumber,
+		Status:        string(diploma.Status()),
 	}, nil
 }
 
-func (s *diplomaService) Verify(req dto.VerifyDiplomaRequest) (dto.VerifyResponse, error) {
+// QueueUpload saves an uploaded diploma without a Polygon transaction and
+// leaves anchoring to the backend: the anchor worker includes it in a Merkle
+// root (ANCHORING_MODE=merkle) or the issuance worker signs storeDiploma for
+// it (ISSUANCE_MODE=server). Until then the diploma reports the pending status
+// and does not verify.
+func (s *diplomaService) QueueUpload(req dto.QueueUploadRequest, universityID uuid.UUID) (*dto.UploadResponse, error) {
 
-	slog.Info("Verifying diploma from diplomaID")
+	if !s.Blockchain.MerkleAnchoringEnabled() && !s.Blockchain.ServerIssuanceEnabled() {
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "Backend anchoring is disabled, confirm the Polygon transaction instead", nil)
+	}
 
-	var err error
-	var response dto.VerifyResponse
-	diplomaID := req.DiplomaID
+	slog.Info("Queueing diploma for backend anchoring", "hash", req.DiplomaHash)
 
-	response.Verified = false
+	university, err := s.uniRepo.GetUniversityByID(universityID.String())
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrUniversityNotFound, "University not found", err)
+	}
 
-	// Fetch metadata from DB if it exists
-	diploma, err := s.repo.GetByDiplomaID(diplomaID)
+	store, address, err := s.Documents.Resolve(req.ArweaveTxID)
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "Unknown document reference", err)
+	}
+
+	encrypted, err := s.Documents.IsEncrypted(req.ArweaveTxID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up the document key: %w", err)
+	}
+
+	// The contract only learns about the hash once its root is anchored, so
+	// the database is the only place to catch duplicates in the meantime
+	if _, err := s.repo.GetByHash(req.DiplomaHash); err == nil {
+		return nil, apperrors.New(apperrors.ErrDiplomaExists, "Diploma already registered", nil)
+	}
+
+	exists, _, err := s.Blockchain.VerifyDiploma(req.DiplomaHash)
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrBlockchainFailed, "Failed to check if diploma exists", err)
+	}
+	if exists {
+		return nil, apperrors.New(apperrors.ErrDiplomaExists, "Diploma already registered in blockchain", nil)
+	}
+
+	diplomaID, err := uuid.NewV7()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate diploma ID: %w", err)
+	}
+
+	diploma := models.Diploma{
+		ID:             diplomaID,
+		PublicID:       helper.GenerateDiplomaPublicIDFromUUID(diplomaID),
+		Hash:           req.DiplomaHash,
+		ArweaveTxID:    req.ArweaveTxID,
+		ArweaveURL:     store.URL(address),
+		Storage:        store.Name(),
+		ContentAddress: address,
+		Encrypted:      encrypted,
+		ArweaveStatus:  initialArweaveStatus(store),
+		Owner:          fmt.Sprintf("%s %s", req.FirstName, req.LastName),
+
+		UniversityID: university.ID,
+	}
+
+	diplomaMetadata := models.DiplomaMetaData{
+		ID:             uuid.Must(uuid.NewV7()),
+		DiplomaID:      diploma.ID,
+		FirstName:      req.FirstName,
+		LastName:       req.LastName,
+		Email:          req.Email,
+		University:     university.Name,
+		Faculty:        req.Faculty,
+		Department:     req.Department,
+		GraduationYear: req.GraduationYear,
+		StudentNumber:  req.StudentNumber,
+		Nationality:    req.Nationality,
+	}
 
-	if err == nil && diploma != nil {
-		response.Verified = true
-		response.StudentName = diploma.Owner
-		if diploma.MetaData.ID != uuid.Nil {
-			response.University = diploma.MetaData.University
-			response.Degree = fmt.Sprintf("%s - %s", diploma.MetaData.Faculty, diploma.MetaData.Department)
-			response.IssueDate = diploma.CreatedAt.Format("2006-01-02")
+	var issuance *models.DiplomaIssuance
+	if s.Blockchain.ServerIssuanceEnabled() {
+		issuance = &models.DiplomaIssuance{
+			ID:           uuid.Must(uuid.NewV7()),
+			DiplomaID:    diploma.ID,
+			UniversityID: university.ID,
+			Status:       models.IssuancePending,
 		}
-		response.PolygonTxHash = diploma.PolygonTxID
-		response.ArweaveTxID = diploma.ArweaveTxID
-		response.ArweaveURL = diploma.ArweaveURL
-		response.DiplomaHash = diploma.Hash
-		response.DiplomaID = diplomaID
 	}
 
-	if !response.Verified {
+	if err := s.saveDiploma(&diploma, &diplomaMetadata, issuance); err != nil {
+		return nil, err
+	}
+
+	return &dto.UploadResponse{
+		Success:     true,
+		DiplomaHash: req.DiplomaHash,
+		ArweaveTxID: req.ArweaveTxID,
+		ArweaveURL:  diploma.ArweaveURL,
+		Storage:     diploma.Storage,
+		Status:      string(diploma.Status()),
+	}, nil
+}
+
+// saveDiploma stores the diploma, its metadata and, for server issuance, the
+// issuance job in one transaction.
+func (s *diplomaService) saveDiploma(diploma *models.Diploma, metadata *models.DiplomaMetaData, issuance *models.DiplomaIssuance) error {
+
+	tx := s.repo.CreateTransaction()
+
+	if err := tx.Create(diploma).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Create(metadata).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if issuance != nil {
+		if err := tx.Create(issuance).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+func (s *diplomaService) Verify(req dto.VerifyDiplomaRequest, universityID uuid.UUID) (dto.VerifyResponse, error) {
+
+	slog.Info("Verifying diploma from diplomaID")
+
+	// Fetch metadata from DB if it exists
+	diploma, err := s.repo.GetByDiplomaIDForUniversity(req.DiplomaID, universityID)
+	if err != nil || diploma == nil {
 		return dto.VerifyResponse{
 			Verified: false,
-		}, fmt.Errorf("diploma not found")
+		}, apperrors.New(apperrors.ErrDiplomaNotFound, "Diploma not found", err)
 	}
 
+	response := buildVerifyResponse(diploma)
+
+	checks := s.crossCheck(diploma)
+	response.Checks = &checks
+	response.Verified = response.Verified && checks.ChainMatch && checks.ArweaveMatch && checks.DBMatch
+
 	return response, nil
 }
 
-func (s *diplomaService) GetArweaveUrlByDiplomaID(diplomaID string) string {
+// GetPublicDiploma runs the full verification for a public ID and returns only
+// the fields that are safe to show to anonymous verifiers.
+func (s *diplomaService) GetPublicDiploma(diplomaID string) (dto.PublicDiplomaResponse, error) {
+
+	diploma, err := s.repo.GetByDiplomaID(diplomaID)
+	if err != nil || diploma == nil {
+		return dto.PublicDiplomaResponse{}, apperrors.New(apperrors.ErrDiplomaNotFound, "Diploma not found", err)
+	}
+
+	verified := buildVerifyResponse(diploma)
+	checks := s.crossCheck(diploma)
+
+	return dto.PublicDiplomaResponse{
+		Verified:         verified.Verified && checks.ChainMatch && checks.ArweaveMatch && checks.DBMatch,
+		Status:           verified.Status,
+		DiplomaID:        diploma.PublicID,
+		StudentName:      diploma.Owner,
+		University:       diploma.MetaData.University,
+		Faculty:          diploma.MetaData.Faculty,
+		Department:       diploma.MetaData.Department,
+		Degree:           verified.Degree,
+		GraduationYear:   diploma.MetaData.GraduationYear,
+		IssueDate:        verified.IssueDate,
+		DiplomaHash:      diploma.Hash,
+		ArweaveTxID:      diploma.ArweaveTxID,
+		ArweaveURL:       diploma.ArweaveURL,
+		Storage:          diploma.Storage,
+		Network:          diploma.Network,
+		PolygonTxHash:    diploma.PolygonTxID,
+		RevocationReason: verified.RevocationReason,
+		RevokedAt:        verified.RevokedAt,
+		Finality:         verified.Finality,
+		Confirmations:    verified.Confirmations,
+		ArweaveStatus:    verified.ArweaveStatus,
+		ArweaveBundleID:  diploma.ArweaveBundleID,
+		Merkle:           verified.Merkle,
+		Checks:           &checks,
+	}, nil
+}
 
-	slog.Info("Fetching diploma by tx id")
+// crossCheck verifies the diploma against the database, the contract and
+// its document store independently of each other. The database row is only used to know
+// which hash to look for; every other source is asked directly.
+func (s *diplomaService) crossCheck(diploma *models.Diploma) dto.VerificationChecks {
 
-	if strings.TrimSpace(diplomaID) == "" {
-		slog.Error("Diploma ID is empty")
+	var checks dto.VerificationChecks
+
+	// Database
+	checks.DBMatch = diploma.Hash != "" &&
+		diploma.ArweaveTxID != "" &&
+		diploma.PolygonTxID != "" &&
+		diploma.PublicID == helper.GenerateDiplomaPublicIDFromUUID(diploma.ID)
+	if !checks.DBMatch {
+		checks.Details = append(checks.Details, "db: record is incomplete or its public ID does not match its key")
+	}
+
+	// Polygon
+	arweaveTxID := diploma.ArweaveTxID
+	if diploma.MerkleRoot != "" {
+		// The leaf commits to the Arweave TxID, so a valid proof vouches for it
+		if err := s.checkMerkleInclusion(diploma); err != nil {
+			checks.Details = append(checks.Details, fmt.Sprintf("chain: %v", err))
+		} else {
+			checks.ChainMatch = true
+		}
+	} else if diploma.PolygonTxID == "" {
+		checks.Details = append(checks.Details, "chain: diploma is waiting for its Merkle root to be anchored")
+	} else {
+		arweaveTxID = s.checkStoredOnChain(diploma, &checks)
+	}
+
+	if diploma.Finality == models.FinalityOrphaned {
+		checks.ChainMatch = false
+		checks.Details = append(checks.Details, fmt.Sprintf("chain: transaction %s is no longer on the canonical chain", diploma.PolygonTxID))
+	}
+
+	// Document store: prefer the reference the contract reports over the one
+	// in the database
+	if arweaveTxID == "" {
+		checks.Details = append(checks.Details, "storage: no document reference to check")
+		return checks
+	}
+
+	store, address, err := s.Documents.Resolve(arweaveTxID)
+	if err != nil {
+		checks.Details = append(checks.Details, fmt.Sprintf("storage: %v", err))
+		return checks
+	}
+
+	tagHash, err := store.GetFileHashTag(address)
+	if err != nil {
+		checks.Details = append(checks.Details, fmt.Sprintf("%s: %v", store.Name(), err))
+		return checks
+	}
+
+	// Encrypted documents are hashed after decryption, the hash on-chain is
+	// the plaintext's
+	data, err := s.Documents.Read(arweaveTxID)
+	if err != nil {
+		checks.Details = append(checks.Details, fmt.Sprintf("%s: %v", store.Name(), err))
+		return checks
+	}
+
+	sum := sha256.Sum256(data)
+	dataHash := hex.EncodeToString(sum[:])
+
+	if tagHash != diploma.Hash {
+		checks.Details = append(checks.Details, fmt.Sprintf("%s: recorded file hash does not match the diploma hash", store.Name()))
+	}
+	if dataHash != diploma.Hash {
+		checks.Details = append(checks.Details, fmt.Sprintf("%s: stored data does not hash to the diploma hash", store.Name()))
+	}
+	checks.ArweaveMatch = tagHash == diploma.Hash && dataHash == diploma.Hash
+
+	if diploma.ArweaveStatus != "" && diploma.ArweaveStatus != models.ArweaveStatusPermanent {
+		checks.Warnings = append(checks.Warnings, fmt.Sprintf("arweave: file is not permanent yet (%s, %d confirmations)", diploma.ArweaveStatus, diploma.ArweaveConfirmations))
+	}
+
+	return checks
+}
+
+// initialArweaveStatus is where tracking starts for a new diploma; the
+// tracker moves it along from there. Other stores are not tracked.
+func initialArweaveStatus(store DocumentStore) models.ArweaveStatus {
+	if store.Name() != config.StorageArweave {
 		return ""
 	}
+	return models.ArweaveStatusPending
+}
+
+// checkStoredOnChain checks a diploma stored with its own storeDiploma call and
+// returns the Arweave TxID to check next.
+func (s *diplomaService) checkStoredOnChain(diploma *models.Diploma, checks *dto.VerificationChecks) string {
+
+	blockchain, err := onNetwork(s.Blockchain, diploma.Network)
+	if err != nil {
+		checks.Details = append(checks.Details, fmt.Sprintf("chain: %v", err))
+		return diploma.ArweaveTxID
+	}
+
+	exists, chainArweaveTxID, err := blockchain.VerifyDiploma(diploma.Hash)
+	switch {
+	case err != nil:
+		checks.Details = append(checks.Details, fmt.Sprintf("chain: %v", err))
+	case !exists:
+		checks.Details = append(checks.Details, "chain: hash is not registered on the contract")
+	case chainArweaveTxID != diploma.ArweaveTxID:
+		checks.Details = append(checks.Details, fmt.Sprintf("chain: contract points to Arweave tx %s", chainArweaveTxID))
+		return chainArweaveTxID
+	default:
+		checks.ChainMatch = true
+	}
+
+	return diploma.ArweaveTxID
+}
+
+// checkMerkleInclusion recomputes the diploma's leaf, folds in its proof and
+// asks the contract whether the resulting root was anchored.
+func (s *diplomaService) checkMerkleInclusion(diploma *models.Diploma) error {
+
+	root, err := merkle.ParseHash(diploma.MerkleRoot)
+	if err != nil {
+		return fmt.Errorf("invalid Merkle root: %w", err)
+	}
+
+	proof := make([]merkle.Hash, 0, len(diploma.MerkleProof))
+	for _, p := range diploma.MerkleProof {
+		h, err := merkle.ParseHash(p)
+		if err != nil {
+			return fmt.Errorf("invalid Merkle proof: %w", err)
+		}
+		proof = append(proof, h)
+	}
+
+	if !merkle.Verify(merkle.Leaf(diploma.Hash, diploma.ArweaveTxID), proof, root) {
+		return errors.New("Merkle proof does not lead to the anchored root")
+	}
+
+	blockchain, err := onNetwork(s.Blockchain, diploma.Network)
+	if err != nil {
+		return err
+	}
+
+	_, anchored, err := blockchain.GetMerkleRootTimestamp(root)
+	if err != nil {
+		return err
+	}
+	if !anchored {
+		return errors.New("Merkle root is not anchored on the contract")
+	}
+
+	return nil
+}
+
+// VerifyFile verifies a diploma by the SHA-256 hash of its PDF. The contract is
+// the source of truth; the database record is joined when one exists and any
+// disagreement between the two is reported explicitly.
+func (s *diplomaService) VerifyFile(fileHash string) (dto.VerifyFileResponse, error) {
+
+	slog.Info("Verifying diploma from file hash", "hash", fileHash)
+
+	response := dto.VerifyFileResponse{
+		DiplomaHash: fileHash,
+	}
+
+	diploma, err := s.repo.GetByHash(fileHash)
+	if err != nil {
+		diploma = nil
+	}
+
+	onChain, onChainArweaveTxID, network, err := s.verifyOnNetworks(fileHash, diploma)
+	if err != nil {
+		return response, err
+	}
+	response.OnChain = onChain
+	response.OnChainArweaveTxID = onChainArweaveTxID
+	response.OnChainNetwork = network
+
+	if diploma != nil {
+		record := buildVerifyResponse(diploma)
+		response.InDatabase = true
+		response.Record = &record
+		response.Status = record.Status
+
+		// Merkle anchored diplomas are only known to the contract by their root
+		if !onChain && diploma.MerkleRoot != "" {
+			if err := s.checkMerkleInclusion(diploma); err != nil {
+				slog.Warn("Merkle inclusion check failed", "hash", fileHash, "err", err)
+			} else {
+				response.OnChain = true
+				response.OnChainArweaveTxID = diploma.ArweaveTxID
+				response.OnChainNetwork = diploma.Network
+			}
+		}
+	}
+
+	switch {
+	case response.OnChain && response.InDatabase:
+		if diploma.ArweaveTxID != onChainArweaveTxID {
+			response.Mismatches = append(response.Mismatches, "arweaveTxID")
+		}
+		if len(response.Mismatches) > 0 {
+			response.Agreement = dto.AgreementMismatch
+		} else {
+			response.Agreement = dto.AgreementMatch
+		}
+	case response.OnChain:
+		response.Agreement = dto.AgreementOnChainOnly
+	case response.InDatabase:
+		response.Agreement = dto.AgreementOffChainOnly
+	default:
+		response.Agreement = dto.AgreementNotFound
+	}
+
+	response.Verified = response.OnChain &&
+		response.Agreement != dto.AgreementMismatch &&
+		response.Status != string(models.DiplomaStatusRevoked)
+
+	return response, nil
+}
+
+// verifyOnNetworks asks the contract of the diploma's own network, or every
+// configured network when the hash has no record, whether it knows the hash.
+func (s *diplomaService) verifyOnNetworks(fileHash string, diploma *models.Diploma) (bool, string, string, error) {
+
+	networks := s.Blockchain.Networks()
+	if diploma != nil && diploma.Network != "" {
+		networks = []string{diploma.Network}
+	}
+
+	for _, network := range networks {
+		blockchain, err := onNetwork(s.Blockchain, network)
+		if err != nil {
+			return false, "", "", err
+		}
+
+		onChain, arweaveTxID, err := blockchain.VerifyDiploma(fileHash)
+		if err != nil {
+			return false, "", "", err
+		}
+		if onChain {
+			return true, arweaveTxID, network, nil
+		}
+	}
+
+	return false, "", "", nil
+}
+
+func buildVerifyResponse(diploma *models.Diploma) dto.VerifyResponse {
+
+	response := dto.VerifyResponse{
+		Verified:      true,
+		Status:        string(diploma.Status()),
+		StudentName:   diploma.Owner,
+		PolygonTxHash: diploma.PolygonTxID,
+		ArweaveTxID:   diploma.ArweaveTxID,
+		ArweaveURL:    diploma.ArweaveURL,
+		Storage:       diploma.Storage,
+		DiplomaHash:   diploma.Hash,
+		DiplomaID:     diploma.PublicID,
+		Network:       diploma.Network,
+	}
+
+	if diploma.PolygonTxID != "" {
+		response.Finality = string(diploma.Finality)
+		response.Confirmations = diploma.Confirmations
+	}
+	response.ArweaveStatus = string(diploma.ArweaveStatus)
+	response.ArweaveBundleID = diploma.ArweaveBundleID
+
+	if diploma.MetaData.ID != uuid.Nil {
+		response.University = diploma.MetaData.University
+		response.Degree = fmt.Sprintf("%s - %s", diploma.MetaData.Faculty, diploma.MetaData.Department)
+		response.IssueDate = diploma.CreatedAt.Format("2006-01-02")
+	}
+
+	if diploma.MerkleRoot != "" {
+		response.Merkle = &dto.MerkleProof{
+			Root:      diploma.MerkleRoot,
+			Proof:     diploma.MerkleProof,
+			LeafIndex: diploma.MerkleLeafIndex,
+		}
+	}
+
+	if diploma.Status() == models.DiplomaStatusPending {
+		response.Verified = false
+	}
+
+	if diploma.Revocation != nil {
+		response.Verified = false
+		response.RevocationReason = diploma.Revocation.Reason
+		response.RevokedAt = diploma.Revocation.RevokedAt.Format(time.RFC3339)
+	}
+
+	return response
+}
+
+// ReadDiplomaPDF returns the PDF of a diploma from the store its reference
+// points to, decrypted when it was stored encrypted.
+func (s *diplomaService) ReadDiplomaPDF(diplomaID string) ([]byte, error) {
 
 	diploma, err := s.repo.GetByDiplomaID(diplomaID)
 	if err != nil {
-		slog.Error("Failed to get diploma by tx id", "err", err)
-		return ""
+		return nil, apperrors.New(apperrors.ErrDiplomaNotFound, "Diploma not found", err)
 	}
 
-	arweaveUrl := diploma.ArweaveURL
-	if arweaveUrl == "" {
-		slog.Error("Arweave URL is empty")
-		return ""
+	pdf, err := s.Documents.Read(diploma.ArweaveTxID)
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrDocumentReadFailed, "Failed to fetch diploma", err)
+	}
+	return pdf, nil
+}
+
+const (
+	defaultRecordsPageSize = 25
+	maxRecordsPageSize     = 100
+)
+
+// GetDiplomaRecords returns one page of the university's diplomas, newest first.
+func (s *diplomaService) GetDiplomaRecords(query dto.DiplomaRecordQuery, universityID uuid.UUID) (*dto.HistoryPageResponse, error) {
+
+	filter := repositories.DiplomaFilter{
+		UniversityID:   universityID,
+		Limit:          query.Limit,
+		GraduationYear: query.GraduationYear,
+		Faculty:        strings.TrimSpace(query.Faculty),
+		Department:     strings.TrimSpace(query.Department),
+		Search:         strings.TrimSpace(query.Search),
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = defaultRecordsPageSize
+	}
+	if filter.Limit > maxRecordsPageSize {
+		filter.Limit = maxRecordsPageSize
+	}
+
+	if query.Cursor != "" {
+		cursor, err := uuid.FromString(query.Cursor)
+		if err != nil {
+			return nil, apperrors.New(apperrors.ErrInvalidRequest, "Invalid cursor", err)
+		}
+		filter.Cursor = cursor
+	}
+
+	if query.From != "" {
+		from, err := time.Parse(time.DateOnly, query.From)
+		if err != nil {
+			return nil, apperrors.New(apperrors.ErrInvalidRequest, "Invalid from date, expected YYYY-MM-DD", err)
+		}
+		filter.From = from
 	}
 
-	return arweaveUrl
+	if query.To != "" {
+		to, err := time.Parse(time.DateOnly, query.To)
+		if err != nil {
+			return nil, apperrors.New(apperrors.ErrInvalidRequest, "Invalid to date, expected YYYY-MM-DD", err)
+		}
+		// Inclusive: everything issued on the "to" day
+		filter.To = to.AddDate(0, 0, 1)
+	}
+
+	pageSize := filter.Limit
+	// Fetch one extra row to know whether there is a next page
+	filter.Limit = pageSize + 1
+
+	diplomas, total, err := s.repo.ListDiplomas(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list diplomas: %w", err)
+	}
+
+	response := &dto.HistoryPageResponse{
+		Items: make([]dto.HistoryResponse, 0, pageSize),
+		Total: total,
+	}
+
+	if len(diplomas) > pageSize {
+		diplomas = diplomas[:pageSize]
+		response.NextCursor = diplomas[pageSize-1].ID.String()
+	}
+
+	for i := range diplomas {
+		response.Items = append(response.Items, toHistoryResponse(&diplomas[i]))
+	}
+
+	return response, nil
 }
 
-func (s *diplomaService) GetAllDiplomaFromDatabase() []dto.HistoryResponse {
+func toHistoryResponse(d *models.Diploma) dto.HistoryResponse {
+	return dto.HistoryResponse{
+		DiplomaID:  d.PublicID,
+		UserName:   d.Owner,
+		Department: d.MetaData.Department,
+		CreateDate: d.CreatedAt,
+		DiplomaPdf: d.ArweaveURL,
+		Status:     string(d.Status()),
+	}
+}
 
-	ch := s.repo.GetAllDiplomaFromDatabase()
+// Revoke marks a diploma as revoked. When on-chain revocation is enabled the
+// revocation is anchored on the contract before it is recorded in the database.
+func (s *diplomaService) Revoke(diplomaID string, req dto.RevokeDiplomaRequest, actor *models.User, universityID uuid.UUID) (*dto.RevokeDiplomaResponse, error) {
 
-	var response []dto.HistoryResponse
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "Revocation reason is required", nil)
+	}
 
-	for d := range ch {
+	diploma, err := s.repo.GetByDiplomaIDForUniversity(diplomaID, universityID)
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrDiplomaNotFound, "Diploma not found", err)
+	}
+
+	if diploma.Revocation != nil {
+		return nil, apperrors.New(apperrors.ErrDiplomaRevoked, "Diploma is already revoked", nil)
+	}
 
-		resp := dto.HistoryResponse{
-			DiplomaID:  d.PublicID,
-			UserName:   d.Owner,
-			Department: d.MetaData.Department,
-			CreateDate: d.CreatedAt,
-			DiplomaPdf: d.ArweaveURL,
+	revocation := models.DiplomaRevocation{
+		ID:            uuid.Must(uuid.NewV7()),
+		DiplomaID:     diploma.ID,
+		Reason:        reason,
+		RevokedBy:     actor.ID,
+		RevokedByName: fmt.Sprintf("%s %s", actor.FirstName, actor.LastName),
+		RevokedAt:     time.Now().UTC(),
+	}
+
+	if s.Blockchain.RevocationOnChainEnabled() {
+		// Revoke on the contract that holds the diploma
+		blockchain, err := onNetwork(s.Blockchain, diploma.Network)
+		if err != nil {
+			return nil, err
+		}
+
+		slog.Info("Anchoring revocation on-chain", "diplomaID", diplomaID, "network", blockchain.Network().Name)
+		result, err := blockchain.RevokeDiploma(diploma.Hash, reason)
+		if err != nil {
+			return nil, err
 		}
+		revocation.PolygonTxID = result.TransactionHash
+		revocation.PolygonURL = result.ExplorerURL
+	}
 
-		response = append(response, resp)
+	if err := s.repo.CreateRevocation(&revocation); err != nil {
+		return nil, fmt.Errorf("failed to save revocation: %w", err)
 	}
 
+	return &dto.RevokeDiplomaResponse{
+		DiplomaID:     diploma.PublicID,
+		Status:        string(models.DiplomaStatusRevoked),
+		Reason:        revocation.Reason,
+		RevokedBy:     revocation.RevokedByName,
+		RevokedAt:     revocation.RevokedAt,
+		PolygonTxHash: revocation.PolygonTxID,
+	}, nil
+}
+
+// GetDiplomasOwnedBy returns the diplomas issued to the user's email address.
+// A student only has a handful of diplomas, so this is always a single page.
+func (s *diplomaService) GetDiplomasOwnedBy(user *models.User) *dto.HistoryPageResponse {
+
+	response := &dto.HistoryPageResponse{
+		Items: []dto.HistoryResponse{},
+	}
+
+	diplomas, err := s.repo.GetByOwnerEmail(user.Email)
+	if err != nil {
+		slog.Error("Failed to get diplomas by owner", "err", err)
+		return response
+	}
+
+	for i := range diplomas {
+		response.Items = append(response.Items, toHistoryResponse(&diplomas[i]))
+	}
+	response.Total = int64(len(response.Items))
+
 	return response
 }
+
+// CanAccessDiploma reports whether the user may open the diploma: students
+// only their own, admins only those issued by their university.
+func (s *diplomaService) CanAccessDiploma(diplomaID string, user *models.User, universityID uuid.UUID) bool {
+
+	diploma, err := s.repo.GetByDiplomaID(diplomaID)
+	if err != nil {
+		return false
+	}
+
+	switch user.Role {
+	case models.RoleStudent:
+		return strings.EqualFold(diploma.MetaData.Email, user.Email)
+	case models.RoleAdmin:
+		return universityID != uuid.Nil && diploma.UniversityID == universityID
+	default:
+		return false
+	}
+}
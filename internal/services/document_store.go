@@ -0,0 +1,410 @@
+package services
+
+import (
+	"BlockCertify/internal/config"
+	"BlockCertify/internal/models"
+	"BlockCertify/internal/pkg/envelope"
+	apperrors "BlockCertify/internal/pkg/errors"
+	"BlockCertify/internal/repositories"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/gofrs/uuid/v5"
+	"gorm.io/gorm"
+)
+
+// DocumentStore keeps diploma PDFs permanently. Upload returns the store's
+// own content address: an Arweave TxID, an IPFS path or an object key.
+type DocumentStore interface {
+	Name() string
+	Upload(filePath, fileHash string) (string, error)
+	// GetFileHashTag returns the diploma hash recorded with the document
+	GetFileHashTag(address string) (string, error)
+	GetData(address string) ([]byte, error)
+	URL(address string) string
+}
+
+// BatchUploader is a DocumentStore that stores many documents together more
+// cheaply than one at a time, like Arweave with ANS-104 bundling.
+type BatchUploader interface {
+	// BatchSize is how many documents one UploadBatch call takes at most;
+	// 0 when batching is turned off
+	BatchSize() int
+	// UploadBatch returns the content addresses in the order of files
+	UploadBatch(files []DocumentFile) ([]string, error)
+}
+
+// hashAddressed is a DocumentStore that names a document after its hash, so
+// its content address is known before the upload and uploading the same
+// diploma again overwrites the earlier copy.
+type hashAddressed interface {
+	AddressFor(fileHash string) string
+}
+
+// DocumentFile is one PDF of a batch upload.
+type DocumentFile struct {
+	FilePath string
+	FileHash string
+}
+
+// DocumentStores holds the store new diplomas are uploaded to and every
+// other configured one, so documents stored before a switch stay readable.
+//
+// Diplomas refer to their document through a reference that is anchored on
+// the contract in place of the Arweave TxID: Arweave documents keep the bare
+// TxID, every other store is written as <store>://<address>.
+//
+// With encryption enabled the PDF is sealed with its own data key before it
+// leaves the server; the data key is kept, wrapped with the master key, in
+// the database under the document reference. The diploma hash stays the
+// hash of the plaintext.
+type DocumentStores struct {
+	active string
+	stores map[string]DocumentStore
+
+	encrypt     bool
+	masterKey   []byte
+	masterKeyID string
+	keys        repositories.DocumentKeyRepository
+}
+
+func NewDocumentStores(active DocumentStore, others ...DocumentStore) *DocumentStores {
+	stores := &DocumentStores{
+		active: active.Name(),
+		stores: map[string]DocumentStore{active.Name(): active},
+	}
+	for _, store := range others {
+		if _, ok := stores.stores[store.Name()]; !ok {
+			stores.stores[store.Name()] = store
+		}
+	}
+	return stores
+}
+
+// NewConfiguredDocumentStores builds every store STORAGE_BACKEND and the
+// storage settings configure next to arweave, which is always readable.
+func NewConfiguredDocumentStores(cfg *config.Config, arweave ArweaveService, keys repositories.DocumentKeyRepository) *DocumentStores {
+
+	all := map[string]DocumentStore{
+		config.StorageArweave: arweave,
+	}
+	if cfg.Storage.Configured(config.StorageIPFS) {
+		all[config.StorageIPFS] = NewIPFSStore(cfg.Storage.IPFS)
+	}
+	if cfg.Storage.Configured(config.StorageS3) {
+		all[config.StorageS3] = NewS3Store(cfg.Storage.S3)
+	}
+	if cfg.Storage.Configured(config.StorageLocal) {
+		all[config.StorageLocal] = NewLocalStore(cfg.Storage.Local)
+	}
+
+	others := make([]DocumentStore, 0, len(all))
+	for name, store := range all {
+		if name != cfg.Storage.Backend {
+			others = append(others, store)
+		}
+	}
+
+	stores := NewDocumentStores(all[cfg.Storage.Backend], others...)
+
+	// The master key stays usable for reading after encryption is turned off
+	if cfg.Storage.MasterKey != "" {
+		masterKey, err := hex.DecodeString(cfg.Storage.MasterKey)
+		if err != nil {
+			slog.Error("Invalid document master key", "err", err)
+		} else {
+			stores.EnableEncryption(cfg.Storage.EncryptDocuments, cfg.Storage.MasterKeyID, masterKey, keys)
+		}
+	}
+
+	slog.Info("Document storage", "backend", cfg.Storage.Backend, "stores", len(all), "encrypted", stores.encrypt)
+	return stores
+}
+
+// EnableEncryption decrypts documents that have a wrapped data key and, when
+// encrypt is set, seals every new upload.
+func (d *DocumentStores) EnableEncryption(encrypt bool, masterKeyID string, masterKey []byte, keys repositories.DocumentKeyRepository) {
+	d.encrypt = encrypt
+	d.masterKeyID = masterKeyID
+	d.masterKey = masterKey
+	d.keys = keys
+}
+
+// Upload stores a PDF in the active store, sealed first when encryption is
+// enabled, and returns its document reference.
+func (d *DocumentStores) Upload(filePath, fileHash string) (string, error) {
+
+	store := d.Active()
+	if !d.encrypt {
+		address, err := store.Upload(filePath, fileHash)
+		if err != nil {
+			return "", err
+		}
+		return d.reference(store, address)
+	}
+
+	sealedPath, wrappedKey, err := d.seal(store, filePath, fileHash)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(sealedPath)
+
+	address, err := store.Upload(sealedPath, fileHash)
+	if err != nil {
+		return "", err
+	}
+	reference, err := d.reference(store, address)
+	if err != nil {
+		return "", err
+	}
+	if err := d.saveKey(reference, fileHash, wrappedKey); err != nil {
+		return "", err
+	}
+
+	slog.Info("Stored encrypted diploma", "store", store.Name(), "reference", reference)
+	return reference, nil
+}
+
+// BatchSize is how many documents UploadBatch takes at most; 0 when the
+// active store uploads them one at a time.
+func (d *DocumentStores) BatchSize() int {
+	if batcher, ok := d.Active().(BatchUploader); ok {
+		return batcher.BatchSize()
+	}
+	return 0
+}
+
+// UploadBatch stores the PDFs together in the active store, sealed first
+// when encryption is enabled, and returns their references in order.
+func (d *DocumentStores) UploadBatch(files []DocumentFile) ([]string, error) {
+
+	store := d.Active()
+	batcher, ok := store.(BatchUploader)
+	if !ok || batcher.BatchSize() == 0 {
+		return nil, fmt.Errorf("document store %q does not batch uploads", store.Name())
+	}
+
+	uploads := files
+	wrappedKeys := make([][]byte, len(files))
+	if d.encrypt {
+		uploads = make([]DocumentFile, len(files))
+		for i, file := range files {
+			sealedPath, wrappedKey, err := d.seal(store, file.FilePath, file.FileHash)
+			if err != nil {
+				return nil, err
+			}
+			defer os.Remove(sealedPath)
+
+			uploads[i] = DocumentFile{FilePath: sealedPath, FileHash: file.FileHash}
+			wrappedKeys[i] = wrappedKey
+		}
+	}
+
+	addresses, err := batcher.UploadBatch(uploads)
+	if err != nil {
+		return nil, err
+	}
+	if len(addresses) != len(files) {
+		return nil, apperrors.New(
+			apperrors.ErrStorageUploadFailed,
+			fmt.Sprintf("Upload to %s failed: %d content addresses for %d files", store.Name(), len(addresses), len(files)),
+			nil,
+		)
+	}
+
+	references := make([]string, len(addresses))
+	for i, address := range addresses {
+		references[i], err = d.reference(store, address)
+		if err != nil {
+			return nil, err
+		}
+		if d.encrypt {
+			if err := d.saveKey(references[i], files[i].FileHash, wrappedKeys[i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return references, nil
+}
+
+// seal encrypts the PDF into a temporary file the caller removes. When store
+// overwrites an earlier copy of the same diploma, the PDF is sealed with the
+// data key already kept for it, so the stored key stays valid whether or not
+// the retried upload goes through.
+func (d *DocumentStores) seal(store DocumentStore, filePath, fileHash string) (string, []byte, error) {
+
+	plaintext, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", nil, apperrors.New(apperrors.ErrStorageUploadFailed, "Failed to read file", err)
+	}
+
+	existing, err := d.existingKey(store, fileHash)
+	if err != nil {
+		return "", nil, apperrors.New(apperrors.ErrStorageUploadFailed, "Failed to load the document key", err)
+	}
+
+	var ciphertext, wrappedKey []byte
+	if existing != nil {
+		wrappedKey = existing.WrappedKey
+		ciphertext, err = envelope.SealWith(d.masterKey, wrappedKey, plaintext, []byte(fileHash))
+	} else {
+		ciphertext, wrappedKey, err = envelope.Seal(d.masterKey, plaintext, []byte(fileHash))
+	}
+	if err != nil {
+		return "", nil, apperrors.New(apperrors.ErrStorageUploadFailed, "Failed to encrypt diploma", err)
+	}
+
+	sealed, err := os.CreateTemp("", "diploma-*.enc")
+	if err != nil {
+		return "", nil, apperrors.New(apperrors.ErrStorageUploadFailed, "Failed to encrypt diploma", err)
+	}
+
+	_, err = sealed.Write(ciphertext)
+	if closeErr := sealed.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(sealed.Name())
+		return "", nil, apperrors.New(apperrors.ErrStorageUploadFailed, "Failed to encrypt diploma", err)
+	}
+
+	return sealed.Name(), wrappedKey, nil
+}
+
+// existingKey returns the data key kept for the address store writes
+// fileHash to, when the store names documents after their hash and has been
+// written to before under the configured master key.
+func (d *DocumentStores) existingKey(store DocumentStore, fileHash string) (*models.DocumentKey, error) {
+
+	addressed, ok := store.(hashAddressed)
+	if !ok {
+		return nil, nil
+	}
+
+	key, err := d.keys.GetByReference(d.Reference(store.Name(), addressed.AddressFor(fileHash)))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// A key wrapped with a retired master key is replaced along with the copy
+	if key.MasterKeyID != d.masterKeyID || key.DiplomaHash != fileHash {
+		return nil, nil
+	}
+	return key, nil
+}
+
+// saveKey stores the wrapped data key of an uploaded document, replacing the
+// key of the copy it overwrote. Without it the document can never be read
+// again.
+func (d *DocumentStores) saveKey(reference, fileHash string, wrappedKey []byte) error {
+	err := d.keys.Save(&models.DocumentKey{
+		ID:          uuid.Must(uuid.NewV7()),
+		Reference:   reference,
+		DiplomaHash: fileHash,
+		MasterKeyID: d.masterKeyID,
+		WrappedKey:  wrappedKey,
+	})
+	if err != nil {
+		return apperrors.New(apperrors.ErrStorageUploadFailed, "Failed to save the document key", err)
+	}
+	return nil
+}
+
+func (d *DocumentStores) reference(store DocumentStore, address string) (string, error) {
+	if address == "" {
+		return "", apperrors.New(
+			apperrors.ErrStorageUploadFailed,
+			fmt.Sprintf("Upload to %s failed: no content address returned", store.Name()),
+			nil,
+		)
+	}
+	return d.Reference(store.Name(), address), nil
+}
+
+// IsEncrypted reports whether the document was sealed on upload.
+func (d *DocumentStores) IsEncrypted(reference string) (bool, error) {
+	if d.keys == nil {
+		return false, nil
+	}
+	_, err := d.keys.GetByReference(reference)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Read returns the document's plaintext, decrypting it when it was stored
+// encrypted.
+func (d *DocumentStores) Read(reference string) ([]byte, error) {
+
+	store, address, err := d.Resolve(reference)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := store.GetData(address)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.keys == nil {
+		return data, nil
+	}
+
+	key, err := d.keys.GetByReference(reference)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return data, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the document key: %w", err)
+	}
+	if key.MasterKeyID != d.masterKeyID {
+		return nil, fmt.Errorf("document is sealed with master key %q, but %q is configured", key.MasterKeyID, d.masterKeyID)
+	}
+
+	return envelope.Open(d.masterKey, key.WrappedKey, data, []byte(key.DiplomaHash))
+}
+
+// Active is the store new diplomas are uploaded to.
+func (d *DocumentStores) Active() DocumentStore {
+	return d.stores[d.active]
+}
+
+// Reference returns how a document of store is referred to on-chain and in
+// the database.
+func (d *DocumentStores) Reference(store, address string) string {
+	if store == config.StorageArweave {
+		return address
+	}
+	return store + "://" + address
+}
+
+// Resolve returns the store and content address a document reference
+// points to.
+func (d *DocumentStores) Resolve(reference string) (DocumentStore, string, error) {
+
+	name, address, ok := strings.Cut(reference, "://")
+	if !ok {
+		name, address = config.StorageArweave, reference
+	}
+	if address == "" {
+		return nil, "", fmt.Errorf("empty document reference")
+	}
+
+	store, ok := d.stores[name]
+	if !ok {
+		return nil, "", fmt.Errorf("document store %q is not configured", name)
+	}
+	return store, address, nil
+}
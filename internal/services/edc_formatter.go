@@ -0,0 +1,141 @@
+package services
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	edcContext = "http://data.europa.eu/snb/model/context/edc-ap"
+	edcSchema  = "http://data.europa.eu/snb/model/ap/edc-generic-full"
+
+	// Records are entered in English; language maps need an explicit tag
+	edcLanguage = "en"
+	turkeyURI   = "http://publications.europa.eu/resource/authority/country/TUR"
+)
+
+// edcFormatter exports a European Digital Credential for Learning (ELM 3,
+// JSON-LD).
+//
+//	Faculty        -> awardingBody organisation, child of the university
+//	Department     -> learning achievement and qualification title
+//	GraduationYear -> awardedBy.awardingDate (31 December of that year when
+//	                  the anchor date falls in another year)
+//	YokCode        -> university registration (legal identifier)
+type edcFormatter struct{}
+
+func NewEDCFormatter() CredentialFormatter {
+	return edcFormatter{}
+}
+
+func (edcFormatter) Name() string {
+	return "edc"
+}
+
+func (edcFormatter) Build(data CredentialData) map[string]any {
+
+	diploma := data.Diploma
+	meta := diploma.MetaData
+	id := diploma.ID.String()
+
+	university := map[string]any{
+		"id":        "urn:epass:org:" + data.University.ID.String(),
+		"type":      "Organisation",
+		"legalName": langString(data.University.Name),
+		"registration": map[string]any{
+			"id":         "urn:epass:legalIdentifier:" + data.University.ID.String(),
+			"type":       "LegalIdentifier",
+			"notation":   data.University.YokCode,
+			"schemeName": "YÖK",
+			"spatial": map[string]any{
+				"id":   turkeyURI,
+				"type": "Concept",
+			},
+		},
+		"location": []any{
+			map[string]any{
+				"id":   "urn:epass:location:" + data.University.ID.String(),
+				"type": "Location",
+				"address": []any{
+					map[string]any{
+						"id":          "urn:epass:address:" + data.University.ID.String(),
+						"type":        "Address",
+						"countryCode": map[string]any{"id": turkeyURI, "type": "Concept"},
+					},
+				},
+			},
+		},
+	}
+
+	faculty := map[string]any{
+		"id":                 "urn:epass:org:" + id + ":faculty",
+		"type":               "Organisation",
+		"legalName":          langString(meta.Faculty),
+		"parentOrganisation": university,
+		"location":           university["location"],
+	}
+
+	awardingDate := diploma.Timestamp.UTC()
+	if awardingDate.Year() != meta.GraduationYear {
+		awardingDate = time.Date(meta.GraduationYear, time.December, 31, 0, 0, 0, 0, time.UTC)
+	}
+
+	achievement := map[string]any{
+		"id":    "urn:epass:learningAchievement:" + id,
+		"type":  "LearningAchievement",
+		"title": langString(degreeTitle(meta)),
+		"awardedBy": map[string]any{
+			"id":            "urn:epass:awardingProcess:" + id,
+			"type":          "AwardingProcess",
+			"awardingBody":  []any{faculty},
+			"awardingDate":  awardingDate.Format(time.RFC3339),
+			"educationalID": diploma.PublicID,
+		},
+		"specifiedBy": map[string]any{
+			"id":          "urn:epass:qualification:" + id,
+			"type":        "Qualification",
+			"title":       langString(meta.Department),
+			"description": langString(fmt.Sprintf("%s, %s", meta.Faculty, data.University.Name)),
+		},
+	}
+
+	subject := map[string]any{
+		"id":         "urn:epass:person:" + id,
+		"type":       "Person",
+		"givenName":  langString(meta.FirstName),
+		"familyName": langString(meta.LastName),
+		"fullName":   langString(diploma.Owner),
+		"hasClaim":   []any{achievement},
+	}
+	if meta.StudentNumber != "" {
+		subject["identifier"] = []any{
+			map[string]any{
+				"id":         "urn:epass:identifier:" + id,
+				"type":       "Identifier",
+				"notation":   meta.StudentNumber,
+				"schemeName": "Student number",
+			},
+		}
+	}
+
+	issuer := map[string]any{}
+	for k, v := range university {
+		issuer[k] = v
+	}
+	issuer["id"] = data.IssuerDID
+
+	return map[string]any{
+		"@context":          []any{credentialsContextV2, edcContext},
+		"id":                credentialID(diploma),
+		"type":              []any{"VerifiableCredential", "EuropeanDigitalCredential"},
+		"credentialSchema":  []any{map[string]any{"id": edcSchema, "type": "ShaclValidator2017"}},
+		"issuer":            issuer,
+		"validFrom":         diploma.Timestamp.UTC().Format(time.RFC3339),
+		"credentialSubject": subject,
+		"evidence":          credentialEvidence(data),
+	}
+}
+
+func langString(value string) map[string]any {
+	return map[string]any{edcLanguage: value}
+}
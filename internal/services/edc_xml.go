@@ -0,0 +1,126 @@
+package services
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"sort"
+)
+
+const (
+	// EDCXMLFormat is the ?format= value for the XML serialization of the
+	// EDC credential. It is built and signed as "edc"; the proof covers the
+	// JSON-LD document and is carried along in the XML.
+	EDCXMLFormat = "edc-xml"
+
+	elmNamespace = "http://data.europa.eu/snb/model/elm/"
+	xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+)
+
+// EncodeEDCXML writes a signed EDC credential in the ELM XML layout:
+//
+//	JSON-LD node        -> element named after its property, id as attribute
+//	type                -> one <type> element per entry
+//	array               -> the element repeated once per item
+//	language map        -> <name xml:lang="en">value</name>
+//	@context            -> one <context> element per entry
+func EncodeEDCXML(credential map[string]any) ([]byte, error) {
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+
+	root := xml.StartElement{
+		Name: xml.Name{Local: "europeanDigitalCredential"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: elmNamespace}},
+	}
+	if err := encodeEDCNode(enc, root, credential); err != nil {
+		return nil, err
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+func encodeEDCNode(enc *xml.Encoder, start xml.StartElement, node map[string]any) error {
+
+	if id, ok := node["id"].(string); ok {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "id"}, Value: id})
+	}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(node))
+	for k := range node {
+		if k != "id" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		name := k
+		if k == "@context" {
+			name = "context"
+		}
+		if err := encodeEDCValue(enc, name, node[k]); err != nil {
+			return err
+		}
+	}
+
+	return enc.EncodeToken(start.End())
+}
+
+func encodeEDCValue(enc *xml.Encoder, name string, value any) error {
+
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+
+	switch v := value.(type) {
+	case []any:
+		for _, item := range v {
+			if err := encodeEDCValue(enc, name, item); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case []string:
+		for _, item := range v {
+			if err := enc.EncodeElement(item, start); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case map[string]any:
+		if lang, text, ok := languageValue(v); ok {
+			start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Space: xmlNamespace, Local: "lang"}, Value: lang})
+			return enc.EncodeElement(text, start)
+		}
+		return encodeEDCNode(enc, start, v)
+
+	case nil:
+		return nil
+
+	case string, bool, int, int64, float64:
+		return enc.EncodeElement(fmt.Sprint(v), start)
+
+	default:
+		return fmt.Errorf("edc xml: %s has unsupported value %T", name, value)
+	}
+}
+
+// languageValue recognises the {"en": "..."} maps made by langString.
+func languageValue(node map[string]any) (string, string, bool) {
+
+	if len(node) != 1 {
+		return "", "", false
+	}
+	text, ok := node[edcLanguage].(string)
+	return edcLanguage, text, ok
+}
@@ -25,7 +25,7 @@ func NewFacultyService(repo repositories.FacultyRepository) FacultyService {
 func (s *facultyService) GetFaculties(universityID uuid.UUID) ([]dto.FacultiesResponse, error) {
 	faculties, err := s.repo.GetFaculties(universityID.String())
 	if err != nil {
-		slog.Error("Failed to get faculties from DB: %v", err)
+		slog.Error("Failed to get faculties from DB", "err", err)
 		return nil, err
 	}
 
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"BlockCertify/internal/config"
+	"BlockCertify/internal/models"
+	"BlockCertify/internal/repositories"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	finalityPollInterval = 15 * time.Second
+	finalityBatchSize    = 500
+	// orphanRecheckInterval spaces out lookups of transactions dropped by a
+	// reorg, which may never come back
+	orphanRecheckInterval = 10 * time.Minute
+)
+
+// FinalityService follows anchoring transactions after their first receipt.
+// A diploma becomes final once its transaction is ConfirmationDepth blocks
+// deep. A reorg that moves the transaction to another block is followed, and
+// one that drops it marks the diploma orphaned until it is mined again.
+type FinalityService interface {
+	Start()
+	CheckPending() error
+}
+
+type finalityService struct {
+	Blockchain BlockchainService
+	repo       repositories.FinalityRepository
+	depth      uint64
+	mu         sync.Mutex
+}
+
+func NewFinalityService(cfg *config.Config, blockchain BlockchainService, repo repositories.FinalityRepository) FinalityService {
+	return &finalityService{
+		Blockchain: blockchain,
+		repo:       repo,
+		depth:      cfg.Blockchain.ConfirmationDepth,
+	}
+}
+
+func (s *finalityService) Start() {
+
+	slog.Info("Tracking anchoring confirmations", "depth", s.depth)
+
+	go func() {
+		ticker := time.NewTicker(finalityPollInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := s.CheckPending(); err != nil {
+				slog.Error("Confirmation tracking failed", "err", err)
+			}
+		}
+	}()
+}
+
+// CheckPending re-checks the anchoring transactions of diplomas that are not
+// final yet, up to finalityBatchSize of them per run.
+func (s *finalityService) CheckPending() error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	diplomas, err := s.repo.GetUnfinalized(finalityBatchSize, time.Now().UTC().Add(-orphanRecheckInterval))
+	if err != nil {
+		return fmt.Errorf("failed to load unfinalized diplomas: %w", err)
+	}
+	if len(diplomas) == 0 {
+		return nil
+	}
+
+	// Each network has its own head; read it once per run
+	heads := make(map[string]uint64)
+	for _, d := range diplomas {
+		if err := s.check(&d, heads); err != nil {
+			slog.Error("Failed to check anchoring transaction", "network", d.Network, "polygonTxHash", d.PolygonTxID, "err", err)
+		}
+	}
+
+	return nil
+}
+
+func (s *finalityService) check(diploma *models.Diploma, heads map[string]uint64) error {
+
+	blockchain, err := onNetwork(s.Blockchain, diploma.Network)
+	if err != nil {
+		return err
+	}
+
+	network := blockchain.Network().Name
+	head, ok := heads[network]
+	if !ok {
+		head, err = blockchain.LatestBlockNumber()
+		if err != nil {
+			return err
+		}
+		heads[network] = head
+	}
+
+	blockNumber, blockHash, found, err := blockchain.GetTransactionBlock(diploma.PolygonTxID)
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		if diploma.Finality != models.FinalityOrphaned {
+			slog.Warn("Anchoring transaction disappeared from the chain",
+				"polygonTxHash", diploma.PolygonTxID, "block", diploma.BlockNumber, "blockHash", diploma.BlockHash)
+		}
+		return s.repo.UpdateFinality(diploma.PolygonTxID, diploma.BlockNumber, diploma.BlockHash, 0, models.FinalityOrphaned)
+	}
+
+	if diploma.BlockHash != "" && blockHash != diploma.BlockHash {
+		slog.Warn("Anchoring transaction moved by a reorg",
+			"polygonTxHash", diploma.PolygonTxID,
+			"oldBlock", diploma.BlockNumber, "oldBlockHash", diploma.BlockHash,
+			"block", blockNumber, "blockHash", blockHash)
+	}
+
+	var confirmations uint64
+	if head >= blockNumber {
+		confirmations = head - blockNumber + 1
+	}
+
+	finality := models.FinalityConfirming
+	if confirmations >= s.depth {
+		finality = models.FinalityFinal
+	}
+
+	if finality == diploma.Finality && blockHash == diploma.BlockHash && confirmations == diploma.Confirmations {
+		return s.repo.MarkChecked(diploma.PolygonTxID)
+	}
+
+	return s.repo.UpdateFinality(diploma.PolygonTxID, blockNumber, blockHash, confirmations, finality)
+}
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"BlockCertify/internal/config"
+	"BlockCertify/internal/dto"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"sync"
+	"time"
+)
+
+const (
+	currencyAR    = "AR"
+	currencyMatic = "MATIC"
+)
+
+// FundingService watches the two wallets new diplomas are paid from: the
+// Arweave wallet for the upload and the backend's Polygon wallet for the
+// anchoring transaction. It prices one more diploma at current rates and
+// warns once either wallet can pay for fewer than FUNDING_ALERT_DIPLOMAS.
+type FundingService interface {
+	Start()
+	Status() *dto.WalletStatusResponse
+}
+
+type fundingService struct {
+	Arweave    ArweaveService
+	Blockchain BlockchainService
+
+	paysArweave   bool
+	documentSize  int
+	alertDiplomas int64
+	interval      time.Duration
+
+	mu  sync.Mutex
+	low map[string]bool // by currency, as of the last check
+}
+
+func NewFundingService(cfg *config.Config, arweave ArweaveService, blockchain BlockchainService) FundingService {
+	return &fundingService{
+		Arweave:    arweave,
+		Blockchain: blockchain,
+
+		// A bundler pays for the bundles it posts itself
+		paysArweave:   cfg.Storage.Backend == config.StorageArweave && cfg.Arweave.Bundling != config.ArweaveBundlingBundler,
+		documentSize:  cfg.Funding.DocumentSize,
+		alertDiplomas: int64(cfg.Funding.AlertDiplomas),
+		interval:      cfg.Funding.CheckInterval,
+
+		low: make(map[string]bool),
+	}
+}
+
+func (s *fundingService) Start() {
+
+	slog.Info("Watching wallet funding", "alertDiplomas", s.alertDiplomas, "interval", s.interval)
+
+	go func() {
+		s.Status()
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			s.Status()
+		}
+	}()
+}
+
+// Status reads both balances and prices and logs an alert for every wallet
+// running low. A wallet whose node cannot be reached is reported with its
+// error instead of failing the whole status.
+func (s *fundingService) Status() *dto.WalletStatusResponse {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := &dto.WalletStatusResponse{
+		Arweave:   s.arweaveFunding(),
+		Matic:     s.maticFunding(),
+		Alerts:    []string{},
+		CheckedAt: time.Now().UTC(),
+	}
+
+	for _, wallet := range []*dto.WalletFundingResponse{&status.Arweave, &status.Matic} {
+		if !wallet.PaysDiplomas {
+			continue
+		}
+		if wallet.Error != "" || wallet.RemainingDiplomas == nil {
+			status.RemainingDiplomas = nil
+			break
+		}
+		if status.RemainingDiplomas == nil || *wallet.RemainingDiplomas < *status.RemainingDiplomas {
+			remaining := *wallet.RemainingDiplomas
+			status.RemainingDiplomas = &remaining
+		}
+	}
+
+	for _, wallet := range []*dto.WalletFundingResponse{&status.Arweave, &status.Matic} {
+		if alert := s.alert(wallet); alert != "" {
+			status.Alerts = append(status.Alerts, alert)
+		}
+	}
+
+	return status
+}
+
+func (s *fundingService) arweaveFunding() dto.WalletFundingResponse {
+
+	funding := dto.WalletFundingResponse{
+		Currency:     currencyAR,
+		PaysDiplomas: s.paysArweave,
+	}
+
+	address, balance, err := s.Arweave.WalletBalance()
+	funding.Address = address
+	if err != nil {
+		funding.Error = fmt.Sprintf("failed to get balance: %v", err)
+		return funding
+	}
+	funding.Balance = formatAR(balance)
+
+	if !s.paysArweave {
+		return funding
+	}
+
+	cost, err := s.Arweave.Price(s.documentSize)
+	if err != nil {
+		funding.Error = fmt.Sprintf("failed to get price: %v", err)
+		return funding
+	}
+	funding.DiplomaCost = formatAR(cost)
+	funding.RemainingDiplomas = remainingDiplomas(balance, new(big.Int), cost)
+	funding.Low = s.isLow(funding.RemainingDiplomas)
+	return funding
+}
+
+func (s *fundingService) maticFunding() dto.WalletFundingResponse {
+
+	funding := dto.WalletFundingResponse{
+		Currency: currencyMatic,
+		Network:  s.Blockchain.Network().Name,
+	}
+
+	cost, err := s.Blockchain.DiplomaCost()
+	if err != nil {
+		funding.PaysDiplomas = true
+		funding.Error = fmt.Sprintf("failed to estimate cost: %v", err)
+		return funding
+	}
+	funding.PaysDiplomas = cost.Sign() > 0
+
+	wallet, err := s.Blockchain.Wallet()
+	if err != nil {
+		funding.Error = fmt.Sprintf("failed to get balance: %v", err)
+		return funding
+	}
+	funding.Address = wallet.Address.Hex()
+	funding.Balance = formatMatic(wallet.Balance)
+
+	if !funding.PaysDiplomas {
+		return funding
+	}
+
+	funding.DiplomaCost = formatMatic(cost)
+	funding.RemainingDiplomas = remainingDiplomas(wallet.Balance, wallet.MinBalance, cost)
+	funding.Low = s.isLow(funding.RemainingDiplomas)
+	return funding
+}
+
+func (s *fundingService) isLow(remaining *int64) bool {
+	return remaining != nil && *remaining < s.alertDiplomas
+}
+
+// alert logs a wallet running low on every check, and once when it is
+// funded again. It returns the alert for the status response.
+func (s *fundingService) alert(wallet *dto.WalletFundingResponse) string {
+
+	// An unknown balance says nothing about whether it was topped up
+	if wallet.Error != "" {
+		return ""
+	}
+
+	wasLow := s.low[wallet.Currency]
+	s.low[wallet.Currency] = wallet.Low
+
+	if !wallet.Low {
+		if wasLow {
+			slog.Info("Wallet funded again", "currency", wallet.Currency, "address", wallet.Address, "balance", wallet.Balance)
+		}
+		return ""
+	}
+
+	remaining := *wallet.RemainingDiplomas
+	if remaining == 0 {
+		slog.Error("Wallet cannot pay for another diploma", "currency", wallet.Currency, "address", wallet.Address,
+			"balance", wallet.Balance, "diplomaCost", wallet.DiplomaCost)
+		return fmt.Sprintf("%s wallet %s cannot pay for another diploma", wallet.Currency, wallet.Address)
+	}
+
+	slog.Warn("Wallet running low", "currency", wallet.Currency, "address", wallet.Address,
+		"balance", wallet.Balance, "remainingDiplomas", remaining, "threshold", s.alertDiplomas)
+	return fmt.Sprintf("%s wallet %s can pay for %d more diplomas", wallet.Currency, wallet.Address, remaining)
+}
+
+// remainingDiplomas is how many times cost fits in what balance holds above
+// reserve, or nil when a diploma costs nothing.
+func remainingDiplomas(balance, reserve, cost *big.Int) *int64 {
+
+	if cost.Sign() <= 0 {
+		return nil
+	}
+
+	spendable := new(big.Int).Sub(balance, reserve)
+	remaining := int64(0)
+	if spendable.Sign() > 0 {
+		count := spendable.Quo(spendable, cost)
+		if count.IsInt64() {
+			remaining = count.Int64()
+		} else {
+			remaining = 1<<63 - 1
+		}
+	}
+	return &remaining
+}
+
+func formatMatic(wei *big.Int) string {
+	return new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(1e18)).Text('f', 12)
+}
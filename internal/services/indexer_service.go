@@ -0,0 +1,258 @@
+package services
+
+import (
+	"BlockCertify/internal/config"
+	"BlockCertify/internal/dto"
+	"BlockCertify/internal/models"
+	"BlockCertify/internal/repositories"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/gofrs/uuid/v5"
+)
+
+const (
+	diplomaStoredIndexer = "diploma_stored"
+	indexerPollInterval  = 15 * time.Second
+	indexerReportLimit   = 1000
+)
+
+// IndexerService copies the DiplomaStored events of the active network's
+// contract into the database, from the configured start block up to the newest block that has
+// CONFIRMATION_DEPTH confirmations, so indexed events are not undone by a
+// reorg. The indexed events are reconciled against the diploma table.
+type IndexerService interface {
+	Start()
+	Sync() (int, error)
+	GetReport() (*dto.IndexerReportResponse, error)
+}
+
+type indexerService struct {
+	contract   repositories.Ledger
+	cursor     string
+	repo       repositories.IndexerRepository
+	enabled    bool
+	startBlock uint64
+	blockRange uint64
+	depth      uint64
+	mu         sync.Mutex
+}
+
+func NewIndexerService(cfg *config.Config, ledgers *repositories.Ledgers, repo repositories.IndexerRepository) IndexerService {
+	contract := ledgers.Active()
+	return &indexerService{
+		contract:   contract,
+		cursor:     diplomaStoredIndexer + ":" + contract.Network().Name,
+		repo:       repo,
+		enabled:    cfg.Blockchain.IndexerEnabled,
+		startBlock: cfg.Blockchain.IndexerStartBlock,
+		blockRange: cfg.Blockchain.IndexerBlockRange,
+		depth:      cfg.Blockchain.ConfirmationDepth,
+	}
+}
+
+// Start backfills from the start block, or from where a previous run stopped,
+// and then follows new blocks. It does nothing unless the indexer is enabled.
+func (s *indexerService) Start() {
+
+	if !s.enabled {
+		return
+	}
+
+	slog.Info("DiplomaStored indexer enabled", "network", s.contract.Network().Name, "startBlock", s.startBlock)
+
+	go func() {
+		ticker := time.NewTicker(indexerPollInterval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := s.Sync(); err != nil {
+				slog.Error("Indexing DiplomaStored events failed", "err", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// Sync indexes every confirmed block after the cursor and returns how many
+// events it stored.
+func (s *indexerService) Sync() (int, error) {
+
+	if !s.enabled {
+		return 0, errors.New("indexer is disabled, set INDEXER_ENABLED=true")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	from := s.startBlock
+	cursor, ok, err := s.repo.GetCursor(s.cursor)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load indexer cursor: %w", err)
+	}
+	if ok {
+		from = cursor + 1
+	}
+
+	head, err := s.contract.BlockNumber()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get latest block: %w", err)
+	}
+	if head+1 < s.depth {
+		return 0, nil
+	}
+	safe := head + 1 - s.depth
+
+	indexed := 0
+	for from <= safe {
+		to := min(from+s.blockRange-1, safe)
+
+		events, err := s.contract.FilterDiplomaStored(from, to)
+		if err != nil {
+			return indexed, fmt.Errorf("failed to filter logs %d-%d: %w", from, to, err)
+		}
+
+		rows := make([]models.DiplomaStoredEvent, len(events))
+		for i, e := range events {
+			var chainDiplomaID string
+			if e.DiplomaID != nil {
+				chainDiplomaID = e.DiplomaID.String()
+			}
+
+			rows[i] = models.DiplomaStoredEvent{
+				ID:              uuid.Must(uuid.NewV7()),
+				ChainDiplomaID:  chainDiplomaID,
+				DiplomaHash:     e.DiplomaHash,
+				ArweaveTxID:     e.ArweaveTxID,
+				Owner:           e.Owner.Hex(),
+				Timestamp:       time.Unix(e.Timestamp.Int64(), 0).UTC(),
+				ContractAddress: e.ContractAddress.Hex(),
+				Network:         s.contract.Network().Name,
+				BlockNumber:     e.BlockNumber,
+				BlockHash:       e.BlockHash.Hex(),
+				TxHash:          e.TxHash.Hex(),
+				LogIndex:        e.LogIndex,
+			}
+		}
+
+		if err := s.repo.SaveEvents(s.cursor, rows, to); err != nil {
+			return indexed, fmt.Errorf("failed to save events %d-%d: %w", from, to, err)
+		}
+
+		indexed += len(rows)
+		from = to + 1
+	}
+
+	if indexed > 0 {
+		slog.Info("Indexed DiplomaStored events", "events", indexed, "block", safe)
+	}
+
+	// Diplomas confirmed after their event was indexed are linked here too
+	if _, err := s.repo.LinkDiplomas(); err != nil {
+		return indexed, fmt.Errorf("failed to link events to diplomas: %w", err)
+	}
+
+	return indexed, nil
+}
+
+// GetReport lists the orphans on either side: events with no diploma row, and
+// diploma rows in the indexed range with no event.
+func (s *indexerService) GetReport() (*dto.IndexerReportResponse, error) {
+
+	cursor, ok, err := s.repo.GetCursor(s.cursor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load indexer cursor: %w", err)
+	}
+
+	network := s.contract.Network().Name
+	report := &dto.IndexerReportResponse{
+		Network:      network,
+		StartBlock:   s.startBlock,
+		ChainOnly:    []dto.IndexedEventResponse{},
+		DatabaseOnly: []dto.UnindexedDiplomaResponse{},
+	}
+	if !ok {
+		return report, nil
+	}
+	report.IndexedBlock = cursor
+
+	report.IndexedEvents, err = s.repo.CountEvents(network)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count events: %w", err)
+	}
+
+	events, err := s.repo.GetChainOnly(network, indexerReportLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load unmatched events: %w", err)
+	}
+	for _, e := range events {
+		report.ChainOnly = append(report.ChainOnly, dto.IndexedEventResponse{
+			ChainDiplomaID: e.ChainDiplomaID,
+			DiplomaHash:    e.DiplomaHash,
+			ArweaveTxID:    e.ArweaveTxID,
+			Owner:          e.Owner,
+			Timestamp:      e.Timestamp,
+			BlockNumber:    e.BlockNumber,
+			TxHash:         e.TxHash,
+		})
+	}
+
+	diplomas, err := s.repo.GetDatabaseOnly(network, s.startBlock, cursor, indexerReportLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load unmatched diplomas: %w", err)
+	}
+	for _, d := range diplomas {
+		if d.BlockNumber == 0 {
+			block, orphan, err := s.locate(d, cursor)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check diploma %s on chain: %w", d.PublicID, err)
+			}
+			if !orphan {
+				continue
+			}
+			d.BlockNumber = block
+		}
+
+		report.DatabaseOnly = append(report.DatabaseOnly, dto.UnindexedDiplomaResponse{
+			DiplomaID:     d.PublicID,
+			DiplomaHash:   d.Hash,
+			PolygonTxHash: d.PolygonTxID,
+			BlockNumber:   d.BlockNumber,
+			UniversityID:  d.UniversityID.String(),
+		})
+	}
+
+	return report, nil
+}
+
+// locate places a diploma anchored before block numbers were recorded by the
+// receipt of its transaction, and reports whether it belongs on the database
+// only side: mined in the indexed range, failed, or unknown to the chain. When
+// the transaction is gone the diploma hash is looked up instead, since it may
+// have been stored by another transaction.
+func (s *indexerService) locate(d models.Diploma, cursor uint64) (uint64, bool, error) {
+
+	receipt, err := s.contract.GetTransactionReceipt(common.HexToHash(d.PolygonTxID))
+	if errors.Is(err, ethereum.NotFound) {
+		stored, arweaveTxID, err := s.contract.VerifyDiploma(d.Hash)
+		if err != nil {
+			return 0, false, err
+		}
+		return 0, !stored || arweaveTxID != d.ArweaveTxID, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	block := receipt.BlockNumber.Uint64()
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return block, true, nil
+	}
+	return block, block >= s.startBlock && block <= cursor, nil
+}
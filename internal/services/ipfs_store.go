@@ -0,0 +1,152 @@
+package services
+
+import (
+	"BlockCertify/internal/config"
+	apperrors "BlockCertify/internal/pkg/errors"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+const storeRequestTimeout = 60 * time.Second
+
+// ipfsStore pins diplomas on an IPFS node through the Kubo RPC API and links
+// them through a public gateway. IPFS keeps no metadata, so each PDF is
+// wrapped in a directory as <hash>.pdf and the content address is the path
+// <directory CIDv1>/<hash>.pdf; the name plays the role of the File-Hash tag
+// and survives encryption, where hashing the data would not.
+type ipfsStore struct {
+	apiURL     string
+	gatewayURL string
+	client     *http.Client
+}
+
+func NewIPFSStore(cfg config.IPFSConfig) DocumentStore {
+	return &ipfsStore{
+		apiURL:     cfg.APIURL,
+		gatewayURL: cfg.GatewayURL,
+		client:     &http.Client{Timeout: storeRequestTimeout},
+	}
+}
+
+func (s *ipfsStore) Name() string {
+	return config.StorageIPFS
+}
+
+func (s *ipfsStore) URL(cid string) string {
+	return fmt.Sprintf("%s/ipfs/%s", s.gatewayURL, cid)
+}
+
+func (s *ipfsStore) Upload(filePath, fileHash string) (string, error) {
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", apperrors.New(apperrors.ErrStorageUploadFailed, "Failed to read file", err)
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	name := fileHash + ".pdf"
+	part, err := form.CreateFormFile("file", name)
+	if err != nil {
+		return "", apperrors.New(apperrors.ErrStorageUploadFailed, "Failed to build IPFS upload", err)
+	}
+	if _, err := part.Write(data); err != nil {
+		return "", apperrors.New(apperrors.ErrStorageUploadFailed, "Failed to build IPFS upload", err)
+	}
+	if err := form.Close(); err != nil {
+		return "", apperrors.New(apperrors.ErrStorageUploadFailed, "Failed to build IPFS upload", err)
+	}
+
+	query := url.Values{"cid-version": {"1"}, "pin": {"true"}, "wrap-with-directory": {"true"}}
+	resp, err := s.client.Post(s.apiURL+"/api/v0/add?"+query.Encode(), form.FormDataContentType(), &body)
+	if err != nil {
+		return "", apperrors.New(apperrors.ErrStorageUploadFailed, "Failed to upload to IPFS", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", apperrors.New(apperrors.ErrStorageUploadFailed, "Failed to upload to IPFS", fmt.Errorf("IPFS node returned %s", resp.Status))
+	}
+
+	// One object per added entry; the wrapping directory has an empty name
+	var directory string
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var added struct {
+			Name string
+			Hash string
+		}
+		if err := decoder.Decode(&added); err == io.EOF {
+			break
+		} else if err != nil {
+			return "", apperrors.New(apperrors.ErrStorageUploadFailed, "Unexpected IPFS add response", err)
+		}
+		if added.Name == "" {
+			directory = added.Hash
+		}
+	}
+	if directory == "" {
+		return "", apperrors.New(apperrors.ErrStorageUploadFailed, "Unexpected IPFS add response", nil)
+	}
+
+	address := directory + "/" + name
+	slog.Info("Pinned diploma on IPFS", "path", address, "hash", fileHash)
+	return address, nil
+}
+
+// GetFileHashTag returns the hash the PDF is named after once the node finds
+// the path. A bare CID has no name, so its data is hashed instead.
+func (s *ipfsStore) GetFileHashTag(address string) (string, error) {
+
+	if !strings.Contains(address, "/") {
+		data, err := s.GetData(address)
+		if err != nil {
+			return "", err
+		}
+		sum := sha256.Sum256(data)
+		return hex.EncodeToString(sum[:]), nil
+	}
+
+	resp, err := s.client.Post(s.apiURL+"/api/v0/files/stat?"+url.Values{"arg": {"/ipfs/" + address}}.Encode(), "", nil)
+	if err != nil {
+		return "", apperrors.New(apperrors.ErrVerificationFailed, "Failed to look up IPFS path", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", apperrors.New(apperrors.ErrVerificationFailed, "Failed to look up IPFS path", fmt.Errorf("IPFS node returned %s", resp.Status))
+	}
+
+	return strings.TrimSuffix(path.Base(address), ".pdf"), nil
+}
+
+func (s *ipfsStore) GetData(cid string) ([]byte, error) {
+
+	resp, err := s.client.Post(s.apiURL+"/api/v0/cat?"+url.Values{"arg": {cid}}.Encode(), "", nil)
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrVerificationFailed, "Failed to fetch IPFS data", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, apperrors.New(apperrors.ErrVerificationFailed, "Failed to fetch IPFS data", fmt.Errorf("IPFS node returned %s", resp.Status))
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrVerificationFailed, "Failed to fetch IPFS data", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,287 @@
+package services
+
+import (
+	"BlockCertify/internal/dto"
+	"BlockCertify/internal/models"
+	apperrors "BlockCertify/internal/pkg/errors"
+	"BlockCertify/internal/repositories"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+const issuancePollInterval = 5 * time.Second
+
+// IssuanceService signs storeDiploma for queued diplomas when
+// ISSUANCE_MODE=server, replacing the MetaMask confirmation.
+type IssuanceService interface {
+	Start()
+	ProcessUnfinished()
+	GetIssuance(diplomaID string, universityID uuid.UUID) (*dto.IssuanceResponse, error)
+	Retry(diplomaID string, universityID uuid.UUID) (*dto.IssuanceResponse, error)
+}
+
+type issuanceService struct {
+	Blockchain   BlockchainService
+	transactions TransactionManager
+	repo         repositories.IssuanceRepository
+	diplomaRepo  repositories.DiplomaRepository
+	uniRepo      repositories.UniversityRepository
+	mu           sync.Mutex
+}
+
+func NewIssuanceService(blockchain BlockchainService, transactions TransactionManager, repo repositories.IssuanceRepository, diplomaRepo repositories.DiplomaRepository, uniRepo repositories.UniversityRepository) IssuanceService {
+	return &issuanceService{
+		Blockchain:   blockchain,
+		transactions: transactions,
+		repo:         repo,
+		diplomaRepo:  diplomaRepo,
+		uniRepo:      uniRepo,
+	}
+}
+
+// Start polls for unfinished issuances. Jobs left behind by a restart are
+// picked up on the first tick. It does nothing unless server issuance is enabled.
+func (s *issuanceService) Start() {
+
+	if !s.Blockchain.ServerIssuanceEnabled() {
+		return
+	}
+
+	slog.Info("Server-side issuance enabled")
+
+	go func() {
+		ticker := time.NewTicker(issuancePollInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			s.ProcessUnfinished()
+		}
+	}()
+}
+
+// ProcessUnfinished moves every pending or submitted issuance one step; Start
+// runs it on every poll.
+func (s *issuanceService) ProcessUnfinished() {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	issuances, err := s.repo.GetUnfinished()
+	if err != nil {
+		slog.Error("Failed to load issuances", "err", err)
+		return
+	}
+
+	for i := range issuances {
+		s.process(&issuances[i])
+	}
+}
+
+func (s *issuanceService) process(issuance *models.DiplomaIssuance) {
+
+	diploma, err := s.diplomaRepo.GetByID(issuance.DiplomaID)
+	if err != nil {
+		s.fail(issuance, fmt.Errorf("diploma not found: %w", err))
+		return
+	}
+
+	switch issuance.Status {
+	case models.IssuancePending:
+		s.submit(issuance, diploma)
+	case models.IssuanceSubmitted:
+		s.confirm(issuance, diploma)
+	}
+}
+
+func (s *issuanceService) submit(issuance *models.DiplomaIssuance, diploma *models.Diploma) {
+
+	university, err := s.uniRepo.GetUniversityByID(diploma.UniversityID.String())
+	if err != nil {
+		s.fail(issuance, fmt.Errorf("university not found: %w", err))
+		return
+	}
+
+	// A failed issuance may have been stored after all: its transaction was
+	// mined after the issuance gave up on it
+	if issuance.Attempts > 0 && s.adopt(issuance, diploma) {
+		return
+	}
+
+	issuance.Attempts++
+
+	submitted, err := s.Blockchain.SubmitDiploma(university.YokCode, diploma.Hash, diploma.ArweaveTxID)
+	if err != nil {
+		s.fail(issuance, err)
+		return
+	}
+
+	now := time.Now().UTC()
+	issuance.Status = models.IssuanceSubmitted
+	issuance.Error = ""
+	issuance.TxHash = submitted.TransactionHash
+	issuance.Signer = submitted.Signer
+	issuance.SubmittedAt = &now
+
+	if err := s.repo.Update(issuance); err != nil {
+		slog.Error("Failed to update issuance", "diplomaID", issuance.DiplomaID, "err", err)
+	}
+}
+
+// adopt moves an issuance whose diploma is already on chain, with the same
+// Arweave TxID and through a transaction the backend sent, on to confirm.
+// It returns false when the diploma still has to be submitted.
+func (s *issuanceService) adopt(issuance *models.DiplomaIssuance, diploma *models.Diploma) bool {
+
+	exists, arweaveTxID, err := s.Blockchain.VerifyDiploma(diploma.Hash)
+	if err != nil || !exists || arweaveTxID != diploma.ArweaveTxID {
+		return false
+	}
+
+	txn, err := s.transactions.Latest(models.TxKindStoreDiploma, diploma.Hash)
+	if err != nil || txn.Status == models.TxFailed {
+		return false
+	}
+
+	slog.Info("Diploma is already on chain, confirming its issuance", "diplomaID", diploma.PublicID, "txHash", txn.TxHash)
+
+	if issuance.SubmittedAt == nil {
+		now := time.Now().UTC()
+		issuance.SubmittedAt = &now
+	}
+	issuance.Status = models.IssuanceSubmitted
+	issuance.Error = ""
+	issuance.TxHash = txn.TxHash
+	issuance.Signer = txn.Signer
+	if err := s.repo.Update(issuance); err != nil {
+		slog.Error("Failed to update issuance", "diplomaID", issuance.DiplomaID, "err", err)
+	}
+
+	s.confirm(issuance, diploma)
+	return true
+}
+
+func (s *issuanceService) confirm(issuance *models.DiplomaIssuance, diploma *models.Diploma) {
+
+	// The outbox may have replaced the transaction with a fee-bumped one
+	txn, err := s.transactions.Latest(models.TxKindStoreDiploma, diploma.Hash)
+	if err != nil {
+		s.fail(issuance, err)
+		return
+	}
+
+	switch txn.Status {
+	case models.TxFailed:
+		s.fail(issuance, fmt.Errorf("transaction %s failed: %s", txn.TxHash, txn.Error))
+		return
+	case models.TxPending, models.TxReplaced:
+		if txn.TxHash != issuance.TxHash {
+			issuance.TxHash = txn.TxHash
+			if err := s.repo.Update(issuance); err != nil {
+				slog.Error("Failed to update issuance", "diplomaID", issuance.DiplomaID, "err", err)
+			}
+		}
+		// Not mined yet, check again on the next tick
+		return
+	}
+
+	blockchain, err := onNetwork(s.Blockchain, txn.Network)
+	if err != nil {
+		s.fail(issuance, err)
+		return
+	}
+
+	result, err := blockchain.ConfirmDiplomaTransaction(txn.TxHash, diploma.Hash, diploma.ArweaveTxID)
+	if err != nil {
+		s.fail(issuance, err)
+		return
+	}
+
+	issuance.TxHash = result.TransactionHash
+	now := time.Now().UTC()
+	issuance.Status = models.IssuanceConfirmed
+	issuance.BlockNumber = result.BlockNumber
+	issuance.BlockHash = result.BlockHash
+	issuance.GasUsed = result.GasUsed
+	issuance.ConfirmedAt = &now
+
+	diploma.Network = result.Network
+	diploma.PolygonTxID = result.TransactionHash
+	diploma.PolygonURL = result.ExplorerURL
+	diploma.BlockNumber = result.BlockNumber
+	diploma.BlockHash = result.BlockHash
+	diploma.Finality = models.FinalityConfirming
+	diploma.Timestamp = result.Timestamp
+
+	if err := s.repo.Confirm(issuance, diploma); err != nil {
+		slog.Error("Failed to save issuance receipt", "diplomaID", issuance.DiplomaID, "txHash", issuance.TxHash, "err", err)
+		return
+	}
+
+	slog.Info("Diploma issued", "diplomaID", diploma.PublicID, "txHash", result.TransactionHash, "block", result.BlockNumber)
+}
+
+func (s *issuanceService) fail(issuance *models.DiplomaIssuance, err error) {
+
+	slog.Error("Diploma issuance failed", "diplomaID", issuance.DiplomaID, "err", err)
+
+	issuance.Status = models.IssuanceFailed
+	issuance.Error = err.Error()
+
+	if err := s.repo.Update(issuance); err != nil {
+		slog.Error("Failed to update issuance", "diplomaID", issuance.DiplomaID, "err", err)
+	}
+}
+
+func (s *issuanceService) GetIssuance(diplomaID string, universityID uuid.UUID) (*dto.IssuanceResponse, error) {
+
+	diploma, err := s.diplomaRepo.GetByDiplomaIDForUniversity(diplomaID, universityID)
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrDiplomaNotFound, "Diploma not found", err)
+	}
+
+	issuance, err := s.repo.GetByDiplomaID(diploma.ID)
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrDiplomaNotFound, "Diploma was not issued by the backend", err)
+	}
+
+	return toIssuanceResponse(diploma.PublicID, issuance), nil
+}
+
+// Retry puts a failed issuance back in the queue.
+func (s *issuanceService) Retry(diplomaID string, universityID uuid.UUID) (*dto.IssuanceResponse, error) {
+
+	diploma, err := s.diplomaRepo.GetByDiplomaIDForUniversity(diplomaID, universityID)
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrDiplomaNotFound, "Diploma not found", err)
+	}
+
+	reset, err := s.repo.ResetFailed(diploma.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reset issuance: %w", err)
+	}
+	if reset == 0 {
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "Issuance has not failed", nil)
+	}
+
+	return s.GetIssuance(diplomaID, universityID)
+}
+
+func toIssuanceResponse(publicID string, issuance *models.DiplomaIssuance) *dto.IssuanceResponse {
+	return &dto.IssuanceResponse{
+		DiplomaID:   publicID,
+		Status:      string(issuance.Status),
+		Attempts:    issuance.Attempts,
+		Error:       issuance.Error,
+		Signer:      issuance.Signer,
+		TxHash:      issuance.TxHash,
+		BlockNumber: issuance.BlockNumber,
+		BlockHash:   issuance.BlockHash,
+		GasUsed:     issuance.GasUsed,
+		SubmittedAt: issuance.SubmittedAt,
+		ConfirmedAt: issuance.ConfirmedAt,
+	}
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"BlockCertify/internal/config"
+	apperrors "BlockCertify/internal/pkg/errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// localStore keeps diplomas in a directory as <hash>.pdf. It is meant for
+// development, tests and deployments that archive the directory themselves;
+// nothing about it is permanent.
+type localStore struct {
+	dir       string
+	publicURL string
+}
+
+func NewLocalStore(cfg config.LocalStorageConfig) DocumentStore {
+	return &localStore{
+		dir:       cfg.Dir,
+		publicURL: cfg.PublicURL,
+	}
+}
+
+func (s *localStore) Name() string {
+	return config.StorageLocal
+}
+
+func (s *localStore) URL(name string) string {
+	return fmt.Sprintf("%s/%s", s.publicURL, name)
+}
+
+// path maps a content address to its file, refusing anything that would
+// leave the directory.
+func (s *localStore) path(name string) (string, error) {
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("invalid document name %q", name)
+	}
+	return filepath.Join(s.dir, name), nil
+}
+
+func (s *localStore) AddressFor(fileHash string) string {
+	return fileHash + ".pdf"
+}
+
+func (s *localStore) Upload(filePath, fileHash string) (string, error) {
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", apperrors.New(apperrors.ErrStorageUploadFailed, "Failed to read file", err)
+	}
+
+	name := s.AddressFor(fileHash)
+	path, err := s.path(name)
+	if err != nil {
+		return "", apperrors.New(apperrors.ErrStorageUploadFailed, "Invalid diploma hash", err)
+	}
+
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return "", apperrors.New(apperrors.ErrStorageUploadFailed, "Failed to create storage directory", err)
+	}
+
+	// Write to a temporary file first so a crash never leaves half a PDF
+	// under the final name
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return "", apperrors.New(apperrors.ErrStorageUploadFailed, "Failed to store file", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return "", apperrors.New(apperrors.ErrStorageUploadFailed, "Failed to store file", err)
+	}
+
+	return name, nil
+}
+
+// GetFileHashTag returns the hash the file was stored under.
+func (s *localStore) GetFileHashTag(name string) (string, error) {
+
+	path, err := s.path(name)
+	if err != nil {
+		return "", apperrors.New(apperrors.ErrVerificationFailed, "Invalid document name", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", apperrors.New(apperrors.ErrVerificationFailed, "Stored file not found", err)
+	}
+
+	return strings.TrimSuffix(name, ".pdf"), nil
+}
+
+func (s *localStore) GetData(name string) ([]byte, error) {
+
+	path, err := s.path(name)
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrVerificationFailed, "Invalid document name", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrVerificationFailed, "Failed to read stored file", err)
+	}
+	return data, nil
+}
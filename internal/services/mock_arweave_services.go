@@ -1,8 +1,15 @@
 package services
 
+import (
+	"BlockCertify/internal/config"
+	"fmt"
+	"math/big"
+)
+
 type MockArweaveService struct {
-	TxID string
-	Err  error
+	TxID     string
+	FileHash string
+	Err      error
 }
 
 func NewMockArweaveService(txID string) *MockArweaveService {
@@ -11,6 +18,14 @@ func NewMockArweaveService(txID string) *MockArweaveService {
 	}
 }
 
+func (m *MockArweaveService) Name() string {
+	return config.StorageArweave
+}
+
+func (m *MockArweaveService) URL(txID string) string {
+	return fmt.Sprintf("https://arweave.net/%s", txID)
+}
+
 func (m *MockArweaveService) Upload(filePath, fileHash string) (string, error) {
 	if m.Err != nil {
 		return "", m.Err
@@ -25,3 +40,60 @@ func (m *MockArweaveService) GetFile(txID string) ([]byte, error) {
 	}
 	return []byte("mock pdf data"), nil
 }
+
+func (m *MockArweaveService) GetFileHashTag(string) (string, error) {
+	if m.Err != nil {
+		return "", m.Err
+	}
+	return m.FileHash, nil
+}
+
+func (m *MockArweaveService) GetData(txID string) ([]byte, error) {
+	return m.GetFile(txID)
+}
+
+func (m *MockArweaveService) TransactionStatus(string) (ArweaveTxStatus, error) {
+	if m.Err != nil {
+		return ArweaveTxStatus{}, m.Err
+	}
+	return ArweaveTxStatus{Known: true, Confirmations: 1}, nil
+}
+
+func (m *MockArweaveService) HasData(string) (bool, error) {
+	return m.Err == nil, m.Err
+}
+
+func (m *MockArweaveService) Repost([]byte, bool) error {
+	return m.Err
+}
+
+func (m *MockArweaveService) AnchorAge([]byte) (int, error) {
+	return 0, m.Err
+}
+
+func (m *MockArweaveService) Reupload([]byte) (string, error) {
+	if m.Err != nil {
+		return "", m.Err
+	}
+	return m.TxID, nil
+}
+
+func (m *MockArweaveService) ReleasePayload(string) {}
+
+func (m *MockArweaveService) BundleOf(string) (string, error) {
+	return "", m.Err
+}
+
+func (m *MockArweaveService) WalletBalance() (string, *big.Int, error) {
+	if m.Err != nil {
+		return "", nil, m.Err
+	}
+	return "mock-arweave-wallet", big.NewInt(1_000_000_000_000), nil
+}
+
+func (m *MockArweaveService) Price(int) (*big.Int, error) {
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	return big.NewInt(1_000_000), nil
+}
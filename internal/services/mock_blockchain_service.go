@@ -1,7 +1,11 @@
 package services
 
 import (
+	"BlockCertify/internal/config"
 	"BlockCertify/internal/dto"
+	"BlockCertify/internal/pkg/merkle"
+	"math/big"
+	"time"
 )
 
 type MockBlockchainService struct{}
@@ -20,3 +24,85 @@ func (m *MockBlockchainService) StoreDiploma(_, _ string) (*dto.BlockchainResult
 		BlockNumber:     0,
 	}, nil
 }
+
+func (m *MockBlockchainService) ConfirmDiplomaTransaction(txHash, _, _ string) (*dto.BlockchainResult, error) {
+	return &dto.BlockchainResult{
+		TransactionHash: txHash,
+		BlockNumber:     0,
+	}, nil
+}
+
+func (m *MockBlockchainService) RevokeDiploma(_, _ string) (*dto.BlockchainResult, error) {
+	return &dto.BlockchainResult{
+		TransactionHash: "DEBUG_FAKE_POLYGON_TX",
+		BlockNumber:     0,
+	}, nil
+}
+
+func (m *MockBlockchainService) RevocationOnChainEnabled() bool {
+	return false
+}
+
+func (m *MockBlockchainService) AnchorMerkleRoot(_ merkle.Hash, _ int) (*dto.BlockchainResult, error) {
+	return &dto.BlockchainResult{
+		TransactionHash: "DEBUG_FAKE_POLYGON_TX",
+		BlockNumber:     0,
+		Timestamp:       time.Now().UTC(),
+	}, nil
+}
+
+func (m *MockBlockchainService) GetMerkleRootTimestamp(merkle.Hash) (time.Time, bool, error) {
+	return time.Time{}, false, nil
+}
+
+func (m *MockBlockchainService) MerkleAnchoringEnabled() bool {
+	return false
+}
+
+func (m *MockBlockchainService) SubmitDiploma(_, _, _ string) (*dto.SubmittedTransaction, error) {
+	return &dto.SubmittedTransaction{
+		TransactionHash: "DEBUG_FAKE_POLYGON_TX",
+	}, nil
+}
+
+func (m *MockBlockchainService) ServerIssuanceEnabled() bool {
+	return false
+}
+
+func (m *MockBlockchainService) GetTransactionBlock(string) (uint64, string, bool, error) {
+	return 0, "", true, nil
+}
+
+func (m *MockBlockchainService) LatestBlockNumber() (uint64, error) {
+	return 0, nil
+}
+
+func (m *MockBlockchainService) GetChainRecord(string) (*dto.ChainDiplomaRecord, error) {
+	return &dto.ChainDiplomaRecord{}, nil
+}
+
+func (m *MockBlockchainService) Network() config.NetworkProfile {
+	return config.NetworkProfile{
+		Name:          "mock",
+		ExplorerTxURL: "https://amoy.polygonscan.com/tx/%s",
+	}
+}
+
+func (m *MockBlockchainService) Networks() []string {
+	return []string{"mock"}
+}
+
+func (m *MockBlockchainService) ForNetwork(string) (BlockchainService, error) {
+	return m, nil
+}
+
+func (m *MockBlockchainService) Wallet() (*ChainWallet, error) {
+	return &ChainWallet{
+		Balance:    big.NewInt(1e18),
+		MinBalance: big.NewInt(3e16),
+	}, nil
+}
+
+func (m *MockBlockchainService) DiplomaCost() (*big.Int, error) {
+	return big.NewInt(1e15), nil
+}
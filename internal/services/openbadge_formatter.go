@@ -0,0 +1,115 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+const openBadgeContext = "https://purl.imsglobal.org/spec/ob/v3p0/context-3.0.3.json"
+
+// achievementNamespace derives stable Achievement IDs, so every graduate of a
+// programme points to the same Achievement.
+var achievementNamespace = uuid.Must(uuid.FromString("0f4c7a2e-8a53-4c43-9d1e-6b7c2f3a9e10"))
+
+// openBadgeFormatter exports an Open Badges 3.0 OpenBadgeCredential.
+//
+//	Faculty        -> achievement.creator (sub-profile of the university)
+//	Department     -> achievement.name / fieldOfStudy
+//	GraduationYear -> credentialSubject.term
+//	YokCode        -> issuer.otherIdentifier
+type openBadgeFormatter struct{}
+
+func NewOpenBadgeFormatter() CredentialFormatter {
+	return openBadgeFormatter{}
+}
+
+func (openBadgeFormatter) Name() string {
+	return "ob3"
+}
+
+func (openBadgeFormatter) Build(data CredentialData) map[string]any {
+
+	diploma := data.Diploma
+	meta := diploma.MetaData
+	validFrom := diploma.Timestamp.UTC().Format(time.RFC3339)
+
+	issuer := map[string]any{
+		"id":   data.IssuerDID,
+		"type": []any{"Profile"},
+		"name": data.University.Name,
+		"otherIdentifier": []any{
+			map[string]any{
+				"type":           "IdentifierEntry",
+				"identifier":     data.University.YokCode,
+				"identifierType": "identifier",
+			},
+		},
+	}
+
+	achievementKey := strings.Join([]string{data.University.YokCode, meta.Faculty, meta.Department}, "|")
+
+	achievement := map[string]any{
+		"id":              "urn:uuid:" + uuid.NewV5(achievementNamespace, achievementKey).String(),
+		"type":            []any{"Achievement"},
+		"achievementType": "Diploma",
+		"name":            degreeTitle(meta),
+		"description":     fmt.Sprintf("%s, %s, %s", meta.Department, meta.Faculty, data.University.Name),
+		"fieldOfStudy":    meta.Department,
+		"criteria": map[string]any{
+			"narrative": fmt.Sprintf("Completed the %s programme of the %s.", meta.Department, meta.Faculty),
+		},
+		"creator": map[string]any{
+			"id":   data.IssuerDID,
+			"type": []any{"Profile"},
+			"name": meta.Faculty,
+			"parentOrg": map[string]any{
+				"id":   data.IssuerDID,
+				"type": []any{"Profile"},
+				"name": data.University.Name,
+			},
+		},
+	}
+
+	// OB3 wants the recipient identified; hash the email rather than leak it
+	emailHash := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(meta.Email))))
+
+	subject := map[string]any{
+		"type": []any{"AchievementSubject"},
+		"identifier": []any{
+			map[string]any{
+				"type":         "IdentityObject",
+				"identityType": "emailAddress",
+				"hashed":       true,
+				"identityHash": "sha256$" + hex.EncodeToString(emailHash[:]),
+			},
+		},
+		"achievement": achievement,
+		"term":        strconv.Itoa(meta.GraduationYear),
+	}
+	if meta.StudentNumber != "" {
+		subject["identifier"] = append(subject["identifier"].([]any), map[string]any{
+			"type":         "IdentityObject",
+			"identityType": "studentId",
+			"hashed":       false,
+			"identityHash": meta.StudentNumber,
+		})
+	}
+
+	return map[string]any{
+		"@context":          []any{credentialsContextV2, openBadgeContext},
+		"id":                credentialID(diploma),
+		"type":              []any{"VerifiableCredential", "OpenBadgeCredential"},
+		"name":              degreeTitle(meta),
+		"issuer":            issuer,
+		"validFrom":         validFrom,
+		"awardedDate":       validFrom,
+		"credentialSubject": subject,
+		"evidence":          credentialEvidence(data),
+	}
+}
@@ -0,0 +1,334 @@
+package services
+
+import (
+	"BlockCertify/internal/config"
+	"BlockCertify/internal/dto"
+	"BlockCertify/internal/models"
+	apperrors "BlockCertify/internal/pkg/errors"
+	"BlockCertify/internal/pkg/merkle"
+	"BlockCertify/internal/repositories"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+const reconciliationPageSize = 100
+
+// ReconciliationService audits drift between Postgres, the contract and the
+// document stores. Each job walks every diploma of a university and records
+// one mismatch per disagreement; with repair enabled it also fills in a
+// missing PolygonTxID or ArweaveURL when the other stores know the value.
+type ReconciliationService interface {
+	Start()
+	StartJob(req dto.ReconciliationRequest, actor *models.User, universityID uuid.UUID) (*dto.ReconciliationJobResponse, error)
+	GetJob(jobID string, universityID uuid.UUID) (*dto.ReconciliationJobResponse, error)
+	ListJobs(universityID uuid.UUID) ([]dto.ReconciliationJobResponse, error)
+}
+
+type reconciliationService struct {
+	Documents  *DocumentStores
+	Blockchain BlockchainService
+	repo       repositories.ReconciliationRepository
+	mu         sync.Mutex
+}
+
+func NewReconciliationService(documents *DocumentStores, blockchain BlockchainService, repo repositories.ReconciliationRepository) ReconciliationService {
+	return &reconciliationService{
+		Documents:  documents,
+		Blockchain: blockchain,
+		repo:       repo,
+	}
+}
+
+// Start closes jobs left running by a previous process.
+func (s *reconciliationService) Start() {
+	failed, err := s.repo.FailRunningJobs("interrupted by a restart")
+	if err != nil {
+		slog.Error("Failed to close interrupted reconciliation jobs", "err", err)
+		return
+	}
+	if failed > 0 {
+		slog.Warn("Closed interrupted reconciliation jobs", "count", failed)
+	}
+}
+
+// StartJob records a new job and runs it in the background. Jobs run one at
+// a time to keep the load on the RPC and Arweave gateways bounded.
+func (s *reconciliationService) StartJob(req dto.ReconciliationRequest, actor *models.User, universityID uuid.UUID) (*dto.ReconciliationJobResponse, error) {
+
+	job := &models.ReconciliationJob{
+		ID:           uuid.Must(uuid.NewV7()),
+		UniversityID: universityID,
+		CreatedBy:    actor.ID,
+		Status:       models.ReconciliationRunning,
+		Repair:       req.Repair,
+	}
+
+	if err := s.repo.CreateJob(job); err != nil {
+		return nil, fmt.Errorf("failed to create reconciliation job: %w", err)
+	}
+
+	go s.run(job)
+
+	return toReconciliationResponse(job), nil
+}
+
+func (s *reconciliationService) run(job *models.ReconciliationJob) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	slog.Info("Reconciliation started", "jobID", job.ID, "universityID", job.UniversityID, "repair", job.Repair)
+
+	err := s.walk(job)
+
+	now := time.Now().UTC()
+	job.FinishedAt = &now
+	job.Status = models.ReconciliationCompleted
+	if err != nil {
+		job.Status = models.ReconciliationFailed
+		job.Error = err.Error()
+	}
+
+	if err := s.repo.UpdateJob(job); err != nil {
+		slog.Error("Failed to update reconciliation job", "jobID", job.ID, "err", err)
+	}
+
+	slog.Info("Reconciliation finished", "jobID", job.ID, "status", job.Status,
+		"checked", job.Checked, "mismatches", job.Mismatches, "repaired", job.Repaired)
+}
+
+func (s *reconciliationService) walk(job *models.ReconciliationJob) error {
+
+	after := uuid.Nil
+	for {
+		diplomas, err := s.repo.GetDiplomas(job.UniversityID, after, reconciliationPageSize)
+		if err != nil {
+			return fmt.Errorf("failed to load diplomas: %w", err)
+		}
+		if len(diplomas) == 0 {
+			return nil
+		}
+
+		var mismatches []models.ReconciliationMismatch
+		for i := range diplomas {
+			found := s.reconcile(&diplomas[i], job.UniversityID, job.Repair)
+			for j := range found {
+				found[j].ID = uuid.Must(uuid.NewV7())
+				found[j].JobID = job.ID
+				if found[j].Repaired {
+					job.Repaired++
+				}
+			}
+			mismatches = append(mismatches, found...)
+		}
+
+		if err := s.repo.AddMismatches(mismatches); err != nil {
+			return fmt.Errorf("failed to save mismatches: %w", err)
+		}
+
+		job.Checked += len(diplomas)
+		job.Mismatches += len(mismatches)
+		if err := s.repo.UpdateJob(job); err != nil {
+			return fmt.Errorf("failed to update job: %w", err)
+		}
+
+		after = diplomas[len(diplomas)-1].ID
+	}
+}
+
+// reconcile checks one diploma against the contract and its document store and repairs
+// missing database fields when asked to. universityID is the university
+// being audited, which a diploma without one is linked to on repair.
+func (s *reconciliationService) reconcile(diploma *models.Diploma, universityID uuid.UUID, repair bool) []models.ReconciliationMismatch {
+
+	var mismatches []models.ReconciliationMismatch
+	report := func(kind models.MismatchKind, expected, actual, details string) *models.ReconciliationMismatch {
+		mismatches = append(mismatches, models.ReconciliationMismatch{
+			DiplomaID:   diploma.ID,
+			PublicID:    diploma.PublicID,
+			DiplomaHash: diploma.Hash,
+			Kind:        kind,
+			Expected:    expected,
+			Actual:      actual,
+			Details:     details,
+		})
+		return &mismatches[len(mismatches)-1]
+	}
+
+	var repairColumns []string
+	arweaveTxID := diploma.ArweaveTxID
+
+	if diploma.UniversityID != universityID {
+		m := report(models.MismatchUniversityMissing, universityID.String(), "", "diploma is hidden from the university's admins")
+		if repair {
+			diploma.UniversityID = universityID
+			repairColumns = append(repairColumns, "UniversityID")
+			m.Actual = universityID.String()
+			m.Repaired = true
+		}
+	}
+
+	// Polygon, on the network the diploma was anchored on
+	blockchain, err := onNetwork(s.Blockchain, diploma.Network)
+	if err != nil {
+		report(models.MismatchReconciliationError, diploma.Network, "", fmt.Sprintf("chain: %v", err))
+	} else if diploma.MerkleRoot != "" {
+		if root, err := merkle.ParseHash(diploma.MerkleRoot); err != nil {
+			report(models.MismatchMerkleRoot, "", diploma.MerkleRoot, err.Error())
+		} else if _, anchored, err := blockchain.GetMerkleRootTimestamp(root); err != nil {
+			report(models.MismatchReconciliationError, "", "", fmt.Sprintf("chain: %v", err))
+		} else if !anchored {
+			report(models.MismatchMerkleRoot, diploma.MerkleRoot, "", "root is not anchored on the contract")
+		}
+	} else {
+		record, err := blockchain.GetChainRecord(diploma.Hash)
+		switch {
+		case err != nil:
+			report(models.MismatchReconciliationError, "", "", fmt.Sprintf("chain: %v", err))
+		case !record.Exists:
+			// Diplomas still queued for backend anchoring are not on-chain yet
+			if diploma.PolygonTxID != "" {
+				report(models.MismatchChainMissing, diploma.Hash, "", "verifyDiploma does not know the hash")
+			}
+		default:
+			if record.ArweaveTxID != diploma.ArweaveTxID {
+				report(models.MismatchChainArweave, diploma.ArweaveTxID, record.ArweaveTxID, "")
+				if diploma.ArweaveTxID == "" {
+					arweaveTxID = record.ArweaveTxID
+				}
+			}
+			if record.DiplomaHash != diploma.Hash || record.RecordArweaveTxID != record.ArweaveTxID {
+				report(models.MismatchChainRecord, diploma.Hash+" / "+record.ArweaveTxID, record.DiplomaHash+" / "+record.RecordArweaveTxID,
+					fmt.Sprintf("stored record on %s disagrees with verifyDiploma", record.ContractAddress))
+			}
+			if diploma.PolygonTxID == "" {
+				m := report(models.MismatchPolygonTxMissing, "", "", "diploma is on-chain but has no transaction hash")
+				if repair {
+					m.Repaired = s.repairPolygonTx(blockchain.Network(), diploma, m)
+					if m.Repaired {
+						repairColumns = append(repairColumns, "Network", "PolygonTxID", "PolygonURL", "BlockNumber", "BlockHash")
+					}
+				}
+			}
+		}
+	}
+
+	// Document store
+	var (
+		store   DocumentStore
+		address string
+	)
+	if arweaveTxID == "" {
+		report(models.MismatchArweaveMissing, "", "", "no document reference")
+	} else if store, address, err = s.Documents.Resolve(arweaveTxID); err != nil {
+		report(models.MismatchReconciliationError, arweaveTxID, "", fmt.Sprintf("storage: %v", err))
+	} else if tagHash, err := store.GetFileHashTag(address); err != nil {
+		report(models.MismatchArweaveMissing, arweaveTxID, "", err.Error())
+	} else if tagHash != diploma.Hash {
+		report(models.MismatchArweaveHash, diploma.Hash, tagHash, fmt.Sprintf("file hash recorded by %s for %s", store.Name(), address))
+	}
+
+	if diploma.ArweaveURL == "" {
+		m := report(models.MismatchArweaveURLMissing, "", "", "")
+		if repair && store != nil {
+			diploma.ArweaveTxID = arweaveTxID
+			diploma.ArweaveURL = store.URL(address)
+			diploma.Storage = store.Name()
+			diploma.ContentAddress = address
+			repairColumns = append(repairColumns, "ArweaveTxID", "ArweaveURL", "Storage", "ContentAddress")
+			m.Actual = diploma.ArweaveURL
+			m.Repaired = true
+		}
+	}
+
+	if len(repairColumns) > 0 {
+		if err := s.repo.RepairDiploma(diploma, repairColumns...); err != nil {
+			slog.Error("Failed to repair diploma", "diplomaID", diploma.PublicID, "err", err)
+			for i := range mismatches {
+				mismatches[i].Repaired = false
+			}
+		}
+	}
+
+	return mismatches
+}
+
+// repairPolygonTx takes the transaction hash from the indexed DiplomaStored
+// event, since the contract itself does not keep it.
+func (s *reconciliationService) repairPolygonTx(network config.NetworkProfile, diploma *models.Diploma, m *models.ReconciliationMismatch) bool {
+
+	event, err := s.repo.GetStoredEvent(network.Name, diploma.Hash)
+	if err != nil {
+		m.Details += "; no indexed DiplomaStored event to repair from"
+		return false
+	}
+
+	diploma.Network = network.Name
+	diploma.PolygonTxID = event.TxHash
+	diploma.PolygonURL = network.TxURL(event.TxHash)
+	diploma.BlockNumber = event.BlockNumber
+	diploma.BlockHash = event.BlockHash
+	m.Actual = event.TxHash
+	return true
+}
+
+func (s *reconciliationService) GetJob(jobID string, universityID uuid.UUID) (*dto.ReconciliationJobResponse, error) {
+
+	id, err := uuid.FromString(jobID)
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "Invalid job ID", err)
+	}
+
+	job, err := s.repo.GetJob(id, universityID)
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrReconciliationNotFound, "Reconciliation job not found", err)
+	}
+
+	response := toReconciliationResponse(job)
+	response.Items = make([]dto.ReconciliationMismatchResponse, 0, len(job.Items))
+	for _, item := range job.Items {
+		response.Items = append(response.Items, dto.ReconciliationMismatchResponse{
+			DiplomaID:   item.PublicID,
+			DiplomaHash: item.DiplomaHash,
+			Kind:        string(item.Kind),
+			Expected:    item.Expected,
+			Actual:      item.Actual,
+			Details:     item.Details,
+			Repaired:    item.Repaired,
+		})
+	}
+
+	return response, nil
+}
+
+func (s *reconciliationService) ListJobs(universityID uuid.UUID) ([]dto.ReconciliationJobResponse, error) {
+
+	jobs, err := s.repo.ListJobs(universityID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reconciliation jobs: %w", err)
+	}
+
+	responses := make([]dto.ReconciliationJobResponse, 0, len(jobs))
+	for i := range jobs {
+		responses = append(responses, *toReconciliationResponse(&jobs[i]))
+	}
+	return responses, nil
+}
+
+func toReconciliationResponse(job *models.ReconciliationJob) *dto.ReconciliationJobResponse {
+	return &dto.ReconciliationJobResponse{
+		JobID:      job.ID.String(),
+		Status:     string(job.Status),
+		Repair:     job.Repair,
+		Error:      job.Error,
+		Checked:    job.Checked,
+		Mismatches: job.Mismatches,
+		Repaired:   job.Repaired,
+		CreatedAt:  job.CreatedAt,
+		FinishedAt: job.FinishedAt,
+	}
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"BlockCertify/internal/config"
+	apperrors "BlockCertify/internal/pkg/errors"
+	"BlockCertify/internal/pkg/sigv4"
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+)
+
+const s3FileHashHeader = "X-Amz-Meta-File-Hash"
+
+// s3Store keeps diplomas in an S3-compatible bucket, addressed path-style so
+// MinIO and other self-hosted stores work without DNS per bucket. The content
+// address is the object key; the diploma hash is kept as object metadata.
+type s3Store struct {
+	cfg    config.S3Config
+	creds  sigv4.Credentials
+	client *http.Client
+}
+
+func NewS3Store(cfg config.S3Config) DocumentStore {
+	return &s3Store{
+		cfg: cfg,
+		creds: sigv4.Credentials{
+			AccessKeyID:     cfg.AccessKeyID,
+			SecretAccessKey: cfg.SecretAccessKey,
+		},
+		client: &http.Client{Timeout: storeRequestTimeout},
+	}
+}
+
+func (s *s3Store) Name() string {
+	return config.StorageS3
+}
+
+func (s *s3Store) objectURL(key string) string {
+	return fmt.Sprintf("%s/%s/%s", s.cfg.Endpoint, s.cfg.Bucket, key)
+}
+
+func (s *s3Store) URL(key string) string {
+	if s.cfg.PublicURL != "" {
+		return fmt.Sprintf("%s/%s", s.cfg.PublicURL, key)
+	}
+	return s.objectURL(key)
+}
+
+func (s *s3Store) AddressFor(fileHash string) string {
+	return s.cfg.Prefix + fileHash + ".pdf"
+}
+
+// Upload stores the PDF under its hash, so retrying an upload overwrites the
+// same object instead of leaving a copy behind.
+func (s *s3Store) Upload(filePath, fileHash string) (string, error) {
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", apperrors.New(apperrors.ErrStorageUploadFailed, "Failed to read file", err)
+	}
+
+	key := s.AddressFor(fileHash)
+
+	req, err := http.NewRequest(http.MethodPut, s.objectURL(key), bytes.NewReader(data))
+	if err != nil {
+		return "", apperrors.New(apperrors.ErrStorageUploadFailed, "Failed to build S3 upload", err)
+	}
+	req.Header.Set("Content-Type", "application/pdf")
+	req.Header.Set(s3FileHashHeader, fileHash)
+
+	resp, err := s.do(req, sigv4.HashHex(data))
+	if err != nil {
+		return "", apperrors.New(apperrors.ErrStorageUploadFailed, "Failed to upload to S3", err)
+	}
+	resp.Body.Close()
+
+	slog.Info("Stored diploma in S3", "bucket", s.cfg.Bucket, "key", key)
+	return key, nil
+}
+
+// GetFileHashTag returns the file-hash metadata written by Upload.
+func (s *s3Store) GetFileHashTag(key string) (string, error) {
+
+	req, err := http.NewRequest(http.MethodHead, s.objectURL(key), nil)
+	if err != nil {
+		return "", apperrors.New(apperrors.ErrVerificationFailed, "Failed to build S3 request", err)
+	}
+
+	resp, err := s.do(req, sigv4.EmptyPayloadHash)
+	if err != nil {
+		return "", apperrors.New(apperrors.ErrVerificationFailed, "Failed to fetch S3 object metadata", err)
+	}
+	resp.Body.Close()
+
+	hash := resp.Header.Get(s3FileHashHeader)
+	if hash == "" {
+		return "", apperrors.New(apperrors.ErrVerificationFailed, "S3 object has no file-hash metadata", nil)
+	}
+	return hash, nil
+}
+
+func (s *s3Store) GetData(key string) ([]byte, error) {
+
+	req, err := http.NewRequest(http.MethodGet, s.objectURL(key), nil)
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrVerificationFailed, "Failed to build S3 request", err)
+	}
+
+	resp, err := s.do(req, sigv4.EmptyPayloadHash)
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrVerificationFailed, "Failed to fetch S3 object", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrVerificationFailed, "Failed to fetch S3 object", err)
+	}
+	return data, nil
+}
+
+// do signs and sends req and turns any non-2xx answer into an error.
+func (s *s3Store) do(req *http.Request, payloadHash string) (*http.Response, error) {
+
+	sigv4.Sign(req, s.creds, s.cfg.Region, "s3", payloadHash, time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		return nil, fmt.Errorf("S3 returned %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return resp, nil
+}
@@ -0,0 +1,657 @@
+package services
+
+import (
+	"BlockCertify/internal/config"
+	"BlockCertify/internal/dto"
+	"BlockCertify/internal/models"
+	apperrors "BlockCertify/internal/pkg/errors"
+	"BlockCertify/internal/repositories"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/gofrs/uuid/v5"
+)
+
+const (
+	outboxPollInterval = 5 * time.Second
+	outboxWaitInterval = 2 * time.Second
+
+	// selfTransferGas is what an empty transfer to an account costs
+	selfTransferGas = 21000
+)
+
+// ErrTransactionPending is returned by Wait when the transaction was sent but
+// not mined in time. The outbox keeps tracking it.
+var ErrTransactionPending = errors.New("transaction is still pending")
+
+// TransactionManager is the outbox for every transaction the backend signs.
+// Nonces are allocated from the database instead of PendingNonceAt, so
+// concurrent sends never collide, and the signed transactions are stored
+// before they are broadcast so a restart picks them up again. Transactions
+// that stay unmined for too long are replaced with higher EIP-1559 fees, and
+// ones the node keeps rejecting fail and give their nonce to a self-transfer.
+type TransactionManager interface {
+	Start()
+	ProcessPending()
+	Send(network string, kind models.TxKind, reference, privateKeyHex string, call *repositories.ContractCall) (*models.ChainTransaction, error)
+	Wait(txn *models.ChainTransaction, timeout time.Duration) (*models.ChainTransaction, error)
+	Latest(kind models.TxKind, reference string) (*models.ChainTransaction, error)
+	GetAnchoringStatus(diplomaID string, universityID uuid.UUID) (*dto.AnchoringStatusResponse, error)
+}
+
+type transactionManager struct {
+	ledgers     *repositories.Ledgers
+	repo        repositories.OutboxRepository
+	diplomaRepo repositories.DiplomaRepository
+	stuckAfter  time.Duration
+	bumpPercent int64
+	maxFeeCap   *big.Int
+	// maxRejections is how often the node may refuse a transaction before
+	// it fails
+	maxRejections int
+
+	keysMu sync.RWMutex
+	keys   map[string]string // signer address -> private key, to re-sign replacements
+	mu     sync.Mutex
+}
+
+func NewTransactionManager(cfg *config.Config, ledgers *repositories.Ledgers, repo repositories.OutboxRepository, diplomaRepo repositories.DiplomaRepository) TransactionManager {
+	maxFeeGwei, _ := new(big.Float).SetString(cfg.Blockchain.TxMaxFeeGwei)
+	maxFeeCap := new(big.Int)
+	maxFeeGwei.Mul(maxFeeGwei, big.NewFloat(1e9)).Int(maxFeeCap)
+
+	m := &transactionManager{
+		ledgers:     ledgers,
+		repo:        repo,
+		diplomaRepo: diplomaRepo,
+		stuckAfter:  cfg.Blockchain.TxStuckAfter,
+		bumpPercent: int64(cfg.Blockchain.TxFeeBumpPercent),
+		maxFeeCap:   maxFeeCap,
+		keys:        make(map[string]string),
+
+		maxRejections: cfg.Blockchain.TxMaxRejections,
+	}
+
+	m.addKey(cfg.Blockchain.PrivateKey)
+	for _, key := range cfg.Blockchain.UniversityPrivateKeys {
+		m.addKey(key)
+	}
+
+	return m
+}
+
+func (m *transactionManager) addKey(privateKeyHex string) (common.Address, error) {
+	address, err := repositories.SignerAddress(privateKeyHex)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	m.keysMu.Lock()
+	m.keys[address.Hex()] = privateKeyHex
+	m.keysMu.Unlock()
+
+	return address, nil
+}
+
+func (m *transactionManager) key(signer string) (string, bool) {
+	m.keysMu.RLock()
+	defer m.keysMu.RUnlock()
+	key, ok := m.keys[signer]
+	return key, ok
+}
+
+// Start tracks pending transactions until they are mined, replaced or failed.
+func (m *transactionManager) Start() {
+	go func() {
+		ticker := time.NewTicker(outboxPollInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			m.ProcessPending()
+		}
+	}()
+}
+
+// Send signs a contract call on network (the active one when empty) with the
+// next free nonce of the key, stores it in the outbox and broadcasts it. A
+// broadcast error is not fatal: the transaction keeps its nonce and is
+// re-sent on the next poll, until the node has rejected it maxRejections
+// times.
+func (m *transactionManager) Send(network string, kind models.TxKind, reference, privateKeyHex string, call *repositories.ContractCall) (*models.ChainTransaction, error) {
+
+	ledger, err := m.ledgers.Get(network)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := m.addKey(privateKeyHex)
+	if err != nil {
+		return nil, err
+	}
+
+	gas, err := ledger.EstimateGas(signer, call)
+	if err != nil {
+		return nil, err
+	}
+
+	gasFeeCap, gasTipCap, err := ledger.GetFeeData()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fee data: %w", err)
+	}
+
+	chainNonce, err := ledger.PendingNonceAt(signer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nonce: %w", err)
+	}
+
+	txn := &models.ChainTransaction{
+		ID:        uuid.Must(uuid.NewV7()),
+		Kind:      kind,
+		Reference: reference,
+		Status:    models.TxPending,
+		Network:   ledger.Network().Name,
+		Signer:    signer.Hex(),
+		To:        call.To.Hex(),
+		Data:      call.Data,
+		Gas:       gas,
+		GasTipCap: gasTipCap.String(),
+		GasFeeCap: gasFeeCap.String(),
+	}
+
+	err = m.repo.Enqueue(txn, chainNonce, func(txn *models.ChainTransaction) error {
+		return m.sign(ledger, txn, privateKeyHex, gasTipCap, gasFeeCap)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to queue transaction: %w", err)
+	}
+
+	slog.Info("Transaction queued", "network", txn.Network, "kind", kind, "txHash", txn.TxHash, "signer", txn.Signer, "nonce", txn.Nonce)
+
+	if err := m.broadcast(ledger, txn); err != nil {
+		slog.Warn("Broadcast failed, will retry", "txHash", txn.TxHash, "err", err)
+	}
+
+	return txn, nil
+}
+
+func (m *transactionManager) sign(ledger repositories.Ledger, txn *models.ChainTransaction, privateKeyHex string, gasTipCap, gasFeeCap *big.Int) error {
+
+	call := &repositories.ContractCall{
+		To:   common.HexToAddress(txn.To),
+		Data: txn.Data,
+	}
+
+	signed, err := ledger.SignTransaction(privateKeyHex, txn.Nonce, txn.Gas, gasTipCap, gasFeeCap, call)
+	if err != nil {
+		return err
+	}
+
+	raw, err := signed.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	txn.TxHash = signed.Hash().Hex()
+	txn.RawTx = raw
+	txn.GasTipCap = gasTipCap.String()
+	txn.GasFeeCap = gasFeeCap.String()
+	return nil
+}
+
+func (m *transactionManager) broadcast(ledger repositories.Ledger, txn *models.ChainTransaction) error {
+
+	var signed types.Transaction
+	if err := signed.UnmarshalBinary(txn.RawTx); err != nil {
+		return err
+	}
+
+	err := ledger.SendTransaction(&signed)
+	if err != nil && !strings.Contains(err.Error(), "already known") {
+		txn.Error = err.Error()
+		// Only count refusals by the node, not failures to reach it
+		var rpcErr rpc.Error
+		if errors.As(err, &rpcErr) {
+			txn.Rejections++
+		}
+		if updateErr := m.repo.Update(txn); updateErr != nil {
+			slog.Error("Failed to update transaction", "txHash", txn.TxHash, "err", updateErr)
+		}
+		return err
+	}
+
+	now := time.Now().UTC()
+	txn.SentAt = &now
+	txn.Error = ""
+	return m.repo.Update(txn)
+}
+
+// Wait blocks until one of the attempts for the transaction's nonce is mined
+// or they all failed. After timeout it returns ErrTransactionPending.
+func (m *transactionManager) Wait(txn *models.ChainTransaction, timeout time.Duration) (*models.ChainTransaction, error) {
+
+	deadline := time.Now().Add(timeout)
+
+	for {
+		attempts, err := m.repo.GetAttempts(txn.Network, txn.Signer, txn.Nonce)
+		if err != nil {
+			return nil, err
+		}
+
+		if settled, err := settledAttempt(attempts); settled != nil || err != nil {
+			return settled, err
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%w after %s: %s", ErrTransactionPending, timeout, txn.TxHash)
+		}
+		time.Sleep(outboxWaitInterval)
+	}
+}
+
+// settledAttempt returns the mined attempt, an error when every attempt
+// failed, or nil while one of them is still pending. A self-transfer that
+// took the nonce over is not an attempt of the transaction.
+func settledAttempt(attempts []models.ChainTransaction) (*models.ChainTransaction, error) {
+
+	var failed *models.ChainTransaction
+	for i := range attempts {
+		if attempts[i].Kind == models.TxKindReleaseNonce {
+			continue
+		}
+		switch attempts[i].Status {
+		case models.TxMined:
+			return &attempts[i], nil
+		case models.TxPending:
+			return nil, nil
+		case models.TxFailed:
+			failed = &attempts[i]
+		}
+	}
+
+	if failed != nil {
+		return nil, errors.New(failed.Error)
+	}
+	return nil, nil
+}
+
+// Latest returns the most relevant transaction sent for reference: the mined
+// one if any, otherwise the newest.
+func (m *transactionManager) Latest(kind models.TxKind, reference string) (*models.ChainTransaction, error) {
+
+	txns, err := m.repo.GetByReferences([]string{reference})
+	if err != nil {
+		return nil, err
+	}
+
+	var latest *models.ChainTransaction
+	for i := range txns {
+		if txns[i].Kind != kind {
+			continue
+		}
+		if txns[i].Status == models.TxMined {
+			return &txns[i], nil
+		}
+		latest = &txns[i]
+	}
+
+	if latest == nil {
+		return nil, fmt.Errorf("no %s transaction for %s", kind, reference)
+	}
+	return latest, nil
+}
+
+// ProcessPending tracks every pending transaction once; Start runs it on
+// every poll.
+func (m *transactionManager) ProcessPending() {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pending, err := m.repo.GetPending()
+	if err != nil {
+		slog.Error("Failed to load pending transactions", "err", err)
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, txn := range pending {
+		nonceKey := fmt.Sprintf("%s/%s/%d", txn.Network, txn.Signer, txn.Nonce)
+		if seen[nonceKey] {
+			continue
+		}
+		seen[nonceKey] = true
+
+		if err := m.track(txn.Network, txn.Signer, txn.Nonce); err != nil {
+			slog.Error("Failed to track transaction", "network", txn.Network, "signer", txn.Signer, "nonce", txn.Nonce, "err", err)
+		}
+	}
+}
+
+// track settles the attempts for one nonce once any of them is mined, and
+// otherwise re-sends, replaces or gives up on the current one.
+func (m *transactionManager) track(network, signer string, nonce uint64) error {
+
+	ledger, err := m.ledgers.Get(network)
+	if err != nil {
+		return err
+	}
+
+	attempts, err := m.repo.GetAttempts(network, signer, nonce)
+	if err != nil {
+		return err
+	}
+
+	// Read the mined nonce before the receipts, so a transaction mined in
+	// between is not mistaken for a nonce taken by someone else
+	minedNonce, err := ledger.NonceAt(common.HexToAddress(signer))
+	if err != nil {
+		return err
+	}
+
+	for i := range attempts {
+		receipt, err := ledger.GetTransactionReceipt(common.HexToHash(attempts[i].TxHash))
+		if errors.Is(err, ethereum.NotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		return m.settle(attempts, i, receipt)
+	}
+
+	if minedNonce > nonce {
+		for i := range attempts {
+			if attempts[i].Status == models.TxPending || attempts[i].Status == models.TxReplaced {
+				attempts[i].Status = models.TxFailed
+				attempts[i].Error = fmt.Sprintf("nonce %d was used by another transaction", nonce)
+			}
+		}
+		slog.Warn("Transaction nonce taken by another transaction", "network", network, "signer", signer, "nonce", nonce)
+		return m.repo.Settle(attempts)
+	}
+
+	var current *models.ChainTransaction
+	for i := range attempts {
+		if attempts[i].Status == models.TxPending {
+			current = &attempts[i]
+		}
+	}
+	if current == nil {
+		return nil
+	}
+
+	if current.SentAt == nil {
+		if current.Rejections >= m.maxRejections {
+			return m.reject(ledger, attempts, current)
+		}
+		return m.broadcast(ledger, current)
+	}
+
+	if time.Since(*current.SentAt) > m.stuckAfter {
+		return m.replace(ledger, attempts, current)
+	}
+	return nil
+}
+
+// reject fails a transaction the node refused maxRejections times. A
+// rejected replacement hands the nonce back to the attempt it replaced,
+// which the node still holds. Otherwise nothing holds the nonce, and every
+// later transaction of the signer would wait for it forever, so an empty
+// self-transfer takes it over.
+func (m *transactionManager) reject(ledger repositories.Ledger, attempts []models.ChainTransaction, current *models.ChainTransaction) error {
+
+	current.Status = models.TxFailed
+	current.Error = fmt.Sprintf("rejected %d times: %s", current.Rejections, current.Error)
+
+	slog.Error("Transaction rejected by the node, giving up", "kind", current.Kind, "txHash", current.TxHash,
+		"signer", current.Signer, "nonce", current.Nonce, "err", current.Error)
+
+	for i := len(attempts) - 1; i >= 0; i-- {
+		previous := &attempts[i]
+		if previous.ReplacedBy == nil || *previous.ReplacedBy != current.ID || previous.SentAt == nil {
+			continue
+		}
+		// Wait stuckAfter again before the next replacement
+		now := time.Now().UTC()
+		previous.Status = models.TxPending
+		previous.ReplacedBy = nil
+		previous.SentAt = &now
+		return m.repo.Settle(attempts)
+	}
+
+	if current.Kind == models.TxKindReleaseNonce {
+		slog.Error("Nonce is blocked, later transactions of the signer wait until it is used",
+			"network", current.Network, "signer", current.Signer, "nonce", current.Nonce)
+		return m.repo.Update(current)
+	}
+
+	privateKeyHex, ok := m.key(current.Signer)
+	if !ok {
+		return fmt.Errorf("no private key configured for %s", current.Signer)
+	}
+
+	gasFeeCap, gasTipCap, err := ledger.GetFeeData()
+	if err != nil {
+		return fmt.Errorf("failed to get fee data: %w", err)
+	}
+
+	release := &models.ChainTransaction{
+		ID:        uuid.Must(uuid.NewV7()),
+		Kind:      models.TxKindReleaseNonce,
+		Reference: current.TxHash,
+		Status:    models.TxPending,
+		Network:   current.Network,
+		Signer:    current.Signer,
+		Nonce:     current.Nonce,
+		To:        current.Signer,
+		Gas:       selfTransferGas,
+	}
+	if err := m.sign(ledger, release, privateKeyHex, gasTipCap, gasFeeCap); err != nil {
+		return err
+	}
+
+	if err := m.repo.AddRelease(current, release); err != nil {
+		return err
+	}
+
+	slog.Info("Releasing nonce with a self-transfer", "signer", current.Signer, "nonce", current.Nonce, "txHash", release.TxHash)
+
+	return m.broadcast(ledger, release)
+}
+
+func (m *transactionManager) settle(attempts []models.ChainTransaction, mined int, receipt *types.Receipt) error {
+
+	now := time.Now().UTC()
+
+	for i := range attempts {
+		txn := &attempts[i]
+		if i != mined {
+			if txn.Status != models.TxFailed {
+				txn.Status = models.TxReplaced
+			}
+			continue
+		}
+
+		txn.BlockNumber = receipt.BlockNumber.Uint64()
+		txn.BlockHash = receipt.BlockHash.Hex()
+		txn.GasUsed = receipt.GasUsed
+		txn.MinedAt = &now
+		txn.ReplacedBy = nil
+
+		if receipt.Status == types.ReceiptStatusSuccessful {
+			txn.Status = models.TxMined
+			txn.Error = ""
+		} else {
+			txn.Status = models.TxFailed
+			txn.Error = "transaction reverted"
+		}
+
+		slog.Info("Transaction settled", "kind", txn.Kind, "txHash", txn.TxHash, "status", txn.Status, "block", txn.BlockNumber)
+	}
+
+	return m.repo.Settle(attempts)
+}
+
+// replace re-signs a stuck transaction with the same nonce and fees raised by
+// bumpPercent over the highest attempt so far, or to the current suggestion
+// if that is higher.
+func (m *transactionManager) replace(ledger repositories.Ledger, attempts []models.ChainTransaction, current *models.ChainTransaction) error {
+
+	privateKeyHex, ok := m.key(current.Signer)
+	if !ok {
+		return fmt.Errorf("no private key configured for %s", current.Signer)
+	}
+
+	suggestedFeeCap, suggestedTipCap, err := ledger.GetFeeData()
+	if err != nil {
+		return fmt.Errorf("failed to get fee data: %w", err)
+	}
+
+	// A rejected replacement may have offered more than the current attempt
+	oldTipCap, oldFeeCap := new(big.Int), new(big.Int)
+	for i := range attempts {
+		if tipCap, ok := new(big.Int).SetString(attempts[i].GasTipCap, 10); ok && tipCap.Cmp(oldTipCap) > 0 {
+			oldTipCap = tipCap
+		}
+		if feeCap, ok := new(big.Int).SetString(attempts[i].GasFeeCap, 10); ok && feeCap.Cmp(oldFeeCap) > 0 {
+			oldFeeCap = feeCap
+		}
+	}
+
+	gasTipCap := maxBig(m.bump(oldTipCap), suggestedTipCap)
+	gasFeeCap := maxBig(m.bump(oldFeeCap), suggestedFeeCap, gasTipCap)
+
+	if gasFeeCap.Cmp(m.maxFeeCap) > 0 {
+		slog.Warn("Stuck transaction not replaced, fee cap reached",
+			"txHash", current.TxHash, "maxFeePerGas", formatGwei(gasFeeCap), "limit", formatGwei(m.maxFeeCap))
+		return nil
+	}
+
+	replacement := &models.ChainTransaction{
+		ID:        uuid.Must(uuid.NewV7()),
+		Kind:      current.Kind,
+		Reference: current.Reference,
+		Status:    models.TxPending,
+		Network:   current.Network,
+		Signer:    current.Signer,
+		Nonce:     current.Nonce,
+		To:        current.To,
+		Data:      current.Data,
+		Gas:       current.Gas,
+	}
+	if err := m.sign(ledger, replacement, privateKeyHex, gasTipCap, gasFeeCap); err != nil {
+		return err
+	}
+
+	if err := m.repo.AddReplacement(current, replacement); err != nil {
+		return err
+	}
+
+	slog.Info("Replacing stuck transaction",
+		"txHash", current.TxHash, "replacement", replacement.TxHash, "nonce", current.Nonce,
+		"maxFeePerGas", formatGwei(gasFeeCap), "maxPriorityFeePerGas", formatGwei(gasTipCap))
+
+	return m.broadcast(ledger, replacement)
+}
+
+func (m *transactionManager) bump(fee *big.Int) *big.Int {
+	if fee == nil {
+		return new(big.Int)
+	}
+	// Round up so a small fee still rises by at least bumpPercent
+	bumped := new(big.Int).Mul(fee, big.NewInt(100+m.bumpPercent))
+	bumped.Add(bumped, big.NewInt(99))
+	return bumped.Div(bumped, big.NewInt(100))
+}
+
+func maxBig(values ...*big.Int) *big.Int {
+	result := new(big.Int)
+	for _, v := range values {
+		if v != nil && v.Cmp(result) > 0 {
+			result.Set(v)
+		}
+	}
+	return result
+}
+
+// GetAnchoringStatus lists the backend transactions that anchor a diploma,
+// directly or through its Merkle root, along with their state.
+func (m *transactionManager) GetAnchoringStatus(diplomaID string, universityID uuid.UUID) (*dto.AnchoringStatusResponse, error) {
+
+	diploma, err := m.diplomaRepo.GetByDiplomaIDForUniversity(diplomaID, universityID)
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrDiplomaNotFound, "Diploma not found", err)
+	}
+
+	references := []string{diploma.Hash}
+	if diploma.MerkleRoot != "" {
+		references = append(references, diploma.MerkleRoot)
+	}
+
+	txns, err := m.repo.GetByReferences(references)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load transactions: %w", err)
+	}
+
+	response := &dto.AnchoringStatusResponse{
+		DiplomaID:    diploma.PublicID,
+		Transactions: make([]dto.ChainTransactionResponse, 0, len(txns)),
+	}
+
+	var anchoring []models.ChainTransaction
+	for _, txn := range txns {
+		response.Transactions = append(response.Transactions, dto.ChainTransactionResponse{
+			Kind:        string(txn.Kind),
+			Network:     txn.Network,
+			Status:      string(txn.Status),
+			Error:       txn.Error,
+			TxHash:      txn.TxHash,
+			Signer:      txn.Signer,
+			Nonce:       txn.Nonce,
+			GasTipCap:   txn.GasTipCap,
+			GasFeeCap:   txn.GasFeeCap,
+			BlockNumber: txn.BlockNumber,
+			GasUsed:     txn.GasUsed,
+			SentAt:      txn.SentAt,
+			MinedAt:     txn.MinedAt,
+			CreatedAt:   txn.CreatedAt,
+		})
+		if txn.Kind != models.TxKindRevokeDiploma {
+			anchoring = append(anchoring, txn)
+		}
+	}
+
+	response.Status = string(anchoringStatus(diploma, anchoring))
+	return response, nil
+}
+
+func anchoringStatus(diploma *models.Diploma, txns []models.ChainTransaction) models.TxStatus {
+
+	// Diplomas confirmed through MetaMask have no outbox transactions
+	if len(txns) == 0 {
+		if diploma.PolygonTxID != "" {
+			return models.TxMined
+		}
+		return models.TxPending
+	}
+
+	status := models.TxFailed
+	for _, txn := range txns {
+		switch txn.Status {
+		case models.TxMined:
+			return models.TxMined
+		case models.TxPending:
+			status = models.TxPending
+		}
+	}
+	return status
+}
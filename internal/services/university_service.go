@@ -2,13 +2,22 @@ package services
 
 import (
 	"BlockCertify/internal/dto"
+	"BlockCertify/internal/helper"
+	"BlockCertify/internal/models"
+	apperrors "BlockCertify/internal/pkg/errors"
 	"BlockCertify/internal/repositories"
 	"log/slog"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
 )
 
 type UniversityService interface {
 	GetUniversitiesFromDBRecord() ([]dto.UniversitiesResponse, error)
 	GetUniversityByID(id string) (dto.UniversitiesResponse, error)
+	CreateUniversity(req dto.UniversityRequest, actor *models.User) (dto.UniversitiesResponse, error)
+	UpdateUniversity(id string, req dto.UniversityRequest, actor *models.User) (dto.UniversitiesResponse, error)
+	DeleteUniversity(id string) error
 }
 
 type universityService struct {
@@ -25,7 +34,7 @@ func (s *universityService) GetUniversitiesFromDBRecord() ([]dto.UniversitiesRes
 
 	universities, err := s.repo.GetUniversitiesFromDBRecord()
 	if err != nil {
-		slog.Error("Failed to get universities from DB: %v", err)
+		slog.Error("Failed to get universities from DB", "err", err)
 		return nil, err
 	}
 	return universities, nil
@@ -35,7 +44,37 @@ func (s *universityService) GetUniversityByID(id string) (dto.UniversitiesRespon
 
 	university, err := s.repo.GetUniversityByID(id)
 	if err != nil {
-		slog.Error("Failed to get university by ID: %v", err)
+		slog.Error("Failed to get university by ID", "err", err)
+		return dto.UniversitiesResponse{}, err
+	}
+
+	return dto.UniversitiesResponse{
+		ID:   university.ID,
+		Name: university.Name,
+	}, nil
+}
+
+func (s *universityService) CreateUniversity(req dto.UniversityRequest, actor *models.User) (dto.UniversitiesResponse, error) {
+
+	if err := helper.Validate.Struct(&req); err != nil {
+		return dto.UniversitiesResponse{}, apperrors.New(apperrors.ErrInvalidRequest, "Invalid university", err)
+	}
+
+	now := time.Now()
+	university := models.Universities{
+		ID:      uuid.Must(uuid.NewV7()),
+		Name:    req.Name,
+		YokCode: req.YokCode,
+		BaseRecordFields: models.BaseRecordFields{
+			CreatedAt:     now,
+			CreatedByName: actor.Email,
+			UpdatedAt:     now,
+			UpdatedByName: actor.Email,
+		},
+	}
+
+	if err := s.repo.Create(&university); err != nil {
+		slog.Error("Failed to create university", "err", err)
 		return dto.UniversitiesResponse{}, err
 	}
 
@@ -44,3 +83,44 @@ func (s *universityService) GetUniversityByID(id string) (dto.UniversitiesRespon
 		Name: university.Name,
 	}, nil
 }
+
+func (s *universityService) UpdateUniversity(id string, req dto.UniversityRequest, actor *models.User) (dto.UniversitiesResponse, error) {
+
+	if err := helper.Validate.Struct(&req); err != nil {
+		return dto.UniversitiesResponse{}, apperrors.New(apperrors.ErrInvalidRequest, "Invalid university", err)
+	}
+
+	university, err := s.repo.GetUniversityByID(id)
+	if err != nil {
+		return dto.UniversitiesResponse{}, apperrors.New(apperrors.ErrUniversityNotFound, "University not found", err)
+	}
+
+	university.Name = req.Name
+	university.YokCode = req.YokCode
+	university.UpdatedAt = time.Now()
+	university.UpdatedByName = actor.Email
+
+	if err := s.repo.Update(&university); err != nil {
+		slog.Error("Failed to update university", "err", err)
+		return dto.UniversitiesResponse{}, err
+	}
+
+	return dto.UniversitiesResponse{
+		ID:   university.ID,
+		Name: university.Name,
+	}, nil
+}
+
+func (s *universityService) DeleteUniversity(id string) error {
+
+	if _, err := s.repo.GetUniversityByID(id); err != nil {
+		return apperrors.New(apperrors.ErrUniversityNotFound, "University not found", err)
+	}
+
+	if err := s.repo.Delete(id); err != nil {
+		slog.Error("Failed to delete university", "err", err)
+		return err
+	}
+
+	return nil
+}
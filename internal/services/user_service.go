@@ -7,39 +7,86 @@ import (
 	apperrors "BlockCertify/internal/pkg/errors"
 	"BlockCertify/internal/repositories"
 	"BlockCertify/internal/security"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"log/slog"
+	"strings"
+	"time"
 
 	"github.com/gofrs/uuid/v5"
 )
 
+// adminInviteTTL is how long an admin invite can be used.
+const adminInviteTTL = 7 * 24 * time.Hour
+
 type UserService interface {
 	Register(req dto.RegisterRequest) error
+	RegisterStudent(req dto.RegisterRequest) error
 	Login(req dto.LoginRequest) (*dto.LoginResponse, error)
+	CreateInvite(universityID string, req dto.AdminInviteRequest, actor *models.User) (*dto.AdminInviteResponse, error)
+	CreateStudentInvite(diplomaID string, universityID uuid.UUID, actor *models.User) (*dto.AdminInviteResponse, error)
+	EnsureSuperadmin(email, password string) error
 }
 
 type userService struct {
 	repo        repositories.UserRepository
 	tokenHelper security.TokenHelper
 	repoUni     repositories.UniversityRepository
+	diplomaRepo repositories.DiplomaRepository
 }
 
-func NewUserService(repo repositories.UserRepository, tokenHelper security.TokenHelper, repoUni repositories.UniversityRepository) UserService {
+func NewUserService(repo repositories.UserRepository, tokenHelper security.TokenHelper, repoUni repositories.UniversityRepository, diplomaRepo repositories.DiplomaRepository) UserService {
 
 	return &userService{
 		repo:        repo,
 		tokenHelper: tokenHelper,
 		repoUni:     repoUni,
+		diplomaRepo: diplomaRepo,
 	}
 }
 
+// Register creates the admin of a university from a superadmin's invite.
 func (s *userService) Register(req dto.RegisterRequest) error {
+	return s.register(req, models.RoleAdmin)
+}
+
+// RegisterStudent creates a student account from an admin's invite. The
+// account has the email of the diploma the invite was issued for, which is
+// how the student's diplomas are found.
+func (s *userService) RegisterStudent(req dto.RegisterRequest) error {
+	return s.register(req, models.RoleStudent)
+}
+
+func (s *userService) register(req dto.RegisterRequest, role models.UserRole) error {
 
 	if err := helper.Validate.Struct(&req); err != nil {
 		return err
 	}
 
-	existing, err := s.repo.Exists(req.Email)
+	invite, err := s.repo.FindInviteByTokenHash(hashInviteToken(req.InviteToken))
+	if err != nil {
+		return apperrors.New(apperrors.ErrInvalidInvite, "Invalid or expired invite", nil)
+	}
+	if invite.UsedAt != nil || time.Now().After(invite.ExpiresAt) || invite.Role != role {
+		return apperrors.New(apperrors.ErrInvalidInvite, "Invalid or expired invite", nil)
+	}
+	if !strings.EqualFold(strings.TrimSpace(req.Email), invite.Email) {
+		return apperrors.New(apperrors.ErrInvalidInvite, "Invite was issued for another email", nil)
+	}
+	if req.UniversityID != "" && req.UniversityID != invite.UniversityID.String() {
+		return apperrors.New(apperrors.ErrInvalidInvite, "Invite was issued for another university", nil)
+	}
+
+	// A student's diplomas are matched on the email exactly as it was issued
+	email := req.Email
+	if role == models.RoleStudent {
+		email = invite.Email
+	}
+
+	existing, err := s.repo.Exists(email)
 	if err != nil {
 		slog.Error(err.Error())
 		return err
@@ -53,41 +100,176 @@ func (s *userService) Register(req dto.RegisterRequest) error {
 		return fmt.Errorf("Failed to hash password: %w", err)
 	}
 
-	uni, err := s.repoUni.GetUniversityByID(req.UniversityID)
+	uni, err := s.repoUni.GetUniversityByID(invite.UniversityID.String())
 	if err != nil {
-		slog.Error("Failed to get university by ID: %v", err)
+		slog.Error("Failed to get university by ID", "err", err)
 		return apperrors.New(apperrors.ErrUniversityNotFound, "University not found", err)
 	}
 
-	tx := s.repo.CreateTransaction()
-	defer tx.Rollback()
-
 	user := models.User{
 		ID:        uuid.Must(uuid.NewV7()),
 		FirstName: req.FirstName,
 		LastName:  req.LastName,
-		Email:     req.Email,
+		Email:     email,
 		Password:  hashedPassword,
-		Role:      models.RoleAdmin,
+		Role:      role,
+	}
+
+	var admin *models.Admin
+	if role == models.RoleAdmin {
+		admin = &models.Admin{
+			ID:           uuid.Must(uuid.NewV7()),
+			UserID:       user.ID,
+			UniversityID: uni.ID,
+		}
 	}
 
-	if err := tx.Create(&user).Error; err != nil {
-		slog.Error("Failed to create user: %v", err)
+	used, err := s.repo.CreateInvited(invite.ID, time.Now().UTC(), &user, admin)
+	if err != nil {
+		slog.Error("Failed to create user", "role", role, "err", err)
 		return apperrors.New(apperrors.ErrUserCreationFailed, "User creation failed", err)
 	}
+	if !used {
+		return apperrors.New(apperrors.ErrInvalidInvite, "Invalid or expired invite", nil)
+	}
+
+	return nil
+}
 
-	admin := models.Admin{
+// CreateInvite lets a superadmin invite the admin of a university.
+func (s *userService) CreateInvite(universityID string, req dto.AdminInviteRequest, actor *models.User) (*dto.AdminInviteResponse, error) {
+
+	if err := helper.Validate.Struct(&req); err != nil {
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "Invalid request", err)
+	}
+
+	uni, err := s.repoUni.GetUniversityByID(universityID)
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrUniversityNotFound, "University not found", err)
+	}
+
+	invite := models.AdminInvite{
 		ID:           uuid.Must(uuid.NewV7()),
-		UserID:       user.ID,
 		UniversityID: uni.ID,
+		Email:        strings.ToLower(strings.TrimSpace(req.Email)),
+		Role:         models.RoleAdmin,
+		CreatedByID:  actor.ID,
 	}
+	return s.saveInvite(&invite, actor)
+}
 
-	if err := tx.Create(&admin).Error; err != nil {
-		slog.Error("Failed to create admin: %v", err)
-		return apperrors.New(apperrors.ErrAdminCreationFailed, "Admin creation failed", err)
+// CreateStudentInvite lets an admin invite the graduate named on one of its
+// university's diplomas to open a student account.
+func (s *userService) CreateStudentInvite(diplomaID string, universityID uuid.UUID, actor *models.User) (*dto.AdminInviteResponse, error) {
+
+	diploma, err := s.diplomaRepo.GetByDiplomaIDForUniversity(diplomaID, universityID)
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrDiplomaNotFound, "Diploma not found", err)
 	}
 
-	return tx.Commit().Error
+	email := strings.TrimSpace(diploma.MetaData.Email)
+	if email == "" {
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "Diploma has no student email", nil)
+	}
+
+	exists, err := s.repo.Exists(email)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, apperrors.New(apperrors.ErrUserExists, "User with this email already exists", nil)
+	}
+
+	invite := models.AdminInvite{
+		ID:           uuid.Must(uuid.NewV7()),
+		UniversityID: universityID,
+		Email:        email,
+		Role:         models.RoleStudent,
+		CreatedByID:  actor.ID,
+	}
+	return s.saveInvite(&invite, actor)
+}
+
+// saveInvite gives the invite a fresh token and stores it. The returned
+// token is the only copy; the invite stores its hash.
+func (s *userService) saveInvite(invite *models.AdminInvite, actor *models.User) (*dto.AdminInviteResponse, error) {
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("Failed to generate invite token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(secret)
+
+	invite.TokenHash = hashInviteToken(token)
+	invite.ExpiresAt = time.Now().UTC().Add(adminInviteTTL)
+	if err := s.repo.CreateInvite(invite); err != nil {
+		slog.Error("Failed to create invite", "role", invite.Role, "err", err)
+		return nil, err
+	}
+
+	slog.Info("User invited", "role", invite.Role, "universityId", invite.UniversityID, "email", invite.Email, "by", actor.Email)
+
+	return &dto.AdminInviteResponse{
+		Token:        token,
+		UniversityID: invite.UniversityID.String(),
+		Email:        invite.Email,
+		ExpiresAt:    invite.ExpiresAt,
+	}, nil
+}
+
+// EnsureSuperadmin creates the platform's first superadmin from
+// SUPERADMIN_EMAIL and SUPERADMIN_PASSWORD. Once any superadmin exists it
+// does nothing, so the password can be removed from the environment.
+func (s *userService) EnsureSuperadmin(email, password string) error {
+
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return nil
+	}
+
+	exists, err := s.repo.HasRole(models.RoleSuperAdmin)
+	if err != nil {
+		return fmt.Errorf("failed to look up superadmins: %w", err)
+	}
+	if exists {
+		return nil
+	}
+
+	taken, err := s.repo.Exists(email)
+	if err != nil {
+		return fmt.Errorf("failed to look up %s: %w", email, err)
+	}
+	if taken {
+		return fmt.Errorf("SUPERADMIN_EMAIL %s already belongs to another user", email)
+	}
+	if len(password) < 8 {
+		return fmt.Errorf("SUPERADMIN_PASSWORD must be at least 8 characters")
+	}
+
+	hashedPassword, err := helper.HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("Failed to hash password: %w", err)
+	}
+
+	user := models.User{
+		ID:        uuid.Must(uuid.NewV7()),
+		FirstName: "Platform",
+		LastName:  "Admin",
+		Email:     email,
+		Password:  hashedPassword,
+		Role:      models.RoleSuperAdmin,
+	}
+	if err := s.repo.Create(&user); err != nil {
+		return fmt.Errorf("failed to create superadmin: %w", err)
+	}
+
+	slog.Info("Superadmin created", "email", email)
+	return nil
+}
+
+func hashInviteToken(token string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(token)))
+	return hex.EncodeToString(sum[:])
 }
 
 func (s *userService) Login(req dto.LoginRequest) (*dto.LoginResponse, error) {
@@ -111,15 +293,10 @@ func (s *userService) Login(req dto.LoginRequest) (*dto.LoginResponse, error) {
 		return nil, apperrors.New(apperrors.ErrTokenCreateFailed, "Token creation failed", err)
 	}
 
-	role := user.Role
-	if role == "" {
-		role = "admin" // Default for now
-	}
-
 	return &dto.LoginResponse{
 		Token:     token,
 		TokenType: "Bearer",
 		ExpiresIn: s.tokenHelper.ExpiresInSeconds(),
-		Role:      "admin",
+		Role:      string(user.Role),
 	}, nil
 }
@@ -0,0 +1,52 @@
+package services
+
+import "time"
+
+// w3cFormatter is the plain W3C Verifiable Credential, the default format.
+type w3cFormatter struct{}
+
+func NewW3CFormatter() CredentialFormatter {
+	return w3cFormatter{}
+}
+
+func (w3cFormatter) Name() string {
+	return "vc"
+}
+
+func (w3cFormatter) Build(data CredentialData) map[string]any {
+
+	diploma := data.Diploma
+	meta := diploma.MetaData
+
+	subject := map[string]any{
+		"type":       "Graduate",
+		"name":       diploma.Owner,
+		"givenName":  meta.FirstName,
+		"familyName": meta.LastName,
+		"diplomaId":  diploma.PublicID,
+		"degree": map[string]any{
+			"type":           "Diploma",
+			"university":     data.University.Name,
+			"yokCode":        data.University.YokCode,
+			"faculty":        meta.Faculty,
+			"department":     meta.Department,
+			"graduationYear": meta.GraduationYear,
+		},
+	}
+	if meta.StudentNumber != "" {
+		subject["studentNumber"] = meta.StudentNumber
+	}
+
+	return map[string]any{
+		"@context": []any{credentialsContextV2},
+		"id":       credentialID(diploma),
+		"type":     []any{"VerifiableCredential", "DiplomaCredential"},
+		"issuer": map[string]any{
+			"id":   data.IssuerDID,
+			"name": data.University.Name,
+		},
+		"validFrom":         diploma.Timestamp.UTC().Format(time.RFC3339),
+		"credentialSubject": subject,
+		"evidence":          credentialEvidence(data),
+	}
+}
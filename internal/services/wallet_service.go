@@ -1,6 +1,7 @@
 package services
 
 import (
+	"BlockCertify/internal/config"
 	"errors"
 	"log/slog"
 
@@ -14,15 +15,18 @@ type WalletService interface {
 }
 
 type walletService struct {
-	client *goar.Client
+	gateway string
+	client  *goar.Client
 }
 
-func NewWalletService() WalletService {
+func NewWalletService(cfg *config.Config) WalletService {
 
-	client := goar.NewClient("https://arweave.net")
+	gateway := cfg.Arweave.GatewayURL()
+	client := goar.NewClient(gateway)
 
 	return &walletService{
-		client: client,
+		gateway: gateway,
+		client:  client,
 	}
 }
 
@@ -33,9 +37,9 @@ func (s *walletService) ConnectWalletFromJSON(keyJSON []byte) (*goar.Wallet, err
 		return nil, errors.New("key is empty")
 	}
 
-	wallet, err := goar.NewWallet(keyJSON, "https://arweave.net")
+	wallet, err := goar.NewWallet(keyJSON, s.gateway)
 	if err != nil {
-		slog.Error("NewWalletFromJSON error:", err)
+		slog.Error("NewWalletFromJSON error", "err", err)
 		return nil, err
 	}
 
@@ -59,7 +63,7 @@ func (s *walletService) GetBalance(wallet *goar.Wallet) string {
 
 	balance, err := s.client.GetWalletBalance(wallet.Signer.Address)
 	if err != nil {
-		slog.Error("GetBalance error:", err)
+		slog.Error("GetBalance error", "err", err)
 		return ""
 	}
 
@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"BlockCertify/internal/dto"
+	"BlockCertify/internal/models"
+	apperrors "BlockCertify/internal/pkg/errors"
+	"BlockCertify/internal/repositories"
+	"BlockCertify/internal/services"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"gorm.io/gorm"
+)
+
+// fakeInviteRepo keeps invites in memory. Registrations that pass every
+// invite check reach Exists, which it does not implement.
+type fakeInviteRepo struct {
+	repositories.UserRepository
+	invites map[string]*models.AdminInvite
+}
+
+func (r *fakeInviteRepo) CreateInvite(invite *models.AdminInvite) error {
+	r.invites[invite.TokenHash] = invite
+	return nil
+}
+
+func (r *fakeInviteRepo) FindInviteByTokenHash(tokenHash string) (*models.AdminInvite, error) {
+	invite, ok := r.invites[tokenHash]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return invite, nil
+}
+
+type fakeUniversityRepo struct {
+	repositories.UniversityRepository
+	university models.Universities
+}
+
+func (r *fakeUniversityRepo) GetUniversityByID(id string) (models.Universities, error) {
+	if id != r.university.ID.String() {
+		return models.Universities{}, gorm.ErrRecordNotFound
+	}
+	return r.university, nil
+}
+
+func TestAdminRegistrationNeedsInvite(t *testing.T) {
+
+	university := models.Universities{ID: uuid.Must(uuid.NewV7())}
+	repo := &fakeInviteRepo{invites: map[string]*models.AdminInvite{}}
+	users := services.NewUserService(repo, nil, &fakeUniversityRepo{university: university}, nil)
+
+	superadmin := &models.User{ID: uuid.Must(uuid.NewV7()), Email: "root@blockcertify.io"}
+	invite, err := users.CreateInvite(university.ID.String(), dto.AdminInviteRequest{Email: "Fatih@University.edu"}, superadmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if invite.Token == "" || invite.Email != "fatih@university.edu" || !invite.ExpiresAt.After(time.Now()) {
+		t.Fatalf("invite = %+v", invite)
+	}
+	for hash := range repo.invites {
+		if hash == invite.Token {
+			t.Fatal("invite token stored in the clear")
+		}
+	}
+
+	register := func(email, universityID, token string) error {
+		return users.Register(dto.RegisterRequest{
+			FirstName:    "Fatih",
+			LastName:     "Demir",
+			Email:        email,
+			UniversityID: universityID,
+			Password:     "securepass123",
+			InviteToken:  token,
+		})
+	}
+
+	other := uuid.Must(uuid.NewV7()).String()
+	cases := map[string]error{
+		"unknown token":    register("fatih@university.edu", "", "not-an-invite"),
+		"other email":      register("someone@university.edu", "", invite.Token),
+		"other university": register("fatih@university.edu", other, invite.Token),
+	}
+	for _, stored := range repo.invites {
+		stored.ExpiresAt = time.Now().Add(-time.Minute)
+	}
+	cases["expired"] = register("fatih@university.edu", "", invite.Token)
+
+	for name, err := range cases {
+		var appErr *apperrors.AppError
+		if !errors.As(err, &appErr) || appErr.Code != apperrors.ErrInvalidInvite {
+			t.Errorf("%s: error = %v, want %s", name, err, apperrors.ErrInvalidInvite)
+		}
+	}
+
+	if _, err := users.CreateInvite(other, dto.AdminInviteRequest{Email: "a@b.edu"}, superadmin); err == nil {
+		t.Error("invited an admin to a university that does not exist")
+	}
+}
@@ -0,0 +1,180 @@
+package tests
+
+import (
+	"BlockCertify/internal/config"
+	"BlockCertify/internal/dto"
+	"BlockCertify/internal/models"
+	"BlockCertify/internal/pkg/merkle"
+	"BlockCertify/internal/services"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// anchorChain leaves anchorRoot transactions pending until mined is set.
+type anchorChain struct {
+	*services.MockBlockchainService
+	mined bool
+	roots []string
+}
+
+func (c *anchorChain) AnchorMerkleRoot(root merkle.Hash, _ int) (*dto.BlockchainResult, error) {
+	c.roots = append(c.roots, root.Hex())
+	result := &dto.BlockchainResult{
+		Network:         "mock",
+		TransactionHash: "0xanchor-" + root.Hex()[2:10],
+		Pending:         !c.mined,
+	}
+	if c.mined {
+		result.BlockNumber = 42
+		result.BlockHash = "0xblock"
+		result.Timestamp = time.Date(2024, time.June, 30, 12, 0, 0, 0, time.UTC)
+	}
+	return result, nil
+}
+
+type fakeAnchorRepo struct {
+	anchors  []models.MerkleAnchor
+	diplomas []models.Diploma
+}
+
+func (r *fakeAnchorRepo) GetPendingDiplomas(limit int) ([]models.Diploma, error) {
+	var pending []models.Diploma
+	for _, d := range r.diplomas {
+		if d.PolygonTxID == "" && d.MerkleAnchorID == nil && len(pending) < limit {
+			pending = append(pending, d)
+		}
+	}
+	return pending, nil
+}
+
+func (r *fakeAnchorRepo) CreateAnchor(anchor *models.MerkleAnchor, diplomas []models.Diploma) error {
+	r.anchors = append(r.anchors, *anchor)
+	for _, d := range diplomas {
+		r.diploma(d.ID).MerkleAnchorID = &anchor.ID
+	}
+	return nil
+}
+
+func (r *fakeAnchorRepo) GetPendingAnchors() ([]models.MerkleAnchor, error) {
+	var pending []models.MerkleAnchor
+	for _, a := range r.anchors {
+		if a.Status == models.MerkleAnchorPending {
+			pending = append(pending, a)
+		}
+	}
+	return pending, nil
+}
+
+func (r *fakeAnchorRepo) GetAnchorDiplomas(anchorID uuid.UUID) ([]models.Diploma, error) {
+	var diplomas []models.Diploma
+	for _, d := range r.diplomas {
+		if d.MerkleAnchorID != nil && *d.MerkleAnchorID == anchorID {
+			diplomas = append(diplomas, d)
+		}
+	}
+	return diplomas, nil
+}
+
+func (r *fakeAnchorRepo) UpdateAnchor(anchor *models.MerkleAnchor) error {
+	for i := range r.anchors {
+		if r.anchors[i].ID == anchor.ID {
+			r.anchors[i] = *anchor
+			return nil
+		}
+	}
+	return fmt.Errorf("anchor %s not found", anchor.ID)
+}
+
+func (r *fakeAnchorRepo) SaveAnchor(anchor *models.MerkleAnchor, diplomas []models.Diploma) error {
+	if err := r.UpdateAnchor(anchor); err != nil {
+		return err
+	}
+	for _, d := range diplomas {
+		*r.diploma(d.ID) = d
+	}
+	return nil
+}
+
+func (r *fakeAnchorRepo) diploma(id uuid.UUID) *models.Diploma {
+	for i := range r.diplomas {
+		if r.diplomas[i].ID == id {
+			return &r.diplomas[i]
+		}
+	}
+	panic("unknown diploma " + id.String())
+}
+
+func (r *fakeAnchorRepo) queue(n int) {
+	for range n {
+		id := uuid.Must(uuid.NewV7())
+		r.diplomas = append(r.diplomas, models.Diploma{
+			ID:          id,
+			Hash:        fmt.Sprintf("%064x", len(r.diplomas)+1),
+			ArweaveTxID: "arweave-" + id.String(),
+		})
+	}
+}
+
+func TestAnchorKeepsPendingBatch(t *testing.T) {
+
+	cfg := &config.Config{Blockchain: config.BlockChainConfig{MerkleBatchSize: 4, MerkleAnchorInterval: time.Minute}}
+	chain := &anchorChain{MockBlockchainService: services.NewMockBlockchainService()}
+	repo := &fakeAnchorRepo{}
+	repo.queue(3)
+
+	// Sent, but not mined within the wait: the batch is kept as a pending anchor
+	anchored, err := services.NewAnchorService(cfg, chain, repo).AnchorPending()
+	if err != nil || anchored != 0 {
+		t.Fatalf("anchored %d, %v", anchored, err)
+	}
+	if len(repo.anchors) != 1 || repo.anchors[0].Status != models.MerkleAnchorPending || repo.anchors[0].PolygonTxID == "" {
+		t.Fatalf("anchors = %+v", repo.anchors)
+	}
+	first := repo.anchors[0].Root
+	for _, d := range repo.diplomas {
+		if d.MerkleAnchorID == nil || *d.MerkleAnchorID != repo.anchors[0].ID || d.PolygonTxID != "" {
+			t.Fatalf("diploma %s not reserved for the pending anchor", d.ID)
+		}
+	}
+
+	// More diplomas arrive before the restart; the pending batch is resent as
+	// it was and the new diploma gets its own
+	repo.queue(1)
+	chain.mined = true
+	anchored, err = services.NewAnchorService(cfg, chain, repo).AnchorPending()
+	if err != nil || anchored != 4 {
+		t.Fatalf("anchored %d, %v", anchored, err)
+	}
+	if !slices.Equal(chain.roots, []string{first, first, repo.anchors[1].Root}) {
+		t.Fatalf("roots sent = %v", chain.roots)
+	}
+	for _, a := range repo.anchors {
+		if a.Status != models.MerkleAnchorAnchored || a.BlockNumber != 42 {
+			t.Errorf("anchor = %+v", a)
+		}
+	}
+
+	for _, d := range repo.diplomas {
+		root, _ := merkle.ParseHash(d.MerkleRoot)
+		proof := make([]merkle.Hash, len(d.MerkleProof))
+		for i, p := range d.MerkleProof {
+			proof[i], _ = merkle.ParseHash(p)
+		}
+		if d.PolygonTxID == "" || !merkle.Verify(merkle.Leaf(d.Hash, d.ArweaveTxID), proof, root) {
+			t.Errorf("diploma %s: root %s, proof %v, tx %q", d.ID, d.MerkleRoot, d.MerkleProof, d.PolygonTxID)
+		}
+	}
+	if repo.diplomas[0].MerkleRoot != first || repo.diplomas[3].MerkleRoot == first {
+		t.Errorf("diplomas anchored under %s, %s, want %s first", repo.diplomas[0].MerkleRoot, repo.diplomas[3].MerkleRoot, first)
+	}
+
+	// Nothing left to anchor
+	chain.roots = nil
+	if anchored, err := services.NewAnchorService(cfg, chain, repo).AnchorPending(); err != nil || anchored != 0 || len(chain.roots) != 0 {
+		t.Errorf("anchored %d, %v, sent %v", anchored, err, chain.roots)
+	}
+}
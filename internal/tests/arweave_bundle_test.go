@@ -0,0 +1,135 @@
+package tests
+
+import (
+	"BlockCertify/internal/config"
+	"BlockCertify/internal/models"
+	"BlockCertify/internal/services"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/everFinance/goar/types"
+	"github.com/everFinance/goar/utils"
+)
+
+// arweaveKeyfile returns a fresh Arweave keyfile (an RSA-4096 JWK).
+func arweaveKeyfile(t *testing.T) string {
+
+	key, err := rsa.GenerateKey(rand.Reader, 4096)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key.Precompute()
+
+	b64 := func(n *big.Int) string {
+		return base64.RawURLEncoding.EncodeToString(n.Bytes())
+	}
+	jwk, err := json.Marshal(map[string]string{
+		"kty": "RSA",
+		"e":   b64(big.NewInt(int64(key.E))),
+		"n":   b64(key.N),
+		"d":   b64(key.D),
+		"p":   b64(key.Primes[0]),
+		"q":   b64(key.Primes[1]),
+		"dp":  b64(key.Precomputed.Dp),
+		"dq":  b64(key.Precomputed.Dq),
+		"qi":  b64(key.Precomputed.Qinv),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(jwk)
+}
+
+func TestArweaveBundlerUpload(t *testing.T) {
+
+	var mu sync.Mutex
+	var posted []*types.BundleItem
+	bundler := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		item, err := utils.DecodeBundleItem(body)
+		if r.URL.Path != "/tx" || err != nil || utils.VerifyBundleItem(*item) != nil {
+			http.Error(w, "invalid data item", http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		posted = append(posted, item)
+		mu.Unlock()
+		_ = json.NewEncoder(w).Encode(map[string]string{"id": item.Id})
+	}))
+	defer bundler.Close()
+
+	cfg := &config.Config{Arweave: config.ArweaveConfig{
+		WalletKey:  arweaveKeyfile(t),
+		Host:       "127.0.0.1",
+		Port:       1,
+		Protocol:   "http",
+		Bundling:   config.ArweaveBundlingBundler,
+		BundlerURL: bundler.URL,
+		BundleSize: 10,
+	}}
+	repo := &fakeArweaveRepo{
+		uploads:   map[string]*models.ArweaveUpload{},
+		dataItems: map[string]*models.ArweaveDataItem{},
+	}
+	stores := services.NewDocumentStores(services.NewArweaveService(cfg, repo))
+
+	if size := stores.BatchSize(); size != 10 {
+		t.Fatalf("batch size = %d", size)
+	}
+
+	dir := t.TempDir()
+	var files []services.DocumentFile
+	for _, content := range []string{"%PDF-1.7 first", "%PDF-1.7 second"} {
+		sum := sha256.Sum256([]byte(content))
+		file := services.DocumentFile{
+			FilePath: filepath.Join(dir, hex.EncodeToString(sum[:4])+".pdf"),
+			FileHash: hex.EncodeToString(sum[:]),
+		}
+		if err := os.WriteFile(file.FilePath, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file)
+	}
+
+	references, err := stores.UploadBatch(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(references) != 2 || len(posted) != 2 {
+		t.Fatalf("references %v, posted %d items", references, len(posted))
+	}
+
+	for i, item := range posted {
+		// A data item ID is referenced like a TxID
+		if references[i] != item.Id {
+			t.Errorf("reference %s, posted item %s", references[i], item.Id)
+		}
+
+		var fileHash string
+		for _, tag := range item.Tags {
+			if tag.Name == "File-Hash" {
+				fileHash = tag.Value
+			}
+		}
+		if fileHash != files[i].FileHash {
+			t.Errorf("item %d: File-Hash %q, want %s", i, fileHash, files[i].FileHash)
+		}
+
+		recorded := repo.dataItems[item.Id]
+		if recorded == nil || recorded.Bundler != bundler.URL || recorded.BundleID != "" {
+			t.Errorf("item %d recorded as %+v", i, recorded)
+		}
+	}
+}
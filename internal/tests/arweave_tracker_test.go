@@ -0,0 +1,565 @@
+package tests
+
+import (
+	"BlockCertify/internal/config"
+	"BlockCertify/internal/models"
+	"BlockCertify/internal/services"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/everFinance/goar/types"
+	"github.com/everFinance/goar/utils"
+	"gorm.io/gorm"
+)
+
+// fakeWeave answers status and data queries from memory and counts reposts
+// and re-uploads.
+type fakeWeave struct {
+	*services.MockArweaveService
+	statuses   map[string]services.ArweaveTxStatus
+	seeded     map[string]bool
+	bundles    map[string]string // data item -> bundle found by the gateway
+	anchorAges map[string]int    // unknown anchors fail, like a gateway that cannot tell
+	reposted   []string
+	chunksOnly []string
+	reuploaded []string
+	released   []string
+}
+
+func (w *fakeWeave) TransactionStatus(txID string) (services.ArweaveTxStatus, error) {
+	return w.statuses[txID], nil
+}
+
+func (w *fakeWeave) HasData(txID string) (bool, error) {
+	return w.seeded[txID], nil
+}
+
+func (w *fakeWeave) BundleOf(itemID string) (string, error) {
+	return w.bundles[itemID], nil
+}
+
+func (w *fakeWeave) AnchorAge(signedTx []byte) (int, error) {
+	age, ok := w.anchorAges[string(signedTx)]
+	if !ok {
+		return 0, fmt.Errorf("anchor block of %s not found", signedTx)
+	}
+	return age, nil
+}
+
+func (w *fakeWeave) Repost(signedTx []byte, mined bool) error {
+	w.reposted = append(w.reposted, string(signedTx))
+	if mined {
+		w.chunksOnly = append(w.chunksOnly, string(signedTx))
+	}
+	return nil
+}
+
+func (w *fakeWeave) Reupload(signedTx []byte) (string, error) {
+	w.reuploaded = append(w.reuploaded, string(signedTx))
+	return "new-" + string(signedTx), nil
+}
+
+func (w *fakeWeave) ReleasePayload(txID string) {
+	w.released = append(w.released, txID)
+}
+
+type fakeArweaveRepo struct {
+	uploads   map[string]*models.ArweaveUpload
+	dataItems map[string]*models.ArweaveDataItem
+	diplomas  []models.Diploma
+}
+
+func (r *fakeArweaveRepo) CreateUpload(upload *models.ArweaveUpload) error {
+	if upload.CreatedAt.IsZero() {
+		upload.CreatedAt = time.Now()
+	}
+	r.uploads[upload.TxID] = upload
+	return nil
+}
+
+func (r *fakeArweaveRepo) GetUpload(txID string) (*models.ArweaveUpload, error) {
+	upload, ok := r.uploads[txID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return upload, nil
+}
+
+func (r *fakeArweaveRepo) GetUnsettledTxIDs(limit int) ([]string, error) {
+	var txIDs []string
+	seen := map[string]bool{}
+	for txID, upload := range r.uploads {
+		if upload.Status != models.ArweaveStatusPermanent && upload.Status != models.ArweaveStatusReplaced {
+			txIDs = append(txIDs, txID)
+			seen[txID] = true
+		}
+	}
+	for _, d := range r.diplomas {
+		txID := d.ArweaveTxID
+		if d.ArweaveBundleID != "" {
+			txID = d.ArweaveBundleID
+		} else if d.ContentAddress != "" {
+			txID = d.ContentAddress
+		}
+		if !seen[txID] && d.ArweaveStatus != models.ArweaveStatusPermanent {
+			txIDs = append(txIDs, txID)
+			seen[txID] = true
+		}
+	}
+	return txIDs, nil
+}
+
+func (r *fakeArweaveRepo) UpdateStatus(txID string, status models.ArweaveStatus, confirmations, blockHeight int) error {
+	if upload, ok := r.uploads[txID]; ok {
+		upload.Status = status
+		upload.Confirmations = confirmations
+		upload.BlockHeight = blockHeight
+		if status == models.ArweaveStatusPermanent {
+			upload.SignedTx = nil
+		}
+	}
+	for i := range r.diplomas {
+		if d := r.diplomas[i]; d.ArweaveTxID == txID || d.ArweaveBundleID == txID || d.ContentAddress == txID {
+			r.diplomas[i].ArweaveStatus = status
+			r.diplomas[i].ArweaveConfirmations = confirmations
+		}
+	}
+	return nil
+}
+
+func (r *fakeArweaveRepo) RecordRepost(txID string, postedAt time.Time) error {
+	r.uploads[txID].Reposts++
+	r.uploads[txID].PostedAt = postedAt
+	return nil
+}
+
+func (r *fakeArweaveRepo) ReplaceUpload(txID, replacementID, url string) error {
+	for _, upload := range r.uploads {
+		if upload.TxID == txID || upload.ReplacedBy == txID {
+			upload.Status = models.ArweaveStatusReplaced
+			upload.ReplacedBy = replacementID
+			upload.SignedTx = nil
+		}
+	}
+	for _, item := range r.dataItems {
+		if item.BundleID == txID {
+			item.BundleID = replacementID
+		}
+	}
+	for i := range r.diplomas {
+		d := &r.diplomas[i]
+		switch {
+		case d.ArweaveBundleID == txID:
+			d.ArweaveBundleID = replacementID
+		case d.ArweaveBundleID == "" && (d.ContentAddress == txID || d.ArweaveTxID == txID):
+			d.ContentAddress = replacementID
+			d.ArweaveURL = url
+		default:
+			continue
+		}
+		d.ArweaveStatus = models.ArweaveStatusPending
+		d.ArweaveConfirmations = 0
+	}
+	return nil
+}
+
+func (r *fakeArweaveRepo) CreateDataItems(items []models.ArweaveDataItem) error {
+	for i := range items {
+		r.dataItems[items[i].ItemID] = &items[i]
+	}
+	return nil
+}
+
+func (r *fakeArweaveRepo) GetDataItem(itemID string) (*models.ArweaveDataItem, error) {
+	item, ok := r.dataItems[itemID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return item, nil
+}
+
+func (r *fakeArweaveRepo) SetBundle(itemID, bundleID string) error {
+	r.dataItems[itemID].BundleID = bundleID
+	for i := range r.diplomas {
+		if r.diplomas[i].ArweaveTxID == itemID {
+			r.diplomas[i].ArweaveBundleID = bundleID
+		}
+	}
+	return nil
+}
+
+func TestArweaveTracking(t *testing.T) {
+
+	old := time.Now().Add(-time.Hour)
+	upload := func(txID string, postedAt time.Time) *models.ArweaveUpload {
+		return &models.ArweaveUpload{TxID: txID, Status: models.ArweaveStatusPending, SignedTx: []byte(txID), PostedAt: postedAt, CreatedAt: postedAt}
+	}
+
+	weave := &fakeWeave{
+		MockArweaveService: services.NewMockArweaveService(""),
+		statuses: map[string]services.ArweaveTxStatus{
+			"mempool":  {Known: true},
+			"shallow":  {Known: true, BlockHeight: 100, Confirmations: 3},
+			"deep":     {Known: true, BlockHeight: 90, Confirmations: 20},
+			"unseeded": {Known: true, BlockHeight: 90, Confirmations: 20},
+		},
+		seeded:  map[string]bool{"deep": true},
+		bundles: map[string]string{"posted": "deep"},
+	}
+	repo := &fakeArweaveRepo{
+		uploads: map[string]*models.ArweaveUpload{
+			"mempool":  upload("mempool", old),
+			"shallow":  upload("shallow", old),
+			"deep":     upload("deep", old),
+			"unseeded": upload("unseeded", old),
+			"lost":     upload("lost", old),
+			"fresh":    upload("fresh", time.Now()),
+		},
+		dataItems: map[string]*models.ArweaveDataItem{
+			// Bundled here, and posted to a bundler that bundled it since
+			"bundled": {ItemID: "bundled", BundleID: "shallow"},
+			"posted":  {ItemID: "posted", Bundler: "http://bundler"},
+			"queued":  {ItemID: "queued", Bundler: "http://bundler"},
+		},
+		diplomas: []models.Diploma{
+			{ArweaveTxID: "deep", ArweaveStatus: models.ArweaveStatusPending},
+			{ArweaveTxID: "legacy", ArweaveStatus: models.ArweaveStatusPending},
+			{ArweaveTxID: "bundled", ArweaveStatus: models.ArweaveStatusPending},
+			{ArweaveTxID: "posted", ArweaveStatus: models.ArweaveStatusPending},
+			{ArweaveTxID: "queued", ArweaveStatus: models.ArweaveStatusPending},
+		},
+	}
+
+	cfg := &config.Config{Arweave: config.ArweaveConfig{ConfirmationDepth: 15}}
+	tracker := services.NewArweaveTracker(cfg, weave, repo)
+	if err := tracker.CheckPending(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]models.ArweaveStatus{
+		"mempool":  models.ArweaveStatusPending,
+		"shallow":  models.ArweaveStatusConfirming,
+		"deep":     models.ArweaveStatusPermanent,
+		"unseeded": models.ArweaveStatusDropped,
+		"lost":     models.ArweaveStatusDropped,
+		// Not propagated yet; too early to call it dropped
+		"fresh": models.ArweaveStatusPending,
+	}
+	for txID, status := range want {
+		if got := repo.uploads[txID].Status; got != status {
+			t.Errorf("%s: status = %s, want %s", txID, got, status)
+		}
+	}
+
+	if repo.uploads["deep"].SignedTx != nil {
+		t.Error("signed copy kept after the upload became permanent")
+	}
+	if d := repo.diplomas[0]; d.ArweaveStatus != models.ArweaveStatusPermanent || d.ArweaveConfirmations != 20 {
+		t.Errorf("diploma status = %s with %d confirmations", d.ArweaveStatus, d.ArweaveConfirmations)
+	}
+	// Without a signed copy a legacy diploma is marked dropped but not reposted
+	if d := repo.diplomas[1]; d.ArweaveStatus != models.ArweaveStatusDropped {
+		t.Errorf("legacy diploma status = %s", d.ArweaveStatus)
+	}
+
+	// Data items take the bundle they travel in; the bundler's may take a while
+	for i, bundleID := range []string{"shallow", "deep", ""} {
+		if d := repo.diplomas[2+i]; d.ArweaveBundleID != bundleID || d.ArweaveStatus != models.ArweaveStatusPending {
+			t.Errorf("data item %s: bundle %q, status %s", d.ArweaveTxID, d.ArweaveBundleID, d.ArweaveStatus)
+		}
+	}
+
+	reposted := map[string]bool{}
+	for _, txID := range weave.reposted {
+		reposted[txID] = true
+	}
+	if len(weave.reposted) != 2 || !reposted["lost"] || !reposted["unseeded"] {
+		t.Fatalf("reposted %v, want lost and unseeded", weave.reposted)
+	}
+	// The mined one only needs its chunks
+	if len(weave.chunksOnly) != 1 || weave.chunksOnly[0] != "unseeded" {
+		t.Errorf("chunks only reposted for %v, want unseeded", weave.chunksOnly)
+	}
+	if len(weave.reuploaded) != 0 || len(weave.released) != 1 || weave.released[0] != "deep" {
+		t.Errorf("reuploaded %v, released %v", weave.reuploaded, weave.released)
+	}
+
+	// A repost is not repeated on the next pass
+	if err := tracker.CheckPending(); err != nil {
+		t.Fatal(err)
+	}
+	if len(weave.reposted) != 2 || repo.uploads["lost"].Reposts != 1 {
+		t.Fatalf("reposted %v again", weave.reposted)
+	}
+
+	// ...which then follows the bundles' status
+	if d := repo.diplomas[2]; d.ArweaveStatus != models.ArweaveStatusConfirming || d.ArweaveConfirmations != 3 {
+		t.Errorf("bundled diploma status = %s with %d confirmations", d.ArweaveStatus, d.ArweaveConfirmations)
+	}
+	if d := repo.diplomas[3]; d.ArweaveStatus != models.ArweaveStatusPermanent {
+		t.Errorf("bundler diploma status = %s", d.ArweaveStatus)
+	}
+}
+
+// arweaveAnchorDepth is how many blocks a transaction anchor is valid for.
+const arweaveAnchorDepth = 50
+
+func TestArweaveReuploadAfterAnchorExpired(t *testing.T) {
+
+	expired := time.Now().Add(-3 * time.Hour)
+	upload := func(txID string) *models.ArweaveUpload {
+		return &models.ArweaveUpload{TxID: txID, Status: models.ArweaveStatusPending, SignedTx: []byte(txID), PostedAt: time.Now().Add(-time.Hour), CreatedAt: expired}
+	}
+
+	weave := &fakeWeave{
+		MockArweaveService: services.NewMockArweaveService(""),
+		statuses: map[string]services.ArweaveTxStatus{
+			// Mined long ago, but its data never arrived
+			"unseeded": {Known: true, BlockHeight: 90, Confirmations: 60},
+		},
+		seeded: map[string]bool{},
+		// The bundle's anchor cannot be fetched, so its age is told by time;
+		// a slow gateway leaves "recent" within its window however long ago
+		// it was uploaded
+		anchorAges: map[string]int{"single": 60, "recent": arweaveAnchorDepth - 1},
+	}
+	repo := &fakeArweaveRepo{
+		uploads: map[string]*models.ArweaveUpload{
+			"single":   upload("single"),
+			"bundle":   upload("bundle"),
+			"recent":   upload("recent"),
+			"unseeded": upload("unseeded"),
+		},
+		dataItems: map[string]*models.ArweaveDataItem{
+			"item": {ItemID: "item", BundleID: "bundle"},
+		},
+		diplomas: []models.Diploma{
+			{ArweaveTxID: "single", ContentAddress: "single", ArweaveURL: "https://arweave.net/single", ArweaveStatus: models.ArweaveStatusPending},
+			{ArweaveTxID: "item", ContentAddress: "item", ArweaveBundleID: "bundle", ArweaveURL: "https://arweave.net/item", ArweaveStatus: models.ArweaveStatusPending},
+		},
+	}
+
+	cfg := &config.Config{Arweave: config.ArweaveConfig{ConfirmationDepth: 15}}
+	tracker := services.NewArweaveTracker(cfg, weave, repo)
+	if err := tracker.CheckPending(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Unmined past the anchor window: uploaded again under a new ID; the
+	// mined one still gets its chunks under its own
+	reuploaded := map[string]bool{}
+	for _, txID := range weave.reuploaded {
+		reuploaded[txID] = true
+	}
+	if len(weave.reuploaded) != 2 || !reuploaded["single"] || !reuploaded["bundle"] {
+		t.Fatalf("reuploaded %v, want single and bundle", weave.reuploaded)
+	}
+	if len(weave.chunksOnly) != 1 || weave.chunksOnly[0] != "unseeded" {
+		t.Errorf("chunks reposted for %v, want unseeded", weave.chunksOnly)
+	}
+	if len(weave.reposted) != 2 || repo.uploads["recent"].Status != models.ArweaveStatusDropped || repo.uploads["recent"].Reposts != 1 {
+		t.Errorf("reposted %v, recent upload = %+v", weave.reposted, repo.uploads["recent"])
+	}
+
+	for _, txID := range []string{"single", "bundle"} {
+		u := repo.uploads[txID]
+		if u.Status != models.ArweaveStatusReplaced || u.ReplacedBy != "new-"+txID || u.SignedTx != nil {
+			t.Errorf("%s: upload = %+v", txID, u)
+		}
+	}
+
+	// The anchored reference stays; the data is read from the replacement
+	single, item := repo.diplomas[0], repo.diplomas[1]
+	if single.ArweaveTxID != "single" || single.ContentAddress != "new-single" || single.ArweaveURL != "https://arweave.net/new-single" {
+		t.Errorf("single diploma = %+v", single)
+	}
+	if item.ArweaveTxID != "item" || item.ArweaveBundleID != "new-bundle" || item.ArweaveURL != "https://arweave.net/item" {
+		t.Errorf("data item diploma = %+v", item)
+	}
+	if repo.dataItems["item"].BundleID != "new-bundle" {
+		t.Errorf("data item bundle = %s", repo.dataItems["item"].BundleID)
+	}
+
+	// From now on the replacements are tracked
+	weave.statuses["new-single"] = services.ArweaveTxStatus{Known: true, BlockHeight: 200, Confirmations: 2}
+	weave.statuses["new-bundle"] = services.ArweaveTxStatus{Known: true, BlockHeight: 200, Confirmations: 2}
+	if err := tracker.CheckPending(); err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range repo.diplomas {
+		if d.ArweaveStatus != models.ArweaveStatusConfirming || d.ArweaveConfirmations != 2 {
+			t.Errorf("%s: status %s with %d confirmations", d.ArweaveTxID, d.ArweaveStatus, d.ArweaveConfirmations)
+		}
+	}
+	if len(weave.reuploaded) != 2 {
+		t.Errorf("reuploaded %v again", weave.reuploaded)
+	}
+}
+
+// fakeGateway accepts transactions and chunks and serves the data of the
+// transactions it has a chunk for; the test data fits in one chunk.
+type fakeGateway struct {
+	mu     sync.Mutex
+	txs    map[string]types.Transaction
+	chunks map[string]int // data root -> chunks received
+	data   map[string][]byte
+}
+
+func (g *fakeGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/balance"):
+		fmt.Fprint(w, "1000000000000")
+	case strings.HasPrefix(r.URL.Path, "/price/"):
+		fmt.Fprint(w, "1000")
+	case r.URL.Path == "/tx_anchor":
+		fmt.Fprint(w, "anchor")
+	case r.URL.Path == "/block/hash/anchor":
+		fmt.Fprint(w, `{"height": 1000}`)
+	case r.URL.Path == "/info":
+		fmt.Fprint(w, `{"height": 1042}`)
+	case r.URL.Path == "/tx" && r.Method == http.MethodPost:
+		var tx types.Transaction
+		if err := json.NewDecoder(r.Body).Decode(&tx); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		g.txs[tx.ID] = tx
+		fmt.Fprint(w, "OK")
+	case r.URL.Path == "/chunk" && r.Method == http.MethodPost:
+		var chunk types.GetChunk
+		if err := json.NewDecoder(r.Body).Decode(&chunk); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		chunkData, _ := utils.Base64Decode(chunk.Chunk)
+		g.chunks[chunk.DataRoot]++
+		g.data[chunk.DataRoot] = chunkData
+		fmt.Fprint(w, "OK")
+	case strings.HasPrefix(r.URL.Path, "/tx/") && strings.HasSuffix(r.URL.Path, "/data"):
+		tx, ok := g.txs[strings.Split(r.URL.Path, "/")[2]]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(g.data[tx.DataRoot])
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+func TestArweaveUploadKeepsHeaderApartFromData(t *testing.T) {
+
+	gw := &fakeGateway{txs: map[string]types.Transaction{}, chunks: map[string]int{}, data: map[string][]byte{}}
+	gateway := httptest.NewServer(gw)
+	defer gateway.Close()
+
+	gatewayURL, _ := url.Parse(gateway.URL)
+	port, _ := strconv.Atoi(gatewayURL.Port())
+	payloadDir := t.TempDir()
+	cfg := &config.Config{Arweave: config.ArweaveConfig{
+		WalletKey:  arweaveKeyfile(t),
+		Host:       gatewayURL.Hostname(),
+		Port:       port,
+		Protocol:   "http",
+		Bundling:   config.ArweaveBundlingOff,
+		PayloadDir: payloadDir,
+	}}
+	repo := &fakeArweaveRepo{
+		uploads:   map[string]*models.ArweaveUpload{},
+		dataItems: map[string]*models.ArweaveDataItem{},
+	}
+	arweave := services.NewArweaveService(cfg, repo)
+
+	pdf := []byte("%PDF-1.7 kept apart")
+	file := filepath.Join(t.TempDir(), "diploma.pdf")
+	if err := os.WriteFile(file, pdf, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	txID, err := arweave.Upload(file, strings.Repeat("cd", 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the header goes to the database; the data is kept on disk
+	upload := repo.uploads[txID]
+	var header types.Transaction
+	if err := json.Unmarshal(upload.SignedTx, &header); err != nil {
+		t.Fatal(err)
+	}
+	if header.ID != txID || header.Data != "" || header.DataRoot == "" || strings.Contains(string(upload.SignedTx), "kept apart") {
+		t.Fatalf("recorded header = %s", upload.SignedTx)
+	}
+	if kept, err := os.ReadFile(filepath.Join(payloadDir, txID)); err != nil || string(kept) != string(pdf) {
+		t.Fatalf("kept data = %q, %v", kept, err)
+	}
+
+	// Its anchor is measured against the current height
+	if age, err := arweave.AnchorAge(upload.SignedTx); err != nil || age != 42 {
+		t.Errorf("anchor age = %d, %v", age, err)
+	}
+
+	// A mined transaction only gets its chunks again
+	delete(gw.txs, txID)
+	if err := arweave.Repost(upload.SignedTx, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, posted := gw.txs[txID]; posted || gw.chunks[header.DataRoot] != 2 {
+		t.Errorf("header posted %v, chunks %d", posted, gw.chunks[header.DataRoot])
+	}
+	if err := arweave.Repost(upload.SignedTx, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, posted := gw.txs[txID]; !posted {
+		t.Error("the header of an unmined transaction was not posted again")
+	}
+
+	// Kept data that no longer matches the header is refused
+	if err := os.WriteFile(filepath.Join(payloadDir, txID), []byte("%PDF-1.7 tampered"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := arweave.Repost(upload.SignedTx, false); err == nil {
+		t.Error("reposted data that does not match the data root")
+	}
+	if err := os.WriteFile(filepath.Join(payloadDir, txID), pdf, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Uploaded again under a new ID, with the same tags; reads of the old
+	// ID are served from it once the replacement is recorded
+	newTxID, err := arweave.Reupload(upload.SignedTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newTxID == txID || repo.uploads[newTxID] == nil {
+		t.Fatalf("reuploaded as %s, recorded %v", newTxID, repo.uploads[newTxID])
+	}
+	if got, want := fmt.Sprint(gw.txs[newTxID].Tags), fmt.Sprint(header.Tags); got != want {
+		t.Errorf("tags = %s, want %s", got, want)
+	}
+
+	delete(gw.txs, txID)
+	if err := repo.ReplaceUpload(txID, newTxID, arweave.URL(newTxID)); err != nil {
+		t.Fatal(err)
+	}
+	arweave.ReleasePayload(txID)
+	if data, err := arweave.GetData(txID); err != nil || string(data) != string(pdf) {
+		t.Errorf("data of the replaced upload = %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(payloadDir, txID)); !os.IsNotExist(err) {
+		t.Errorf("data of the replaced upload still kept: %v", err)
+	}
+}
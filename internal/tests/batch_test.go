@@ -0,0 +1,145 @@
+package tests
+
+import (
+	"BlockCertify/internal/dto"
+	"BlockCertify/internal/models"
+	"BlockCertify/internal/repositories"
+	"BlockCertify/internal/services"
+	"testing"
+
+	"github.com/gofrs/uuid/v5"
+	"gorm.io/gorm"
+)
+
+// fakeBatchRepo keeps jobs and items apart, like the tables, so a job is
+// never returned with its items loaded.
+type fakeBatchRepo struct {
+	repositories.BatchJobRepository
+	jobs  []models.BatchJob
+	items []models.BatchJobItem
+}
+
+func (r *fakeBatchRepo) GetJob(jobID, universityID uuid.UUID) (*models.BatchJob, error) {
+	for _, job := range r.jobs {
+		if job.ID == jobID && job.UniversityID == universityID {
+			return &job, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeBatchRepo) ListJobs(universityID uuid.UUID) ([]models.BatchJob, error) {
+	var jobs []models.BatchJob
+	for i := len(r.jobs) - 1; i >= 0; i-- {
+		if r.jobs[i].UniversityID == universityID {
+			jobs = append(jobs, r.jobs[i])
+		}
+	}
+	return jobs, nil
+}
+
+func (r *fakeBatchRepo) CountItems(jobIDs ...uuid.UUID) ([]repositories.BatchItemCount, error) {
+	var counts []repositories.BatchItemCount
+	for _, jobID := range jobIDs {
+		for _, item := range r.items {
+			if item.JobID != jobID {
+				continue
+			}
+			found := false
+			for i := range counts {
+				if counts[i].JobID == jobID && counts[i].Status == item.Status {
+					counts[i].Count++
+					found = true
+				}
+			}
+			if !found {
+				counts = append(counts, repositories.BatchItemCount{JobID: jobID, Status: item.Status, Count: 1})
+			}
+		}
+	}
+	return counts, nil
+}
+
+func (r *fakeBatchRepo) GetItems(jobID uuid.UUID, afterRow, limit int) ([]models.BatchJobItem, error) {
+	var items []models.BatchJobItem
+	for _, item := range r.items {
+		if item.JobID == jobID && item.RowNumber > afterRow && len(items) < limit {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+func (r *fakeBatchRepo) addJob(universityID uuid.UUID, statuses ...models.BatchItemStatus) uuid.UUID {
+	job := models.BatchJob{
+		ID:           uuid.Must(uuid.NewV7()),
+		UniversityID: universityID,
+		Status:       models.BatchJobCompletedWithErrors,
+		TotalItems:   len(statuses),
+	}
+	r.jobs = append(r.jobs, job)
+	for i, status := range statuses {
+		r.items = append(r.items, models.BatchJobItem{
+			ID:        uuid.Must(uuid.NewV7()),
+			JobID:     job.ID,
+			RowNumber: i + 1,
+			Status:    status,
+		})
+	}
+	return job.ID
+}
+
+func TestBatchJobCountsAndItemPages(t *testing.T) {
+
+	universityID := uuid.Must(uuid.NewV7())
+	repo := &fakeBatchRepo{}
+	first := repo.addJob(universityID, models.BatchItemUploaded, models.BatchItemUploaded, models.BatchItemFailed, models.BatchItemInvalid, models.BatchItemPending)
+	second := repo.addJob(universityID, models.BatchItemUploading)
+	repo.addJob(uuid.Must(uuid.NewV7()), models.BatchItemUploaded)
+
+	svc := services.NewBatchService(nil, nil, nil, repo, t.TempDir())
+
+	jobs, err := svc.ListJobs(universityID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 2 || jobs[0].ID != second || jobs[1].ID != first {
+		t.Fatalf("jobs = %+v", jobs)
+	}
+	if j := jobs[1]; j.Uploaded != 2 || j.Failed != 1 || j.Invalid != 1 || j.Pending != 1 || len(j.Items) != 0 {
+		t.Errorf("first job = %+v", j)
+	}
+	if j := jobs[0]; j.Pending != 1 || j.Uploaded != 0 {
+		t.Errorf("second job = %+v", j)
+	}
+
+	var rows []int
+	query := dto.BatchJobItemQuery{Limit: 2}
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("the item pages do not end")
+		}
+		job, err := svc.GetJob(first, universityID, query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The counts cover the whole job, not the page
+		if job.Uploaded != 2 || job.Failed != 1 || len(job.Items) > 2 {
+			t.Fatalf("page = %+v", job)
+		}
+		for _, item := range job.Items {
+			rows = append(rows, item.RowNumber)
+		}
+		if job.NextCursor == 0 {
+			break
+		}
+		query.Cursor = job.NextCursor
+	}
+	if len(rows) != 5 || rows[0] != 1 || rows[4] != 5 {
+		t.Errorf("rows = %v", rows)
+	}
+
+	if _, err := svc.GetJob(first, uuid.Must(uuid.NewV7()), dto.BatchJobItemQuery{}); err == nil {
+		t.Error("a job of another university was returned")
+	}
+}
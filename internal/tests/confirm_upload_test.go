@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"BlockCertify/internal/config"
+	apperrors "BlockCertify/internal/pkg/errors"
+	"BlockCertify/internal/repositories"
+	"BlockCertify/internal/services"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// TestConfirmDiplomaTransaction checks that a browser-submitted transaction
+// is only confirmed when it stored the posted diploma on our contract.
+func TestConfirmDiplomaTransaction(t *testing.T) {
+
+	owner, err := repositories.SignerAddress(config.DevPrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outsiderKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend, chainID := newSimulatedBackend(t, owner, crypto.PubkeyToAddress(outsiderKey.PublicKey))
+
+	deploy := func() *repositories.ContractRepository {
+		network := config.NetworkProfile{Name: config.DevNetwork, ChainID: chainID, ContractVersion: "v2"}
+		address, err := repositories.DeployContractWithClient(backend.Client(), network, "../../contracts/build", config.DevPrivateKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		network.ContractAddress = address.Hex()
+		ledger, err := repositories.NewContractRepositoryWithClient(network, "", backend.Client())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ledger
+	}
+	ledger, other := deploy(), deploy()
+
+	const arweaveTxID = "confirmed-arweave-tx"
+	hash := strings.Repeat("ab", 32)
+
+	call, err := ledger.PackStoreDiploma(hash, arweaveTxID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := mineCall(t, ledger, config.DevPrivateKey, call)
+
+	// The same diploma stored on a contract that is not ours
+	call, err = other.PackStoreDiploma(hash, arweaveTxID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	foreign := mineCall(t, other, config.DevPrivateKey, call)
+
+	// An outsider's store is mined, but reverts
+	call, err = ledger.PackStoreDiploma(strings.Repeat("cd", 32), arweaveTxID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outsiderHex := hex.EncodeToString(crypto.FromECDSA(outsiderKey))
+	gasPrice, gasTipCap, err := ledger.GetFeeData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := ledger.SignTransaction(outsiderHex, 0, 300000, gasTipCap, new(big.Int).Mul(gasPrice, big.NewInt(2)), call)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ledger.SendTransaction(tx); err != nil {
+		t.Fatal(err)
+	}
+	reverted := waitReceipt(t, ledger, tx.Hash())
+	if reverted.Status != types.ReceiptStatusFailed {
+		t.Fatal("the outsider's store did not revert")
+	}
+
+	cfg := &config.Config{}
+	cfg.Blockchain.PrivateKey = config.DevPrivateKey
+	cfg.Blockchain.MinBalance = "0"
+	blockchain := services.NewBlockChainService(cfg, repositories.LedgersOf(ledger), &unsentOutbox{t: t})
+
+	cases := []struct {
+		name        string
+		txHash      string
+		diplomaHash string
+		arweaveTxID string
+		code        string
+	}{
+		{"malformed hash", "0x1234", hash, arweaveTxID, apperrors.ErrInvalidRequest},
+		{"unknown transaction", "0x" + strings.Repeat("01", 32), hash, arweaveTxID, apperrors.ErrTransactionNotFound},
+		{"reverted", reverted.TxHash.Hex(), hash, arweaveTxID, apperrors.ErrTransactionMismatch},
+		{"other contract", foreign.TxHash.Hex(), hash, arweaveTxID, apperrors.ErrTransactionMismatch},
+		{"other diploma hash", stored.TxHash.Hex(), strings.Repeat("ef", 32), arweaveTxID, apperrors.ErrTransactionMismatch},
+		{"other Arweave TxID", stored.TxHash.Hex(), hash, "forged-arweave-tx", apperrors.ErrTransactionMismatch},
+	}
+	for _, tc := range cases {
+		if _, err := blockchain.ConfirmDiplomaTransaction(tc.txHash, tc.diplomaHash, tc.arweaveTxID); !isAppError(err, tc.code) {
+			t.Errorf("%s: %v, want %s", tc.name, err, tc.code)
+		}
+	}
+
+	result, err := blockchain.ConfirmDiplomaTransaction(stored.TxHash.Hex(), hash, arweaveTxID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.TransactionHash != stored.TxHash.Hex() || result.BlockNumber != stored.BlockNumber.Uint64() || result.BlockHash != stored.BlockHash.Hex() {
+		t.Errorf("result = %+v", result)
+	}
+}
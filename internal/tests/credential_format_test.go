@@ -0,0 +1,224 @@
+package tests
+
+import (
+	"BlockCertify/internal/models"
+	"BlockCertify/internal/pkg/vc"
+	"BlockCertify/internal/services"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"encoding/xml"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+func testCredentialData() services.CredentialData {
+
+	diplomaID := uuid.Must(uuid.NewV7())
+
+	return services.CredentialData{
+		Diploma: &models.Diploma{
+			ID:          diplomaID,
+			PublicID:    "DPL-TEST",
+			Hash:        "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+			ArweaveTxID: "arweave-tx",
+			ArweaveURL:  "https://arweave.net/arweave-tx",
+			PolygonTxID: "0xabc",
+			PolygonURL:  "https://amoy.polygonscan.com/tx/0xabc",
+			Owner:       "Ada Lovelace",
+			Timestamp:   time.Date(2024, time.June, 30, 12, 0, 0, 0, time.UTC),
+			MetaData: models.DiplomaMetaData{
+				FirstName:      "Ada",
+				LastName:       "Lovelace",
+				Email:          "ada@example.edu",
+				Faculty:        "Faculty of Engineering",
+				Department:     "Computer Engineering",
+				GraduationYear: 2024,
+				StudentNumber:  "20200001",
+			},
+		},
+		University: models.Universities{
+			ID:      uuid.Must(uuid.NewV7()),
+			Name:    "Example University",
+			YokCode: "YOK-1234",
+		},
+		IssuerDID:       "did:key:z6MkExample",
+		ChainID:         80002,
+		ContractAddress: "0x0000000000000000000000000000000000000001",
+	}
+}
+
+// The *_export schemas pin the fields of the official schemas our exports
+// must fill in, and the values the test diploma maps to.
+func TestCredentialFormatsMatchSchemas(t *testing.T) {
+
+	cases := []struct {
+		formatter services.CredentialFormatter
+		schema    string
+	}{
+		{services.NewOpenBadgeFormatter(), "testdata/schemas/ob3_export.schema.json"},
+		{services.NewEDCFormatter(), "testdata/schemas/edc_export.schema.json"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.formatter.Name(), func(t *testing.T) {
+			validateCredential(t, tc.schema, tc.formatter.Build(testCredentialData()))
+		})
+	}
+}
+
+// TestCredentialFormatsMatchOfficialSchemas validates the exports against
+// the published schemas in testdata/schemas/official (see the README there).
+func TestCredentialFormatsMatchOfficialSchemas(t *testing.T) {
+
+	cases := []struct {
+		formatter services.CredentialFormatter
+		pattern   string
+	}{
+		{services.NewOpenBadgeFormatter(), "ob_v3p0_achievementcredential_schema.json"},
+		{services.NewEDCFormatter(), "edc_*.json"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.formatter.Name(), func(t *testing.T) {
+
+			schemas, err := filepath.Glob(filepath.Join("testdata/schemas/official", tc.pattern))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(schemas) == 0 {
+				t.Skipf("no official schema matching %s in testdata/schemas/official", tc.pattern)
+			}
+
+			for _, schema := range schemas {
+				validateCredential(t, schema, tc.formatter.Build(testCredentialData()))
+			}
+		})
+	}
+}
+
+func TestCredentialFormatsCanBeSigned(t *testing.T) {
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := testCredentialData()
+	data.IssuerDID = vc.DIDKey(publicKey)
+
+	for _, formatter := range []services.CredentialFormatter{
+		services.NewW3CFormatter(),
+		services.NewOpenBadgeFormatter(),
+		services.NewEDCFormatter(),
+	} {
+		credential := formatter.Build(data)
+		if err := vc.Sign(credential, privateKey, vc.VerificationMethod(data.IssuerDID), time.Now()); err != nil {
+			t.Fatalf("%s: %v", formatter.Name(), err)
+		}
+		if _, err := vc.Verify(roundTrip(t, credential)); err != nil {
+			t.Errorf("%s: %v", formatter.Name(), err)
+		}
+	}
+}
+
+func roundTrip(t *testing.T, v map[string]any) map[string]any {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out map[string]any
+	if err := json.Unmarshal(raw, &out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func validateCredential(t *testing.T, schemaFile string, credential map[string]any) {
+	t.Helper()
+
+	schema, err := jsonschema.NewCompiler().Compile(schemaFile)
+	if err != nil {
+		t.Fatalf("%s: %v", schemaFile, err)
+	}
+	if err := schema.Validate(roundTrip(t, credential)); err != nil {
+		t.Errorf("%s: %v", schemaFile, err)
+	}
+}
+
+func TestEDCXMLExport(t *testing.T) {
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := testCredentialData()
+	data.IssuerDID = vc.DIDKey(publicKey)
+
+	credential := services.NewEDCFormatter().Build(data)
+	if err := vc.Sign(credential, privateKey, vc.VerificationMethod(data.IssuerDID), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := services.EncodeEDCXML(credential)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		XMLName xml.Name `xml:"http://data.europa.eu/snb/model/elm/ europeanDigitalCredential"`
+		ID      string   `xml:"id,attr"`
+		Context []string `xml:"context"`
+		Type    []string `xml:"type"`
+		Issuer  struct {
+			ID        string `xml:"id,attr"`
+			LegalName struct {
+				Lang string `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+				Text string `xml:",chardata"`
+			} `xml:"legalName"`
+			Registration struct {
+				Notation string `xml:"notation"`
+			} `xml:"registration"`
+		} `xml:"issuer"`
+		Subject struct {
+			HasClaim []struct {
+				AwardedBy struct {
+					AwardingBody []struct {
+						LegalName string `xml:"legalName"`
+					} `xml:"awardingBody"`
+				} `xml:"awardedBy"`
+			} `xml:"hasClaim"`
+		} `xml:"credentialSubject"`
+		Evidence []struct {
+			ID string `xml:"id,attr"`
+		} `xml:"evidence"`
+		Proof struct {
+			VerificationMethod string `xml:"verificationMethod"`
+			ProofValue         string `xml:"proofValue"`
+		} `xml:"proof"`
+	}
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		t.Fatalf("export is not well-formed: %v\n%s", err, body)
+	}
+
+	if doc.ID != credential["id"] || len(doc.Context) != 2 || len(doc.Type) != 2 || doc.Type[1] != "EuropeanDigitalCredential" {
+		t.Errorf("credential = %s %v %v", doc.ID, doc.Context, doc.Type)
+	}
+	if doc.Issuer.ID != data.IssuerDID || doc.Issuer.LegalName.Lang != "en" || doc.Issuer.LegalName.Text != "Example University" || doc.Issuer.Registration.Notation != "YOK-1234" {
+		t.Errorf("issuer = %+v", doc.Issuer)
+	}
+	if len(doc.Subject.HasClaim) != 1 || len(doc.Subject.HasClaim[0].AwardedBy.AwardingBody) != 1 || doc.Subject.HasClaim[0].AwardedBy.AwardingBody[0].LegalName != "Faculty of Engineering" {
+		t.Errorf("subject = %+v", doc.Subject)
+	}
+	if len(doc.Evidence) != 2 || doc.Evidence[0].ID != data.Diploma.ArweaveURL {
+		t.Errorf("evidence = %+v", doc.Evidence)
+	}
+	if doc.Proof.VerificationMethod != vc.VerificationMethod(data.IssuerDID) || doc.Proof.ProofValue == "" {
+		t.Errorf("proof = %+v", doc.Proof)
+	}
+}
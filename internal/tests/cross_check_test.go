@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"BlockCertify/internal/config"
+	"BlockCertify/internal/dto"
+	"BlockCertify/internal/models"
+	"BlockCertify/internal/services"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+func TestVerifyCrossChecksEveryLayer(t *testing.T) {
+
+	universityID := uuid.Must(uuid.NewV7())
+	repo := &memoryDiplomas{}
+	chain := &registeredChain{MockBlockchainService: services.NewMockBlockchainService(), stored: map[string]string{}}
+	local := services.NewLocalStore(config.LocalStorageConfig{Dir: t.TempDir()})
+	stores := services.NewDocumentStores(local)
+
+	// issue stores data, the diploma's own PDF unless given, under the PDF's
+	// hash, anchors it when asked and records the diploma
+	issue := func(name string, data []byte, anchored bool) *models.Diploma {
+		pdf := []byte("%PDF-1.7 " + name)
+		if data == nil {
+			data = pdf
+		}
+		file := filepath.Join(t.TempDir(), name+".pdf")
+		if err := os.WriteFile(file, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		reference, err := stores.Upload(file, hashPDF(pdf))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		diploma := repo.add(universityID, name)
+		diploma.Hash = hashPDF(pdf)
+		diploma.ArweaveTxID = reference
+		if anchored {
+			chain.stored[diploma.Hash] = reference
+		}
+		return diploma
+	}
+
+	issue("intact", nil, true)
+	issue("unanchored", nil, false)
+	issue("elsewhere", nil, true)
+	issue("tampered", []byte("%PDF-1.7 swapped"), true)
+	issue("orphaned", nil, true).Finality = models.FinalityOrphaned
+	// Its public ID is not derived from its key
+	issue("inconsistent", nil, true).PublicID = "BC-000000000000"
+
+	// The contract points the hash to another document than the database
+	elsewhere, _ := repo.GetByHash(hashPDF([]byte("%PDF-1.7 elsewhere")))
+	chain.stored[elsewhere.Hash] = stores.Reference(local.Name(), "elsewhere.pdf")
+
+	diplomas := services.NewDiplomaService(stores, chain, repo, nil)
+
+	cases := []struct {
+		name                         string
+		chain, storage, db, verified bool
+	}{
+		{"intact", true, true, true, true},
+		{"unanchored", false, true, true, false},
+		{"elsewhere", false, false, true, false},
+		{"tampered", true, false, true, false},
+		{"orphaned", false, true, true, false},
+		{"inconsistent", true, true, false, false},
+	}
+	for _, tc := range cases {
+		diploma, _ := repo.GetByHash(hashPDF([]byte("%PDF-1.7 " + tc.name)))
+		response, err := diplomas.Verify(dto.VerifyDiplomaRequest{DiplomaID: diploma.PublicID}, universityID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		checks := response.Checks
+		if checks.ChainMatch != tc.chain || checks.ArweaveMatch != tc.storage || checks.DBMatch != tc.db || response.Verified != tc.verified {
+			t.Errorf("%s: verified %v, checks %+v", tc.name, response.Verified, checks)
+		}
+		// Every layer that disagrees says why
+		if !tc.verified && len(checks.Details) == 0 {
+			t.Errorf("%s: not verified without details", tc.name)
+		}
+	}
+}
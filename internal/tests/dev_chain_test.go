@@ -0,0 +1,142 @@
+package tests
+
+import (
+	"BlockCertify/internal/config"
+	"BlockCertify/internal/repositories"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// TestDevChainIssuance deploys the v1 contract on a local node, stores a
+// diploma through the ledger and reads it back. It needs a running node
+// (make dev-chain) and the compiled contracts (make contracts):
+//
+//	DEV_CHAIN_RPC_URL=http://127.0.0.1:8545 go test ./internal/tests -run DevChain
+func TestDevChainIssuance(t *testing.T) {
+
+	rpcURL := os.Getenv("DEV_CHAIN_RPC_URL")
+	if rpcURL == "" {
+		t.Skip("DEV_CHAIN_RPC_URL is not set")
+	}
+
+	network := config.NetworkProfile{
+		Name:            config.DevNetwork,
+		ChainID:         31337,
+		RPCURL:          rpcURL,
+		ContractVersion: "v1",
+	}
+
+	address, err := repositories.DeployContract(network, "../../contracts/build", config.DevPrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	network.ContractAddress = address.Hex()
+
+	ledger, err := repositories.NewContractRepository(network, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ledger.Close()
+
+	checkIssuance(t, ledger, config.DevPrivateKey)
+}
+
+// devDiplomaHash is the diploma checkIssuance stores.
+const devDiplomaHash = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+// checkIssuance stores a diploma through the ledger, signed with
+// privateKeyHex, and reads it back.
+func checkIssuance(t *testing.T, ledger *repositories.ContractRepository, privateKeyHex string) {
+	t.Helper()
+
+	const (
+		hash        = devDiplomaHash
+		arweaveTxID = "dev-arweave-tx"
+	)
+
+	signer, err := repositories.SignerAddress(privateKeyHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	call, err := ledger.PackStoreDiploma(hash, arweaveTxID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receipt := mineCall(t, ledger, privateKeyHex, call)
+
+	event, err := ledger.ParseDiplomaStored(receipt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.DiplomaHash != hash || event.ArweaveTxID != arweaveTxID || event.Owner != signer {
+		t.Fatalf("unexpected DiplomaStored event %+v", event)
+	}
+
+	exists, storedTxID, err := ledger.VerifyDiploma(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !exists || storedTxID != arweaveTxID {
+		t.Fatalf("verifyDiploma = %v, %q", exists, storedTxID)
+	}
+}
+
+// mineCall signs call with privateKeyHex, sends it and waits for a
+// successful receipt.
+func mineCall(t *testing.T, ledger *repositories.ContractRepository, privateKeyHex string, call *repositories.ContractCall) *types.Receipt {
+	t.Helper()
+
+	signer, err := repositories.SignerAddress(privateKeyHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gas, err := ledger.EstimateGas(signer, call)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce, err := ledger.PendingNonceAt(signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gasPrice, gasTipCap, err := ledger.GetFeeData()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := ledger.SignTransaction(privateKeyHex, nonce, gas, gasTipCap, new(big.Int).Mul(gasPrice, big.NewInt(2)), call)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ledger.SendTransaction(tx); err != nil {
+		t.Fatal(err)
+	}
+
+	receipt := waitReceipt(t, ledger, tx.Hash())
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("transaction %s reverted", tx.Hash().Hex())
+	}
+	return receipt
+}
+
+// waitReceipt waits up to 10 seconds for the receipt of txHash.
+func waitReceipt(t *testing.T, ledger *repositories.ContractRepository, txHash common.Hash) *types.Receipt {
+	t.Helper()
+
+	for deadline := time.Now().Add(10 * time.Second); ; {
+		receipt, err := ledger.GetTransactionReceipt(txHash)
+		if err == nil {
+			return receipt
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("transaction %s not mined: %v", txHash.Hex(), err)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"BlockCertify/internal/config"
+	"BlockCertify/internal/handlers"
+	"BlockCertify/internal/models"
+	"BlockCertify/internal/repositories"
+	"BlockCertify/internal/services"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// pdfDiplomas looks diplomas up by public ID.
+type pdfDiplomas struct {
+	repositories.DiplomaRepository
+	diplomas map[string]*models.Diploma
+}
+
+func (r *pdfDiplomas) GetByDiplomaID(diplomaID string) (*models.Diploma, error) {
+	diploma, ok := r.diplomas[diplomaID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return diploma, nil
+}
+
+func TestDiplomaPDFReadsThroughDocumentStores(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// The public URL leads nowhere, so only reads through the store succeed
+	local := services.NewLocalStore(config.LocalStorageConfig{
+		Dir:       t.TempDir(),
+		PublicURL: "http://127.0.0.1:1/documents",
+	})
+	stores := services.NewDocumentStores(local)
+
+	plainPDF := []byte("%PDF-1.7 plaintext diploma")
+	upload := filepath.Join(t.TempDir(), "plain.pdf")
+	if err := os.WriteFile(upload, plainPDF, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	address, err := local.Upload(upload, "plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plainReference := stores.Reference(local.Name(), address)
+
+	stores.EnableEncryption(true, "1", bytes.Repeat([]byte{7}, 32), memoryDocumentKeys{})
+	sealedPDF := []byte("%PDF-1.7 encrypted diploma")
+	upload = filepath.Join(t.TempDir(), "sealed.pdf")
+	if err := os.WriteFile(upload, sealedPDF, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sealedReference, err := stores.Upload(upload, "sealed")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := &pdfDiplomas{diplomas: map[string]*models.Diploma{
+		"PLAIN":   {ArweaveTxID: plainReference, ArweaveURL: local.URL(address)},
+		"SEALED":  {ArweaveTxID: sealedReference, Encrypted: true},
+		"MISSING": {ArweaveTxID: stores.Reference(local.Name(), "missing.pdf")},
+	}}
+	handler := handlers.NewDiplomaHandler(services.NewDiplomaService(stores, nil, repo, nil))
+
+	r := gin.New()
+	r.GET("/diplomas/:diplomaId/pdf", handler.GetDiplomaById)
+
+	cases := []struct {
+		diplomaID string
+		status    int
+		body      []byte
+	}{
+		{"PLAIN", http.StatusOK, plainPDF},
+		{"SEALED", http.StatusOK, sealedPDF},
+		{"UNKNOWN", http.StatusNotFound, nil},
+		{"MISSING", http.StatusBadGateway, nil},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/diplomas/"+tc.diplomaID+"/pdf", nil))
+
+		if w.Code != tc.status {
+			t.Errorf("%s: status = %d, want %d: %s", tc.diplomaID, w.Code, tc.status, w.Body)
+			continue
+		}
+		if tc.body != nil && !bytes.Equal(w.Body.Bytes(), tc.body) {
+			t.Errorf("%s: body = %q", tc.diplomaID, w.Body)
+		}
+	}
+}
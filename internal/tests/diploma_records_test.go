@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"BlockCertify/internal/dto"
+	apperrors "BlockCertify/internal/pkg/errors"
+	"BlockCertify/internal/services"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+func TestDiplomaRecordPages(t *testing.T) {
+
+	universityID := uuid.Must(uuid.NewV7())
+	repo := &memoryDiplomas{}
+	var issued []string
+	for i := 1; i <= 5; i++ {
+		issued = append(issued, repo.add(universityID, fmt.Sprintf("Graduate %d", i)).PublicID)
+	}
+	repo.add(uuid.Must(uuid.NewV7()), "Graduate of another university")
+
+	diplomas := services.NewDiplomaService(nil, nil, repo, nil)
+
+	var listed []string
+	query := dto.DiplomaRecordQuery{Limit: 2}
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("the record pages do not end")
+		}
+		page, err := diplomas.GetDiplomaRecords(query, universityID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The total counts every match, not the page
+		if page.Total != 5 || len(page.Items) > 2 {
+			t.Fatalf("page = %+v", page)
+		}
+		for _, item := range page.Items {
+			listed = append(listed, item.DiplomaID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+
+	// Newest first, each diploma once
+	if len(listed) != 5 {
+		t.Fatalf("listed %v", listed)
+	}
+	for i, diplomaID := range listed {
+		if diplomaID != issued[4-i] {
+			t.Errorf("listed %v, want %v reversed", listed, issued)
+			break
+		}
+	}
+
+	// The page size is bounded, and one extra row is asked for to tell
+	// whether another page follows
+	for limit, want := range map[int]int{0: 26, -1: 26, 5: 6, 1000: 101} {
+		if _, err := diplomas.GetDiplomaRecords(dto.DiplomaRecordQuery{Limit: limit}, universityID); err != nil {
+			t.Fatal(err)
+		}
+		if repo.filter.Limit != want {
+			t.Errorf("limit %d: asked for %d rows, want %d", limit, repo.filter.Limit, want)
+		}
+	}
+
+	query = dto.DiplomaRecordQuery{Faculty: " Engineering ", Search: " 2021 ", GraduationYear: 2024, From: "2024-06-01", To: "2024-06-30"}
+	if _, err := diplomas.GetDiplomaRecords(query, universityID); err != nil {
+		t.Fatal(err)
+	}
+	filter := repo.filter
+	// The to date is inclusive: the whole day is matched
+	if filter.Faculty != "Engineering" || filter.Search != "2021" || filter.GraduationYear != 2024 ||
+		!filter.From.Equal(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)) || !filter.To.Equal(time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("filter = %+v", filter)
+	}
+
+	for _, invalid := range []dto.DiplomaRecordQuery{
+		{Cursor: "BC-123456789ABC"},
+		{From: "01/06/2024"},
+		{To: "2024-13-01"},
+	} {
+		if _, err := diplomas.GetDiplomaRecords(invalid, universityID); !isAppError(err, apperrors.ErrInvalidRequest) {
+			t.Errorf("query %+v: %v", invalid, err)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package tests
+
+import (
+	"BlockCertify/internal/config"
+	"BlockCertify/internal/models"
+	"BlockCertify/internal/services"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestLocalDocumentStore(t *testing.T) {
+
+	dir := t.TempDir()
+	local := services.NewLocalStore(config.LocalStorageConfig{
+		Dir:       filepath.Join(dir, "documents"),
+		PublicURL: "http://localhost:8080/documents",
+	})
+	stores := services.NewDocumentStores(local, services.NewMockArweaveService(""))
+
+	pdf := []byte("%PDF-1.7 diploma")
+	sum := sha256.Sum256(pdf)
+	hash := hex.EncodeToString(sum[:])
+
+	upload := filepath.Join(dir, "upload.pdf")
+	if err := os.WriteFile(upload, pdf, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	address, err := stores.Active().Upload(upload, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reference := stores.Reference(local.Name(), address)
+	if reference != "local://"+hash+".pdf" {
+		t.Fatalf("reference = %s", reference)
+	}
+
+	store, resolved, err := stores.Resolve(reference)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.Name() != config.StorageLocal || resolved != address {
+		t.Fatalf("resolved %s to %s %s", reference, store.Name(), resolved)
+	}
+	if got := store.URL(resolved); got != "http://localhost:8080/documents/"+hash+".pdf" {
+		t.Fatalf("URL = %s", got)
+	}
+
+	tag, err := store.GetFileHashTag(resolved)
+	if err != nil || tag != hash {
+		t.Fatalf("file hash = %q, %v", tag, err)
+	}
+	data, err := store.GetData(resolved)
+	if err != nil || string(data) != string(pdf) {
+		t.Fatalf("data = %q, %v", data, err)
+	}
+
+	// Names that would leave the directory are refused
+	if _, err := store.GetData("../upload.pdf"); err == nil {
+		t.Fatal("read outside the storage directory")
+	}
+}
+
+func TestDocumentReferences(t *testing.T) {
+
+	stores := services.NewDocumentStores(services.NewMockArweaveService(""))
+
+	// Arweave references are the bare TxID, as before other stores existed
+	store, address, err := stores.Resolve("ar-tx-1")
+	if err != nil || store.Name() != config.StorageArweave || address != "ar-tx-1" {
+		t.Fatalf("resolved ar-tx-1 to %v %s %v", store, address, err)
+	}
+	if got := stores.Reference(config.StorageArweave, "ar-tx-1"); got != "ar-tx-1" {
+		t.Fatalf("reference = %s", got)
+	}
+
+	if _, _, err := stores.Resolve("ipfs://bafybeigdyrzt"); err == nil {
+		t.Fatal("resolved a reference to a store that is not configured")
+	}
+	if _, _, err := stores.Resolve(""); err == nil {
+		t.Fatal("resolved an empty reference")
+	}
+}
+
+// memoryDocumentKeys keeps document keys in memory in place of Postgres.
+type memoryDocumentKeys map[string]*models.DocumentKey
+
+func (m memoryDocumentKeys) Save(key *models.DocumentKey) error {
+	m[key.Reference] = key
+	return nil
+}
+
+func (m memoryDocumentKeys) GetByReference(reference string) (*models.DocumentKey, error) {
+	key, ok := m[reference]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return key, nil
+}
+
+func TestEncryptedDocumentStore(t *testing.T) {
+
+	dir := t.TempDir()
+	local := services.NewLocalStore(config.LocalStorageConfig{Dir: dir})
+	stores := services.NewDocumentStores(local)
+	keys := memoryDocumentKeys{}
+	stores.EnableEncryption(true, "1", bytes.Repeat([]byte{7}, 32), keys)
+
+	pdf := []byte("%PDF-1.7 diploma")
+	sum := sha256.Sum256(pdf)
+	hash := hex.EncodeToString(sum[:])
+
+	upload := filepath.Join(t.TempDir(), "upload.pdf")
+	if err := os.WriteFile(upload, pdf, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	reference, err := stores.Upload(upload, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if encrypted, err := stores.IsEncrypted(reference); err != nil || !encrypted {
+		t.Fatalf("encrypted = %v, %v", encrypted, err)
+	}
+
+	// The store only ever sees the ciphertext, under the plaintext's hash
+	store, address, err := stores.Resolve(reference)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := store.GetData(address)
+	if err != nil || bytes.Equal(stored, pdf) {
+		t.Fatalf("stored %q, %v", stored, err)
+	}
+	if tag, err := store.GetFileHashTag(address); err != nil || tag != hash {
+		t.Fatalf("file hash = %q, %v", tag, err)
+	}
+
+	data, err := stores.Read(reference)
+	if err != nil || !bytes.Equal(data, pdf) {
+		t.Fatalf("read %q, %v", data, err)
+	}
+
+	// A retried upload overwrites the same file, sealed with the key already
+	// stored for it
+	wrappedKey := keys[reference].WrappedKey
+	if again, err := stores.Upload(upload, hash); err != nil || again != reference {
+		t.Fatalf("retried upload = %q, %v", again, err)
+	}
+	if !bytes.Equal(keys[reference].WrappedKey, wrappedKey) {
+		t.Error("retried upload replaced the data key")
+	}
+	if data, err := stores.Read(reference); err != nil || !bytes.Equal(data, pdf) {
+		t.Fatalf("read after retry %q, %v", data, err)
+	}
+
+	// Documents uploaded before encryption was enabled read as they are
+	plain := services.NewDocumentStores(local)
+	address, err = local.Upload(upload, "plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reference = plain.Reference(local.Name(), address)
+	if encrypted, _ := stores.IsEncrypted(reference); encrypted {
+		t.Fatal("plaintext document reported as encrypted")
+	}
+	if data, err := stores.Read(reference); err != nil || !bytes.Equal(data, pdf) {
+		t.Fatalf("read %q, %v", data, err)
+	}
+}
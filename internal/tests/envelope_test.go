@@ -0,0 +1,72 @@
+package tests
+
+import (
+	"BlockCertify/internal/pkg/envelope"
+	"bytes"
+	"testing"
+)
+
+func TestEnvelope(t *testing.T) {
+
+	masterKey := bytes.Repeat([]byte{7}, 32)
+	plaintext := []byte("%PDF-1.7 diploma")
+	aad := []byte("diploma-hash")
+
+	ciphertext, wrappedKey, err := envelope.Seal(masterKey, plaintext, aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(ciphertext, plaintext) {
+		t.Fatal("ciphertext contains the plaintext")
+	}
+
+	opened, err := envelope.Open(masterKey, wrappedKey, ciphertext, aad)
+	if err != nil || !bytes.Equal(opened, plaintext) {
+		t.Fatalf("opened %q, %v", opened, err)
+	}
+
+	// A document sealed for one diploma does not open as another
+	if _, err := envelope.Open(masterKey, wrappedKey, ciphertext, []byte("other-hash")); err == nil {
+		t.Fatal("opened with the wrong diploma hash")
+	}
+
+	tampered := bytes.Clone(ciphertext)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := envelope.Open(masterKey, wrappedKey, tampered, aad); err == nil {
+		t.Fatal("opened a tampered document")
+	}
+
+	if _, err := envelope.Open(bytes.Repeat([]byte{8}, 32), wrappedKey, ciphertext, aad); err == nil {
+		t.Fatal("opened with the wrong master key")
+	}
+
+	// Sealing again under the stored key keeps the stored key valid
+	resealed, err := envelope.SealWith(masterKey, wrappedKey, []byte("%PDF-1.7 again"), aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opened, err := envelope.Open(masterKey, wrappedKey, resealed, aad); err != nil || string(opened) != "%PDF-1.7 again" {
+		t.Fatalf("opened %q, %v", opened, err)
+	}
+}
+
+func TestWrapSecret(t *testing.T) {
+
+	masterKey := bytes.Repeat([]byte{7}, 32)
+	seed := bytes.Repeat([]byte{1}, 32)
+
+	wrapped, err := envelope.Wrap(masterKey, seed, []byte("issuer-key/a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(wrapped, seed) {
+		t.Fatal("wrapped secret contains the seed")
+	}
+
+	if unwrapped, err := envelope.Unwrap(masterKey, wrapped, []byte("issuer-key/a")); err != nil || !bytes.Equal(unwrapped, seed) {
+		t.Fatalf("unwrapped %x, %v", unwrapped, err)
+	}
+	if _, err := envelope.Unwrap(masterKey, wrapped, []byte("issuer-key/b")); err == nil {
+		t.Fatal("unwrapped for another purpose")
+	}
+}
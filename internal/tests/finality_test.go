@@ -0,0 +1,224 @@
+package tests
+
+import (
+	"BlockCertify/internal/config"
+	"BlockCertify/internal/models"
+	"BlockCertify/internal/services"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+)
+
+type fakeChainBlock struct {
+	number uint64
+	hash   string
+}
+
+// fakeChain serves receipts and the chain head from memory.
+type fakeChain struct {
+	*services.MockBlockchainService
+	head   uint64
+	blocks map[string]fakeChainBlock // tx hash -> including block
+}
+
+func (c *fakeChain) GetTransactionBlock(txHash string) (uint64, string, bool, error) {
+	block, ok := c.blocks[txHash]
+	return block.number, block.hash, ok, nil
+}
+
+func (c *fakeChain) LatestBlockNumber() (uint64, error) {
+	return c.head, nil
+}
+
+// fakeNetworks routes ForNetwork to one fakeChain per network name.
+type fakeNetworks struct {
+	*fakeChain
+	name   string
+	others map[string]*fakeNetworks
+}
+
+func (c *fakeNetworks) Network() config.NetworkProfile {
+	return config.NetworkProfile{Name: c.name}
+}
+
+func (c *fakeNetworks) ForNetwork(network string) (services.BlockchainService, error) {
+	if other, ok := c.others[network]; ok {
+		return other, nil
+	}
+	return nil, errors.New("unknown network")
+}
+
+type fakeFinalityRepo struct {
+	diplomas []models.Diploma
+	checks   map[string]int // lookups by transaction
+}
+
+func (r *fakeFinalityRepo) GetUnfinalized(limit int, orphanedBefore time.Time) ([]models.Diploma, error) {
+	var result []models.Diploma
+	seen := map[string]bool{}
+	for _, d := range r.diplomas {
+		if d.Finality == models.FinalityFinal || seen[d.PolygonTxID] {
+			continue
+		}
+		if d.Finality == models.FinalityOrphaned && d.FinalityCheckedAt != nil && !d.FinalityCheckedAt.Before(orphanedBefore) {
+			continue
+		}
+		seen[d.PolygonTxID] = true
+		result = append(result, d)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		a, b := result[i].FinalityCheckedAt, result[j].FinalityCheckedAt
+		return a == nil && b != nil || a != nil && b != nil && a.Before(*b)
+	})
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+func (r *fakeFinalityRepo) MarkChecked(polygonTxID string) error {
+	now := time.Now()
+	for i := range r.diplomas {
+		if r.diplomas[i].PolygonTxID == polygonTxID {
+			r.diplomas[i].FinalityCheckedAt = &now
+		}
+	}
+	if r.checks == nil {
+		r.checks = map[string]int{}
+	}
+	r.checks[polygonTxID]++
+	return nil
+}
+
+func (r *fakeFinalityRepo) UpdateFinality(polygonTxID string, blockNumber uint64, blockHash string, confirmations uint64, finality models.Finality) error {
+	for i := range r.diplomas {
+		d := &r.diplomas[i]
+		if d.PolygonTxID == polygonTxID {
+			d.BlockNumber = blockNumber
+			d.BlockHash = blockHash
+			d.Confirmations = confirmations
+			d.Finality = finality
+		}
+	}
+	return r.MarkChecked(polygonTxID)
+}
+
+func TestFinalityTracking(t *testing.T) {
+
+	chain := &fakeChain{
+		MockBlockchainService: services.NewMockBlockchainService(),
+		head:                  100,
+		blocks:                map[string]fakeChainBlock{"0xaa": {100, "0xb100"}},
+	}
+	repo := &fakeFinalityRepo{diplomas: []models.Diploma{
+		// Two leaves of the same Merkle anchor
+		{PolygonTxID: "0xaa", BlockNumber: 100, BlockHash: "0xb100", Finality: models.FinalityConfirming},
+		{PolygonTxID: "0xaa", BlockNumber: 100, BlockHash: "0xb100", Finality: models.FinalityConfirming},
+	}}
+
+	cfg := &config.Config{Blockchain: config.BlockChainConfig{ConfirmationDepth: 3}}
+	svc := services.NewFinalityService(cfg, chain, repo)
+
+	step := func(name string, finality models.Finality, confirmations, block uint64, hash string) {
+		t.Helper()
+		if err := svc.CheckPending(); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		for _, d := range repo.diplomas {
+			if d.Finality != finality || d.Confirmations != confirmations || d.BlockNumber != block || d.BlockHash != hash {
+				t.Fatalf("%s: got %s/%d confirmations in %d %s, want %s/%d in %d %s",
+					name, d.Finality, d.Confirmations, d.BlockNumber, d.BlockHash, finality, confirmations, block, hash)
+			}
+		}
+	}
+
+	step("just mined", models.FinalityConfirming, 1, 100, "0xb100")
+
+	// A reorg drops the block and the transaction with it
+	delete(chain.blocks, "0xaa")
+	step("reorged out", models.FinalityOrphaned, 0, 100, "0xb100")
+
+	// It is mined again in a later block, which is only noticed once the
+	// orphan is due for another lookup
+	chain.head = 101
+	chain.blocks["0xaa"] = fakeChainBlock{101, "0xb101"}
+	step("orphan backs off", models.FinalityOrphaned, 0, 100, "0xb100")
+
+	long := time.Now().Add(-time.Hour)
+	for i := range repo.diplomas {
+		repo.diplomas[i].FinalityCheckedAt = &long
+	}
+	chain.blocks["0xaa"] = fakeChainBlock{101, "0xb101"}
+	step("mined again", models.FinalityConfirming, 1, 101, "0xb101")
+
+	chain.head = 103
+	step("deep enough", models.FinalityFinal, 3, 101, "0xb101")
+
+	// Final diplomas are not checked again
+	delete(chain.blocks, "0xaa")
+	step("after final", models.FinalityFinal, 3, 101, "0xb101")
+}
+
+func TestFinalityFollowsDiplomaNetwork(t *testing.T) {
+
+	// The diploma was anchored on amoy before the switch to polygon, where the
+	// head is far ahead and the transaction does not exist
+	amoy := &fakeNetworks{name: "amoy", fakeChain: &fakeChain{
+		MockBlockchainService: services.NewMockBlockchainService(),
+		head:                  52,
+		blocks:                map[string]fakeChainBlock{"0xaa": {50, "0xb50"}},
+	}}
+	polygon := &fakeNetworks{name: "polygon", fakeChain: &fakeChain{
+		MockBlockchainService: services.NewMockBlockchainService(),
+		head:                  9000,
+		blocks:                map[string]fakeChainBlock{},
+	}}
+	polygon.others = map[string]*fakeNetworks{"amoy": amoy, "polygon": polygon}
+
+	repo := &fakeFinalityRepo{diplomas: []models.Diploma{
+		{Network: "amoy", PolygonTxID: "0xaa", BlockNumber: 50, BlockHash: "0xb50", Finality: models.FinalityConfirming},
+	}}
+
+	cfg := &config.Config{Blockchain: config.BlockChainConfig{ConfirmationDepth: 32}}
+	svc := services.NewFinalityService(cfg, polygon, repo)
+
+	if err := svc.CheckPending(); err != nil {
+		t.Fatal(err)
+	}
+
+	d := repo.diplomas[0]
+	if d.Finality != models.FinalityConfirming || d.Confirmations != 3 {
+		t.Fatalf("got %s with %d confirmations, want confirming with 3 from the amoy head", d.Finality, d.Confirmations)
+	}
+}
+
+func TestFinalityChecksEachTransactionInTurn(t *testing.T) {
+
+	chain := &fakeChain{
+		MockBlockchainService: services.NewMockBlockchainService(),
+		head:                  100,
+		blocks:                map[string]fakeChainBlock{},
+	}
+	// A large Merkle anchor followed by another transaction
+	repo := &fakeFinalityRepo{}
+	for range 600 {
+		repo.diplomas = append(repo.diplomas, models.Diploma{PolygonTxID: "0xaa", BlockNumber: 90, BlockHash: "0xb90", Finality: models.FinalityConfirming})
+	}
+	repo.diplomas = append(repo.diplomas, models.Diploma{PolygonTxID: "0xbb", BlockNumber: 95, BlockHash: "0xb95", Finality: models.FinalityConfirming})
+	chain.blocks["0xaa"] = fakeChainBlock{90, "0xb90"}
+	chain.blocks["0xbb"] = fakeChainBlock{95, "0xb95"}
+
+	cfg := &config.Config{Blockchain: config.BlockChainConfig{ConfirmationDepth: 64}}
+	svc := services.NewFinalityService(cfg, chain, repo)
+
+	for range 3 {
+		if err := svc.CheckPending(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if repo.checks["0xaa"] != 3 || repo.checks["0xbb"] != 3 {
+		t.Fatalf("checks = %v, want each transaction once per run", repo.checks)
+	}
+}
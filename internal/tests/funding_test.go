@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"BlockCertify/internal/config"
+	"BlockCertify/internal/models"
+	apperrors "BlockCertify/internal/pkg/errors"
+	"BlockCertify/internal/services"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestArweaveUploadRefusedWithoutFunds(t *testing.T) {
+
+	var posted bool
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/wallet/") && strings.HasSuffix(r.URL.Path, "/balance"):
+			fmt.Fprint(w, "1000")
+		case strings.HasPrefix(r.URL.Path, "/price/"):
+			fmt.Fprint(w, "5000")
+		default:
+			posted = true
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+		}
+	}))
+	defer gateway.Close()
+
+	gatewayURL, _ := url.Parse(gateway.URL)
+	port, _ := strconv.Atoi(gatewayURL.Port())
+	cfg := &config.Config{Arweave: config.ArweaveConfig{
+		WalletKey: arweaveKeyfile(t),
+		Host:      gatewayURL.Hostname(),
+		Port:      port,
+		Protocol:  "http",
+		Bundling:  config.ArweaveBundlingOff,
+	}}
+
+	repo := &fakeArweaveRepo{
+		uploads:   map[string]*models.ArweaveUpload{},
+		dataItems: map[string]*models.ArweaveDataItem{},
+	}
+	arweave := services.NewArweaveService(cfg, repo)
+
+	file := filepath.Join(t.TempDir(), "diploma.pdf")
+	if err := os.WriteFile(file, []byte("%PDF-1.7 unpaid"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := arweave.Upload(file, strings.Repeat("ab", 32))
+	var appErr *apperrors.AppError
+	if !errors.As(err, &appErr) || appErr.Code != apperrors.ErrInsufficientBalance {
+		t.Fatalf("upload error = %v, want %s", err, apperrors.ErrInsufficientBalance)
+	}
+	if posted || len(repo.uploads) != 0 {
+		t.Error("an upload the wallet cannot pay for was posted")
+	}
+}
+
+// fundedWeave reports a fixed balance and price, in winston.
+type fundedWeave struct {
+	*services.MockArweaveService
+	balance, price int64
+}
+
+func (w *fundedWeave) WalletBalance() (string, *big.Int, error) {
+	return "arweave-wallet", big.NewInt(w.balance), nil
+}
+
+func (w *fundedWeave) Price(int) (*big.Int, error) {
+	return big.NewInt(w.price), nil
+}
+
+// fundedChain reports a fixed backend wallet and diploma cost, in wei.
+type fundedChain struct {
+	*services.MockBlockchainService
+	balance, minBalance, cost int64
+	err                       error
+}
+
+func (c *fundedChain) Wallet() (*services.ChainWallet, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &services.ChainWallet{Balance: big.NewInt(c.balance), MinBalance: big.NewInt(c.minBalance)}, nil
+}
+
+func (c *fundedChain) DiplomaCost() (*big.Int, error) {
+	return big.NewInt(c.cost), nil
+}
+
+func TestFundingStatus(t *testing.T) {
+
+	cfg := &config.Config{
+		Storage: config.StorageConfig{Backend: config.StorageArweave},
+		Arweave: config.ArweaveConfig{Bundling: config.ArweaveBundlingOff},
+		Funding: config.FundingConfig{DocumentSize: 256 << 10, AlertDiplomas: 50, CheckInterval: time.Minute},
+	}
+	weave := &fundedWeave{MockArweaveService: services.NewMockArweaveService(""), balance: 1000, price: 10}
+	chain := &fundedChain{MockBlockchainService: services.NewMockBlockchainService(), balance: 1000, minBalance: 300, cost: 10}
+
+	funding := services.NewFundingService(cfg, weave, chain)
+
+	status := funding.Status()
+	if got := status.Arweave.RemainingDiplomas; got == nil || *got != 100 || status.Arweave.Low {
+		t.Errorf("arweave funding = %+v", status.Arweave)
+	}
+	// MIN_BALANCE is kept back
+	if got := status.Matic.RemainingDiplomas; got == nil || *got != 70 || status.Matic.Low {
+		t.Errorf("matic funding = %+v", status.Matic)
+	}
+	if got := status.RemainingDiplomas; got == nil || *got != 70 || len(status.Alerts) != 0 {
+		t.Errorf("status = %+v", status)
+	}
+
+	// Below the threshold, then dry
+	chain.balance = 500
+	weave.balance = 5
+	status = funding.Status()
+	if !status.Matic.Low || !status.Arweave.Low || *status.RemainingDiplomas != 0 || len(status.Alerts) != 2 {
+		t.Errorf("status = %+v", status)
+	}
+
+	// A bundler pays for Arweave, and an unreachable node leaves the total unknown
+	cfg.Arweave.Bundling = config.ArweaveBundlingBundler
+	chain.err = errors.New("node down")
+	status = services.NewFundingService(cfg, weave, chain).Status()
+	if status.Arweave.PaysDiplomas || status.Arweave.RemainingDiplomas != nil {
+		t.Errorf("arweave funding with a bundler = %+v", status.Arweave)
+	}
+	if !status.Matic.PaysDiplomas || status.Matic.Error == "" || status.RemainingDiplomas != nil || len(status.Alerts) != 0 {
+		t.Errorf("status with the node down = %+v", status)
+	}
+}
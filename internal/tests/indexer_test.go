@@ -0,0 +1,293 @@
+package tests
+
+import (
+	"BlockCertify/internal/config"
+	"BlockCertify/internal/models"
+	"BlockCertify/internal/repositories"
+	"BlockCertify/internal/services"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/gofrs/uuid/v5"
+)
+
+// indexChain serves DiplomaStored events, receipts and stored hashes.
+type indexChain struct {
+	repositories.Ledger
+
+	head     uint64
+	events   []repositories.DiplomaStoredEvent
+	receipts map[common.Hash]*types.Receipt
+	// stored maps a diploma hash to its Arweave TxID
+	stored map[string]string
+	// failFrom makes the range starting at that block fail
+	failFrom uint64
+	filtered [][2]uint64
+}
+
+func (c *indexChain) Network() config.NetworkProfile {
+	return config.NetworkProfile{Name: config.DevNetwork}
+}
+
+func (c *indexChain) BlockNumber() (uint64, error) {
+	return c.head, nil
+}
+
+func (c *indexChain) FilterDiplomaStored(from, to uint64) ([]repositories.DiplomaStoredEvent, error) {
+	if c.failFrom != 0 && from == c.failFrom {
+		return nil, errors.New("node down")
+	}
+	c.filtered = append(c.filtered, [2]uint64{from, to})
+
+	var events []repositories.DiplomaStoredEvent
+	for _, e := range c.events {
+		if e.BlockNumber >= from && e.BlockNumber <= to {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func (c *indexChain) GetTransactionReceipt(txHash common.Hash) (*types.Receipt, error) {
+	receipt, ok := c.receipts[txHash]
+	if !ok {
+		return nil, ethereum.NotFound
+	}
+	return receipt, nil
+}
+
+func (c *indexChain) VerifyDiploma(diplomaHash string) (bool, string, error) {
+	arweaveTxID, ok := c.stored[diplomaHash]
+	return ok, arweaveTxID, nil
+}
+
+// fakeIndexerRepo keeps the cursor, events and diplomas in memory and
+// applies the same filters as the SQL queries.
+type fakeIndexerRepo struct {
+	cursors  map[string]uint64
+	events   []models.DiplomaStoredEvent
+	diplomas []models.Diploma
+}
+
+func (r *fakeIndexerRepo) GetCursor(name string) (uint64, bool, error) {
+	block, ok := r.cursors[name]
+	return block, ok, nil
+}
+
+func (r *fakeIndexerRepo) SaveEvents(name string, events []models.DiplomaStoredEvent, block uint64) error {
+	r.events = append(r.events, events...)
+	r.cursors[name] = block
+	return nil
+}
+
+func (r *fakeIndexerRepo) LinkDiplomas() (int64, error) {
+	var linked int64
+	for i := range r.events {
+		for _, d := range r.diplomas {
+			if r.events[i].DiplomaID == nil && d.Hash == r.events[i].DiplomaHash && d.Network == r.events[i].Network {
+				r.events[i].DiplomaID = &d.ID
+				linked++
+			}
+		}
+	}
+	return linked, nil
+}
+
+func (r *fakeIndexerRepo) CountEvents(network string) (int64, error) {
+	var count int64
+	for _, e := range r.events {
+		if e.Network == network {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *fakeIndexerRepo) GetChainOnly(network string, limit int) ([]models.DiplomaStoredEvent, error) {
+	var events []models.DiplomaStoredEvent
+	for _, e := range r.events {
+		if e.Network == network && e.DiplomaID == nil {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func (r *fakeIndexerRepo) GetDatabaseOnly(network string, fromBlock, toBlock uint64, limit int) ([]models.Diploma, error) {
+	var diplomas []models.Diploma
+	for _, d := range r.diplomas {
+		if d.Network != network || d.PolygonTxID == "" || d.MerkleRoot != "" {
+			continue
+		}
+		if d.BlockNumber != 0 && (d.BlockNumber < fromBlock || d.BlockNumber > toBlock) {
+			continue
+		}
+		indexed := slices.ContainsFunc(r.events, func(e models.DiplomaStoredEvent) bool {
+			return e.DiplomaHash == d.Hash && e.Network == d.Network
+		})
+		if !indexed {
+			diplomas = append(diplomas, d)
+		}
+	}
+	return diplomas, nil
+}
+
+func indexerConfig() *config.Config {
+	return &config.Config{Blockchain: config.BlockChainConfig{
+		ConfirmationDepth: 5,
+		IndexerEnabled:    true,
+		IndexerStartBlock: 100,
+		IndexerBlockRange: 10,
+	}}
+}
+
+func storedEvent(block uint64, hash string) repositories.DiplomaStoredEvent {
+	return repositories.DiplomaStoredEvent{
+		DiplomaHash: hash,
+		ArweaveTxID: "arweave-" + hash,
+		Timestamp:   big.NewInt(1_700_000_000),
+		BlockNumber: block,
+		TxHash:      common.BigToHash(new(big.Int).SetUint64(block)),
+	}
+}
+
+func TestIndexerCursor(t *testing.T) {
+
+	chain := &indexChain{
+		head:   3,
+		events: []repositories.DiplomaStoredEvent{storedEvent(105, "a"), storedEvent(118, "b"), storedEvent(133, "c")},
+	}
+	repo := &fakeIndexerRepo{cursors: map[string]uint64{}}
+	indexer := services.NewIndexerService(indexerConfig(), repositories.LedgersOf(chain), repo)
+	cursor := func() uint64 {
+		block, ok, _ := repo.GetCursor("diploma_stored:" + config.DevNetwork)
+		if !ok {
+			t.Fatal("cursor was not saved")
+		}
+		return block
+	}
+
+	// Fewer blocks than the confirmation depth
+	if n, err := indexer.Sync(); err != nil || n != 0 || len(chain.filtered) != 0 {
+		t.Fatalf("sync below the depth = %d, %v, filtered %v", n, err, chain.filtered)
+	}
+
+	// From the start block up to head - depth + 1, in ranges of 10 blocks
+	chain.head = 124
+	if n, err := indexer.Sync(); err != nil || n != 2 {
+		t.Fatalf("first sync = %d, %v", n, err)
+	}
+	want := [][2]uint64{{100, 109}, {110, 119}, {120, 120}}
+	if !slices.Equal(chain.filtered, want) || cursor() != 120 {
+		t.Fatalf("filtered %v, cursor %d", chain.filtered, cursor())
+	}
+
+	// Nothing new is confirmed yet
+	chain.filtered = nil
+	if n, err := indexer.Sync(); err != nil || n != 0 || len(chain.filtered) != 0 {
+		t.Fatalf("sync without new blocks = %d, %v, filtered %v", n, err, chain.filtered)
+	}
+
+	// A failed range keeps the cursor after the last saved one
+	chain.head = 145
+	chain.failFrom = 131
+	if _, err := indexer.Sync(); err == nil {
+		t.Fatal("sync with a failing node succeeded")
+	}
+	if cursor() != 130 {
+		t.Fatalf("cursor after a failed range = %d, want 130", cursor())
+	}
+
+	chain.failFrom = 0
+	chain.filtered = nil
+	if n, err := indexer.Sync(); err != nil || n != 1 {
+		t.Fatalf("resumed sync = %d, %v", n, err)
+	}
+	want = [][2]uint64{{131, 140}, {141, 141}}
+	if !slices.Equal(chain.filtered, want) || cursor() != 141 || len(repo.events) != 3 {
+		t.Fatalf("filtered %v, cursor %d, events %d", chain.filtered, cursor(), len(repo.events))
+	}
+}
+
+func TestIndexerReport(t *testing.T) {
+
+	chain := &indexChain{
+		head:     124,
+		events:   []repositories.DiplomaStoredEvent{storedEvent(105, "indexed"), storedEvent(112, "unknown")},
+		receipts: map[common.Hash]*types.Receipt{},
+		stored:   map[string]string{"moved": "arweave-moved"},
+	}
+	repo := &fakeIndexerRepo{cursors: map[string]uint64{}}
+
+	diploma := func(hash string, block uint64) models.Diploma {
+		return models.Diploma{
+			ID:          uuid.Must(uuid.NewV7()),
+			PublicID:    "DPL-" + hash,
+			Hash:        hash,
+			ArweaveTxID: "arweave-" + hash,
+			Network:     config.DevNetwork,
+			PolygonTxID: common.BytesToHash([]byte(hash)).Hex(),
+			BlockNumber: block,
+		}
+	}
+	mined := func(hash string, block uint64, status uint64) {
+		chain.receipts[common.BytesToHash([]byte(hash))] = &types.Receipt{
+			Status:      status,
+			BlockNumber: new(big.Int).SetUint64(block),
+		}
+	}
+
+	repo.diplomas = []models.Diploma{
+		diploma("indexed", 105),
+		diploma("missing", 110),
+		diploma("before-start", 50),
+		diploma("merkle", 111),
+		// Anchored before block numbers were recorded
+		diploma("legacy-in-range", 0),
+		diploma("legacy-after-cursor", 0),
+		diploma("legacy-failed", 0),
+		diploma("moved", 0),
+		diploma("dropped", 0),
+	}
+	repo.diplomas[3].MerkleRoot = "0xroot"
+	mined("legacy-in-range", 115, types.ReceiptStatusSuccessful)
+	mined("legacy-after-cursor", 200, types.ReceiptStatusSuccessful)
+	mined("legacy-failed", 300, types.ReceiptStatusFailed)
+
+	indexer := services.NewIndexerService(indexerConfig(), repositories.LedgersOf(chain), repo)
+	if _, err := indexer.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := indexer.GetReport()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.IndexedBlock != 120 || report.IndexedEvents != 2 {
+		t.Errorf("indexed block %d, events %d", report.IndexedBlock, report.IndexedEvents)
+	}
+	if len(report.ChainOnly) != 1 || report.ChainOnly[0].DiplomaHash != "unknown" {
+		t.Errorf("chain only = %+v", report.ChainOnly)
+	}
+
+	got := map[string]uint64{}
+	for _, d := range report.DatabaseOnly {
+		got[d.DiplomaHash] = d.BlockNumber
+	}
+	want := map[string]uint64{
+		"missing":         110,
+		"legacy-in-range": 115,
+		"legacy-failed":   300,
+		"dropped":         0,
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("database only = %v, want %v", got, want)
+	}
+}
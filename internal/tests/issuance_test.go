@@ -0,0 +1,294 @@
+package tests
+
+import (
+	"BlockCertify/internal/dto"
+	"BlockCertify/internal/models"
+	apperrors "BlockCertify/internal/pkg/errors"
+	"BlockCertify/internal/repositories"
+	"BlockCertify/internal/services"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"gorm.io/gorm"
+)
+
+// issuanceChain signs storeDiploma and mines it when told to.
+type issuanceChain struct {
+	*services.MockBlockchainService
+
+	// stored maps a diploma hash on chain to its Arweave TxID
+	stored  map[string]string
+	submits int
+}
+
+func (c *issuanceChain) ServerIssuanceEnabled() bool {
+	return true
+}
+
+func (c *issuanceChain) VerifyDiploma(diplomaHash string) (bool, string, error) {
+	arweaveTxID, ok := c.stored[diplomaHash]
+	return ok, arweaveTxID, nil
+}
+
+func (c *issuanceChain) SubmitDiploma(_, diplomaHash, _ string) (*dto.SubmittedTransaction, error) {
+	if _, ok := c.stored[diplomaHash]; ok {
+		return nil, apperrors.New(apperrors.ErrDiplomaExists, "Diploma already registered in blockchain", nil)
+	}
+	c.submits++
+	return &dto.SubmittedTransaction{
+		Network:         "mock",
+		TransactionHash: fmt.Sprintf("0x%d", c.submits),
+		Signer:          "0xsigner",
+	}, nil
+}
+
+func (c *issuanceChain) ConfirmDiplomaTransaction(txHash, _, _ string) (*dto.BlockchainResult, error) {
+	return &dto.BlockchainResult{
+		Network:         "mock",
+		TransactionHash: txHash,
+		ExplorerURL:     "https://amoy.polygonscan.com/tx/" + txHash,
+		BlockNumber:     42,
+		BlockHash:       "0xblock",
+		GasUsed:         21000,
+		Timestamp:       time.Date(2024, time.June, 30, 12, 0, 0, 0, time.UTC),
+	}, nil
+}
+
+// issuanceOutbox reports the latest storeDiploma transaction per hash.
+type issuanceOutbox struct {
+	services.TransactionManager
+	latest map[string]*models.ChainTransaction
+}
+
+func (o *issuanceOutbox) Latest(kind models.TxKind, reference string) (*models.ChainTransaction, error) {
+	txn, ok := o.latest[reference]
+	if !ok || kind != models.TxKindStoreDiploma {
+		return nil, fmt.Errorf("no %s transaction for %s", kind, reference)
+	}
+	return txn, nil
+}
+
+type fakeIssuanceRepo struct {
+	issuance *models.DiplomaIssuance
+	diploma  *models.Diploma
+}
+
+func (r *fakeIssuanceRepo) GetUnfinished() ([]models.DiplomaIssuance, error) {
+	if r.issuance.Status != models.IssuancePending && r.issuance.Status != models.IssuanceSubmitted {
+		return nil, nil
+	}
+	return []models.DiplomaIssuance{*r.issuance}, nil
+}
+
+func (r *fakeIssuanceRepo) GetByDiplomaID(diplomaID uuid.UUID) (*models.DiplomaIssuance, error) {
+	if diplomaID != r.issuance.DiplomaID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	issuance := *r.issuance
+	return &issuance, nil
+}
+
+func (r *fakeIssuanceRepo) Update(issuance *models.DiplomaIssuance) error {
+	saved := *issuance
+	r.issuance = &saved
+	return nil
+}
+
+func (r *fakeIssuanceRepo) Confirm(issuance *models.DiplomaIssuance, diploma *models.Diploma) error {
+	saved := *issuance
+	r.issuance = &saved
+	anchored := *diploma
+	r.diploma = &anchored
+	return nil
+}
+
+func (r *fakeIssuanceRepo) ResetFailed(diplomaID uuid.UUID) (int64, error) {
+	if diplomaID != r.issuance.DiplomaID || r.issuance.Status != models.IssuanceFailed {
+		return 0, nil
+	}
+	r.issuance.Status = models.IssuancePending
+	r.issuance.Error = ""
+	return 1, nil
+}
+
+type issuanceDiplomas struct {
+	repositories.DiplomaRepository
+	repo *fakeIssuanceRepo
+}
+
+func (r *issuanceDiplomas) GetByID(id uuid.UUID) (*models.Diploma, error) {
+	if id != r.repo.diploma.ID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	diploma := *r.repo.diploma
+	return &diploma, nil
+}
+
+func (r *issuanceDiplomas) GetByDiplomaIDForUniversity(diplomaID string, universityID uuid.UUID) (*models.Diploma, error) {
+	if diplomaID != r.repo.diploma.PublicID || universityID != r.repo.diploma.UniversityID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.GetByID(r.repo.diploma.ID)
+}
+
+type issuanceFixture struct {
+	chain      *issuanceChain
+	outbox     *issuanceOutbox
+	repo       *fakeIssuanceRepo
+	service    services.IssuanceService
+	diploma    models.Diploma
+	university models.Universities
+}
+
+func newIssuanceFixture() *issuanceFixture {
+
+	university := models.Universities{ID: uuid.Must(uuid.NewV7()), YokCode: "YOK-1234"}
+	diploma := models.Diploma{
+		ID:           uuid.Must(uuid.NewV7()),
+		PublicID:     "DPL-ISSUE",
+		UniversityID: university.ID,
+		Hash:         "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+		ArweaveTxID:  "arweave-tx",
+	}
+
+	f := &issuanceFixture{
+		chain:  &issuanceChain{MockBlockchainService: services.NewMockBlockchainService(), stored: map[string]string{}},
+		outbox: &issuanceOutbox{latest: map[string]*models.ChainTransaction{}},
+		repo: &fakeIssuanceRepo{
+			issuance: &models.DiplomaIssuance{
+				ID:           uuid.Must(uuid.NewV7()),
+				DiplomaID:    diploma.ID,
+				UniversityID: university.ID,
+				Status:       models.IssuancePending,
+			},
+			diploma: &diploma,
+		},
+		diploma:    diploma,
+		university: university,
+	}
+	f.service = services.NewIssuanceService(f.chain, f.outbox, f.repo, &issuanceDiplomas{repo: f.repo}, &fakeUniversityRepo{university: university})
+	return f
+}
+
+// send records the outbox transaction for the diploma.
+func (f *issuanceFixture) send(txHash string, status models.TxStatus) {
+	f.outbox.latest[f.diploma.Hash] = &models.ChainTransaction{
+		Network: "mock",
+		Kind:    models.TxKindStoreDiploma,
+		TxHash:  txHash,
+		Signer:  "0xsigner",
+		Status:  status,
+	}
+}
+
+func (f *issuanceFixture) expect(t *testing.T, status models.IssuanceStatus, txHash string, attempts int) {
+	t.Helper()
+	issuance := f.repo.issuance
+	if issuance.Status != status || issuance.TxHash != txHash || issuance.Attempts != attempts {
+		t.Fatalf("issuance = %s %q after %d attempts (%s), want %s %q after %d", issuance.Status, issuance.TxHash, issuance.Attempts, issuance.Error, status, txHash, attempts)
+	}
+}
+
+func TestIssuanceSubmitsAndConfirms(t *testing.T) {
+
+	f := newIssuanceFixture()
+
+	f.service.ProcessUnfinished()
+	f.expect(t, models.IssuanceSubmitted, "0x1", 1)
+	if f.repo.issuance.SubmittedAt == nil || f.repo.issuance.Signer != "0xsigner" {
+		t.Errorf("submitted issuance = %+v", f.repo.issuance)
+	}
+
+	// Still pending, and then replaced by a fee-bumped transaction
+	f.send("0x1", models.TxPending)
+	f.service.ProcessUnfinished()
+	f.expect(t, models.IssuanceSubmitted, "0x1", 1)
+
+	f.send("0x1b", models.TxPending)
+	f.service.ProcessUnfinished()
+	f.expect(t, models.IssuanceSubmitted, "0x1b", 1)
+
+	f.send("0x1b", models.TxMined)
+	f.service.ProcessUnfinished()
+	f.expect(t, models.IssuanceConfirmed, "0x1b", 1)
+
+	issuance, diploma := f.repo.issuance, f.repo.diploma
+	if issuance.BlockNumber != 42 || issuance.ConfirmedAt == nil {
+		t.Errorf("confirmed issuance = %+v", issuance)
+	}
+	if diploma.PolygonTxID != "0x1b" || diploma.BlockNumber != 42 || diploma.Finality != models.FinalityConfirming || diploma.Network != "mock" {
+		t.Errorf("anchored diploma = %+v", diploma)
+	}
+
+	// Confirmed issuances are no longer processed
+	f.service.ProcessUnfinished()
+	if f.chain.submits != 1 {
+		t.Errorf("diploma was submitted %d times", f.chain.submits)
+	}
+
+	if _, err := f.service.Retry(f.diploma.PublicID, f.university.ID); !isAppError(err, apperrors.ErrInvalidRequest) {
+		t.Errorf("retrying a confirmed issuance: %v", err)
+	}
+}
+
+func TestIssuanceRetry(t *testing.T) {
+
+	f := newIssuanceFixture()
+
+	f.service.ProcessUnfinished()
+	f.send("0x1", models.TxFailed)
+	f.service.ProcessUnfinished()
+	f.expect(t, models.IssuanceFailed, "0x1", 1)
+
+	// Retried while nothing is on chain: signed again
+	response, err := f.service.Retry(f.diploma.PublicID, f.university.ID)
+	if err != nil || response.Status != string(models.IssuancePending) {
+		t.Fatalf("retry = %+v, %v", response, err)
+	}
+	f.service.ProcessUnfinished()
+	f.expect(t, models.IssuanceSubmitted, "0x2", 2)
+
+	// The node gave up on the transaction, but it was mined after all
+	f.send("0x2", models.TxFailed)
+	f.service.ProcessUnfinished()
+	f.expect(t, models.IssuanceFailed, "0x2", 2)
+
+	f.send("0x2", models.TxMined)
+	f.chain.stored[f.diploma.Hash] = f.diploma.ArweaveTxID
+	if _, err := f.service.Retry(f.diploma.PublicID, f.university.ID); err != nil {
+		t.Fatal(err)
+	}
+	f.service.ProcessUnfinished()
+	f.expect(t, models.IssuanceConfirmed, "0x2", 2)
+	if f.chain.submits != 2 || f.repo.diploma.PolygonTxID != "0x2" {
+		t.Errorf("submits %d, diploma anchored by %q", f.chain.submits, f.repo.diploma.PolygonTxID)
+	}
+}
+
+func TestIssuanceRetryOfForeignDiploma(t *testing.T) {
+
+	f := newIssuanceFixture()
+	f.repo.issuance.Status = models.IssuanceFailed
+	f.repo.issuance.Attempts = 1
+
+	// Stored with another file: the retry must not adopt it
+	f.chain.stored[f.diploma.Hash] = "other-arweave-tx"
+	f.send("0x1", models.TxMined)
+
+	if _, err := f.service.Retry(f.diploma.PublicID, f.university.ID); err != nil {
+		t.Fatal(err)
+	}
+	f.service.ProcessUnfinished()
+	f.expect(t, models.IssuanceFailed, "", 2)
+	if f.repo.issuance.Error != "Diploma already registered in blockchain" {
+		t.Errorf("error = %q", f.repo.issuance.Error)
+	}
+}
+
+func isAppError(err error, code string) bool {
+	var appErr *apperrors.AppError
+	return errors.As(err, &appErr) && appErr.Code == code
+}
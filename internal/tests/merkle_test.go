@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"BlockCertify/internal/pkg/merkle"
+	"fmt"
+	"testing"
+)
+
+func TestMerkleProofs(t *testing.T) {
+
+	for _, size := range []int{1, 2, 3, 5, 8, 13} {
+		var leaves []merkle.Hash
+		for i := 0; i < size; i++ {
+			leaves = append(leaves, merkle.Leaf(fmt.Sprintf("hash-%d", i), fmt.Sprintf("tx-%d", i)))
+		}
+
+		tree, err := merkle.New(leaves)
+		if err != nil {
+			t.Fatalf("size %d: %v", size, err)
+		}
+
+		for i, leaf := range leaves {
+			proof, err := tree.Proof(i)
+			if err != nil {
+				t.Fatalf("size %d leaf %d: %v", size, i, err)
+			}
+			if !merkle.Verify(leaf, proof, tree.Root()) {
+				t.Errorf("size %d leaf %d: proof does not verify", size, i)
+			}
+		}
+	}
+}
+
+func TestMerkleRejectsForeignLeaf(t *testing.T) {
+
+	leaves := []merkle.Hash{
+		merkle.Leaf("a", "tx-a"),
+		merkle.Leaf("b", "tx-b"),
+		merkle.Leaf("c", "tx-c"),
+	}
+
+	tree, err := merkle.New(leaves)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proof, err := tree.Proof(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Same diploma hash, different Arweave transaction
+	if merkle.Verify(merkle.Leaf("b", "tx-other"), proof, tree.Root()) {
+		t.Error("proof verified a leaf that is not in the tree")
+	}
+}
+
+func TestMerkleParseHash(t *testing.T) {
+
+	leaf := merkle.Leaf("a", "tx-a")
+
+	parsed, err := merkle.ParseHash(leaf.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed != leaf {
+		t.Error("hash did not round trip through Hex")
+	}
+
+	if _, err := merkle.ParseHash("0x1234"); err == nil {
+		t.Error("expected an error for a short hash")
+	}
+}
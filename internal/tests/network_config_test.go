@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"BlockCertify/internal/config"
+	"testing"
+)
+
+// setRequiredEnv sets the variables config.Load refuses to start without.
+func setRequiredEnv(t *testing.T) {
+	t.Helper()
+	for key, value := range map[string]string{
+		"JWT_EXP_HOURS":   "1",
+		"ARWEAVE_KEY":     "arweave_keyfile.json",
+		"PRIVATE_KEY":     "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318",
+		"APP_DB_HOST":     "localhost",
+		"APP_DB_PORT":     "5432",
+		"APP_DB_USERNAME": "test",
+		"APP_DB_PASSWORD": "test",
+		"APP_DB_NAME":     "test",
+	} {
+		t.Setenv(key, value)
+	}
+}
+
+func TestNetworkProfiles(t *testing.T) {
+
+	setRequiredEnv(t)
+	t.Setenv("NETWORK", "polygon")
+	t.Setenv("CONTRACT_ADDRESS", "0x00000000000000000000000000000000000000c2")
+	t.Setenv("CONTRACT_VERSION", "v2")
+	t.Setenv("LEGACY_CONTRACTS", "v1:0x00000000000000000000000000000000000000c1")
+	t.Setenv("LEGACY_NETWORKS", "amoy")
+	t.Setenv("AMOY_CONTRACT_ADDRESS", "0x00000000000000000000000000000000000000a1")
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	active := cfg.Blockchain.ActiveNetwork()
+	if active.Name != "polygon" || active.ChainID != 137 || active.ContractVersion != "v2" {
+		t.Fatalf("unexpected active network %+v", active)
+	}
+	if got := active.TxURL("0xabc"); got != "https://polygonscan.com/tx/0xabc" {
+		t.Fatalf("explorer URL = %s", got)
+	}
+	if len(active.LegacyContracts) != 1 || active.LegacyContracts[0].Version != "v1" {
+		t.Fatalf("unexpected legacy contracts %+v", active.LegacyContracts)
+	}
+
+	amoy, ok := cfg.Blockchain.Networks["amoy"]
+	if !ok {
+		t.Fatal("legacy network amoy is not configured")
+	}
+	if amoy.ChainID != 80002 || amoy.ContractAddress != "0x00000000000000000000000000000000000000a1" || amoy.ContractVersion != "v1" {
+		t.Fatalf("unexpected legacy network %+v", amoy)
+	}
+
+	// Records written before networks were recorded belong to the active one
+	if cfg.Blockchain.UntaggedNetwork != "polygon" {
+		t.Fatalf("untagged records network = %s", cfg.Blockchain.UntaggedNetwork)
+	}
+}
+
+func TestDevNetworkProfile(t *testing.T) {
+
+	setRequiredEnv(t)
+	t.Setenv("NETWORK", "dev")
+	t.Setenv("PRIVATE_KEY", "")
+
+	// No contract address: it is deployed on startup
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !cfg.Blockchain.DevMode() {
+		t.Fatal("NETWORK=dev is not in dev mode")
+	}
+	dev := cfg.Blockchain.ActiveNetwork()
+	if dev.ChainID != 31337 || dev.RPCURL != "http://127.0.0.1:8545" || dev.ContractAddress != "" {
+		t.Fatalf("unexpected dev network %+v", dev)
+	}
+	if got := dev.TxURL("0xabc"); got != "" {
+		t.Fatalf("dev network has no explorer, got %s", got)
+	}
+	if cfg.Blockchain.PrivateKey != config.DevPrivateKey {
+		t.Fatal("dev network does not default to the prefunded dev key")
+	}
+	if cfg.Blockchain.ConfirmationDepth != 1 {
+		t.Fatalf("confirmation depth = %d", cfg.Blockchain.ConfirmationDepth)
+	}
+
+	// Other networks still need a contract address
+	t.Setenv("NETWORK", "amoy")
+	t.Setenv("PRIVATE_KEY", config.DevPrivateKey)
+	if _, err := config.Load(); err == nil {
+		t.Fatal("amoy without a contract address was accepted")
+	}
+}
@@ -0,0 +1,436 @@
+package tests
+
+import (
+	"BlockCertify/internal/config"
+	"BlockCertify/internal/models"
+	"BlockCertify/internal/repositories"
+	"BlockCertify/internal/services"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// nodeError is an error returned by the node itself, as opposed to a
+// failure to reach it.
+type nodeError string
+
+func (e nodeError) Error() string  { return string(e) }
+func (e nodeError) ErrorCode() int { return -32000 }
+
+// fakeLedger signs for real but keeps its mempool and blocks in memory.
+type fakeLedger struct {
+	repositories.Ledger
+
+	mu           sync.Mutex
+	chainID      *big.Int
+	pendingNonce uint64
+	minedNonce   uint64
+	feeCap       *big.Int
+	tipCap       *big.Int
+	// sendErr refuses every broadcast when set
+	sendErr  error
+	sent     []*types.Transaction
+	receipts map[common.Hash]*types.Receipt
+}
+
+func newFakeLedger() *fakeLedger {
+	return &fakeLedger{
+		chainID:  big.NewInt(1337),
+		feeCap:   big.NewInt(30e9),
+		tipCap:   big.NewInt(2e9),
+		receipts: map[common.Hash]*types.Receipt{},
+	}
+}
+
+func (l *fakeLedger) Network() config.NetworkProfile {
+	return config.NetworkProfile{Name: config.DevNetwork, ChainID: int(l.chainID.Int64())}
+}
+
+func (l *fakeLedger) ExplorerTxURL(string) string { return "" }
+
+func (l *fakeLedger) EstimateGas(common.Address, *repositories.ContractCall) (uint64, error) {
+	return 100000, nil
+}
+
+func (l *fakeLedger) GetFeeData() (*big.Int, *big.Int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return new(big.Int).Set(l.feeCap), new(big.Int).Set(l.tipCap), nil
+}
+
+func (l *fakeLedger) PendingNonceAt(common.Address) (uint64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.pendingNonce, nil
+}
+
+func (l *fakeLedger) NonceAt(common.Address) (uint64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.minedNonce, nil
+}
+
+func (l *fakeLedger) SignTransaction(privateKeyHex string, nonce, gas uint64, gasTipCap, gasFeeCap *big.Int, call *repositories.ContractCall) (*types.Transaction, error) {
+	key, err := crypto.HexToECDSA(privateKeyHex)
+	if err != nil {
+		return nil, err
+	}
+	tx := types.NewTx(&types.DynamicFeeTx{
+		ChainID:   l.chainID,
+		Nonce:     nonce,
+		GasTipCap: gasTipCap,
+		GasFeeCap: gasFeeCap,
+		Gas:       gas,
+		To:        &call.To,
+		Value:     big.NewInt(0),
+		Data:      call.Data,
+	})
+	return types.SignTx(tx, types.LatestSignerForChainID(l.chainID), key)
+}
+
+func (l *fakeLedger) SendTransaction(tx *types.Transaction) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.sendErr != nil {
+		return l.sendErr
+	}
+	l.sent = append(l.sent, tx)
+	return nil
+}
+
+func (l *fakeLedger) GetTransactionReceipt(txHash common.Hash) (*types.Receipt, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	receipt, ok := l.receipts[txHash]
+	if !ok {
+		return nil, ethereum.NotFound
+	}
+	return receipt, nil
+}
+
+// mine includes the transaction with txHash in the next block.
+func (l *fakeLedger) mine(txHash string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.minedNonce++
+	l.receipts[common.HexToHash(txHash)] = &types.Receipt{
+		Status:      types.ReceiptStatusSuccessful,
+		BlockNumber: big.NewInt(int64(100 + l.minedNonce)),
+		BlockHash:   common.HexToHash("0xb1"),
+		GasUsed:     21000,
+	}
+}
+
+// fakeOutbox is an in-memory OutboxRepository.
+type fakeOutbox struct {
+	mu     sync.Mutex
+	txns   map[string]*models.ChainTransaction // by ID
+	nonces map[string]uint64                   // next nonce by network/signer
+	seq    int
+	order  map[string]int // insertion order, for created_at ordering
+}
+
+func newFakeOutbox() *fakeOutbox {
+	return &fakeOutbox{
+		txns:   map[string]*models.ChainTransaction{},
+		nonces: map[string]uint64{},
+		order:  map[string]int{},
+	}
+}
+
+func (r *fakeOutbox) put(txn *models.ChainTransaction) {
+	if _, ok := r.order[txn.ID.String()]; !ok {
+		r.seq++
+		r.order[txn.ID.String()] = r.seq
+	}
+	stored := *txn
+	r.txns[txn.ID.String()] = &stored
+}
+
+func (r *fakeOutbox) list(match func(*models.ChainTransaction) bool) []models.ChainTransaction {
+	var txns []models.ChainTransaction
+	for _, txn := range r.txns {
+		if match(txn) {
+			txns = append(txns, *txn)
+		}
+	}
+	sort.Slice(txns, func(i, j int) bool {
+		if txns[i].Nonce != txns[j].Nonce {
+			return txns[i].Nonce < txns[j].Nonce
+		}
+		return r.order[txns[i].ID.String()] < r.order[txns[j].ID.String()]
+	})
+	return txns
+}
+
+func (r *fakeOutbox) Enqueue(txn *models.ChainTransaction, chainNonce uint64, sign func(txn *models.ChainTransaction) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := txn.Network + "/" + txn.Signer
+	txn.Nonce = max(r.nonces[key], chainNonce)
+	if err := sign(txn); err != nil {
+		return err
+	}
+	r.put(txn)
+	r.nonces[key] = txn.Nonce + 1
+	return nil
+}
+
+func (r *fakeOutbox) AddReplacement(replaced, replacement *models.ChainTransaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.put(replacement)
+	replaced.Status = models.TxReplaced
+	replaced.ReplacedBy = &replacement.ID
+	r.put(replaced)
+	return nil
+}
+
+func (r *fakeOutbox) AddRelease(rejected, release *models.ChainTransaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.put(release)
+	rejected.ReplacedBy = &release.ID
+	r.put(rejected)
+	return nil
+}
+
+func (r *fakeOutbox) GetPending() ([]models.ChainTransaction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.list(func(txn *models.ChainTransaction) bool { return txn.Status == models.TxPending }), nil
+}
+
+func (r *fakeOutbox) GetAttempts(network, signer string, nonce uint64) ([]models.ChainTransaction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.list(func(txn *models.ChainTransaction) bool {
+		return txn.Network == network && txn.Signer == signer && txn.Nonce == nonce
+	}), nil
+}
+
+func (r *fakeOutbox) GetByReferences(references []string) ([]models.ChainTransaction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.list(func(txn *models.ChainTransaction) bool {
+		for _, reference := range references {
+			if txn.Reference == reference {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
+
+func (r *fakeOutbox) Update(txn *models.ChainTransaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.put(txn)
+	return nil
+}
+
+func (r *fakeOutbox) Settle(attempts []models.ChainTransaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range attempts {
+		r.put(&attempts[i])
+	}
+	return nil
+}
+
+func newOutbox(t *testing.T, stuckAfter time.Duration) (services.TransactionManager, *fakeLedger, *fakeOutbox, string) {
+	t.Helper()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateKeyHex := hex.EncodeToString(crypto.FromECDSA(key))
+
+	cfg := &config.Config{Blockchain: config.BlockChainConfig{
+		PrivateKey:       privateKeyHex,
+		TxStuckAfter:     stuckAfter,
+		TxFeeBumpPercent: 20,
+		TxMaxFeeGwei:     "1000",
+		TxMaxRejections:  2,
+	}}
+
+	ledger := newFakeLedger()
+	outbox := newFakeOutbox()
+	manager := services.NewTransactionManager(cfg, repositories.LedgersOf(ledger), outbox, nil)
+	return manager, ledger, outbox, privateKeyHex
+}
+
+func storeCall() *repositories.ContractCall {
+	return &repositories.ContractCall{To: common.HexToAddress("0xc0ffee"), Data: []byte{0x01}}
+}
+
+func TestOutboxAllocatesNonces(t *testing.T) {
+
+	manager, ledger, _, key := newOutbox(t, time.Hour)
+
+	// Transactions sent with the key from elsewhere are not reused
+	ledger.pendingNonce = 5
+
+	first, err := manager.Send("", models.TxKindStoreDiploma, "hash-1", key, storeCall())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The node has not seen the first one yet
+	second, err := manager.Send("", models.TxKindStoreDiploma, "hash-2", key, storeCall())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Nonce != 5 || second.Nonce != 6 {
+		t.Fatalf("nonces = %d, %d, want 5, 6", first.Nonce, second.Nonce)
+	}
+	if first.SentAt == nil || len(ledger.sent) != 2 {
+		t.Fatalf("sent %d transactions", len(ledger.sent))
+	}
+
+	if _, err := manager.Wait(first, 0); !errors.Is(err, services.ErrTransactionPending) {
+		t.Fatalf("wait = %v, want %v", err, services.ErrTransactionPending)
+	}
+}
+
+func TestOutboxBumpsStuckTransactions(t *testing.T) {
+
+	manager, ledger, outbox, key := newOutbox(t, time.Nanosecond)
+
+	txn, err := manager.Send("", models.TxKindAnchorRoot, "root-1", key, storeCall())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manager.ProcessPending()
+
+	attempts, _ := outbox.GetAttempts(txn.Network, txn.Signer, txn.Nonce)
+	if len(attempts) != 2 || attempts[0].Status != models.TxReplaced || attempts[1].Status != models.TxPending {
+		t.Fatalf("attempts = %+v", attempts)
+	}
+	// 30 gwei raised by 20%
+	if attempts[1].GasFeeCap != "36000000000" || attempts[1].GasTipCap != "2400000000" {
+		t.Errorf("replacement fees = %s / %s", attempts[1].GasFeeCap, attempts[1].GasTipCap)
+	}
+	if attempts[1].Nonce != txn.Nonce || attempts[1].TxHash == txn.TxHash {
+		t.Errorf("replacement = %+v", attempts[1])
+	}
+
+	ledger.mine(attempts[1].TxHash)
+	manager.ProcessPending()
+
+	mined, err := manager.Wait(txn, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mined.TxHash != attempts[1].TxHash || mined.BlockNumber == 0 {
+		t.Fatalf("mined = %+v", mined)
+	}
+	attempts, _ = outbox.GetAttempts(txn.Network, txn.Signer, txn.Nonce)
+	if attempts[0].Status != models.TxReplaced {
+		t.Errorf("original = %s, want replaced", attempts[0].Status)
+	}
+}
+
+func TestOutboxReleasesRejectedNonce(t *testing.T) {
+
+	manager, ledger, outbox, key := newOutbox(t, time.Hour)
+
+	// Failing to reach the node is not a rejection
+	ledger.sendErr = errors.New("connection refused")
+	txn, err := manager.Send("", models.TxKindStoreDiploma, "hash-1", key, storeCall())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txn.Rejections != 0 {
+		t.Fatalf("rejections = %d", txn.Rejections)
+	}
+
+	ledger.sendErr = nodeError("intrinsic gas too low")
+	manager.ProcessPending()
+	manager.ProcessPending()
+
+	// The second rejection reached TX_MAX_REJECTIONS; the next poll gives up
+	ledger.sendErr = nil
+	manager.ProcessPending()
+
+	attempts, _ := outbox.GetAttempts(txn.Network, txn.Signer, txn.Nonce)
+	if len(attempts) != 2 {
+		t.Fatalf("attempts = %+v", attempts)
+	}
+	rejected, release := attempts[0], attempts[1]
+	if rejected.Status != models.TxFailed || rejected.Rejections != 2 {
+		t.Errorf("rejected = %s after %d rejections", rejected.Status, rejected.Rejections)
+	}
+	if release.Kind != models.TxKindReleaseNonce || release.To != txn.Signer || release.Reference != txn.TxHash || release.SentAt == nil {
+		t.Errorf("release = %+v", release)
+	}
+
+	// Callers learn the transaction failed without waiting for the release
+	if _, err := manager.Wait(txn, 0); err == nil || errors.Is(err, services.ErrTransactionPending) {
+		t.Fatalf("wait = %v, want the rejection", err)
+	}
+
+	ledger.mine(release.TxHash)
+	manager.ProcessPending()
+
+	attempts, _ = outbox.GetAttempts(txn.Network, txn.Signer, txn.Nonce)
+	if attempts[0].Status != models.TxFailed || attempts[1].Status != models.TxMined {
+		t.Errorf("after the release was mined: %s, %s", attempts[0].Status, attempts[1].Status)
+	}
+	if latest, err := manager.Latest(models.TxKindStoreDiploma, "hash-1"); err != nil || latest.Status != models.TxFailed {
+		t.Errorf("latest = %+v, %v", latest, err)
+	}
+
+	next, err := manager.Send("", models.TxKindStoreDiploma, "hash-2", key, storeCall())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.Nonce != txn.Nonce+1 {
+		t.Errorf("next nonce = %d, want %d", next.Nonce, txn.Nonce+1)
+	}
+}
+
+func TestOutboxRejectedReplacementKeepsOriginal(t *testing.T) {
+
+	manager, ledger, outbox, key := newOutbox(t, time.Nanosecond)
+
+	txn, err := manager.Send("", models.TxKindStoreDiploma, "hash-1", key, storeCall())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every replacement is refused
+	ledger.sendErr = nodeError("replacement transaction underpriced")
+	manager.ProcessPending() // replace, rejected once
+	manager.ProcessPending() // rejected twice
+	manager.ProcessPending() // give up on it
+
+	attempts, _ := outbox.GetAttempts(txn.Network, txn.Signer, txn.Nonce)
+	if len(attempts) != 2 || attempts[0].Status != models.TxPending || attempts[1].Status != models.TxFailed {
+		t.Fatalf("attempts = %+v", attempts)
+	}
+	for _, attempt := range attempts {
+		if attempt.Kind == models.TxKindReleaseNonce {
+			t.Fatal("released a nonce the node still holds")
+		}
+	}
+
+	// The next replacement outbids the rejected one
+	ledger.sendErr = nil
+	manager.ProcessPending()
+
+	attempts, _ = outbox.GetAttempts(txn.Network, txn.Signer, txn.Nonce)
+	if len(attempts) != 3 || attempts[2].Status != models.TxPending || attempts[2].GasFeeCap != "43200000000" {
+		t.Fatalf("attempts = %+v", attempts)
+	}
+}
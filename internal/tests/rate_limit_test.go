@@ -0,0 +1,64 @@
+package tests
+
+import (
+	"BlockCertify/internal/config"
+	"BlockCertify/internal/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimitIgnoresForwardedForFromUntrustedClients(t *testing.T) {
+
+	gin.SetMode(gin.TestMode)
+
+	for _, tc := range []struct {
+		name    string
+		proxies string
+		// whether a client rotating X-Forwarded-For escapes the limit
+		spoofable bool
+	}{
+		{"no trusted proxies", "", false},
+		{"behind a trusted proxy", "10.0.0.0/8", true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+
+			setRequiredEnv(t)
+			t.Setenv("CONTRACT_ADDRESS", "0x00000000000000000000000000000000000000c1")
+			t.Setenv("TRUSTED_PROXIES", tc.proxies)
+			cfg, err := config.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r := gin.New()
+			if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+				t.Fatal(err)
+			}
+			r.Use(middleware.NewRateLimiter(1, 1).Limit())
+			r.GET("/public", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			var limited bool
+			for _, forwardedFor := range []string{"203.0.113.1", "203.0.113.2", "203.0.113.3"} {
+				req := httptest.NewRequest(http.MethodGet, "/public", nil)
+				req.RemoteAddr = "10.1.2.3:40000"
+				req.Header.Set("X-Forwarded-For", forwardedFor)
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				limited = limited || w.Code == http.StatusTooManyRequests
+			}
+			if limited == tc.spoofable {
+				t.Errorf("limited = %v with TRUSTED_PROXIES=%q", limited, tc.proxies)
+			}
+		})
+	}
+
+	setRequiredEnv(t)
+	t.Setenv("CONTRACT_ADDRESS", "0x00000000000000000000000000000000000000c1")
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8,nginx")
+	if _, err := config.Load(); err == nil {
+		t.Error("a host name was accepted as a trusted proxy")
+	}
+}
@@ -0,0 +1,285 @@
+package tests
+
+import (
+	"BlockCertify/internal/dto"
+	"BlockCertify/internal/handlers"
+	"BlockCertify/internal/models"
+	"BlockCertify/internal/services"
+	"encoding/csv"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid/v5"
+)
+
+type reconciliationChain struct {
+	*services.MockBlockchainService
+	records map[string]*dto.ChainDiplomaRecord
+}
+
+func (c *reconciliationChain) GetChainRecord(diplomaHash string) (*dto.ChainDiplomaRecord, error) {
+	if record, ok := c.records[diplomaHash]; ok {
+		return record, nil
+	}
+	return &dto.ChainDiplomaRecord{}, nil
+}
+
+// reconciliationArweave serves File-Hash tags by TxID.
+type reconciliationArweave struct {
+	*services.MockArweaveService
+	tags map[string]string
+}
+
+func (a *reconciliationArweave) GetFileHashTag(txID string) (string, error) {
+	if tag, ok := a.tags[txID]; ok {
+		return tag, nil
+	}
+	return "", errors.New("transaction not found")
+}
+
+type fakeReconciliationRepo struct {
+	mu         sync.Mutex
+	job        models.ReconciliationJob
+	mismatches []models.ReconciliationMismatch
+	diplomas   []models.Diploma
+	events     map[string]*models.DiplomaStoredEvent
+	repaired   map[uuid.UUID][]string
+}
+
+func (r *fakeReconciliationRepo) CreateJob(job *models.ReconciliationJob) error {
+	return r.UpdateJob(job)
+}
+
+func (r *fakeReconciliationRepo) UpdateJob(job *models.ReconciliationJob) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.job = *job
+	return nil
+}
+
+func (r *fakeReconciliationRepo) GetJob(uuid.UUID, uuid.UUID) (*models.ReconciliationJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job := r.job
+	job.Items = append([]models.ReconciliationMismatch(nil), r.mismatches...)
+	return &job, nil
+}
+
+func (r *fakeReconciliationRepo) ListJobs(uuid.UUID) ([]models.ReconciliationJob, error) {
+	return nil, nil
+}
+
+func (r *fakeReconciliationRepo) FailRunningJobs(string) (int64, error) {
+	return 0, nil
+}
+
+func (r *fakeReconciliationRepo) AddMismatches(mismatches []models.ReconciliationMismatch) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.mismatches = append(r.mismatches, mismatches...)
+	return nil
+}
+
+// GetDiplomas returns the university's diplomas and, as if their metadata
+// named it, every diploma linked to none.
+func (r *fakeReconciliationRepo) GetDiplomas(universityID uuid.UUID, afterID uuid.UUID, limit int) ([]models.Diploma, error) {
+	var page []models.Diploma
+	for _, d := range r.diplomas {
+		if d.UniversityID != universityID && d.UniversityID != uuid.Nil {
+			continue
+		}
+		if d.ID.String() > afterID.String() && len(page) < limit {
+			page = append(page, d)
+		}
+	}
+	return page, nil
+}
+
+func (r *fakeReconciliationRepo) GetStoredEvent(_, diplomaHash string) (*models.DiplomaStoredEvent, error) {
+	if event, ok := r.events[diplomaHash]; ok {
+		return event, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func (r *fakeReconciliationRepo) RepairDiploma(diploma *models.Diploma, columns ...string) error {
+	r.repaired[diploma.ID] = columns
+	return nil
+}
+
+func TestReconciliationFindsAndRepairsDrift(t *testing.T) {
+
+	healthy := models.Diploma{ID: uuid.Must(uuid.NewV7()), PublicID: "OK", Hash: "h1", ArweaveTxID: "ar1", ArweaveURL: "https://arweave.net/ar1", PolygonTxID: "0x1"}
+	missingTx := models.Diploma{ID: uuid.Must(uuid.NewV7()), PublicID: "NOTX", Hash: "h2", ArweaveTxID: "ar2"}
+	wrongFile := models.Diploma{ID: uuid.Must(uuid.NewV7()), PublicID: "BAD", Hash: "h3", ArweaveTxID: "ar3", ArweaveURL: "https://arweave.net/ar3", PolygonTxID: "0x3"}
+	queued := models.Diploma{ID: uuid.Must(uuid.NewV7()), PublicID: "QUEUED", Hash: "h4", ArweaveTxID: "ar4", ArweaveURL: "https://arweave.net/ar4"}
+
+	chain := &reconciliationChain{
+		MockBlockchainService: services.NewMockBlockchainService(),
+		records: map[string]*dto.ChainDiplomaRecord{
+			"h1": {Exists: true, ArweaveTxID: "ar1", DiplomaHash: "h1", RecordArweaveTxID: "ar1"},
+			"h2": {Exists: true, ArweaveTxID: "ar2", DiplomaHash: "h2", RecordArweaveTxID: "ar2"},
+			"h3": {Exists: true, ArweaveTxID: "ar3", DiplomaHash: "h3", RecordArweaveTxID: "ar3"},
+		},
+	}
+	arweave := &reconciliationArweave{
+		MockArweaveService: services.NewMockArweaveService(""),
+		tags:               map[string]string{"ar1": "h1", "ar2": "h2", "ar3": "other", "ar4": "h4"},
+	}
+	repo := &fakeReconciliationRepo{
+		diplomas: []models.Diploma{healthy, missingTx, wrongFile, queued},
+		events:   map[string]*models.DiplomaStoredEvent{"h2": {TxHash: "0x2", BlockNumber: 42}},
+		repaired: make(map[uuid.UUID][]string),
+	}
+
+	svc := services.NewReconciliationService(services.NewDocumentStores(arweave), chain, repo)
+	started, err := svc.StartJob(dto.ReconciliationRequest{Repair: true}, &models.User{}, uuid.Nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var job *dto.ReconciliationJobResponse
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		job, err = svc.GetJob(started.JobID, uuid.Nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Status != string(models.ReconciliationRunning) {
+			break
+		}
+	}
+
+	if job.Status != string(models.ReconciliationCompleted) || job.Checked != 4 {
+		t.Fatalf("job = %+v", job)
+	}
+
+	found := make(map[string]dto.ReconciliationMismatchResponse)
+	for _, item := range job.Items {
+		found[item.DiplomaID+"/"+item.Kind] = item
+	}
+	if len(found) != 3 {
+		t.Fatalf("mismatches = %+v", job.Items)
+	}
+
+	if m := found["NOTX/"+string(models.MismatchPolygonTxMissing)]; !m.Repaired || m.Actual != "0x2" {
+		t.Errorf("missing tx: %+v", m)
+	}
+	if m := found["NOTX/"+string(models.MismatchArweaveURLMissing)]; !m.Repaired || m.Actual != "https://arweave.net/ar2" {
+		t.Errorf("missing Arweave URL: %+v", m)
+	}
+	if m := found["BAD/"+string(models.MismatchArweaveHash)]; m.Actual != "other" || m.Repaired {
+		t.Errorf("wrong file: %+v", m)
+	}
+	if job.Repaired != 2 || len(repo.repaired[missingTx.ID]) == 0 {
+		t.Errorf("repaired = %d, columns = %v", job.Repaired, repo.repaired)
+	}
+}
+
+func TestReconciliationAuditsUnlinkedDiplomas(t *testing.T) {
+
+	universityID := uuid.Must(uuid.NewV7())
+	linked := models.Diploma{ID: uuid.Must(uuid.NewV7()), PublicID: "LINKED", UniversityID: universityID, Hash: "h1", ArweaveTxID: "ar1", ArweaveURL: "https://arweave.net/ar1", PolygonTxID: "0x1"}
+	unlinked := models.Diploma{ID: uuid.Must(uuid.NewV7()), PublicID: "UNLINKED", Hash: "h2", ArweaveTxID: "ar2", ArweaveURL: "https://arweave.net/ar2", PolygonTxID: "0x2"}
+	other := models.Diploma{ID: uuid.Must(uuid.NewV7()), PublicID: "OTHER", UniversityID: uuid.Must(uuid.NewV7()), Hash: "h3"}
+
+	chain := &reconciliationChain{
+		MockBlockchainService: services.NewMockBlockchainService(),
+		records: map[string]*dto.ChainDiplomaRecord{
+			"h1": {Exists: true, ArweaveTxID: "ar1", DiplomaHash: "h1", RecordArweaveTxID: "ar1"},
+			"h2": {Exists: true, ArweaveTxID: "ar2", DiplomaHash: "h2", RecordArweaveTxID: "ar2"},
+		},
+	}
+	arweave := &reconciliationArweave{
+		MockArweaveService: services.NewMockArweaveService(""),
+		tags:               map[string]string{"ar1": "h1", "ar2": "h2"},
+	}
+	repo := &fakeReconciliationRepo{
+		diplomas: []models.Diploma{linked, unlinked, other},
+		repaired: make(map[uuid.UUID][]string),
+	}
+
+	svc := services.NewReconciliationService(services.NewDocumentStores(arweave), chain, repo)
+	started, err := svc.StartJob(dto.ReconciliationRequest{Repair: true}, &models.User{}, universityID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var job *dto.ReconciliationJobResponse
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		job, err = svc.GetJob(started.JobID, universityID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Status != string(models.ReconciliationRunning) {
+			break
+		}
+	}
+
+	if job.Status != string(models.ReconciliationCompleted) || job.Checked != 2 {
+		t.Fatalf("job = %+v", job)
+	}
+	if len(job.Items) != 1 {
+		t.Fatalf("mismatches = %+v", job.Items)
+	}
+	if m := job.Items[0]; m.DiplomaID != "UNLINKED" || m.Kind != string(models.MismatchUniversityMissing) || !m.Repaired || m.Actual != universityID.String() {
+		t.Errorf("unlinked diploma: %+v", m)
+	}
+	if columns := repo.repaired[unlinked.ID]; len(columns) != 1 || columns[0] != "UniversityID" {
+		t.Errorf("repaired columns = %v", columns)
+	}
+}
+
+// reconciliationReport serves a finished job.
+type reconciliationReport struct {
+	services.ReconciliationService
+	job dto.ReconciliationJobResponse
+}
+
+func (r *reconciliationReport) GetJob(string, uuid.UUID) (*dto.ReconciliationJobResponse, error) {
+	return &r.job, nil
+}
+
+func TestReconciliationCSVEscapesFormulas(t *testing.T) {
+
+	gin.SetMode(gin.TestMode)
+
+	service := &reconciliationReport{job: dto.ReconciliationJobResponse{
+		JobID: "job",
+		Items: []dto.ReconciliationMismatchResponse{{
+			DiplomaID: "=HYPERLINK(\"http://evil\")",
+			Kind:      string(models.MismatchChainArweave),
+			Expected:  "+1",
+			Actual:    "-2",
+			Details:   "@SUM(A1)",
+		}},
+	}}
+
+	r := gin.New()
+	r.GET("/reconciliation/:jobId/report", func(c *gin.Context) {
+		c.Set("universityID", uuid.Must(uuid.NewV7()))
+	}, handlers.NewReconciliationHandler(service).DownloadReport)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/reconciliation/job/report", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("rows = %v", rows)
+	}
+	want := []string{"'=HYPERLINK(\"http://evil\")", "", string(models.MismatchChainArweave), "'+1", "'-2", "'@SUM(A1)", "false"}
+	if strings.Join(rows[1], "|") != strings.Join(want, "|") {
+		t.Errorf("row = %q, want %q", rows[1], want)
+	}
+}
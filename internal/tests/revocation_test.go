@@ -0,0 +1,194 @@
+package tests
+
+import (
+	"BlockCertify/internal/config"
+	"BlockCertify/internal/dto"
+	"BlockCertify/internal/helper"
+	"BlockCertify/internal/models"
+	apperrors "BlockCertify/internal/pkg/errors"
+	"BlockCertify/internal/repositories"
+	"BlockCertify/internal/services"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/gofrs/uuid/v5"
+	"gorm.io/gorm"
+)
+
+// memoryDiplomas keeps diplomas in memory and lists them newest first, like
+// the repository.
+type memoryDiplomas struct {
+	repositories.DiplomaRepository
+	diplomas []*models.Diploma
+	filter   repositories.DiplomaFilter // of the last listing
+}
+
+func (r *memoryDiplomas) add(universityID uuid.UUID, owner string) *models.Diploma {
+	id := uuid.Must(uuid.NewV7())
+	diploma := &models.Diploma{
+		ID:           id,
+		PublicID:     helper.GenerateDiplomaPublicIDFromUUID(id),
+		UniversityID: universityID,
+		Owner:        owner,
+		PolygonTxID:  "0x" + id.String(),
+		Network:      "mock",
+	}
+	r.diplomas = append(r.diplomas, diploma)
+	return diploma
+}
+
+func (r *memoryDiplomas) GetByDiplomaID(diplomaID string) (*models.Diploma, error) {
+	for _, diploma := range r.diplomas {
+		if diploma.PublicID == diplomaID {
+			return diploma, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryDiplomas) GetByHash(hash string) (*models.Diploma, error) {
+	for _, diploma := range r.diplomas {
+		if diploma.Hash == hash {
+			return diploma, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryDiplomas) GetByDiplomaIDForUniversity(diplomaID string, universityID uuid.UUID) (*models.Diploma, error) {
+	diploma, err := r.GetByDiplomaID(diplomaID)
+	if err != nil || diploma.UniversityID != universityID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return diploma, nil
+}
+
+func (r *memoryDiplomas) CreateRevocation(revocation *models.DiplomaRevocation) error {
+	for _, diploma := range r.diplomas {
+		if diploma.ID == revocation.DiplomaID {
+			diploma.Revocation = revocation
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (r *memoryDiplomas) ListDiplomas(filter repositories.DiplomaFilter) ([]models.Diploma, int64, error) {
+	r.filter = filter
+
+	var matching []models.Diploma
+	for _, diploma := range r.diplomas {
+		if diploma.UniversityID == filter.UniversityID {
+			matching = append(matching, *diploma)
+		}
+	}
+	sort.Slice(matching, func(i, j int) bool {
+		return matching[i].ID.String() > matching[j].ID.String()
+	})
+
+	var page []models.Diploma
+	for _, diploma := range matching {
+		if filter.Cursor != uuid.Nil && diploma.ID.String() >= filter.Cursor.String() {
+			continue
+		}
+		if len(page) < filter.Limit {
+			page = append(page, diploma)
+		}
+	}
+	return page, int64(len(matching)), nil
+}
+
+// revokingChain anchors revocations, or fails to with err.
+type revokingChain struct {
+	*services.MockBlockchainService
+	revoked []string
+	err     error
+}
+
+func (c *revokingChain) RevocationOnChainEnabled() bool {
+	return true
+}
+
+func (c *revokingChain) RevokeDiploma(diplomaHash, reason string) (*dto.BlockchainResult, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	c.revoked = append(c.revoked, diplomaHash)
+	return &dto.BlockchainResult{TransactionHash: "0xrevoked-" + diplomaHash}, nil
+}
+
+func (c *revokingChain) ForNetwork(string) (services.BlockchainService, error) {
+	return c, nil
+}
+
+func TestDiplomaRevocation(t *testing.T) {
+
+	universityID := uuid.Must(uuid.NewV7())
+	repo := &memoryDiplomas{}
+	diploma := repo.add(universityID, "Ayşe Yılmaz")
+	diploma.Hash = "revoked-hash"
+	unanchored := repo.add(universityID, "Mehmet Kaya")
+	unanchored.Hash = "unanchored-hash"
+	kept := repo.add(universityID, "Elif Demir")
+
+	chain := &revokingChain{MockBlockchainService: services.NewMockBlockchainService()}
+	documents := services.NewDocumentStores(services.NewLocalStore(config.LocalStorageConfig{Dir: t.TempDir()}))
+	diplomas := services.NewDiplomaService(documents, chain, repo, nil)
+	registrar := &models.User{ID: uuid.Must(uuid.NewV7()), FirstName: "Zeynep", LastName: "Arslan"}
+
+	if _, err := diplomas.Revoke(diploma.PublicID, dto.RevokeDiplomaRequest{Reason: "  "}, registrar, universityID); !isAppError(err, apperrors.ErrInvalidRequest) {
+		t.Errorf("revoking without a reason: %v", err)
+	}
+	if _, err := diplomas.Revoke(diploma.PublicID, dto.RevokeDiplomaRequest{Reason: "issued in error"}, registrar, uuid.Must(uuid.NewV7())); !isAppError(err, apperrors.ErrDiplomaNotFound) {
+		t.Errorf("revoking another university's diploma: %v", err)
+	}
+
+	// Nothing is recorded when the revocation cannot be anchored
+	chain.err = apperrors.New(apperrors.ErrBlockchainFailed, "Failed to revoke", errors.New("node unreachable"))
+	if _, err := diplomas.Revoke(unanchored.PublicID, dto.RevokeDiplomaRequest{Reason: "issued in error"}, registrar, universityID); !isAppError(err, apperrors.ErrBlockchainFailed) {
+		t.Errorf("revoking with the chain down: %v", err)
+	}
+	if unanchored.Revocation != nil {
+		t.Errorf("revocation recorded without its transaction: %+v", unanchored.Revocation)
+	}
+	chain.err = nil
+
+	revoked, err := diplomas.Revoke(diploma.PublicID, dto.RevokeDiplomaRequest{Reason: " issued in error "}, registrar, universityID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revoked.Status != string(models.DiplomaStatusRevoked) || revoked.Reason != "issued in error" || revoked.RevokedBy != "Zeynep Arslan" || revoked.PolygonTxHash != "0xrevoked-revoked-hash" {
+		t.Errorf("revocation = %+v", revoked)
+	}
+	if r := diploma.Revocation; r == nil || r.RevokedBy != registrar.ID || r.PolygonTxID != revoked.PolygonTxHash {
+		t.Errorf("recorded revocation = %+v", r)
+	}
+	if len(chain.revoked) != 1 || chain.revoked[0] != "revoked-hash" {
+		t.Errorf("revoked on-chain: %v", chain.revoked)
+	}
+
+	if _, err := diplomas.Revoke(diploma.PublicID, dto.RevokeDiplomaRequest{Reason: "twice"}, registrar, universityID); !isAppError(err, apperrors.ErrDiplomaRevoked) {
+		t.Errorf("revoking twice: %v", err)
+	}
+
+	verified, err := diplomas.Verify(dto.VerifyDiplomaRequest{DiplomaID: diploma.PublicID}, universityID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verified.Verified || verified.Status != string(models.DiplomaStatusRevoked) || verified.RevocationReason != "issued in error" || verified.RevokedAt == "" {
+		t.Errorf("verification of a revoked diploma = %+v", verified)
+	}
+
+	records, err := diplomas.GetDiplomaRecords(dto.DiplomaRecordQuery{}, universityID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses := map[string]string{}
+	for _, item := range records.Items {
+		statuses[item.DiplomaID] = item.Status
+	}
+	if statuses[diploma.PublicID] != string(models.DiplomaStatusRevoked) || statuses[kept.PublicID] != string(models.DiplomaStatusValid) || statuses[unanchored.PublicID] != string(models.DiplomaStatusValid) {
+		t.Errorf("record statuses = %v", statuses)
+	}
+}
@@ -0,0 +1,190 @@
+package tests
+
+import (
+	"BlockCertify/internal/config"
+	"BlockCertify/internal/models"
+	apperrors "BlockCertify/internal/pkg/errors"
+	"BlockCertify/internal/pkg/merkle"
+	"BlockCertify/internal/repositories"
+	"BlockCertify/internal/services"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+)
+
+// TestSimulatedChainIssuance deploys each contract version from the
+// committed build on an in-memory chain, stores a diploma and reads it back,
+// checks that only issuers can store, that a revoked diploma no longer
+// verifies on v2 and that v1 refuses revocation and anchoring. It needs no
+// node:
+//
+//	go test ./internal/tests -run SimulatedChain
+func TestSimulatedChainIssuance(t *testing.T) {
+
+	owner, err := repositories.SignerAddress(config.DevPrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outsiderKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	outsider := crypto.PubkeyToAddress(outsiderKey.PublicKey)
+
+	backend, chainID := newSimulatedBackend(t, owner, outsider)
+
+	// Only v2 has an issuer registry; v1 lets its deployer alone store
+	outsiderRevert := map[string]string{
+		"v1": "Only the issuer can store",
+		"v2": "Only registered issuers",
+	}
+
+	for _, version := range []string{"v1", "v2"} {
+		t.Run(version, func(t *testing.T) {
+
+			network := config.NetworkProfile{
+				Name:            config.DevNetwork,
+				ChainID:         chainID,
+				ContractVersion: version,
+			}
+
+			address, err := repositories.DeployContractWithClient(backend.Client(), network, "../../contracts/build", config.DevPrivateKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			network.ContractAddress = address.Hex()
+
+			ledger, err := repositories.NewContractRepositoryWithClient(network, "", backend.Client())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			checkIssuance(t, ledger, config.DevPrivateKey)
+
+			call, err := ledger.PackStoreDiploma(strings.Repeat("ab", 32), "outsider-arweave-tx")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := ledger.EstimateGas(outsider, call); err == nil || !strings.Contains(err.Error(), outsiderRevert[version]) {
+				t.Fatalf("outsider storing a diploma: %v", err)
+			}
+
+			if version == "v1" {
+				checkV1Unsupported(t, ledger)
+				return
+			}
+
+			call, err = ledger.PackRevokeDiploma(devDiplomaHash, "issued in error")
+			if err != nil {
+				t.Fatal(err)
+			}
+			mineCall(t, ledger, config.DevPrivateKey, call)
+
+			valid, _, err := ledger.VerifyDiploma(devDiplomaHash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if valid {
+				t.Error("a revoked diploma still verifies")
+			}
+			record, err := ledger.GetDiplomaRecord(devDiplomaHash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !record.Revoked || record.ArweaveTxID == "" {
+				t.Errorf("record of the revoked diploma = %+v", record)
+			}
+		})
+	}
+}
+
+// newSimulatedBackend starts an in-memory chain funding accounts, mined
+// every 50ms so deployments and transactions that wait for their receipt
+// go through. It returns the chain ID.
+func newSimulatedBackend(t *testing.T, accounts ...common.Address) (*simulated.Backend, int) {
+	t.Helper()
+
+	funds := new(big.Int).Exp(big.NewInt(10), big.NewInt(20), nil)
+	alloc := types.GenesisAlloc{}
+	for _, account := range accounts {
+		alloc[account] = types.Account{Balance: funds}
+	}
+	backend := simulated.NewBackend(alloc)
+	t.Cleanup(func() { backend.Close() })
+
+	done := make(chan struct{})
+	t.Cleanup(func() { close(done) })
+	go func() {
+		ticker := time.NewTicker(50 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				backend.Commit()
+			}
+		}
+	}()
+
+	chainID, err := backend.Client().ChainID(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return backend, int(chainID.Int64())
+}
+
+// checkV1Unsupported checks that the legacy contract refuses revocation and
+// Merkle anchoring before anything is sent, and has no roots to look up.
+func checkV1Unsupported(t *testing.T, ledger *repositories.ContractRepository) {
+	t.Helper()
+
+	var unsupported *repositories.UnsupportedError
+	if _, err := ledger.PackRevokeDiploma(devDiplomaHash, "issued in error"); !errors.As(err, &unsupported) {
+		t.Errorf("revoking on v1: %v", err)
+	}
+	if _, err := ledger.PackAnchorRoot([32]byte{1}, 2); !errors.As(err, &unsupported) {
+		t.Errorf("anchoring on v1: %v", err)
+	}
+	timestamp, err := ledger.GetRootTimestamp([32]byte{1})
+	if err != nil || timestamp.Sign() != 0 {
+		t.Errorf("root timestamp on v1 = %v, %v", timestamp, err)
+	}
+
+	// The service refuses both before they reach the outbox
+	outbox := &unsentOutbox{t: t}
+	cfg := &config.Config{}
+	cfg.Blockchain.PrivateKey = config.DevPrivateKey
+	cfg.Blockchain.MinBalance = "0"
+	blockchain := services.NewBlockChainService(cfg, repositories.LedgersOf(ledger), outbox)
+
+	if _, err := blockchain.RevokeDiploma(devDiplomaHash, "issued in error"); !isAppError(err, apperrors.ErrNotSupported) {
+		t.Errorf("revoking through the service on v1: %v", err)
+	}
+	if _, err := blockchain.AnchorMerkleRoot(merkle.Hash{1}, 2); !isAppError(err, apperrors.ErrNotSupported) {
+		t.Errorf("anchoring through the service on v1: %v", err)
+	}
+}
+
+// unsentOutbox fails the test when anything is queued.
+type unsentOutbox struct {
+	services.TransactionManager
+	t *testing.T
+}
+
+func (o *unsentOutbox) Latest(kind models.TxKind, reference string) (*models.ChainTransaction, error) {
+	return nil, fmt.Errorf("no %s transaction for %s", kind, reference)
+}
+
+func (o *unsentOutbox) Send(network string, kind models.TxKind, reference, _ string, _ *repositories.ContractCall) (*models.ChainTransaction, error) {
+	o.t.Errorf("%s %s was queued on %s", kind, reference, network)
+	return nil, fmt.Errorf("unexpected %s transaction", kind)
+}
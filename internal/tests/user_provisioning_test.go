@@ -0,0 +1,194 @@
+package tests
+
+import (
+	"BlockCertify/internal/dto"
+	"BlockCertify/internal/models"
+	apperrors "BlockCertify/internal/pkg/errors"
+	"BlockCertify/internal/repositories"
+	"BlockCertify/internal/security"
+	"BlockCertify/internal/services"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"gorm.io/gorm"
+)
+
+// fakeUserRepo adds users to fakeInviteRepo, so registrations go through.
+type fakeUserRepo struct {
+	fakeInviteRepo
+	users  []models.User
+	admins []models.Admin
+}
+
+func newFakeUserRepo() *fakeUserRepo {
+	return &fakeUserRepo{fakeInviteRepo: fakeInviteRepo{invites: map[string]*models.AdminInvite{}}}
+}
+
+func (r *fakeUserRepo) FindByEmail(email string) (*models.User, error) {
+	for i := range r.users {
+		if r.users[i].Email == email {
+			return &r.users[i], nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeUserRepo) Exists(email string) (bool, error) {
+	_, err := r.FindByEmail(email)
+	return err == nil, nil
+}
+
+func (r *fakeUserRepo) Create(user *models.User) error {
+	r.users = append(r.users, *user)
+	return nil
+}
+
+func (r *fakeUserRepo) HasRole(role models.UserRole) (bool, error) {
+	for _, user := range r.users {
+		if user.Role == role {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeUserRepo) CreateInvited(inviteID uuid.UUID, usedAt time.Time, user *models.User, admin *models.Admin) (bool, error) {
+	for _, invite := range r.invites {
+		if invite.ID != inviteID {
+			continue
+		}
+		if invite.UsedAt != nil {
+			return false, nil
+		}
+		invite.UsedAt = &usedAt
+		r.users = append(r.users, *user)
+		if admin != nil {
+			r.admins = append(r.admins, *admin)
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// fakeStudentDiplomas holds diplomas of one university, looked up by public ID.
+type fakeStudentDiplomas struct {
+	repositories.DiplomaRepository
+	universityID uuid.UUID
+	diplomas     map[string]*models.Diploma
+}
+
+func (r *fakeStudentDiplomas) GetByDiplomaIDForUniversity(diplomaID string, universityID uuid.UUID) (*models.Diploma, error) {
+	diploma, ok := r.diplomas[diplomaID]
+	if !ok || universityID != r.universityID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return diploma, nil
+}
+
+func TestSuperadminBootstrap(t *testing.T) {
+
+	repo := newFakeUserRepo()
+	users := services.NewUserService(repo, security.NewJWTHelper("test-secret", 1), nil, nil)
+
+	if err := users.EnsureSuperadmin("", ""); err != nil || len(repo.users) != 0 {
+		t.Fatalf("without SUPERADMIN_EMAIL: %v, users = %d", err, len(repo.users))
+	}
+	if err := users.EnsureSuperadmin("root@blockcertify.io", "short"); err == nil {
+		t.Error("created a superadmin with a short password")
+	}
+
+	repo.users = append(repo.users, models.User{ID: uuid.Must(uuid.NewV7()), Email: "admin@university.edu", Role: models.RoleAdmin})
+	if err := users.EnsureSuperadmin("admin@university.edu", "securepass123"); err == nil {
+		t.Error("an admin's account was taken over as superadmin")
+	}
+
+	if err := users.EnsureSuperadmin("root@blockcertify.io", "securepass123"); err != nil {
+		t.Fatal(err)
+	}
+	login, err := users.Login(dto.LoginRequest{Email: "root@blockcertify.io", Password: "securepass123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if login.Role != string(models.RoleSuperAdmin) || login.Token == "" {
+		t.Errorf("login = %+v", login)
+	}
+
+	// Only the first superadmin is seeded; a changed email creates no other
+	if err := users.EnsureSuperadmin("other@blockcertify.io", "securepass123"); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.users) != 2 {
+		t.Errorf("users = %+v", repo.users)
+	}
+}
+
+func TestStudentProvisioning(t *testing.T) {
+
+	university := models.Universities{ID: uuid.Must(uuid.NewV7())}
+	repo := newFakeUserRepo()
+	diplomas := &fakeStudentDiplomas{
+		universityID: university.ID,
+		diplomas: map[string]*models.Diploma{
+			"DIP-1":        {MetaData: models.DiplomaMetaData{Email: "Ayse.Yilmaz@student.edu"}},
+			"DIP-NO-EMAIL": {},
+		},
+	}
+	users := services.NewUserService(repo, security.NewJWTHelper("test-secret", 1), &fakeUniversityRepo{university: university}, diplomas)
+	admin := &models.User{ID: uuid.Must(uuid.NewV7()), Email: "admin@university.edu"}
+
+	if _, err := users.CreateStudentInvite("DIP-1", uuid.Must(uuid.NewV7()), admin); !isAppError(err, apperrors.ErrDiplomaNotFound) {
+		t.Errorf("invite for another university's diploma: %v", err)
+	}
+	if _, err := users.CreateStudentInvite("DIP-NO-EMAIL", university.ID, admin); !isAppError(err, apperrors.ErrInvalidRequest) {
+		t.Errorf("invite for a diploma without email: %v", err)
+	}
+
+	invite, err := users.CreateStudentInvite("DIP-1", university.ID, admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if invite.Email != "Ayse.Yilmaz@student.edu" || invite.UniversityID != university.ID.String() {
+		t.Fatalf("invite = %+v", invite)
+	}
+	adminInvite, err := users.CreateInvite(university.ID.String(), dto.AdminInviteRequest{Email: "ayse.yilmaz@student.edu"}, admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(token string) dto.RegisterRequest {
+		return dto.RegisterRequest{
+			FirstName:   "Ayşe",
+			LastName:    "Yılmaz",
+			Email:       "ayse.yilmaz@student.edu",
+			Password:    "securepass123",
+			InviteToken: token,
+		}
+	}
+
+	// Each invite only opens the account of its own role
+	if err := users.Register(request(invite.Token)); !isAppError(err, apperrors.ErrInvalidInvite) {
+		t.Errorf("admin registration with a student invite: %v", err)
+	}
+	if err := users.RegisterStudent(request(adminInvite.Token)); !isAppError(err, apperrors.ErrInvalidInvite) {
+		t.Errorf("student registration with an admin invite: %v", err)
+	}
+
+	if err := users.RegisterStudent(request(invite.Token)); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.users) != 1 || len(repo.admins) != 0 {
+		t.Fatalf("users = %+v, admins = %+v", repo.users, repo.admins)
+	}
+	// The account carries the diploma's email, which its diplomas are found by
+	if student := repo.users[0]; student.Role != models.RoleStudent || student.Email != "Ayse.Yilmaz@student.edu" {
+		t.Errorf("student = %+v", student)
+	}
+
+	if err := users.RegisterStudent(request(invite.Token)); !isAppError(err, apperrors.ErrInvalidInvite) {
+		t.Errorf("invite used twice: %v", err)
+	}
+	if _, err := users.CreateStudentInvite("DIP-1", university.ID, admin); !isAppError(err, apperrors.ErrUserExists) {
+		t.Errorf("invite for a graduate who already has an account: %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+package tests
+
+import (
+	"BlockCertify/internal/config"
+	"BlockCertify/internal/dto"
+	"BlockCertify/internal/handlers"
+	"BlockCertify/internal/models"
+	"BlockCertify/internal/services"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid/v5"
+)
+
+// registeredChain knows the Arweave TxID stored for each diploma hash.
+type registeredChain struct {
+	*services.MockBlockchainService
+	stored map[string]string
+}
+
+func (c *registeredChain) VerifyDiploma(diplomaHash string) (bool, string, error) {
+	arweaveTxID, ok := c.stored[diplomaHash]
+	return ok, arweaveTxID, nil
+}
+
+func hashPDF(pdf []byte) string {
+	sum := sha256.Sum256(pdf)
+	return hex.EncodeToString(sum[:])
+}
+
+// postDiplomaFile posts file as the diploma field of a multipart form.
+func postDiplomaFile(r http.Handler, field, filename string, file []byte) *httptest.ResponseRecorder {
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile(field, filename)
+	part.Write(file)
+	form.Close()
+
+	request := httptest.NewRequest(http.MethodPost, "/verify/file", &body)
+	request.Header.Set("Content-Type", form.FormDataContentType())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, request)
+	return w
+}
+
+func TestVerifyDiplomaFile(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	pdf := func(name string) []byte { return []byte("%PDF-1.7 " + name) }
+	universityID := uuid.Must(uuid.NewV7())
+	repo := &memoryDiplomas{}
+	record := func(name, arweaveTxID string) *models.Diploma {
+		diploma := repo.add(universityID, name)
+		diploma.Hash = hashPDF(pdf(name))
+		diploma.ArweaveTxID = arweaveTxID
+		return diploma
+	}
+	record("matching", "ar-matching")
+	record("mismatching", "ar-mismatching")
+	record("unanchored", "ar-unanchored")
+	record("revoked", "ar-revoked").Revocation = &models.DiplomaRevocation{Reason: "issued in error", RevokedAt: time.Now()}
+
+	chain := &registeredChain{
+		MockBlockchainService: services.NewMockBlockchainService(),
+		stored: map[string]string{
+			hashPDF(pdf("matching")):    "ar-matching",
+			hashPDF(pdf("mismatching")): "ar-elsewhere",
+			hashPDF(pdf("chain-only")):  "ar-chain-only",
+			hashPDF(pdf("revoked")):     "ar-revoked",
+		},
+	}
+	documents := services.NewDocumentStores(services.NewLocalStore(config.LocalStorageConfig{Dir: t.TempDir()}))
+	handler := handlers.NewDiplomaHandler(services.NewDiplomaService(documents, chain, repo, nil))
+
+	r := gin.New()
+	r.POST("/verify/file", handler.VerifyFile)
+
+	cases := []struct {
+		name       string
+		verified   bool
+		agreement  string
+		mismatches string
+	}{
+		{"matching", true, dto.AgreementMatch, "[]"},
+		{"mismatching", false, dto.AgreementMismatch, "[arweaveTxID]"},
+		{"chain-only", true, dto.AgreementOnChainOnly, "[]"},
+		{"unanchored", false, dto.AgreementOffChainOnly, "[]"},
+		{"revoked", false, dto.AgreementMatch, "[]"},
+		{"unknown", false, dto.AgreementNotFound, "[]"},
+	}
+	for _, tc := range cases {
+		w := postDiplomaFile(r, "diploma", tc.name+".pdf", pdf(tc.name))
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: status = %d: %s", tc.name, w.Code, w.Body)
+			continue
+		}
+
+		var response dto.VerifyFileResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		if response.DiplomaHash != hashPDF(pdf(tc.name)) {
+			t.Errorf("%s: hashed as %s", tc.name, response.DiplomaHash)
+		}
+		mismatches := fmt.Sprint(response.Mismatches)
+		if response.Mismatches == nil {
+			mismatches = "[]"
+		}
+		if response.Verified != tc.verified || response.Agreement != tc.agreement || mismatches != tc.mismatches {
+			t.Errorf("%s: verified %v, agreement %s, mismatches %s", tc.name, response.Verified, response.Agreement, mismatches)
+		}
+	}
+
+	// Only a PDF under the diploma field is accepted
+	if w := postDiplomaFile(r, "diploma", "matching.txt", pdf("matching")); w.Code != http.StatusBadRequest {
+		t.Errorf("text file: status = %d", w.Code)
+	}
+	if w := postDiplomaFile(r, "file", "matching.pdf", pdf("matching")); w.Code != http.StatusBadRequest {
+		t.Errorf("missing diploma field: status = %d", w.Code)
+	}
+}